@@ -0,0 +1,62 @@
+// Package authorization はクラス内のロールと権限の対応を一元的に管理します。
+// ロールを直接比較せず、Can でロールが権限を持っているかを確認してください。
+package authorization
+
+import "github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+
+// Permission はクラス内で行える操作
+type Permission string
+
+const (
+	ClassRead        Permission = "class.read"
+	ClassManage      Permission = "class.manage"
+	BoardRead        Permission = "board.read"
+	BoardWrite       Permission = "board.write"
+	ScheduleRead     Permission = "schedule.read"
+	ScheduleWrite    Permission = "schedule.write"
+	AttendanceRead   Permission = "attendance.read"
+	AttendanceManage Permission = "attendance.manage"
+	MembersRead      Permission = "members.read"
+	MembersApprove   Permission = "members.approve"
+	MembersManage    Permission = "members.manage"
+	ChatRead         Permission = "chat.read"
+	ChatWrite        Permission = "chat.write"
+	APIKeysManage    Permission = "api_keys.manage"
+	AuditRead        Permission = "audit.read"
+)
+
+// matrix はロールごとの権限
+var matrix = map[models.Role][]Permission{
+	models.RoleAdmin: {
+		ClassRead, ClassManage, BoardRead, BoardWrite, ScheduleRead, ScheduleWrite,
+		AttendanceRead, AttendanceManage, MembersRead, MembersApprove, MembersManage,
+		ChatRead, ChatWrite, APIKeysManage, AuditRead,
+	},
+	models.RoleAssistant: {
+		ClassRead, BoardRead, BoardWrite, ScheduleRead, ScheduleWrite,
+		AttendanceRead, AttendanceManage, MembersRead, MembersApprove,
+		ChatRead, ChatWrite,
+	},
+	models.RoleUser: {
+		ClassRead, BoardRead, ScheduleRead, MembersRead, ChatRead, ChatWrite,
+	},
+	// 申請中、招待中のユーザーは参加するクラスの情報のみを参照できる
+	models.RoleApplicant: {ClassRead},
+	models.RoleInvite:    {ClassRead},
+	models.RoleBlacklist: {},
+}
+
+// Can はロールが権限を持っているかを返します。クラスに所属していない場合 (空文字) は常にfalseです。
+func Can(role models.Role, permission Permission) bool {
+	for _, p := range matrix[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions はロールが持つ権限を返します。
+func Permissions(role models.Role) []Permission {
+	return append([]Permission(nil), matrix[role]...)
+}
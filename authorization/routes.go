@@ -0,0 +1,150 @@
+package authorization
+
+// SourceKind はリクエストのどこからクラスIDを取得するか
+type SourceKind int
+
+const (
+	// NoClass はクラスに属さないルート
+	NoClass SourceKind = iota
+	// FromParam はパスパラメータ
+	FromParam
+	// FromQuery はクエリパラメータ
+	FromQuery
+	// FromForm はフォームの値
+	FromForm
+	// FromJSON はJSONボディのフィールド (オブジェクトまたはオブジェクトの配列)
+	FromJSON
+	// FromResource はパスパラメータのIDが指すリソース (掲示板、スケジュール、出欠) のクラス
+	FromResource
+)
+
+// Resource はクラスに属するリソース
+type Resource string
+
+const (
+	ResourceClassBoard    Resource = "class_board"
+	ResourceClassSchedule Resource = "class_schedule"
+	ResourceAttendance    Resource = "attendance"
+)
+
+// ClassSource はクラスIDの取得元
+type ClassSource struct {
+	Kind     SourceKind
+	Name     string
+	Resource Resource
+}
+
+// Param, Query, Form, JSON, ResourceParam はクラスIDの取得元を生成します。
+func Param(name string) ClassSource { return ClassSource{Kind: FromParam, Name: name} }
+func Query(name string) ClassSource { return ClassSource{Kind: FromQuery, Name: name} }
+func Form(name string) ClassSource  { return ClassSource{Kind: FromForm, Name: name} }
+func JSON(name string) ClassSource  { return ClassSource{Kind: FromJSON, Name: name} }
+func ResourceParam(resource Resource, name string) ClassSource {
+	return ClassSource{Kind: FromResource, Name: name, Resource: resource}
+}
+
+// Rule はルートに必要な権限
+type Rule struct {
+	Permission Permission
+	Class      ClassSource
+	// Self を指定した場合、このパスパラメータが認証されたユーザー本人であれば権限を確認しない
+	Self string
+}
+
+// Routes はクラスに関するルートと必要な権限の一覧です。キーは "METHOD フルパス" です。
+// ClassPermissionMiddleware を使用するグループのルートは全てここに登録する必要があり、
+// 登録されていないルートは拒否されます。クラスに属さないルートは Rule{} で登録します。
+var Routes = map[string]Rule{
+	// 掲示板
+	"GET /api/gin/cb":                 {BoardRead, Query("cid"), ""},
+	"GET /api/gin/cb/:id":             {BoardRead, ResourceParam(ResourceClassBoard, "id"), ""},
+	"GET /api/gin/cb/announced":       {BoardRead, Query("cid"), ""},
+	"POST /api/gin/cb":                {BoardWrite, Form("cid"), ""},
+	"PATCH /api/gin/cb/:id/:cid/:uid": {BoardWrite, ResourceParam(ResourceClassBoard, "id"), ""},
+	"DELETE /api/gin/cb/:id":          {BoardWrite, ResourceParam(ResourceClassBoard, "id"), ""},
+	"GET /api/gin/cb/subscribe":       {BoardRead, Query("cid"), ""},
+	"GET /api/gin/cb/search":          {BoardRead, Query("cid"), ""},
+
+	// スケジュール
+	"GET /api/gin/cs":        {ScheduleRead, Query("cid"), ""},
+	"GET /api/gin/cs/:id":    {ScheduleRead, ResourceParam(ResourceClassSchedule, "id"), ""},
+	"POST /api/gin/cs":       {ScheduleWrite, JSON("cid"), ""},
+	"PATCH /api/gin/cs/:id":  {ScheduleWrite, ResourceParam(ResourceClassSchedule, "id"), ""},
+	"DELETE /api/gin/cs/:id": {ScheduleWrite, ResourceParam(ResourceClassSchedule, "id"), ""},
+	"GET /api/gin/cs/live":   {ScheduleRead, Query("cid"), ""},
+	"GET /api/gin/cs/date":   {ScheduleRead, Query("cid"), ""},
+
+	// 出欠
+	"POST /api/gin/attendances":                     {AttendanceManage, JSON("cid"), ""},
+	"GET /api/gin/attendances/class/:classId":       {AttendanceRead, Param("classId"), ""},
+	"GET /api/gin/attendances/schedule/:scheduleId": {AttendanceRead, ResourceParam(ResourceClassSchedule, "scheduleId"), ""},
+	"DELETE /api/gin/attendances/:id":               {AttendanceManage, ResourceParam(ResourceAttendance, "id"), ""},
+
+	// クラス
+	"GET /api/gin/cl/:cid":                 {ClassRead, Param("cid"), ""},
+	"POST /api/gin/cl/create":              {},
+	"PATCH /api/gin/cl/:uid/:cid":          {ClassManage, Param("cid"), ""},
+	"DELETE /api/gin/cl/:uid/:cid":         {ClassManage, Param("cid"), ""},
+	"GET /api/gin/cl/:cid/audit":           {AuditRead, Param("cid"), ""},
+	"POST /api/gin/cl/:cid/transfer":       {ClassManage, Param("cid"), ""},
+	"POST /api/gin/cl/:cid/members/import": {MembersManage, Param("cid"), ""},
+	"GET /api/gin/cl/:cid/members/export":  {MembersRead, Param("cid"), ""},
+
+	// メンバー
+	"GET /api/gin/cu/class/:cid/members":             {MembersRead, Param("cid"), ""},
+	"GET /api/gin/cu/class/:cid/applicants":          {MembersApprove, Param("cid"), ""},
+	"GET /api/gin/cu/class/:cid/waitlist":            {MembersApprove, Param("cid"), ""},
+	"POST /api/gin/cu/class/:cid/applicants/approve": {MembersApprove, Param("cid"), ""},
+	"POST /api/gin/cu/class/:cid/applicants/reject":  {MembersApprove, Param("cid"), ""},
+	"GET /api/gin/cu/class/:cid/bans":                {MembersManage, Param("cid"), ""},
+	"POST /api/gin/cu/class/:cid/bans":               {MembersManage, Param("cid"), ""},
+	"DELETE /api/gin/cu/class/:cid/bans/:uid":        {MembersManage, Param("cid"), ""},
+	"GET /api/gin/cu/:uid/:cid/info":                 {MembersRead, Param("cid"), "uid"},
+	"GET /api/gin/cu/:uid/classes":                   {},
+	"GET /api/gin/cu/:uid/favorite-classes":          {},
+	"GET /api/gin/cu/:uid/classes/by-role":           {},
+	"PATCH /api/gin/cu/:uid/:cid/role/:roleName":     {MembersApprove, Param("cid"), ""},
+	"PATCH /api/gin/cu/:uid/:cid/toggle-favorite":    {},
+	"PUT /api/gin/cu/:uid/:cid/:rename":              {MembersManage, Param("cid"), "uid"},
+	"DELETE /api/gin/cu/:uid/:cid/remove":            {MembersManage, Param("cid"), "uid"},
+	"GET /api/gin/cu/:uid/classes/search":            {},
+
+	// チャット (ルームIDはスケジュールID)
+	"POST /api/gin/chat/create-room/:scheduleId":    {ScheduleWrite, ResourceParam(ResourceClassSchedule, "scheduleId"), ""},
+	"GET /api/gin/chat/room/:scheduleId/:userId":    {ChatRead, ResourceParam(ResourceClassSchedule, "scheduleId"), ""},
+	"POST /api/gin/chat/room/:scheduleId":           {ChatWrite, ResourceParam(ResourceClassSchedule, "scheduleId"), ""},
+	"DELETE /api/gin/chat/room/:scheduleId":         {ScheduleWrite, ResourceParam(ResourceClassSchedule, "scheduleId"), ""},
+	"GET /api/gin/chat/stream/:scheduleId":          {ChatRead, ResourceParam(ResourceClassSchedule, "scheduleId"), ""},
+	"GET /api/gin/chat/messages/:roomid":            {ChatRead, ResourceParam(ResourceClassSchedule, "roomid"), ""},
+	"POST /api/gin/chat/dm/:senderId/:receiverId":   {},
+	"GET /api/gin/chat/dm/:senderId/:receiverId":    {},
+	"DELETE /api/gin/chat/dm/:senderId/:receiverId": {},
+
+	// クラスのAPIキー
+	"GET /api/gin/api-keys/class/:cid":        {APIKeysManage, Param("cid"), ""},
+	"POST /api/gin/api-keys/class/:cid":       {APIKeysManage, Param("cid"), ""},
+	"PATCH /api/gin/api-keys/class/:cid/:id":  {APIKeysManage, Param("cid"), ""},
+	"DELETE /api/gin/api-keys/class/:cid/:id": {APIKeysManage, Param("cid"), ""},
+
+	// クラスコードの管理
+	"GET /api/gin/cc/class/:cid/codes":                 {MembersManage, Param("cid"), ""},
+	"POST /api/gin/cc/class/:cid/codes":                {MembersManage, Param("cid"), ""},
+	"POST /api/gin/cc/class/:cid/codes/:id/regenerate": {MembersManage, Param("cid"), ""},
+	"DELETE /api/gin/cc/class/:cid/codes/:id":          {MembersManage, Param("cid"), ""},
+	"PUT /api/gin/cc/class/:cid/codes/:id/secret":      {MembersManage, Param("cid"), ""},
+
+	// 承諾と辞退は招待されたユーザー自身の操作のため、サービスで招待を確認する
+	"GET /api/gin/invitations/class/:cid":           {MembersManage, Param("cid"), ""},
+	"POST /api/gin/invitations/class/:cid":          {MembersManage, Param("cid"), ""},
+	"POST /api/gin/invitations/class/:cid/links":    {MembersManage, Param("cid"), ""},
+	"DELETE /api/gin/invitations/class/:cid/:id":    {MembersManage, Param("cid"), ""},
+	"POST /api/gin/invitations/class/:cid/accept":   {},
+	"POST /api/gin/invitations/class/:cid/decline":  {},
+	"POST /api/gin/invitations/links/:token/accept": {},
+}
+
+// RuleFor はルートに必要な権限を返します。
+func RuleFor(method string, fullPath string) (Rule, bool) {
+	rule, ok := Routes[method+" "+fullPath]
+	return rule, ok
+}
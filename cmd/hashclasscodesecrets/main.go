@@ -0,0 +1,39 @@
+// hashclasscodesecrets は平文で保存されているクラスコードのシークレットを bcrypt のハッシュに置き換えるコマンドです。
+//
+// 移行手順:
+//  1. ハッシュに対応したサーバーをデプロイする (移行前の平文のシークレットもそのまま検証できます)
+//  2. go run ./cmd/hashclasscodesecrets で既存のシークレットをハッシュにする
+//
+// 既にハッシュのシークレットは変更しないため、繰り返し実行できます。
+package main
+
+import (
+	"log"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/migration"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("環境変数ファイルが読み込めませんでした。")
+	}
+
+	db, err := migration.InitDB()
+	if err != nil {
+		log.Fatalf("データベースの初期化に失敗しました: %v", err)
+	}
+	// ハッシュが入るようにシークレットの列だけを広げる (他のスキーマの変更は起動時の RUN_MIGRATIONS で行う)
+	if err := db.Migrator().AlterColumn(&models.ClassCode{}, "Secret"); err != nil {
+		log.Fatalf("シークレットの列の変更に失敗しました: %v", err)
+	}
+
+	migrated, err := services.HashClassCodeSecrets(repositories.NewClassCodeRepository(db))
+	if err != nil {
+		log.Fatalf("シークレットのハッシュ化に失敗しました (%d 件は完了): %v", migrated, err)
+	}
+	log.Printf("%d 件のクラスコードのシークレットをハッシュにしました", migrated)
+}
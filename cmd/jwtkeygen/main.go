@@ -0,0 +1,35 @@
+// jwtkeygen はJWT署名用の新しい秘密鍵を生成するコマンドです。
+//
+// 鍵のローテーション手順:
+//  1. go run ./cmd/jwtkeygen -alg RS256 -out $JWT_KEYS_DIR/<新しいkid>.pem で鍵を追加する
+//  2. 全てのレプリカに鍵が配布され、/.well-known/jwks.json に公開された後で JWT_ACTIVE_KID を新しいkidに切り替える
+//  3. 古い鍵で署名されたリフレッシュトークンが期限切れになる (7日) まで古い鍵を残し、その後削除する
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+)
+
+func main() {
+	alg := flag.String("alg", services.SigningAlgRS256, "signing algorithm (RS256 or EdDSA)")
+	out := flag.String("out", "", "output PEM file path")
+	flag.Parse()
+
+	if *out == "" {
+		log.Fatal("-out を指定してください")
+	}
+
+	pemBytes, err := services.GenerateSigningKeyPEM(*alg)
+	if err != nil {
+		log.Fatalf("鍵の生成に失敗しました: %v", err)
+	}
+
+	if err := os.WriteFile(*out, pemBytes, 0600); err != nil {
+		log.Fatalf("鍵の書き込みに失敗しました: %v", err)
+	}
+	log.Printf("%s の鍵を %s に書き込みました", *alg, *out)
+}
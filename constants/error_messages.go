@@ -2,27 +2,58 @@ package constants
 
 // クライアントエラー関連のエラーメッセージ
 const (
-	InvalidRequest       = "無効なリクエストです"          // 400 Bad Request
-	BadRequestMessage    = "リクエストが不正です"          // 400 Bad Request
-	ErrNoFileHeaderJP    = "ファイルヘッダが提供されていません"   // 400 Bad Request
-	ErrFileSizeJP        = "ファイルサイズが10MBを超えています" // 400 Bad Request
-	ErrMimeTypeJP        = "ファイルタイプが画像ではありません"   // 400 Bad Request
-	ErrNoDateJP          = "日付が提供されていません"        // 400 Bad Request
-	ErrInvalidInput      = "無効な入力です"             // 400 Bad Request
-	ErrNoUserID          = "ユーザーIDが提供されていません"    // 400 Bad Request
-	RefreshTokenRequired = "refresh_tokenが必要です"  // 400 Bad Request
-	AuthCodeRequired     = "authCodeが必要です"       // 400 Bad Request
+	InvalidRequest       = "無効なリクエストです"                    // 400 Bad Request
+	BadRequestMessage    = "リクエストが不正です"                    // 400 Bad Request
+	ErrNoFileHeaderJP    = "ファイルヘッダが提供されていません"             // 400 Bad Request
+	ErrFileSizeJP        = "ファイルサイズが10MBを超えています"           // 400 Bad Request
+	ErrMimeTypeJP        = "ファイルタイプが画像ではありません"             // 400 Bad Request
+	ErrNoDateJP          = "日付が提供されていません"                  // 400 Bad Request
+	ErrInvalidInput      = "無効な入力です"                       // 400 Bad Request
+	ErrNoUserID          = "ユーザーIDが提供されていません"              // 400 Bad Request
+	RefreshTokenRequired = "refresh_tokenが必要です"            // 400 Bad Request
+	AuthCodeRequired     = "authCodeが必要です"                 // 400 Bad Request
+	InvalidOAuthState    = "stateが無効または期限切れです"             // 400 Bad Request
+	OAuthCodeExchange    = "認可コードの検証に失敗しました"               // 400 Bad Request
+	SameUserMerge        = "同じユーザーは統合できません"                // 400 Bad Request
+	InvalidRole          = "無効なロールです"                      // 400 Bad Request
+	InvalidAPIKeyScope   = "APIキーのスコープが無効です"               // 400 Bad Request
+	InvalidOwner         = "オーナーにできないユーザーです"               // 400 Bad Request
+	InvalidExpiry        = "有効期限は未来の日時にしてください"             // 400 Bad Request
+	InvalidCSV           = "CSVの形式が正しくありません"               // 400 Bad Request
+	ErrCSVFileSize       = "CSVファイルのサイズが1MBを超えています"        // 400 Bad Request
+	TooManyImportRows    = "一度に追加できるのは1000行までです"           // 400 Bad Request
+	InvalidImportUser    = "ユーザーIDまたはプロバイダのアカウントを指定してください" // 400 Bad Request
+	DuplicateImportRow   = "同じユーザーが複数の行に含まれています"           // 400 Bad Request
+	NicknameTooLong      = "ニックネームは50文字以内にしてください"          // 400 Bad Request
+	InvalidCursor        = "カーソルが無効です"                     // 400 Bad Request
+	InvalidSecret        = "シークレットは20文字以内にしてください"          // 400 Bad Request
 )
 
 // 認証関連のエラーメッセージ
 const (
-	Unauthorized          = "認証に失敗しました"          // 401 Unauthorized
-	SecretMismatch        = "シークレットが一致しません"      // 401 Unauthorized
-	CodeNotFound          = "コードが見つかりません"        // 404 Not Found
-	ClassNotFound         = "クラスが見つかりません"        // 404 Not Found
-	ApplyingClassNotFound = "申請中のクラスが見つかりません"    // 404 Not Found
-	UserNotFound          = "ユーザーが見つかりません"       // 404 Not Found
-	UserNClassNotFound    = "ユーザーまたはクラスが見つかりません" // 404 Not Found
+	Unauthorized          = "認証に失敗しました"                     // 401 Unauthorized
+	InvalidRefreshToken   = "リフレッシュトークンが無効です"               // 401 Unauthorized
+	InvalidIDToken        = "IDトークンの検証に失敗しました"              // 401 Unauthorized
+	InvalidAPIKey         = "APIキーが無効または期限切れです"             // 401 Unauthorized
+	APIKeyScopeDenied     = "APIキーにこの操作のスコープがありません"         // 403 Forbidden
+	Forbidden             = "この操作を行う権限がありません"               // 403 Forbidden
+	SecretMismatch        = "シークレットが一致しません"                 // 401 Unauthorized
+	CodeNotFound          = "コードが見つかりません"                   // 404 Not Found
+	ResourceNotFound      = "リソースが見つかりません"                  // 404 Not Found
+	ClassNotFound         = "クラスが見つかりません"                   // 404 Not Found
+	ApplyingClassNotFound = "申請中のクラスが見つかりません"               // 404 Not Found
+	InvitationNotFound    = "招待が見つからないか、使用済みまたは期限切れです"      // 404 Not Found
+	UserNotFound          = "ユーザーが見つかりません"                  // 404 Not Found
+	UserNClassNotFound    = "ユーザーまたはクラスが見つかりません"            // 404 Not Found
+	AuthProviderNotFound  = "ログインプロバイダが見つかりません"             // 404 Not Found
+	IdentityAlreadyLinked = "このアカウントは既に他のユーザーに紐づいています"      // 409 Conflict
+	LastIdentity          = "最後のアカウントの紐づけは解除できません"          // 409 Conflict
+	LastAdmin             = "クラスには少なくとも1人の管理者が必要です"         // 409 Conflict
+	BanOwner              = "クラスのオーナーは利用停止にできません"           // 409 Conflict
+	AlreadyMember         = "既にクラスに所属しています"                 // 409 Conflict
+	BannedUser            = "クラスで利用停止中のユーザーです"              // 409 Conflict
+	ClassFull             = "クラスの定員に達しています"                 // 409 Conflict
+	TooManyRequests       = "リクエストが多すぎます。しばらくしてから再度お試しください" // 429 Too Many Requests
 )
 
 // サーバーエラー&データベース関連のエラーメッセージ
@@ -50,4 +81,17 @@ const (
 	CreateOrUpdateSuccess   = "作成または更新に成功しました"    // 200 OK
 	DeleteSuccess           = "削除に成功しました"         // 200 OK
 	MessageSent             = "メッセージが送信されました"     // 200 OK
+	LogoutSuccess           = "ログアウトしました"         // 200 OK
+	TokensRevoked           = "全てのトークンが失効されました"   // 200 OK
+	IdentityUnlinked        = "アカウントの紐づけを解除しました"  // 200 OK
+	SessionRevoked          = "セッションを失効させました"     // 200 OK
+	UsersMerged             = "アカウントを統合しました"      // 200 OK
+	OwnershipTransferred    = "クラスのオーナーを変更しました"   // 200 OK
+	ApplicationsApproved    = "参加申請を承認しました"       // 200 OK
+	ApplicationsRejected    = "参加申請を却下しました"       // 200 OK
+	InvitationAccepted      = "招待を承諾しました"         // 200 OK
+	InvitationDeclined      = "招待を辞退しました"         // 200 OK
+	InvitationRevoked       = "招待を取り消しました"        // 200 OK
+	BanLifted               = "利用停止を解除しました"       // 200 OK
+	ClassCodeRevoked        = "クラスコードを無効にしました"    // 200 OK
 )
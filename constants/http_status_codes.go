@@ -22,6 +22,7 @@ const (
 	StatusNotFound         = 404 // Not Found
 	StatusMethodNotAllowed = 405 // Method Not Allowed
 	StatusConflict         = 409 // Conflict
+	StatusTooManyRequests  = 429 // Too Many Requests
 
 	/*
 		サーバーエラー ステータスコード
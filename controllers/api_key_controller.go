@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyController APIキーを管理するコントローラ
+type APIKeyController struct {
+	Service services.APIKeyService
+}
+
+// NewAPIKeyController APIKeyControllerを生成
+func NewAPIKeyController(service services.APIKeyService) *APIKeyController {
+	return &APIKeyController{Service: service}
+}
+
+// CreateAPIKey godoc
+// @Summary APIキーの発行
+// @Description サービス全体 (サービス管理者のみ)、またはクラス (クラス管理者のみ) のAPIキーを発行します。キーは発行時にのみ返されます。スコープは attendance:read, attendance:write, schedule:read, schedule:write, board:read, board:write です。
+// @Tags API Key
+// @Accept json
+// @Produce json
+// @Param cid path int false "クラスID (クラスのキーの場合)"
+// @Param request body dto.CreateAPIKeyRequest true "キーの名前、スコープ、有効期限"
+// @Success 201 {object} dto.CreatedAPIKeyDTO "発行したAPIキー"
+// @Failure 400 {object} map[string]interface{} "リクエストまたはスコープが不正な場合のエラー"
+// @Failure 403 {object} map[string]interface{} "管理者ではない場合のエラー"
+// @Router /api-keys [post]
+// @Router /api-keys/class/{cid} [post]
+// @Security Bearer
+func (controller *APIKeyController) CreateAPIKey(c *gin.Context) {
+	cid, ok := apiKeyClassParam(c)
+	if !ok {
+		return
+	}
+
+	var request dto.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	key, err := controller.Service.Create(c.GetUint("userID"), cid, request)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusCreated, key)
+}
+
+// ListAPIKeys godoc
+// @Summary APIキーの一覧
+// @Description サービス全体、またはクラスのAPIキーを返します。キーそのものは返されません。
+// @Tags API Key
+// @Produce json
+// @Param cid path int false "クラスID (クラスのキーの場合)"
+// @Success 200 {array} dto.APIKeyDTO "APIキーの一覧"
+// @Failure 403 {object} map[string]interface{} "管理者ではない場合のエラー"
+// @Router /api-keys [get]
+// @Router /api-keys/class/{cid} [get]
+// @Security Bearer
+func (controller *APIKeyController) ListAPIKeys(c *gin.Context) {
+	cid, ok := apiKeyClassParam(c)
+	if !ok {
+		return
+	}
+
+	keys, err := controller.Service.List(c.GetUint("userID"), cid)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, keys)
+}
+
+// UpdateAPIKey godoc
+// @Summary APIキーの更新
+// @Description APIキーの名前、スコープ、有効期限を更新します。
+// @Tags API Key
+// @Accept json
+// @Produce json
+// @Param cid path int false "クラスID (クラスのキーの場合)"
+// @Param id path int true "APIキーID"
+// @Param request body dto.UpdateAPIKeyRequest true "更新する項目"
+// @Success 200 {object} dto.APIKeyDTO "更新したAPIキー"
+// @Failure 400 {object} map[string]interface{} "リクエストまたはスコープが不正な場合のエラー"
+// @Failure 403 {object} map[string]interface{} "管理者ではない場合のエラー"
+// @Failure 404 {object} map[string]interface{} "APIキーが見つからない場合のエラー"
+// @Router /api-keys/{id} [patch]
+// @Router /api-keys/class/{cid}/{id} [patch]
+// @Security Bearer
+func (controller *APIKeyController) UpdateAPIKey(c *gin.Context) {
+	cid, ok := apiKeyClassParam(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	var request dto.UpdateAPIKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	key, err := controller.Service.Update(c.GetUint("userID"), cid, uint(id), request)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, key)
+}
+
+// DeleteAPIKey godoc
+// @Summary APIキーの削除
+// @Description APIキーを削除します。削除したキーは直ちに使用できなくなります。
+// @Tags API Key
+// @Produce json
+// @Param cid path int false "クラスID (クラスのキーの場合)"
+// @Param id path int true "APIキーID"
+// @Success 200 {object} map[string]interface{} "削除に成功しました"
+// @Failure 403 {object} map[string]interface{} "管理者ではない場合のエラー"
+// @Failure 404 {object} map[string]interface{} "APIキーが見つからない場合のエラー"
+// @Router /api-keys/{id} [delete]
+// @Router /api-keys/class/{cid}/{id} [delete]
+// @Security Bearer
+func (controller *APIKeyController) DeleteAPIKey(c *gin.Context) {
+	cid, ok := apiKeyClassParam(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	if err := controller.Service.Delete(c.GetUint("userID"), cid, uint(id)); err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, constants.DeleteSuccess)
+}
+
+// apiKeyClassParam はパスのクラスIDを返します。サービス全体のキーのルートではnilを返します。
+func apiKeyClassParam(c *gin.Context) (*uint, bool) {
+	if c.Param("cid") == "" {
+		return nil, true
+	}
+	cid, err := strconv.ParseUint(c.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return nil, false
+	}
+	id := uint(cid)
+	return &id, true
+}
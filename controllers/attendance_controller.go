@@ -8,6 +8,7 @@ import (
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 type ErrorResponse struct {
@@ -56,7 +57,8 @@ func NewAttendanceController(service services.AttendanceService) *AttendanceCont
 // @Router /attendances [post]
 func (ac *AttendanceController) CreateOrUpdateAttendances(ctx *gin.Context) {
 	var attendances []AttendanceInput
-	if err := ctx.ShouldBindJSON(&attendances); err != nil {
+	// 権限の確認に使用したボディを読み込む
+	if err := ctx.ShouldBindBodyWith(&attendances, binding.JSON); err != nil {
 		log.Printf("Error binding JSON: %v", err)
 		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
 		return
@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+)
+
+// AuthController ログインプロバイダに依存しない認証関連の処理を行うコントローラ
+type AuthController struct {
+	JWTService services.JWTService
+}
+
+// NewAuthController AuthControllerを生成
+func NewAuthController(jwtService services.JWTService) *AuthController {
+	return &AuthController{
+		JWTService: jwtService,
+	}
+}
+
+// Logout godoc
+// @Summary ログアウト
+// @Description 提供されたリフレッシュトークンが属するトークンファミリーと、リクエストに使用したアクセストークンを失効させます。
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param refresh_token body string true "リフレッシュトークン"
+// @Success 200 {object} map[string]interface{} "ログアウトしました"
+// @Failure 400 {object} map[string]interface{} "JSON形式が不正、またはリフレッシュトークンが提供されていない場合のエラー"
+// @Failure 401 {object} map[string]interface{} "リフレッシュトークンが無効、または他のユーザーのものである場合のエラー"
+// @Router /auth/logout [post]
+// @Security Bearer
+func (controller *AuthController) Logout(c *gin.Context) {
+	var requestBody map[string]string
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	refreshToken, ok := requestBody["refresh_token"]
+	if !ok || refreshToken == "" {
+		respondWithError(c, constants.StatusBadRequest, constants.RefreshTokenRequired)
+		return
+	}
+	refreshToken = strings.TrimSpace(strings.TrimPrefix(refreshToken, "Bearer "))
+
+	userID := c.GetUint("userID")
+	if err := controller.JWTService.RevokeRefreshToken(refreshToken, userID); err != nil {
+		handleServiceError(c, fmt.Errorf("Failed to revoke refresh token: %w", err))
+		return
+	}
+
+	if token, ok := c.Get("accessToken"); ok {
+		if err := controller.JWTService.RevokeToken(token.(*jwt.Token)); err != nil {
+			handleServiceError(c, err)
+			return
+		}
+	}
+
+	respondWithSuccess(c, constants.StatusOK, gin.H{"message": constants.LogoutSuccess})
+}
+
+// RevokeAllTokens godoc
+// @Summary 全デバイスからログアウト
+// @Description ログイン中のユーザーに発行済みの全てのアクセストークンとリフレッシュトークンを失効させます。
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} map[string]interface{} "全てのトークンが失効されました"
+// @Failure 401 {object} map[string]interface{} "認証エラー"
+// @Failure 500 {object} map[string]interface{} "サーバー内部エラー"
+// @Router /auth/revoke-all [post]
+// @Security Bearer
+func (controller *AuthController) RevokeAllTokens(c *gin.Context) {
+	userID := c.GetUint("userID")
+	if err := controller.JWTService.RevokeAllUserTokens(userID); err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, gin.H{"message": constants.TokensRevoked, "userID": userID})
+}
+
+// RevokeUserTokens godoc
+// @Summary ユーザーのトークンを一括失効
+// @Description サービス管理者が、指定したユーザーに発行済みの全てのアクセストークンとリフレッシュトークンを失効させます。
+// @Tags Auth
+// @Produce json
+// @Param userID path int true "ユーザーID"
+// @Success 200 {object} map[string]interface{} "全てのトークンが失効されました"
+// @Failure 400 {object} map[string]interface{} "無効なユーザーID"
+// @Failure 403 {object} map[string]interface{} "サービス管理者ではない場合のエラー"
+// @Failure 500 {object} map[string]interface{} "サーバー内部エラー"
+// @Router /auth/users/{userID}/revoke [post]
+// @Security Bearer
+func (controller *AuthController) RevokeUserTokens(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("userID"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.ErrNoUserID)
+		return
+	}
+
+	if err := controller.JWTService.RevokeAllUserTokens(uint(userID)); err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, gin.H{"message": constants.TokensRevoked, "userID": userID})
+}
+
+// ListSessions godoc
+// @Summary ログイン中のセッション一覧
+// @Description ログイン中のユーザーのセッション (端末、IPアドレス、ログインプロバイダ、最終利用時刻) を最終利用時刻の新しい順に返します。リクエストに使用したセッションは current が true になります。
+// @Tags Auth
+// @Produce json
+// @Success 200 {array} dto.SessionDTO "セッションの一覧"
+// @Failure 401 {object} map[string]interface{} "認証エラー"
+// @Failure 500 {object} map[string]interface{} "サーバー内部エラー"
+// @Router /auth/sessions [get]
+// @Security Bearer
+func (controller *AuthController) ListSessions(c *gin.Context) {
+	currentSessionID := ""
+	if token, ok := c.Get("accessToken"); ok {
+		if claims, ok := token.(*jwt.Token).Claims.(jwt.MapClaims); ok {
+			currentSessionID, _ = claims["sid"].(string)
+		}
+	}
+
+	sessions, err := controller.JWTService.ListSessions(c.GetUint("userID"), currentSessionID)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, sessions)
+}
+
+// RevokeSession godoc
+// @Summary セッションの失効
+// @Description 指定したセッションを失効させます。失効したセッションのリフレッシュトークンとアクセストークンは使用できなくなります。
+// @Tags Auth
+// @Produce json
+// @Param id path string true "セッションID"
+// @Success 200 {object} map[string]interface{} "セッションを失効させました"
+// @Failure 401 {object} map[string]interface{} "認証エラー"
+// @Failure 404 {object} map[string]interface{} "セッションが見つからない場合のエラー"
+// @Failure 500 {object} map[string]interface{} "サーバー内部エラー"
+// @Router /auth/sessions/{id} [delete]
+// @Security Bearer
+func (controller *AuthController) RevokeSession(c *gin.Context) {
+	if err := controller.JWTService.RevokeSession(c.GetUint("userID"), c.Param("id")); err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, gin.H{"message": constants.SessionRevoked})
+}
+
+// JWKS godoc
+// @Summary JWT検証用の公開鍵を取得
+// @Description フロントエンドやストリーミングサーバーがアクセストークンを検証するための公開鍵をJWK Set形式で返します。HS256モードでは空の鍵セットを返します。
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} dto.JSONWebKeySet "JWK Set"
+// @Router /.well-known/jwks.json [get]
+func (controller *AuthController) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(constants.StatusOK, controller.JWTService.JWKS())
+}
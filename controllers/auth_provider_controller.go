@@ -0,0 +1,271 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// oauthStateCookie はログインを開始したブラウザにstateを保存するクッキーの名前
+// 認可コードの処理時にリクエストのstateと照合し、他のブラウザで開始されたログインを受け付けないようにします。
+const oauthStateCookie = "oauthstate"
+
+// AuthProviderController ログインプロバイダ (Google, LINE, Microsoft, GitHub など) によるログインを行うコントローラ
+type AuthProviderController struct {
+	Service    services.AuthProviderService
+	JWTService services.JWTService
+}
+
+// NewAuthProviderController AuthProviderControllerを生成
+func NewAuthProviderController(service services.AuthProviderService, jwtService services.JWTService) *AuthProviderController {
+	return &AuthProviderController{
+		Service:    service,
+		JWTService: jwtService,
+	}
+}
+
+// LoginHandler godoc
+// @Summary ログインプロバイダの認可URLを取得
+// @Description ログインプロバイダのログインページへの認可URLを返します。URLにはstateとPKCEのcode_challengeが含まれ、stateは10分間、一度だけ使用できます。stateはHttpOnlyのクッキー (oauthstate) にも保存され、認可コードの処理時に照合されます。
+// @Tags Auth
+// @ID auth-provider-login-handler
+// @Produce json
+// @Param provider path string true "ログインプロバイダ名 (google, line, microsoft, github など)"
+// @Success 200 {object} map[string]interface{} "ログインページへの認可URL"
+// @Failure 404 {object} map[string]interface{} "ログインプロバイダが登録されていない場合のエラー"
+// @Router /auth/{provider}/login [get]
+func (controller *AuthProviderController) LoginHandler(c *gin.Context) {
+	url, state, err := controller.Service.AuthCodeURL(c.Param("provider"))
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	setOAuthStateCookie(c, state, int(services.OAuthStateTTL.Seconds()))
+
+	respondWithSuccess(c, constants.StatusOK, gin.H{"url": url})
+}
+
+// ProcessAuthCode godoc
+// @Summary 認可コードを処理
+// @Description ログイン後に受け取った認可コードとstateを検証し、プロバイダのユーザー情報に対応するユーザーのトークンを生成します。ユーザーが存在しない場合は作成します。
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param provider path string true "ログインプロバイダ名 (google, line, microsoft, github など)"
+// @Param authCode body string true "プロバイダから受け取った認可コード"
+// @Param state body string true "認可URLに含まれていたstate"
+// @Success 200 {object} map[string]interface{} "ユーザー情報及びトークン情報"
+// @Failure 400 {object} map[string]interface{} "認可コードがない、stateが無効・期限切れ・使用済み・ログインを開始したブラウザのクッキーと一致しない、または認可コードの検証に失敗した場合のエラー"
+// @Failure 401 {object} map[string]interface{} "id_tokenの署名、発行者、対象者、nonce、有効期限の検証に失敗した場合のエラー"
+// @Failure 404 {object} map[string]interface{} "ログインプロバイダが登録されていない場合のエラー"
+// @Router /auth/{provider}/process [post]
+func (controller *AuthProviderController) ProcessAuthCode(c *gin.Context) {
+	var requestBody map[string]string
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	authCode, ok := requestBody["authCode"]
+	if !ok || authCode == "" {
+		respondWithError(c, constants.StatusBadRequest, constants.AuthCodeRequired)
+		return
+	}
+
+	// 他のブラウザで開始されたログインのstateは使用済みにせずに拒否する
+	state := requestBody["state"]
+	cookie, err := c.Cookie(oauthStateCookie)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(state)) != 1 {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidOAuthState)
+		return
+	}
+	setOAuthStateCookie(c, "", -1)
+
+	user, err := controller.Service.Login(c.Param("provider"), authCode, state)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	accessToken, refreshToken, err := controller.JWTService.StartSession(user.ID, dto.SessionInfo{
+		Provider:  c.Param("provider"),
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	})
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, gin.H{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"user": map[string]interface{}{
+			"name":  user.Name,
+			"image": user.Image,
+			"id":    user.ID,
+		},
+	})
+}
+
+// RefreshAccessTokenHandler godoc
+// @Summary アクセストークンの更新
+// @Description 提供されたリフレッシュトークンを使用してアクセストークンを更新します。リフレッシュトークンは毎回ローテーションされ、使用済みのトークンが再利用された場合はそのトークンファミリー全体が失効します。
+// @Tags Auth
+// @Accept  json
+// @Produce  json
+// @Param   provider          path    string  true  "ログインプロバイダ名 (google, line, microsoft, github など)"
+// @Param   refresh_token     body    string  true  "リフレッシュトークン"
+// @Success 200 {object} map[string]interface{} "新しいアクセストークン、リフレッシュトークンと有効期限が返されます"
+// @Failure 400 {object} map[string]interface{} "JSON形式が不正、またはリフレッシュトークンが提供されていない場合のエラー"
+// @Failure 401 {object} map[string]interface{} "リフレッシュトークンが無効、期限切れ、失効済み、または再利用された場合の認証エラー"
+// @Failure 404 {object} map[string]interface{} "ログインプロバイダが登録されていない場合のエラー"
+// @Failure 500 {object} map[string]interface{} "未処理のエラーによる内部サーバーエラー"
+// @Router /auth/{provider}/refresh-token [post]
+func (controller *AuthProviderController) RefreshAccessTokenHandler(c *gin.Context) {
+	if !controller.Service.HasProvider(c.Param("provider")) {
+		respondWithError(c, constants.StatusNotFound, constants.AuthProviderNotFound)
+		return
+	}
+
+	refreshAccessToken(c, controller.JWTService)
+}
+
+// ListIdentities godoc
+// @Summary 紐づいているアカウントの一覧
+// @Description ログイン中のユーザーに紐づいているログインプロバイダのアカウントを返します。
+// @Tags Auth
+// @Produce json
+// @Success 200 {array} dto.UserIdentityDTO "紐づいているアカウントの一覧"
+// @Failure 500 {object} map[string]interface{} "サーバー内部エラー"
+// @Router /auth/identities [get]
+// @Security Bearer
+func (controller *AuthProviderController) ListIdentities(c *gin.Context) {
+	identities, err := controller.Service.ListIdentities(c.GetUint("userID"))
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, identities)
+}
+
+// LinkURLHandler godoc
+// @Summary アカウントの紐づけ用の認可URLを取得
+// @Description ログイン中のユーザーにプロバイダのアカウントを紐づけるための認可URLを返します。URLのstateはこのユーザーのアカウントの紐づけにのみ使用でき、10分間、一度だけ使用できます。
+// @Tags Auth
+// @Produce json
+// @Param provider path string true "ログインプロバイダ名 (google, line, microsoft, github など)"
+// @Success 200 {object} map[string]interface{} "ログインページへの認可URL"
+// @Failure 404 {object} map[string]interface{} "ログインプロバイダが登録されていない場合のエラー"
+// @Router /auth/identities/{provider}/url [get]
+// @Security Bearer
+func (controller *AuthProviderController) LinkURLHandler(c *gin.Context) {
+	url, err := controller.Service.LinkURL(c.GetUint("userID"), c.Param("provider"))
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, gin.H{"url": url})
+}
+
+// LinkIdentity godoc
+// @Summary アカウントの紐づけ
+// @Description /auth/identities/{provider}/url で取得した認可URLでログインし、受け取った認可コードとstateを使ってプロバイダのアカウントをログイン中のユーザーに紐づけます。
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param provider path string true "ログインプロバイダ名 (google, line, microsoft, github など)"
+// @Param request body dto.LinkIdentityRequest true "プロバイダから受け取った認可コードとstate"
+// @Success 200 {object} dto.UserIdentityDTO "紐づけたアカウント"
+// @Failure 400 {object} map[string]interface{} "認可コードがない、stateが無効・紐づけ用でない・他のユーザーのもの、または認可コードの検証に失敗した場合のエラー"
+// @Failure 404 {object} map[string]interface{} "ログインプロバイダが登録されていない場合のエラー"
+// @Failure 409 {object} map[string]interface{} "アカウントが既に他のユーザーに紐づいている場合のエラー"
+// @Router /auth/identities/{provider} [post]
+// @Security Bearer
+func (controller *AuthProviderController) LinkIdentity(c *gin.Context) {
+	var request dto.LinkIdentityRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.AuthCodeRequired)
+		return
+	}
+
+	identity, err := controller.Service.LinkIdentity(c.GetUint("userID"), c.Param("provider"), request.AuthCode, request.State)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, identity)
+}
+
+// UnlinkIdentity godoc
+// @Summary アカウントの紐づけ解除
+// @Description ログイン中のユーザーに紐づいているアカウントの紐づけを解除します。最後の一つのアカウントは解除できません。
+// @Tags Auth
+// @Produce json
+// @Param identityID path int true "アカウントID"
+// @Success 200 {object} map[string]interface{} "アカウントの紐づけを解除しました"
+// @Failure 400 {object} map[string]interface{} "アカウントIDが不正な場合のエラー"
+// @Failure 404 {object} map[string]interface{} "アカウントが見つからない場合のエラー"
+// @Failure 409 {object} map[string]interface{} "最後のアカウントの紐づけを解除しようとした場合のエラー"
+// @Router /auth/identities/{identityID} [delete]
+// @Security Bearer
+func (controller *AuthProviderController) UnlinkIdentity(c *gin.Context) {
+	identityID, err := strconv.ParseUint(c.Param("identityID"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	if err := controller.Service.UnlinkIdentity(c.GetUint("userID"), uint(identityID)); err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, constants.IdentityUnlinked)
+}
+
+// setOAuthStateCookie はstateのクッキーを設定します。maxAge が負の場合はクッキーを削除します。
+// HTTPSのリクエストの場合のみ Secure を付けます。
+func setOAuthStateCookie(c *gin.Context, state string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthStateCookie, state, maxAge, "/api/gin/auth", "", secure, true)
+}
+
+// refreshAccessToken はリクエストのリフレッシュトークンを使用してアクセストークンを更新し、レスポンスを返します。
+func refreshAccessToken(c *gin.Context, jwtService services.JWTService) {
+	var requestBody map[string]string
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	refreshToken := strings.TrimSpace(strings.TrimPrefix(requestBody["refresh_token"], "Bearer "))
+	if refreshToken == "" {
+		respondWithError(c, constants.StatusBadRequest, constants.RefreshTokenRequired)
+		return
+	}
+
+	tokenDetails, newRefreshToken, err := jwtService.RefreshAccessToken(refreshToken)
+	if err != nil {
+		handleServiceError(c, fmt.Errorf("Failed to refresh access token: %w", err))
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, gin.H{
+		"access_token":  tokenDetails.Raw,
+		"refresh_token": newRefreshToken,
+		"expires_in":    tokenDetails.Claims,
+	})
+}
@@ -3,24 +3,33 @@ package controllers
 import (
 	"context"
 	"io"
+	"strconv"
+	"time"
 
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/authorization"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
 )
 
+// streamPermissionCheckInterval はストリーム中にチャットや掲示板の権限を再確認する間隔
+// 接続中に利用停止になったユーザーをチャットルームや掲示板の更新の購読から切断するために使用します。
+const streamPermissionCheckInterval = 30 * time.Second
+
 // ChatController チャットコントローラ
 type ChatController struct {
 	chatManager *services.Manager
 	redisClient *redis.Client
+	authz       services.AuthorizationService
 }
 
 // NewChatController ChatControllerを生成
-func NewChatController(chatMgr *services.Manager, redisClient *redis.Client) *ChatController {
+func NewChatController(chatMgr *services.Manager, redisClient *redis.Client, authz services.AuthorizationService) *ChatController {
 	return &ChatController{
 		chatManager: chatMgr,
 		redisClient: redisClient,
+		authz:       authz,
 	}
 }
 
@@ -103,7 +112,7 @@ func (c *ChatController) DeleteChatRoom(ctx *gin.Context) {
 
 // StreamChat godoc
 // @Summary チャットをストリーム
-// @Description チャットをストリームする。
+// @Description チャットをストリームする。接続中にチャットの権限がなくなった場合 (利用停止など) は切断する。
 // @Tags Chat Room
 // @Accept json
 // @Produce json
@@ -112,14 +121,30 @@ func (c *ChatController) DeleteChatRoom(ctx *gin.Context) {
 // @Security Bearer
 func (c *ChatController) StreamChat(ctx *gin.Context) {
 	scheduleId := ctx.Param("scheduleId")
+	csid, err := strconv.ParseUint(scheduleId, 10, 32)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+	cid, err := c.authz.ClassIDOf(authorization.ResourceClassSchedule, uint(csid))
+	if err != nil {
+		handleServiceError(ctx, err)
+		return
+	}
+	userID := ctx.GetUint("userID")
+
 	listener := c.chatManager.OpenListener(scheduleId)
 	defer c.chatManager.CloseListener(scheduleId, listener)
+	ticker := time.NewTicker(streamPermissionCheckInterval)
+	defer ticker.Stop()
 
 	ctx.Stream(func(w io.Writer) bool {
 		select {
 		case message := <-listener:
 			ctx.SSEvent("message", message)
 			return true
+		case <-ticker.C:
+			return c.authz.Authorize(userID, cid, authorization.ChatRead) == nil
 		case <-ctx.Request.Context().Done():
 			return false
 		}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// ClassApplicationController クラスへの参加申請を承認、却下するコントローラ
+type ClassApplicationController struct {
+	Service services.ClassApplicationService
+}
+
+// NewClassApplicationController ClassApplicationControllerを生成
+func NewClassApplicationController(service services.ClassApplicationService) *ClassApplicationController {
+	return &ClassApplicationController{Service: service}
+}
+
+// ListApplicants godoc
+// @Summary 申請中のユーザー一覧
+// @Description クラスに参加を申請しているユーザーを申請の古い順に返します。
+// @Tags Class User
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Success 200 {array} dto.ApplicantDTO "申請中のユーザー"
+// @Failure 400 {object} map[string]interface{} "リクエストが不正な場合のエラー"
+// @Failure 403 {object} map[string]interface{} "承認の権限がない場合のエラー"
+// @Router /cu/class/{cid}/applicants [get]
+// @Security Bearer
+func (controller *ClassApplicationController) ListApplicants(c *gin.Context) {
+	cid, err := strconv.ParseUint(c.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	applicants, err := controller.Service.ListPending(c.GetUint("userID"), uint(cid))
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, applicants)
+}
+
+// ListWaitlist godoc
+// @Summary キャンセル待ちのユーザー一覧
+// @Description 定員に達していたためキャンセル待ちになっているユーザーを、承認される順に返します。メンバーが退会すると先頭から自動的に承認されます。
+// @Tags Class User
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Success 200 {array} dto.ApplicantDTO "キャンセル待ちのユーザー"
+// @Failure 400 {object} map[string]interface{} "リクエストが不正な場合のエラー"
+// @Failure 403 {object} map[string]interface{} "承認の権限がない場合のエラー"
+// @Router /cu/class/{cid}/waitlist [get]
+// @Security Bearer
+func (controller *ClassApplicationController) ListWaitlist(c *gin.Context) {
+	cid, err := strconv.ParseUint(c.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	applicants, err := controller.Service.ListWaitlist(c.GetUint("userID"), uint(cid))
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, applicants)
+}
+
+// ApproveApplicants godoc
+// @Summary 参加申請の承認
+// @Description 申請中のユーザーをまとめて承認し、USER にします。定員に達している場合はキャンセル待ちになり、waitlisted に含まれます。申請中ではないユーザーが含まれる場合は何も変更しません。
+// @Tags Class User
+// @Accept json
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param request body dto.DecideApplicationsRequest true "承認するユーザーIDと理由"
+// @Success 200 {object} map[string]interface{} "message: 参加申請を承認しました, waitlisted: キャンセル待ちになったユーザーID"
+// @Failure 400 {object} map[string]interface{} "リクエストが不正な場合のエラー"
+// @Failure 403 {object} map[string]interface{} "承認の権限がない場合のエラー"
+// @Failure 404 {object} map[string]interface{} "申請中ではないユーザーが含まれる場合のエラー"
+// @Router /cu/class/{cid}/applicants/approve [post]
+// @Security Bearer
+func (controller *ClassApplicationController) ApproveApplicants(c *gin.Context) {
+	controller.decide(c, true, constants.ApplicationsApproved)
+}
+
+// RejectApplicants godoc
+// @Summary 参加申請の却下
+// @Description 申請中のユーザーをまとめて却下し、クラスから削除します。理由は申請したユーザーが参照できます。申請中ではないユーザーが含まれる場合は何も変更しません。
+// @Tags Class User
+// @Accept json
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param request body dto.DecideApplicationsRequest true "却下するユーザーIDと理由"
+// @Success 200 {object} map[string]interface{} "message: 参加申請を却下しました"
+// @Failure 400 {object} map[string]interface{} "リクエストが不正な場合のエラー"
+// @Failure 403 {object} map[string]interface{} "承認の権限がない場合のエラー"
+// @Failure 404 {object} map[string]interface{} "申請中ではないユーザーが含まれる場合のエラー"
+// @Router /cu/class/{cid}/applicants/reject [post]
+// @Security Bearer
+func (controller *ClassApplicationController) RejectApplicants(c *gin.Context) {
+	controller.decide(c, false, constants.ApplicationsRejected)
+}
+
+func (controller *ClassApplicationController) decide(c *gin.Context, approve bool, message string) {
+	cid, err := strconv.ParseUint(c.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	var request dto.DecideApplicationsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	waitlisted, err := controller.Service.Decide(c.GetUint("userID"), uint(cid), request, approve)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, gin.H{"message": message, "uids": request.UIDs, "waitlisted": waitlisted})
+}
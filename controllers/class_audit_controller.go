@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// ClassAuditController クラスの操作履歴を参照するコントローラ
+type ClassAuditController struct {
+	Service services.ClassAuditService
+}
+
+// NewClassAuditController ClassAuditControllerを生成
+func NewClassAuditController(service services.ClassAuditService) *ClassAuditController {
+	return &ClassAuditController{Service: service}
+}
+
+// ListClassAuditEvents godoc
+// @Summary クラスの操作履歴
+// @Description メンバーの参加、ロールの変更、削除、お気に入りの切り替えの履歴を新しい順に返します。クラスの管理者のみ参照できます。
+// @Tags Class
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param action query string false "操作 (member.joined, member.role_changed, member.removed, member.favorite_toggled)"
+// @Param actor_id query int false "操作したユーザーID"
+// @Param target_id query int false "操作されたユーザーID"
+// @Param from query string false "この日時以降 (RFC3339)"
+// @Param to query string false "この日時より前 (RFC3339)"
+// @Param cursor query string false "前のページの next_cursor"
+// @Param limit query int false "件数 (最大100)" default(20)
+// @Success 200 {object} pagination.Page[dto.ClassAuditEventDTO] "操作履歴"
+// @Failure 400 {object} map[string]interface{} "リクエストまたはカーソルが不正な場合のエラー"
+// @Failure 403 {object} map[string]interface{} "管理者ではない場合のエラー"
+// @Router /cl/{cid}/audit [get]
+// @Security Bearer
+func (controller *ClassAuditController) ListClassAuditEvents(c *gin.Context) {
+	cid, err := strconv.ParseUint(c.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	var query dto.ClassAuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	page, err := controller.Service.List(c.GetUint("userID"), uint(cid), query)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, page)
+}
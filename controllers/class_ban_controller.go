@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// ClassBanController クラスでの利用停止を管理するコントローラ
+type ClassBanController struct {
+	Service services.ClassBanService
+}
+
+// NewClassBanController ClassBanControllerを生成
+func NewClassBanController(service services.ClassBanService) *ClassBanController {
+	return &ClassBanController{Service: service}
+}
+
+// ListBans godoc
+// @Summary 利用停止中のユーザー一覧
+// @Description クラスで利用停止中のユーザーを、利用停止にした新しい順に返します。
+// @Tags Class User
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Success 200 {array} dto.ClassBanDTO "利用停止中のユーザー"
+// @Failure 400 {object} map[string]interface{} "リクエストが不正な場合のエラー"
+// @Failure 403 {object} map[string]interface{} "管理者ではない場合のエラー"
+// @Router /cu/class/{cid}/bans [get]
+// @Security Bearer
+func (controller *ClassBanController) ListBans(c *gin.Context) {
+	cid, err := strconv.ParseUint(c.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	bans, err := controller.Service.List(c.GetUint("userID"), uint(cid))
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, bans)
+}
+
+// BanUser godoc
+// @Summary ユーザーの利用停止
+// @Description ユーザーをクラスで利用停止 (BLACKLIST) にします。利用停止中のユーザーはクラスの掲示板やチャットを利用できず、クラスコードや招待でも参加できません。期限を指定した場合は期限が切れると自動的に解除されます。既に利用停止中の場合は理由と期限を更新します。接続中のチャットや掲示板の更新の購読は権限の再確認で切断されます。クラスのオーナーは利用停止にできません。
+// @Tags Class User
+// @Accept json
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param request body dto.BanUserRequest true "利用停止にするユーザー、理由、期限"
+// @Success 200 {object} dto.ClassBanDTO "利用停止"
+// @Failure 400 {object} map[string]interface{} "リクエストまたは期限が不正な場合のエラー"
+// @Failure 403 {object} map[string]interface{} "管理者ではない場合のエラー"
+// @Failure 404 {object} map[string]interface{} "ユーザーが見つからない場合のエラー"
+// @Failure 409 {object} map[string]interface{} "クラスのオーナーまたは最後の管理者を利用停止にする場合のエラー"
+// @Router /cu/class/{cid}/bans [post]
+// @Security Bearer
+func (controller *ClassBanController) BanUser(c *gin.Context) {
+	cid, err := strconv.ParseUint(c.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	var request dto.BanUserRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	ban, err := controller.Service.Ban(c.GetUint("userID"), uint(cid), request)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, ban)
+}
+
+// LiftBan godoc
+// @Summary 利用停止の解除
+// @Description ユーザーの利用停止を解除し、クラスから削除します。解除されたユーザーは再度参加を申請できます。
+// @Tags Class User
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param uid path int true "ユーザーID"
+// @Success 200 {object} map[string]interface{} "message: 利用停止を解除しました"
+// @Failure 400 {object} map[string]interface{} "リクエストが不正な場合のエラー"
+// @Failure 403 {object} map[string]interface{} "管理者ではない場合のエラー"
+// @Failure 404 {object} map[string]interface{} "利用停止中ではない場合のエラー"
+// @Router /cu/class/{cid}/bans/{uid} [delete]
+// @Security Bearer
+func (controller *ClassBanController) LiftBan(c *gin.Context) {
+	cid, err := strconv.ParseUint(c.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+	uid, err := strconv.ParseUint(c.Param("uid"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	if err := controller.Service.Lift(c.GetUint("userID"), uint(uid), uint(cid)); err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, gin.H{"message": constants.BanLifted})
+}
@@ -6,7 +6,9 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/authorization"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
@@ -19,13 +21,15 @@ import (
 type ClassBoardController struct {
 	classBoardService services.ClassBoardService
 	uploader          utils.Uploader
+	authz             services.AuthorizationService
 }
 
 // NewClassBoardController ClassBoardControllerを生成
-func NewClassBoardController(service services.ClassBoardService, uploader utils.Uploader) *ClassBoardController {
+func NewClassBoardController(service services.ClassBoardService, uploader utils.Uploader, authz services.AuthorizationService) *ClassBoardController {
 	return &ClassBoardController{
 		classBoardService: service,
 		uploader:          uploader,
+		authz:             authz,
 	}
 }
 
@@ -76,7 +80,7 @@ func (c *ClassBoardController) CreateClassBoard(ctx *gin.Context) {
 	}
 
 	msg := fmt.Sprintf("data: %s\n\n", "New class board created")
-	c.classBoardService.GetUpdateNotifier().Broadcast <- []byte(msg)
+	c.classBoardService.GetUpdateNotifier().Broadcast <- services.BoardUpdate{CID: result.CID, Message: []byte(msg)}
 
 	respondWithSuccess(ctx, constants.StatusOK, result)
 }
@@ -113,15 +117,16 @@ func (c *ClassBoardController) GetClassBoardByID(ctx *gin.Context) {
 
 // GetAllClassBoards godoc
 // @Summary 全てのグループ掲示板を取得
-// @Description cidに基づいて、グループの全ての掲示板を取得します。
+// @Description cidに基づいて、グループの掲示板を新しい順に取得します。cursor を指定した場合は page を無視します。
 // @Tags Class Board
 // @CrossOrigin
 // @Accept json
 // @Produce json
 // @Param cid query int true "Class ID"
+// @Param cursor query string false "前のページの next_cursor"
 // @Param page query int false "Page number" default(1)
-// @Param pageSize query int false "Number of items per page" default(10)
-// @Success 200 {array} []models.ClassBoard "全てのグループ掲示板のリスト"
+// @Param pageSize query int false "Number of items per page (最大100)" default(10)
+// @Success 200 {object} pagination.Page[models.ClassBoard] "グループ掲示板のページ"
 // @Failure 400 {object} string "Invalid request"
 // @Failure 500 {object} string "サーバーエラーが発生しました"
 // @Router /cb [get]
@@ -133,19 +138,12 @@ func (c *ClassBoardController) GetAllClassBoards(ctx *gin.Context) {
 		return
 	}
 
-	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
-	if err != nil || page < 1 {
-		respondWithError(ctx, constants.StatusBadRequest, "Invalid page number")
+	query, ok := bindPageQuery(ctx, "pageSize")
+	if !ok {
 		return
 	}
 
-	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "10"))
-	if err != nil || pageSize < 1 {
-		respondWithError(ctx, constants.StatusBadRequest, "Invalid page size")
-		return
-	}
-
-	result, err := c.classBoardService.GetAllClassBoards(uint(cid), page, pageSize)
+	result, err := c.classBoardService.GetAllClassBoards(uint(cid), query)
 	if err != nil {
 		handleServiceError(ctx, err)
 		return
@@ -233,7 +231,7 @@ func (c *ClassBoardController) UpdateClassBoard(ctx *gin.Context) {
 	}
 
 	msg := fmt.Sprintf("data: %s\n\n", "Class board updated")
-	c.classBoardService.GetUpdateNotifier().Broadcast <- []byte(msg)
+	c.classBoardService.GetUpdateNotifier().Broadcast <- services.BoardUpdate{CID: result.CID, Message: []byte(msg)}
 
 	respondWithSuccess(ctx, constants.StatusOK, result)
 }
@@ -291,16 +289,24 @@ func (c *ClassBoardController) handleImageUpload(ctx *gin.Context, cid uint) (st
 
 // SubscribeClassBoardUpdates godoc
 // @Summary クラス掲示板の更新を購読
-// @Description クラス掲示板の更新を購読します。
+// @Description 指定したクラスの掲示板の更新を購読します。接続中に掲示板の閲覧権限がなくなった場合 (利用停止など) は切断します。
 // @Tags Class Board
 // @CrossOrigin
 // @Produce text/event-stream
+// @Param cid query int true "Class ID"
 // @Success 200 {string} string "Class board updates subscribed"
+// @Failure 400 {string} string "Invalid class ID"
 // @Failure 500 {string} string "Error setting up SSE connection."
 // @Router /cb/subscribe [get]
 // @Security Bearer
 // @Notes Clients should reconnect automatically in case the connection closes.
 func (c *ClassBoardController) SubscribeClassBoardUpdates(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Query("cid"), 10, 64)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, "Invalid class ID")
+		return
+	}
+
 	ctx.Writer.Header().Set("Content-Type", "text/event-stream")
 	ctx.Writer.Header().Set("Cache-Control", "no-cache")
 	ctx.Writer.Header().Set("Connection", "keep-alive")
@@ -317,10 +323,22 @@ func (c *ClassBoardController) SubscribeClassBoardUpdates(ctx *gin.Context) {
 	}()
 
 	// Register the client for updates
-	notifier.Register <- ctx.Writer
-
-	// Keep the connection open until the client disconnects
-	<-ctx.Request.Context().Done()
+	notifier.Register <- services.BoardSubscriber{CID: uint(cid), Writer: ctx.Writer}
+
+	// Keep the connection open until the client disconnects or loses access to the class board
+	userID := ctx.GetUint("userID")
+	ticker := time.NewTicker(streamPermissionCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if c.authz.Authorize(userID, uint(cid), authorization.BoardRead) != nil {
+				return
+			}
+		case <-ctx.Request.Context().Done():
+			return
+		}
+	}
 }
 
 // SearchClassBoards godoc
@@ -4,19 +4,18 @@ import (
 	"strconv"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
 	"github.com/gin-gonic/gin"
 )
 
 type ClassCodeController struct {
 	classCodeService services.ClassCodeService
-	classUserService services.ClassUserService
 }
 
-func NewClassCodeController(classCodeService services.ClassCodeService, classUserService services.ClassUserService) *ClassCodeController {
+func NewClassCodeController(classCodeService services.ClassCodeService) *ClassCodeController {
 	return &ClassCodeController{
 		classCodeService: classCodeService,
-		classUserService: classUserService,
 	}
 }
 
@@ -51,7 +50,7 @@ func (c *ClassCodeController) CheckSecretExists(ctx *gin.Context) {
 
 // VerifyClassCode godoc
 // @Summary グループコードとシークレットを検証＆ユーザーに役割を割り当てる
-// @Description グループコードと、該当する場合はそのシークレットを確認する。また、指定されたユーザーに役割を割り当てる。
+// @Description グループコードと、該当する場合はそのシークレットを確認する。また、指定されたユーザーにコードのロールを割り当てる (APPLICANT の場合は参加申請)。
 // @Tags Class Code
 // @Accept json
 // @Produce json
@@ -61,7 +60,9 @@ func (c *ClassCodeController) CheckSecretExists(ctx *gin.Context) {
 // @Success 200 {object} string "グループコードが検証されました"
 // @Failure 400 {object} string "無効なリクエストです"
 // @Failure 401 {object} string "シークレットが一致しません"
+// @Failure 403 {object} string "利用停止中のユーザーです"
 // @Failure 404 {object} string "コードが見つかりません"
+// @Failure 409 {object} string "既にクラスに所属しています"
 // @Router /cc/verifyClassCode [get]
 // @Security Bearer
 func (c *ClassCodeController) VerifyClassCode(ctx *gin.Context) {
@@ -89,11 +90,13 @@ func (c *ClassCodeController) VerifyClassCode(ctx *gin.Context) {
 		return
 	}
 
-	roleName := "APPLICANT"
-	cid := uint(uid)
-	err = c.classUserService.AssignRole(uint(uid), cid, roleName)
+	classCode, err := c.classCodeService.FindClassCode(code)
 	if err != nil {
-		respondWithError(ctx, constants.StatusInternalServerError, constants.AssignError)
+		respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
+		return
+	}
+	if err := c.classCodeService.Redeem(uint(uid), classCode); err != nil {
+		handleServiceError(ctx, err)
 		return
 	}
 
@@ -102,7 +105,7 @@ func (c *ClassCodeController) VerifyClassCode(ctx *gin.Context) {
 
 // VerifyAndRequestAccess godoc
 // @Summary クラスコードを確認してアクセスを要求する
-// @Description クラスコードを確認し、必要な場合はシークレットもチェックしてから、申請者としてアクセス要求を提出します。
+// @Description クラスコードを確認し、必要な場合はシークレットもチェックしてから、コードのロールで参加します。ロールが APPLICANT のコードの場合は申請者としてアクセス要求を提出します。
 // @Tags Class Code
 // @Accept json
 // @Produce json
@@ -112,7 +115,9 @@ func (c *ClassCodeController) VerifyClassCode(ctx *gin.Context) {
 // @Success 200 {object} map[string]interface{} "Access request submitted successfully with validation result."
 // @Failure 400 {string} string "Invalid request"
 // @Failure 401 {string} string "Invalid or missing secret"
+// @Failure 403 {string} string "Banned from the class"
 // @Failure 404 {string} string "Class code not found"
+// @Failure 409 {string} string "Already a member of the class"
 // @Failure 500 {string} string "Internal server error or error assigning role"
 // @Router /cc/verifyAndRequestAccess [get]
 // @Security Bearer
@@ -136,18 +141,14 @@ func (c *ClassCodeController) VerifyAndRequestAccess(ctx *gin.Context) {
 		return
 	}
 
-	if classCode.Secret != nil {
-		if secret == "" || *classCode.Secret != secret {
-			respondWithError(ctx, constants.StatusUnauthorized, "Invalid or missing secret")
-			return
-		}
+	if classCode.Secret != nil && !c.classCodeService.VerifySecret(classCode, secret) {
+		respondWithError(ctx, constants.StatusUnauthorized, "Invalid or missing secret")
+		return
 	}
 
-	roleName := "APPLICANT"
 	cid := classCode.CID
-	err = c.classUserService.AssignRole(uint(uid), cid, roleName)
-	if err != nil {
-		respondWithError(ctx, constants.StatusInternalServerError, "Error assigning role")
+	if err := c.classCodeService.Redeem(uint(uid), classCode); err != nil {
+		handleServiceError(ctx, err)
 		return
 	}
 
@@ -155,5 +156,177 @@ func (c *ClassCodeController) VerifyAndRequestAccess(ctx *gin.Context) {
 		"valid":   true,
 		"message": "Access request submitted successfully.",
 		"cid":     cid,
+		"role":    classCode.Role,
 	})
 }
+
+// ListClassCodes godoc
+// @Summary クラスコードの一覧
+// @Description クラスの全てのコードを、無効にしたものも含めて作成順に返します。シークレットの値は返されません。
+// @Tags Class Code
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Success 200 {array} dto.ClassCodeDTO "クラスコードの一覧"
+// @Failure 400 {object} map[string]interface{} "リクエストが不正な場合のエラー"
+// @Failure 403 {object} map[string]interface{} "管理者ではない場合のエラー"
+// @Router /cc/class/{cid}/codes [get]
+// @Security Bearer
+func (c *ClassCodeController) ListClassCodes(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	classCodes, err := c.classCodeService.List(ctx.GetUint("userID"), uint(cid))
+	if err != nil {
+		handleServiceError(ctx, err)
+		return
+	}
+
+	respondWithSuccess(ctx, constants.StatusOK, classCodes)
+}
+
+// CreateClassCode godoc
+// @Summary クラスコードの作成
+// @Description 参加後のロール、有効期限、使用回数の上限を指定してクラスコードを作成します。ロールは APPLICANT、USER、ASSISTANT です。
+// @Tags Class Code
+// @Accept json
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param request body dto.CreateClassCodeRequest true "ロール、シークレット、有効期限、使用回数の上限"
+// @Success 201 {object} dto.ClassCodeDTO "作成したクラスコード"
+// @Failure 400 {object} map[string]interface{} "リクエスト、ロールまたは有効期限が不正な場合のエラー"
+// @Failure 403 {object} map[string]interface{} "管理者ではない場合のエラー"
+// @Router /cc/class/{cid}/codes [post]
+// @Security Bearer
+func (c *ClassCodeController) CreateClassCode(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	var request dto.CreateClassCodeRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	classCode, err := c.classCodeService.Create(ctx.GetUint("userID"), uint(cid), request)
+	if err != nil {
+		handleServiceError(ctx, err)
+		return
+	}
+
+	respondWithSuccess(ctx, constants.StatusCreated, classCode)
+}
+
+// RegenerateClassCode godoc
+// @Summary クラスコードの再発行
+// @Description コードの値を新しくし、使用回数をリセットして有効にします。以前の値は使用できなくなります。
+// @Tags Class Code
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param id path int true "クラスコードID"
+// @Success 200 {object} dto.ClassCodeDTO "再発行したクラスコード"
+// @Failure 400 {object} map[string]interface{} "リクエストが不正な場合のエラー"
+// @Failure 403 {object} map[string]interface{} "管理者ではない場合のエラー"
+// @Failure 404 {object} map[string]interface{} "クラスコードが見つからない場合のエラー"
+// @Router /cc/class/{cid}/codes/{id}/regenerate [post]
+// @Security Bearer
+func (c *ClassCodeController) RegenerateClassCode(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	classCode, err := c.classCodeService.Regenerate(ctx.GetUint("userID"), uint(cid), uint(id))
+	if err != nil {
+		handleServiceError(ctx, err)
+		return
+	}
+
+	respondWithSuccess(ctx, constants.StatusOK, classCode)
+}
+
+// RevokeClassCode godoc
+// @Summary クラスコードの無効化
+// @Description クラスコードを無効にします。無効にしたコードは再発行すると再び使用できます。
+// @Tags Class Code
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param id path int true "クラスコードID"
+// @Success 200 {object} map[string]interface{} "message: クラスコードを無効にしました"
+// @Failure 400 {object} map[string]interface{} "リクエストが不正な場合のエラー"
+// @Failure 403 {object} map[string]interface{} "管理者ではない場合のエラー"
+// @Failure 404 {object} map[string]interface{} "クラスコードが見つからない場合のエラー"
+// @Router /cc/class/{cid}/codes/{id} [delete]
+// @Security Bearer
+func (c *ClassCodeController) RevokeClassCode(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	if err := c.classCodeService.Revoke(ctx.GetUint("userID"), uint(cid), uint(id)); err != nil {
+		handleServiceError(ctx, err)
+		return
+	}
+
+	respondWithSuccess(ctx, constants.StatusOK, gin.H{"message": constants.ClassCodeRevoked})
+}
+
+// UpdateClassCodeSecret godoc
+// @Summary クラスコードのシークレットの変更
+// @Description コードの値を変えずにシークレットを変更します。secret が null または空の場合はシークレットを削除します。
+// @Tags Class Code
+// @Accept json
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param id path int true "クラスコードID"
+// @Param request body dto.UpdateClassCodeSecretRequest true "新しいシークレット"
+// @Success 200 {object} dto.ClassCodeDTO "変更したクラスコード"
+// @Failure 400 {object} map[string]interface{} "リクエストまたはシークレットが不正な場合のエラー"
+// @Failure 403 {object} map[string]interface{} "管理者ではない場合のエラー"
+// @Failure 404 {object} map[string]interface{} "クラスコードが見つからない場合のエラー"
+// @Router /cc/class/{cid}/codes/{id}/secret [put]
+// @Security Bearer
+func (c *ClassCodeController) UpdateClassCodeSecret(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	var request dto.UpdateClassCodeSecretRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	classCode, err := c.classCodeService.UpdateSecret(ctx.GetUint("userID"), uint(cid), uint(id), request.Secret)
+	if err != nil {
+		handleServiceError(ctx, err)
+		return
+	}
+
+	respondWithSuccess(ctx, constants.StatusOK, classCode)
+}
@@ -66,13 +66,11 @@ func (cc *ClassController) GetClass(ctx *gin.Context) {
 	}
 
 	if classCode != nil {
-		classCodeResponse := gin.H{
-			"code": classCode.Code,
+		// シークレットはハッシュで保存しているため、あるかどうかのみを返す
+		response["classCode"] = gin.H{
+			"code":      classCode.Code,
+			"hasSecret": classCode.Secret != nil && *classCode.Secret != "",
 		}
-		if classCode.Secret != nil {
-			classCodeResponse["secret"] = classCode.Secret
-		}
-		response["classCode"] = classCodeResponse
 	}
 
 	respondWithSuccess(ctx, constants.StatusOK, response)
@@ -87,11 +85,12 @@ func (cc *ClassController) GetClass(ctx *gin.Context) {
 // @Param name formData string true "クラスの名前"
 // @Param limitation formData int false "クラスの定員数"
 // @Param description formData string false "クラスの説明"
-// @Param uid formData int true "クラスを作成するユーザーのUID"
+// @Param uid formData int true "クラスを作成するユーザーのUID (認証されたユーザーと一致する必要があります)"
 // @Param secret formData string false "クラス加入暗証番号"
 // @Param image formData file false "クラスの画像"
 // @Success 201 {object} map[string]interface{} "message: クラスが正常に作成されました"
 // @Failure 400 {object} map[string]interface{} "error: 不正なリクエストのエラーメッセージ"
+// @Failure 403 {object} map[string]interface{} "error: 権限エラー"
 // @Failure 500 {object} map[string]interface{} "error: サーバー内部エラー"
 // @Router /cl/create [post]
 // @Security Bearer
@@ -102,6 +101,11 @@ func (cc *ClassController) CreateClass(ctx *gin.Context) {
 		return
 	}
 
+	if createDTO.UID != ctx.GetUint("userID") {
+		handleServiceError(ctx, services.ErrForbidden)
+		return
+	}
+
 	classID, err := cc.classService.CreateClass(createDTO)
 	if err != nil {
 		handleServiceError(ctx, err)
@@ -149,7 +153,7 @@ func (cc *ClassController) handleImageUpload(ctx *gin.Context) (string, error) {
 
 // UpdateClass godoc
 // @Summary クラス情報を更新
-// @Description 指定されたIDを持つクラスの情報を更新します。
+// @Description 指定されたIDを持つクラスの情報を更新します。uidは認証されたユーザーと一致し、クラスの管理者である必要があります。
 // @Tags Class
 // @Accept multipart/form-data
 // @Produce json
@@ -162,11 +166,12 @@ func (cc *ClassController) handleImageUpload(ctx *gin.Context) (string, error) {
 // @Success 200 {object} map[string]interface{} "message: クラスが正常に更新されました"
 // @Failure 400 {object} map[string]interface{} "error: 不正なリクエストのエラーメッセージ"
 // @Failure 401 {object} map[string]interface{} "error: 認証エラー"
+// @Failure 403 {object} map[string]interface{} "error: 権限エラー"
 // @Failure 500 {object} map[string]interface{} "error: サーバー内部エラー"
 // @Router /cl/{uid}/{cid} [patch]
 // @Security Bearer
 func (cc *ClassController) UpdateClass(ctx *gin.Context) {
-	userID, _ := strconv.ParseUint(ctx.Param("uid"), 10, 32)
+	userID := ctx.GetUint("userID")
 	classID, _ := strconv.ParseUint(ctx.Param("cid"), 10, 32)
 
 	var updateDTO dto.UpdateClassRequest
@@ -189,7 +194,11 @@ func (cc *ClassController) UpdateClass(ctx *gin.Context) {
 		}
 	}
 
-	if err := cc.classService.UpdateClass(uint(classID), uint(userID), updateDTO); err != nil {
+	if err := cc.classService.UpdateClass(uint(classID), userID, updateDTO); err != nil {
+		if errors.Is(err, services.ErrForbidden) {
+			handleServiceError(ctx, err)
+			return
+		}
 		respondWithError(ctx, constants.StatusInternalServerError, "Class update failed: "+err.Error())
 		return
 	}
@@ -199,7 +208,7 @@ func (cc *ClassController) UpdateClass(ctx *gin.Context) {
 
 // DeleteClass godoc
 // @Summary クラスを削除
-// @Description 指定されたIDを持つクラスを削除します。
+// @Description 指定されたIDを持つクラスを削除します。uidは認証されたユーザーと一致し、クラスの管理者である必要があります。
 // @Tags Class
 // @Accept json
 // @Produce json
@@ -207,18 +216,58 @@ func (cc *ClassController) UpdateClass(ctx *gin.Context) {
 // @Param cid path int true "クラスID"
 // @Success 200 {object} map[string]interface{} "message: クラスが正常に削除されました"
 // @Failure 401 {object} map[string]interface{} "error: 認証エラー"
+// @Failure 403 {object} map[string]interface{} "error: 権限エラー"
 // @Failure 500 {object} map[string]interface{} "error: サーバー内部エラー"
 // @Router /cl/{uid}/{cid} [delete]
 // @Security Bearer
 func (cc *ClassController) DeleteClass(ctx *gin.Context) {
-	userID, _ := strconv.ParseUint(ctx.Param("uid"), 10, 32)
+	userID := ctx.GetUint("userID")
 	classID, _ := strconv.ParseUint(ctx.Param("cid"), 10, 32)
 
-	err := cc.classService.DeleteClass(uint(classID), uint(userID))
+	err := cc.classService.DeleteClass(uint(classID), userID)
 	if err != nil {
+		if errors.Is(err, services.ErrForbidden) {
+			handleServiceError(ctx, err)
+			return
+		}
 		respondWithError(ctx, constants.StatusUnauthorized, fmt.Sprintf("Error: %v", err))
 		return
 	}
 
 	respondWithSuccess(ctx, constants.StatusOK, gin.H{"message": constants.DeleteSuccess})
 }
+
+// TransferOwnership godoc
+// @Summary クラスのオーナーを変更
+// @Description クラスのオーナーを他のメンバーに変更し、新しいオーナーを管理者にします。previous_owner_role (ADMIN、ASSISTANT、USER) を指定した場合は元のオーナーのロールも変更します。クラスのオーナーのみ実行できます。
+// @Tags Class
+// @Accept json
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param request body dto.TransferOwnershipRequest true "新しいオーナーと元のオーナーのロール"
+// @Success 200 {object} map[string]interface{} "message: クラスのオーナーを変更しました"
+// @Failure 400 {object} map[string]interface{} "error: リクエストまたは新しいオーナーが不正です"
+// @Failure 403 {object} map[string]interface{} "error: 権限エラー"
+// @Failure 404 {object} map[string]interface{} "error: クラスが見つかりません"
+// @Router /cl/{cid}/transfer [post]
+// @Security Bearer
+func (cc *ClassController) TransferOwnership(ctx *gin.Context) {
+	classID, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	var request dto.TransferOwnershipRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	if err := cc.classService.TransferOwnership(uint(classID), ctx.GetUint("userID"), request); err != nil {
+		handleServiceError(ctx, err)
+		return
+	}
+
+	respondWithSuccess(ctx, constants.StatusOK, gin.H{"message": constants.OwnershipTransferred})
+}
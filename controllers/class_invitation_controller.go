@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// ClassInvitationController クラスへの招待を管理するコントローラ
+type ClassInvitationController struct {
+	Service services.ClassInvitationService
+}
+
+// NewClassInvitationController ClassInvitationControllerを生成
+func NewClassInvitationController(service services.ClassInvitationService) *ClassInvitationController {
+	return &ClassInvitationController{Service: service}
+}
+
+// InviteUser godoc
+// @Summary ユーザーの招待
+// @Description 既存のユーザーをクラスに招待します。招待されたユーザーは承諾するまで INVITE のロールになり、クラス一覧に表示されます。ロールは USER または ASSISTANT です。
+// @Tags Class Invitation
+// @Accept json
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param request body dto.InviteUserRequest true "招待するユーザー、ロール、有効期限"
+// @Success 201 {object} dto.ClassInvitationDTO "作成した招待"
+// @Failure 400 {object} map[string]interface{} "リクエスト、ロールまたは有効期限が不正な場合のエラー"
+// @Failure 403 {object} map[string]interface{} "管理者ではない、またはユーザーが利用停止中の場合のエラー"
+// @Failure 404 {object} map[string]interface{} "ユーザーが見つからない場合のエラー"
+// @Failure 409 {object} map[string]interface{} "既にクラスに所属している場合のエラー"
+// @Router /invitations/class/{cid} [post]
+// @Security Bearer
+func (controller *ClassInvitationController) InviteUser(c *gin.Context) {
+	cid, err := strconv.ParseUint(c.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	var request dto.InviteUserRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	invitation, err := controller.Service.Invite(c.GetUint("userID"), uint(cid), request)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusCreated, invitation)
+}
+
+// CreateInvitationLink godoc
+// @Summary 招待リンクの発行
+// @Description 1回だけ使用できる招待リンクのトークンを発行します。トークンは発行時にのみ返されます。ロールは USER または ASSISTANT です。
+// @Tags Class Invitation
+// @Accept json
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param request body dto.CreateInvitationLinkRequest true "ロールと有効期限"
+// @Success 201 {object} dto.CreatedInvitationLinkDTO "発行した招待リンク"
+// @Failure 400 {object} map[string]interface{} "リクエスト、ロールまたは有効期限が不正な場合のエラー"
+// @Failure 403 {object} map[string]interface{} "管理者ではない場合のエラー"
+// @Router /invitations/class/{cid}/links [post]
+// @Security Bearer
+func (controller *ClassInvitationController) CreateInvitationLink(c *gin.Context) {
+	cid, err := strconv.ParseUint(c.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	var request dto.CreateInvitationLinkRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	link, err := controller.Service.CreateLink(c.GetUint("userID"), uint(cid), request)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusCreated, link)
+}
+
+// ListInvitations godoc
+// @Summary 招待の一覧
+// @Description クラスの未使用で期限内の招待を新しい順に返します。招待リンクのトークンは返されません。
+// @Tags Class Invitation
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Success 200 {array} dto.ClassInvitationDTO "招待の一覧"
+// @Failure 400 {object} map[string]interface{} "リクエストが不正な場合のエラー"
+// @Failure 403 {object} map[string]interface{} "管理者ではない場合のエラー"
+// @Router /invitations/class/{cid} [get]
+// @Security Bearer
+func (controller *ClassInvitationController) ListInvitations(c *gin.Context) {
+	cid, err := strconv.ParseUint(c.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	invitations, err := controller.Service.List(c.GetUint("userID"), uint(cid))
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, invitations)
+}
+
+// RevokeInvitation godoc
+// @Summary 招待の取り消し
+// @Description 未使用の招待を取り消します。ユーザーへの招待の場合、INVITE のロールも削除します。
+// @Tags Class Invitation
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param id path int true "招待ID"
+// @Success 200 {object} map[string]interface{} "message: 招待を取り消しました"
+// @Failure 400 {object} map[string]interface{} "リクエストが不正な場合のエラー"
+// @Failure 403 {object} map[string]interface{} "管理者ではない場合のエラー"
+// @Failure 404 {object} map[string]interface{} "招待が見つからない場合のエラー"
+// @Router /invitations/class/{cid}/{id} [delete]
+// @Security Bearer
+func (controller *ClassInvitationController) RevokeInvitation(c *gin.Context) {
+	cid, err := strconv.ParseUint(c.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	if err := controller.Service.Revoke(c.GetUint("userID"), uint(cid), uint(id)); err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, gin.H{"message": constants.InvitationRevoked})
+}
+
+// AcceptInvitation godoc
+// @Summary 招待の承諾
+// @Description 自分へのクラスの招待を承諾し、招待のロールでクラスに参加します。定員に達している場合は参加できません。
+// @Tags Class Invitation
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Success 200 {object} map[string]interface{} "message: 招待を承諾しました"
+// @Failure 400 {object} map[string]interface{} "リクエストが不正な場合のエラー"
+// @Failure 404 {object} map[string]interface{} "招待が見つからないか期限切れの場合のエラー"
+// @Failure 409 {object} map[string]interface{} "クラスの定員に達している場合のエラー"
+// @Router /invitations/class/{cid}/accept [post]
+// @Security Bearer
+func (controller *ClassInvitationController) AcceptInvitation(c *gin.Context) {
+	cid, err := strconv.ParseUint(c.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	if err := controller.Service.Accept(c.GetUint("userID"), uint(cid)); err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, gin.H{"message": constants.InvitationAccepted, "cid": cid})
+}
+
+// DeclineInvitation godoc
+// @Summary 招待の辞退
+// @Description 自分へのクラスの招待を辞退し、クラスから削除されます。
+// @Tags Class Invitation
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Success 200 {object} map[string]interface{} "message: 招待を辞退しました"
+// @Failure 400 {object} map[string]interface{} "リクエストが不正な場合のエラー"
+// @Failure 404 {object} map[string]interface{} "招待が見つからない場合のエラー"
+// @Router /invitations/class/{cid}/decline [post]
+// @Security Bearer
+func (controller *ClassInvitationController) DeclineInvitation(c *gin.Context) {
+	cid, err := strconv.ParseUint(c.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	if err := controller.Service.Decline(c.GetUint("userID"), uint(cid)); err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, gin.H{"message": constants.InvitationDeclined})
+}
+
+// AcceptInvitationLink godoc
+// @Summary 招待リンクでの参加
+// @Description 招待リンクのトークンでクラスに参加します。リンクは1回だけ使用できます。申請中のクラスの場合は申請を省略して参加します。
+// @Tags Class Invitation
+// @Produce json
+// @Param token path string true "招待リンクのトークン"
+// @Success 200 {object} map[string]interface{} "message: 招待を承諾しました, cid: 参加したクラスID"
+// @Failure 403 {object} map[string]interface{} "クラスで利用停止中の場合のエラー"
+// @Failure 404 {object} map[string]interface{} "招待が見つからないか、使用済みまたは期限切れの場合のエラー"
+// @Failure 409 {object} map[string]interface{} "既にクラスに所属している、またはクラスの定員に達している場合のエラー"
+// @Router /invitations/links/{token}/accept [post]
+// @Security Bearer
+func (controller *ClassInvitationController) AcceptInvitationLink(c *gin.Context) {
+	cid, err := controller.Service.AcceptLink(c.GetUint("userID"), c.Param("token"))
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, gin.H{"message": constants.InvitationAccepted, "cid": cid})
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 // ClassScheduleController インタフェースを実装
@@ -39,7 +40,8 @@ func NewClassScheduleController(service services.ClassScheduleService) *ClassSch
 // @Security Bearer
 func (controller *ClassScheduleController) CreateClassSchedule(c *gin.Context) {
 	var dto dto.ClassScheduleDTO
-	if err := c.ShouldBindJSON(&dto); err != nil {
+	// 権限の確認に使用したボディを読み込む
+	if err := c.ShouldBindBodyWith(&dto, binding.JSON); err != nil {
 		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
 		return
 	}
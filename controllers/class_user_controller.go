@@ -1,11 +1,16 @@
 package controllers
 
 import (
+	"encoding/csv"
 	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"gorm.io/gorm"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
@@ -72,45 +77,32 @@ func (c *ClassUserController) GetUserClassUserInfo(ctx *gin.Context) {
 
 // GetUserClasses godoc
 // @Summary ユーザーが参加しているクラスのリストを取得
-// @Description 特定のユーザーが参加している全てのクラスの情報を取得します。
+// @Description 特定のユーザーが参加している全てのクラスの情報をクラスIDの順に取得します。cursor を指定した場合は page を無視します。
 // @Tags Class User
 // @Accept json
 // @Produce json
 // @Param uid path int true "ユーザーID"
+// @Param cursor query string false "前のページの next_cursor"
 // @Param page query int false "Page number" default(1)
-// @Param limit query int false "Page size" default(10)
-// @Success 200 {array} models.Class "成功"
+// @Param limit query int false "Page size (最大100)" default(10)
+// @Success 200 {object} pagination.Page[dto.UserClassInfoDTO] "成功"
+// @Failure 400 {object} map[string]interface{} "無効なリクエストまたはカーソル"
 // @Router /cu/{uid}/classes [get]
 // @Security Bearer
 func (c *ClassUserController) GetUserClasses(ctx *gin.Context) {
-	uidStr := ctx.Param("uid")
-	pageStr := ctx.DefaultQuery("page", "1")
-	limitStr := ctx.DefaultQuery("limit", "10")
-
-	uid, err := strconv.ParseUint(uidStr, 10, 32)
+	uid, err := strconv.ParseUint(ctx.Param("uid"), 10, 32)
 	if err != nil {
 		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
 		return
 	}
-	page, _ := strconv.Atoi(pageStr)
-	limit, _ := strconv.Atoi(limitStr)
-
-	classes, err := c.classUserService.GetUserClasses(uint(uid), page, limit)
-	if err != nil {
-		respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
+	query, ok := bindPageQuery(ctx, "limit")
+	if !ok {
 		return
 	}
-	//if err != nil {
-	//	if errors.Is(err, services.ErrNotFound) {
-	//		respondWithError(ctx, constants.StatusNotFound, constants.ClassNotFound)
-	//	} else {
-	//		respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
-	//	}
-	//	return
-	//}
 
-	if len(classes) == 0 {
-		respondWithError(ctx, constants.StatusNotFound, constants.ClassNotFound)
+	classes, err := c.classUserService.GetUserClasses(uint(uid), query)
+	if err != nil {
+		handleServiceError(ctx, err)
 		return
 	}
 
@@ -119,14 +111,19 @@ func (c *ClassUserController) GetUserClasses(ctx *gin.Context) {
 
 // GetClassMembers godoc
 // @Summary クラスメンバーの情報を取得
-// @Description 指定されたcidのクラスに所属しているメンバーの情報を取得します。
+// @Description 指定されたcidのクラスに所属しているメンバーの情報を取得します。ロールでの絞り込み、ニックネームでの検索、並び替えができ、カーソルで1ページずつ返します。
 // @Tags Class User
 // @Accept  json
 // @Produce  json
 // @Param cid path int true "クラスID"
-// @Param role query string false "ロール名"
-// @Success 200 {array} dto.ClassMemberDTO "成功時、クラスメンバーの情報を返します"
-// @Failure 400 {object} map[string]interface{} "無効なクラスIDが指定された場合のエラーメッセージ"
+// @Param role query []string false "ロール名 (複数指定可)" collectionFormat(multi)
+// @Param q query string false "ニックネームの部分一致"
+// @Param sort query string false "並び替え (nickname, role, joined_at)" default(nickname)
+// @Param order query string false "順序 (asc, desc)" default(asc)
+// @Param cursor query string false "前のページの next_cursor"
+// @Param limit query int false "件数 (最大100)" default(50)
+// @Success 200 {object} dto.ClassMemberPageDTO "成功時、クラスメンバーの情報を返します"
+// @Failure 400 {object} map[string]interface{} "無効なクラスID、ロール、カーソルが指定された場合のエラーメッセージ"
 // @Failure 500 {object} map[string]interface{} "サーバー内部エラー"
 // @Router /cu/class/{cid}/members [get]
 // @Security Bearer
@@ -137,34 +134,33 @@ func (c *ClassUserController) GetClassMembers(ctx *gin.Context) {
 		return
 	}
 
-	roleName := ctx.DefaultQuery("role", "")
-
-	members, err := c.classUserService.GetClassMembers(uint(cid), roleName)
-	if err != nil {
-		respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
+	var query dto.ClassMemberQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
 		return
 	}
 
-	if len(members) == 0 {
-		respondWithSuccess(ctx, constants.StatusOK, []dto.ClassMemberDTO{})
+	page, err := c.classUserService.ListClassMembers(uint(cid), query)
+	if err != nil {
+		handleServiceError(ctx, err)
 		return
 	}
 
-	respondWithSuccess(ctx, constants.StatusOK, members)
+	respondWithSuccess(ctx, constants.StatusOK, page)
 }
 
 // GetFavoriteClasses godoc
 // @Summary お気に入りのクラス情報を取得
-// @Description ユーザーIDに基づいて、お気에入りに設定されたクラスの情報を取得します。
+// @Description ユーザーIDに基づいて、お気に入りに設定されたクラスの情報をクラスIDの順に取得します。cursor を指定した場合は page を無視します。
 // @Tags Class User
 // @Accept json
 // @Produce json
 // @Param uid path int true "ユーザーID"
+// @Param cursor query string false "前のページの next_cursor"
 // @Param page query int false "Page number" default(1)
-// @Param limit query int false "Page size" default(10)
-// @Success 200 {array} dto.UserClassInfoDTO "成功"
-// @Failure 400 {string} string "無効なリクエスト"
-// @Failure 404 {string} string "クラスが見つかりません"
+// @Param limit query int false "Page size (最大100)" default(10)
+// @Success 200 {object} pagination.Page[dto.UserClassInfoDTO] "成功"
+// @Failure 400 {string} string "無効なリクエストまたはカーソル"
 // @Failure 500 {string} string "サーバーエラーが発生しました"
 // @Router /cu/{uid}/favorite-classes [get]
 // @Security Bearer
@@ -175,24 +171,14 @@ func (c *ClassUserController) GetFavoriteClasses(ctx *gin.Context) {
 		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
 		return
 	}
-
-	pageStr := ctx.DefaultQuery("page", "1")
-	limitStr := ctx.DefaultQuery("limit", "10")
-	page, _ := strconv.Atoi(pageStr)
-	limit, _ := strconv.Atoi(limitStr)
-
-	favoriteClasses, err := c.classUserService.GetFavoriteClasses(uint(uid), page, limit)
-	if err != nil {
-		if errors.Is(err, services.ErrNotFound) {
-			respondWithError(ctx, constants.StatusNotFound, constants.ClassNotFound)
-		} else {
-			respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
-		}
+	query, ok := bindPageQuery(ctx, "limit")
+	if !ok {
 		return
 	}
 
-	if len(favoriteClasses) == 0 {
-		respondWithError(ctx, constants.StatusNotFound, constants.ClassNotFound)
+	favoriteClasses, err := c.classUserService.GetFavoriteClasses(uint(uid), query)
+	if err != nil {
+		handleServiceError(ctx, err)
 		return
 	}
 
@@ -201,17 +187,17 @@ func (c *ClassUserController) GetFavoriteClasses(ctx *gin.Context) {
 
 // GetUserClassesByRole godoc
 // @Summary ユーザーとロールに関連するクラス情報を取得
-// @Description ユーザーIDとロール名に基づいて、ユーザーが所属しているクラスの情報を取得します。
+// @Description ユーザーIDとロール名に基づいて、ユーザーが所属しているクラスの情報をクラスIDの順に取得します。cursor を指定した場合は page を無視します。
 // @Tags Class User
 // @Accept json
 // @Produce json
 // @Param uid path int true "ユーザーID"
 // @Param role query string true "ロール名"
+// @Param cursor query string false "前のページの next_cursor"
 // @Param page query int false "Page number" default(1)
-// @Param limit query int false "Page size" default(10)
-// @Success 200 {array} dto.UserClassInfoDTO "成功"
-// @Failure 400 {string} string "無効なリクエスト"
-// @Failure 404 {string} string "クラスが見つかりません"
+// @Param limit query int false "Page size (最大100)" default(10)
+// @Success 200 {object} pagination.Page[dto.UserClassInfoDTO] "成功"
+// @Failure 400 {string} string "無効なリクエスト、ロールまたはカーソル"
 // @Failure 500 {string} string "サーバーエラーが発生しました"
 // @Router /cu/{uid}/classes/by-role [get]
 // @Security Bearer
@@ -224,24 +210,14 @@ func (c *ClassUserController) GetUserClassesByRole(ctx *gin.Context) {
 		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
 		return
 	}
-
-	pageStr := ctx.DefaultQuery("page", "1")
-	limitStr := ctx.DefaultQuery("limit", "10")
-	page, _ := strconv.Atoi(pageStr)
-	limit, _ := strconv.Atoi(limitStr)
-
-	classes, err := c.classUserService.GetUserClassesByRole(uint(uid), roleName, page, limit)
-	if err != nil {
-		if errors.Is(err, services.ErrNotFound) {
-			respondWithError(ctx, constants.StatusNotFound, constants.ClassNotFound)
-		} else {
-			respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
-		}
+	query, ok := bindPageQuery(ctx, "limit")
+	if !ok {
 		return
 	}
 
-	if len(classes) == 0 {
-		respondWithSuccess(ctx, constants.StatusOK, []dto.UserClassInfoDTO{})
+	classes, err := c.classUserService.GetUserClassesByRole(uint(uid), models.Role(roleName), query)
+	if err != nil {
+		handleServiceError(ctx, err)
 		return
 	}
 
@@ -250,16 +226,18 @@ func (c *ClassUserController) GetUserClassesByRole(ctx *gin.Context) {
 
 // ChangeUserRole godoc
 // @Summary ユーザーのロールを変更
-// @Description 指定されたユーザーIDとクラスIDに基づいて、ユーザーのロールを変更します。
+// @Description 指定されたユーザーIDとクラスIDに基づいて、ユーザーのロールを変更します。リクエストしたユーザーがクラスの管理者である必要があります。
 // @Tags Class User
 // @Accept json
 // @Produce json
-// @Param uid path int true "ユーザーID"
+// @Param uid path int true "ロールを変更されるユーザーID"
 // @Param cid path int true "クラスID"
 // @Param roleName path string true "ロール名"
 // @Success 200 {string} string "Role updated successfully"
 // @Failure 400 {string} string "Invalid request"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 404 {string} string "User or class not found"
+// @Failure 409 {string} string "Class must keep at least one admin, or the class is full"
 // @Router /cu/{uid}/{cid}/role/{roleName} [patch]
 // @Security Bearer
 func (c *ClassUserController) ChangeUserRole(ctx *gin.Context) {
@@ -279,13 +257,12 @@ func (c *ClassUserController) ChangeUserRole(ctx *gin.Context) {
 		return
 	}
 
-	if !isValidRoleName(roleName) {
-		respondWithError(ctx, constants.StatusBadRequest, "Invalid Role Name")
-		return
-	}
-
-	err = c.classUserService.AssignRole(uint(uid), uint(cid), roleName)
+	err = c.classUserService.ChangeUserRole(ctx.GetUint("userID"), uint(uid), uint(cid), models.Role(roleName))
 	if err != nil {
+		if errors.Is(err, services.ErrForbidden) || errors.Is(err, services.ErrInvalidRole) || errors.Is(err, services.ErrLastAdmin) || errors.Is(err, services.ErrClassFull) {
+			handleServiceError(ctx, err)
+			return
+		}
 		respondWithError(ctx, constants.StatusInternalServerError, "Error changing role")
 		return
 	}
@@ -293,23 +270,9 @@ func (c *ClassUserController) ChangeUserRole(ctx *gin.Context) {
 	respondWithSuccess(ctx, constants.StatusOK, "Role updated successfully")
 }
 
-func isValidRoleName(roleName string) bool {
-	validRoleNames := map[string]bool{
-		"USER":      true,
-		"ADMIN":     true,
-		"ASSISTANT": true,
-		"APPLICANT": true,
-		"BLACKLIST": true,
-		"INVITE":    true,
-	}
-
-	_, isValid := validRoleNames[roleName]
-	return isValid
-}
-
 // UpdateUserName godoc
 // @Summary ユーザーの名前を更新
-// @Description 特定のユーザーIDとグループIDに対してユーザーの名前を更新します。
+// @Description 特定のユーザーIDとグループIDに対してユーザーの名前を更新します。本人またはクラスの管理者のみ更新できます。
 // @Tags Class User
 // @ID update-user-name
 // @Accept json
@@ -318,6 +281,7 @@ func isValidRoleName(roleName string) bool {
 // @Param cid path int true "Class ID"
 // @Param body body UpdateUserNameRequest true "新しいニックネーム"
 // @Success 200 {string} string "成功"
+// @Failure 403 {string} string "権限がありません"
 // @Failure 500 {string} string "サーバーエラーが発生しました"
 // @Router /cu/{uid}/{cid}/rename [put]
 // @Security Bearer
@@ -339,7 +303,7 @@ func (c *ClassUserController) UpdateUserName(ctx *gin.Context) {
 		return
 	}
 
-	err = c.classUserService.UpdateUserName(uint(uid), uint(cid), requestBody.NewName)
+	err = c.classUserService.UpdateUserName(ctx.GetUint("userID"), uint(uid), uint(cid), requestBody.NewName)
 	if err != nil {
 		handleServiceError(ctx, err)
 		return
@@ -385,7 +349,7 @@ func (c *ClassUserController) ToggleFavorite(ctx *gin.Context) {
 
 // RemoveUserFromClass godoc
 // @Summary ユーザーをクラスから削除
-// @Description 指定したユーザーIDとクラスIDに基づいて、ユーザーをクラスから削除します。
+// @Description 指定したユーザーIDとクラスIDに基づいて、ユーザーをクラスから削除します。本人の退会、またはクラスの管理者による削除のみ可能です。
 // @Tags Class User
 // @Accept json
 // @Produce json
@@ -393,7 +357,9 @@ func (c *ClassUserController) ToggleFavorite(ctx *gin.Context) {
 // @Param cid path int true "クラスID"
 // @Success 200 {string} string "成功"
 // @Failure 400 {string} string "無効なリクエスト"
+// @Failure 403 {string} string "権限がありません"
 // @Failure 404 {string} string "ユーザーまたはクラスが見つかりません"
+// @Failure 409 {string} string "最後の管理者、または利用停止中のユーザーは削除できません"
 // @Failure 500 {string} string "サーバーエラーが発生しました"
 // @Router /cu/{uid}/{cid}/remove [delete]
 // @Security Bearer
@@ -412,10 +378,12 @@ func (c *ClassUserController) RemoveUserFromClass(ctx *gin.Context) {
 		return
 	}
 
-	err = c.classUserService.RemoveUserFromClass(uint(uid), uint(cid))
+	err = c.classUserService.RemoveUserFromClass(ctx.GetUint("userID"), uint(uid), uint(cid))
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			respondWithError(ctx, constants.StatusNotFound, constants.UserNotFound)
+		} else if errors.Is(err, services.ErrForbidden) || errors.Is(err, services.ErrLastAdmin) || errors.Is(err, services.ErrBannedMember) {
+			handleServiceError(ctx, err)
 		} else {
 			respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
 		}
@@ -466,3 +434,106 @@ func (c *ClassUserController) SearchUserClassesByName(ctx *gin.Context) {
 
 	respondWithSuccess(ctx, constants.StatusOK, classes)
 }
+
+// maxImportFileSize はメンバーの一括追加で受け付けるCSVファイルのサイズの上限
+const maxImportFileSize = 1 << 20
+
+// ImportMembers godoc
+// @Summary メンバーの一括追加
+// @Description CSVファイルのユーザーをまとめてクラスに追加します。ヘッダーは uid, provider, subject, nickname, role で、ユーザーは uid または provider と subject (ログインプロバイダのアカウント) で指定します。nickname を省略した場合はユーザー名、role を省略した場合は USER になります。全ての行を検証し、エラーのない行のみを1つのトランザクションで追加します。dry_run=true の場合は追加せずに行ごとの結果のみを返します。
+// @Tags Class User
+// @Accept multipart/form-data
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param file formData file true "メンバーのCSVファイル (1MBまで、1000行まで)"
+// @Param dry_run query bool false "検証のみを行う場合は true"
+// @Success 200 {object} dto.MemberImportResultDTO "行ごとの結果"
+// @Failure 400 {object} map[string]interface{} "CSVの形式が正しくない、または行数が多すぎる場合のエラー"
+// @Failure 403 {object} map[string]interface{} "管理者ではない場合のエラー"
+// @Router /cl/{cid}/members/import [post]
+// @Security Bearer
+func (c *ClassUserController) ImportMembers(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+	dryRun, err := strconv.ParseBool(ctx.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.ErrNoFileHeaderJP)
+		return
+	}
+	if fileHeader.Size > maxImportFileSize {
+		respondWithError(ctx, constants.StatusBadRequest, constants.ErrCSVFileSize)
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		respondWithError(ctx, constants.StatusInternalServerError, constants.ErrOpenFileJP)
+		return
+	}
+	defer file.Close()
+
+	result, err := c.classUserService.ImportMembers(ctx.GetUint("userID"), uint(cid), file, dryRun)
+	if err != nil {
+		handleServiceError(ctx, err)
+		return
+	}
+
+	respondWithSuccess(ctx, constants.StatusOK, result)
+}
+
+// escapeCSVCell は表計算ソフトで数式として実行されないように、数式の開始に使われる文字で始まる値の先頭に ' を付けます。
+// ImportMembers は読み込むときに付けた ' を取り除きます。
+func escapeCSVCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// ExportMembers godoc
+// @Summary メンバーのエクスポート
+// @Description クラスの ADMIN, ASSISTANT, USER のメンバーを uid, nickname, role のCSVで返します。ImportMembers でそのまま読み込めます。= + - @ などで始まるニックネームは表計算ソフトで数式として扱われないように先頭に ' を付けます。
+// @Tags Class User
+// @Produce text/csv
+// @Param cid path int true "クラスID"
+// @Param role query string false "ロール名 (ADMIN, ASSISTANT, USER)"
+// @Success 200 {string} string "メンバーのCSV"
+// @Failure 400 {object} map[string]interface{} "無効なクラスIDまたはロールが指定された場合のエラー"
+// @Failure 403 {object} map[string]interface{} "メンバーを参照する権限がない場合のエラー"
+// @Router /cl/{cid}/members/export [get]
+// @Security Bearer
+func (c *ClassUserController) ExportMembers(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	members, err := c.classUserService.ExportMembers(uint(cid), models.Role(ctx.Query("role")))
+	if err != nil {
+		handleServiceError(ctx, err)
+		return
+	}
+
+	ctx.Header("Content-Type", "text/csv; charset=utf-8")
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="class_%d_members.csv"`, cid))
+	ctx.Status(constants.StatusOK)
+
+	writer := csv.NewWriter(ctx.Writer)
+	_ = writer.Write([]string{"uid", "nickname", "role"})
+	for _, member := range members {
+		_ = writer.Write([]string{strconv.FormatUint(uint64(member.Uid), 10), escapeCSVCell(member.Nickname), string(member.Role)})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Printf("メンバーのCSVの書き込みに失敗しました: %v", err)
+	}
+}
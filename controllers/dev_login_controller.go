@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// DevLoginController 開発環境及びCI用のログインを行うコントローラ
+type DevLoginController struct {
+	Service    services.DevLoginService
+	JWTService services.JWTService
+}
+
+// NewDevLoginController DevLoginControllerを生成
+func NewDevLoginController(service services.DevLoginService, jwtService services.JWTService) *DevLoginController {
+	return &DevLoginController{
+		Service:    service,
+		JWTService: jwtService,
+	}
+}
+
+// Login godoc
+// @Summary 開発用ログイン
+// @Description 外部のログインプロバイダを使わずにトークンを発行します。user_idを指定した場合は既存のユーザーで、指定しない場合はnameに対応する開発用ユーザーでログインし、ユーザーが存在しない場合は作成します。リリースモード以外でDEV_LOGIN_ENABLED=trueの場合のみ有効です。
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.DevLoginRequest false "ログインするユーザー"
+// @Success 200 {object} map[string]interface{} "ユーザー情報及びトークン情報"
+// @Failure 400 {object} map[string]interface{} "リクエストが不正な場合のエラー"
+// @Failure 404 {object} map[string]interface{} "指定したユーザーが存在しない場合のエラー"
+// @Router /auth/dev-login [post]
+func (controller *DevLoginController) Login(c *gin.Context) {
+	var request dto.DevLoginRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+			return
+		}
+	}
+
+	user, err := controller.Service.Login(request)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	accessToken, refreshToken, err := controller.JWTService.StartSession(user.ID, dto.SessionInfo{
+		Provider:  services.DevLoginProvider,
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	})
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, constants.StatusOK, gin.H{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"user": map[string]interface{}{
+			"name":  user.Name,
+			"image": user.Image,
+			"id":    user.ID,
+		},
+	})
+}
+
+// RefreshAccessTokenHandler godoc
+// @Summary 開発用ログインのアクセストークンの更新
+// @Description 開発用ログインで発行したリフレッシュトークンを使用してアクセストークンを更新します。リフレッシュトークンは毎回ローテーションされます。
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param refresh_token body string true "リフレッシュトークン"
+// @Success 200 {object} map[string]interface{} "新しいアクセストークン、リフレッシュトークンと有効期限が返されます"
+// @Failure 400 {object} map[string]interface{} "JSON形式が不正、またはリフレッシュトークンが提供されていない場合のエラー"
+// @Failure 401 {object} map[string]interface{} "リフレッシュトークンが無効、期限切れ、失効済み、または再利用された場合の認証エラー"
+// @Router /auth/dev-login/refresh-token [post]
+func (controller *DevLoginController) RefreshAccessTokenHandler(c *gin.Context) {
+	refreshAccessToken(c, controller.JWTService)
+}
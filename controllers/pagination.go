@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/pagination"
+	"github.com/gin-gonic/gin"
+)
+
+// bindPageQuery はクエリの cursor, page と件数を取得します。件数のパラメータ名は limitKey です。
+// 値が不正な場合はエラーを返し、false を返します。
+func bindPageQuery(ctx *gin.Context, limitKey string) (pagination.Query, bool) {
+	query := pagination.Query{Cursor: ctx.Query("cursor")}
+	params := []struct {
+		key   string
+		value *int
+	}{{"page", &query.Page}, {limitKey, &query.Limit}}
+	for _, param := range params {
+		raw := ctx.Query(param.key)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+			return pagination.Query{}, false
+		}
+		*param.value = n
+	}
+	return query, true
+}
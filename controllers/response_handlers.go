@@ -15,6 +15,54 @@ func handleServiceError(ctx *gin.Context, err error) {
 		respondWithError(ctx, constants.StatusNotFound, constants.CodeNotFound)
 	case errors.Is(err, services.ErrUnauthorized):
 		respondWithError(ctx, constants.StatusUnauthorized, constants.Unauthorized)
+	case errors.Is(err, services.ErrForbidden):
+		respondWithError(ctx, constants.StatusForbidden, constants.Forbidden)
+	case errors.Is(err, services.ErrInvalidRefreshToken), errors.Is(err, services.ErrRefreshTokenReused):
+		respondWithError(ctx, constants.StatusUnauthorized, constants.InvalidRefreshToken)
+	case errors.Is(err, services.ErrInvalidOAuthState):
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidOAuthState)
+	case errors.Is(err, services.ErrOAuthCodeExchange):
+		respondWithError(ctx, constants.StatusBadRequest, constants.OAuthCodeExchange)
+	case errors.Is(err, services.ErrUnknownAuthProvider):
+		respondWithError(ctx, constants.StatusNotFound, constants.AuthProviderNotFound)
+	case errors.Is(err, services.ErrIdentityAlreadyLinked):
+		respondWithError(ctx, constants.StatusConflict, constants.IdentityAlreadyLinked)
+	case errors.Is(err, services.ErrLastIdentity):
+		respondWithError(ctx, constants.StatusConflict, constants.LastIdentity)
+	case errors.Is(err, services.ErrSameUser):
+		respondWithError(ctx, constants.StatusBadRequest, constants.SameUserMerge)
+	case errors.Is(err, services.ErrInvalidIDToken):
+		respondWithError(ctx, constants.StatusUnauthorized, constants.InvalidIDToken)
+	case errors.Is(err, services.ErrInvalidRole):
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRole)
+	case errors.Is(err, services.ErrInvalidAPIKey):
+		respondWithError(ctx, constants.StatusUnauthorized, constants.InvalidAPIKey)
+	case errors.Is(err, services.ErrInvalidScope):
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidAPIKeyScope)
+	case errors.Is(err, services.ErrLastAdmin):
+		respondWithError(ctx, constants.StatusConflict, constants.LastAdmin)
+	case errors.Is(err, services.ErrBanOwner):
+		respondWithError(ctx, constants.StatusConflict, constants.BanOwner)
+	case errors.Is(err, services.ErrBannedMember):
+		respondWithError(ctx, constants.StatusConflict, constants.BannedUser)
+	case errors.Is(err, services.ErrInvalidOwner):
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidOwner)
+	case errors.Is(err, services.ErrAlreadyMember):
+		respondWithError(ctx, constants.StatusConflict, constants.AlreadyMember)
+	case errors.Is(err, services.ErrClassFull):
+		respondWithError(ctx, constants.StatusConflict, constants.ClassFull)
+	case errors.Is(err, services.ErrInvalidInvitation):
+		respondWithError(ctx, constants.StatusNotFound, constants.InvitationNotFound)
+	case errors.Is(err, services.ErrInvalidExpiry):
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidExpiry)
+	case errors.Is(err, services.ErrInvalidSecret):
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidSecret)
+	case errors.Is(err, services.ErrInvalidCSV):
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidCSV)
+	case errors.Is(err, services.ErrTooManyImportRows):
+		respondWithError(ctx, constants.StatusBadRequest, constants.TooManyImportRows)
+	case errors.Is(err, services.ErrInvalidCursor):
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidCursor)
 	case errors.Is(err, services.ErrDatabase):
 		respondWithError(ctx, constants.StatusInternalServerError, constants.DatabaseError)
 	default:
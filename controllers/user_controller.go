@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
 	"github.com/gin-gonic/gin"
 )
@@ -20,12 +21,12 @@ func NewCreateUserController(userService services.UserService) *UserController {
 
 // GetApplyingClasses godoc
 // @Summary 申し込んだクラスを取得
-// @Description ユーザーが申し込んだクラスを取得します。
+// @Description ユーザーがクラスごとに最後に申し込んだ申請のうち、申請中または却下された申請を新しい順に取得します。却下された申請には理由が含まれます。
 // @Tags User
 // @Accept json
 // @Produce json
 // @Param userID path int true "ユーザーID"
-// @Success 200 {array} models.ClassUser
+// @Success 200 {array} dto.ClassApplicationDTO
 // @Failure 400 {object} string "無効なユーザーID"
 // @Failure 404 {object} string "申請中のクラスが見つかりません"
 // @Failure 500 {object} string "内部サーバーエラー"
@@ -114,3 +115,32 @@ func (c *UserController) RemoveUserFromService(ctx *gin.Context) {
 
 	respondWithSuccess(ctx, constants.StatusOK, gin.H{"deletedUserID": userID})
 }
+
+// MergeUsers godoc
+// @Summary アカウントの統合
+// @Description 統合元ユーザーのクラス所属、出欠、掲示板、クラスコード、ログインアカウントを統合先ユーザーに移動し、統合元ユーザーを削除します。プラットフォーム管理者のみが実行できます。
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param request body dto.MergeUsersRequest true "統合元と統合先のユーザーID"
+// @Success 200 {object} map[string]interface{} "message: アカウントを統合しました"
+// @Failure 400 {object} map[string]interface{} "error: 不正なリクエスト、または同じユーザーを指定した場合のエラー"
+// @Failure 403 {object} map[string]interface{} "error: プラットフォーム管理者ではない場合のエラー"
+// @Failure 404 {object} map[string]interface{} "error: ユーザーが見つかりません"
+// @Failure 500 {object} map[string]interface{} "error: サーバー内部エラーです"
+// @Router /u/merge [post]
+// @Security Bearer
+func (uc *UserController) MergeUsers(ctx *gin.Context) {
+	var request dto.MergeUsersRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	if err := uc.userService.MergeUsers(request.SourceUserID, request.TargetUserID); err != nil {
+		handleServiceError(ctx, err)
+		return
+	}
+
+	respondWithSuccess(ctx, constants.StatusOK, gin.H{"message": constants.UsersMerged, "userID": request.TargetUserID})
+}
@@ -0,0 +1,38 @@
+package dto
+
+import "time"
+
+// CreateAPIKeyRequest APIキーの発行リクエスト
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// UpdateAPIKeyRequest APIキーの更新リクエスト
+// 指定された項目のみ更新します。
+type UpdateAPIKeyRequest struct {
+	Name      *string    `json:"name" binding:"omitempty,max=100"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// APIKeyDTO APIキーの情報
+type APIKeyDTO struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CID        *uint      `json:"cid"`
+	UID        uint       `json:"uid"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatedAPIKeyDTO 発行したAPIキー
+// キーは発行時にのみ返され、再表示はできません。
+type CreatedAPIKeyDTO struct {
+	APIKeyDTO
+	Key string `json:"key"`
+}
@@ -0,0 +1,9 @@
+package dto
+
+// ExternalUserInfo ログインプロバイダで認証されたユーザーの情報
+type ExternalUserInfo struct {
+	Provider string `json:"provider"`
+	Subject  string `json:"subject"`
+	Name     string `json:"name"`
+	Picture  string `json:"picture"`
+}
@@ -0,0 +1,29 @@
+package dto
+
+import "time"
+
+// DecideApplicationsRequest 参加申請の承認、却下リクエストDTO
+type DecideApplicationsRequest struct {
+	UIDs   []uint  `json:"uids" binding:"required,min=1,max=100,dive,required"` // 対象のユーザID
+	Reason *string `json:"reason" binding:"omitempty,max=255"`                  // 理由 (任意)
+}
+
+// ApplicantDTO クラスの申請中のユーザー
+type ApplicantDTO struct {
+	UID       uint      `json:"uid"`
+	Name      string    `json:"name"`
+	Image     string    `json:"image"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// ClassApplicationDTO ユーザーのクラスへの参加申請
+// 却下された場合は Status が rejected になり、Reason に理由が入ります。
+type ClassApplicationDTO struct {
+	CID       uint       `json:"cid"`
+	ClassName string     `json:"class_name"`
+	Image     *string    `json:"image"`
+	Status    string     `json:"status"`
+	Reason    *string    `json:"reason"`
+	AppliedAt time.Time  `json:"applied_at"`
+	DecidedAt *time.Time `json:"decided_at"`
+}
@@ -0,0 +1,27 @@
+package dto
+
+import "time"
+
+// ClassAuditQuery クラスの操作履歴の検索条件
+// 新しい順に返し、Cursor には前のページの next_cursor を指定します。
+type ClassAuditQuery struct {
+	Action   string     `form:"action"`
+	ActorID  *uint      `form:"actor_id"`
+	TargetID *uint      `form:"target_id"`
+	From     *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To       *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Cursor   string     `form:"cursor"`
+	Limit    int        `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// ClassAuditEventDTO クラスの操作履歴
+type ClassAuditEventDTO struct {
+	ID        uint      `json:"id"`
+	CID       uint      `json:"cid"`
+	ActorID   uint      `json:"actor_id"`
+	TargetID  uint      `json:"target_id"`
+	Action    string    `json:"action"`
+	OldValue  string    `json:"old_value"`
+	NewValue  string    `json:"new_value"`
+	CreatedAt time.Time `json:"created_at"`
+}
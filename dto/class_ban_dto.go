@@ -0,0 +1,21 @@
+package dto
+
+import "time"
+
+// BanUserRequest クラスでの利用停止リクエストDTO
+type BanUserRequest struct {
+	UID       uint       `json:"uid" binding:"required"`             // 利用停止にするユーザID
+	Reason    *string    `json:"reason" binding:"omitempty,max=255"` // 理由 (任意)
+	ExpiresAt *time.Time `json:"expires_at"`                         // 指定しない場合は解除するまで利用停止
+}
+
+// ClassBanDTO クラスで利用停止中のユーザー
+type ClassBanDTO struct {
+	UID       uint       `json:"uid"`
+	Name      string     `json:"name"`
+	Image     string     `json:"image"`
+	BannedBy  uint       `json:"banned_by"`
+	Reason    *string    `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	BannedAt  time.Time  `json:"banned_at"`
+}
@@ -1,6 +1,9 @@
 package dto
 
 import (
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 )
@@ -21,3 +24,32 @@ type ClassCodeRequest struct {
 func (r *ClassCodeRequest) Bind(c *gin.Context) error {
 	return c.ShouldBindWith(r, binding.JSON)
 }
+
+// CreateClassCodeRequest クラスコードの作成リクエスト
+type CreateClassCodeRequest struct {
+	Role      models.Role `json:"role" binding:"required"`            // 参加後のロール (APPLICANT, USER, ASSISTANT)
+	Secret    *string     `json:"secret" binding:"omitempty,max=20"`  // 指定しない場合はシークレットなし
+	ExpiresAt *time.Time  `json:"expires_at"`                         // 指定しない場合は期限なし
+	MaxUses   *int        `json:"max_uses" binding:"omitempty,min=1"` // 指定しない場合は回数の制限なし
+}
+
+// UpdateClassCodeSecretRequest クラスコードのシークレットの変更リクエスト
+type UpdateClassCodeSecretRequest struct {
+	Secret *string `json:"secret" binding:"omitempty,max=20"` // null または空の場合はシークレットを削除
+}
+
+// ClassCodeDTO クラスコード
+// シークレットの値は返さず、シークレットがあるかのみを返します。
+type ClassCodeDTO struct {
+	ID        uint        `json:"id"`
+	CID       uint        `json:"cid"`
+	Code      string      `json:"code"`
+	Role      models.Role `json:"role"`
+	HasSecret bool        `json:"has_secret"`
+	ExpiresAt *time.Time  `json:"expires_at"`
+	MaxUses   *int        `json:"max_uses"`
+	Uses      int         `json:"uses"`
+	Enabled   bool        `json:"enabled"`
+	Usable    bool        `json:"usable"`
+	CreatedAt time.Time   `json:"created_at"`
+}
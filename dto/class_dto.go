@@ -1,5 +1,7 @@
 package dto
 
+import "github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+
 // CreateClassRequest クラス作成リクエストDTO
 type CreateClassRequest struct {
 	Name        string  `form:"name"`                   // クラス名
@@ -14,3 +16,9 @@ type UpdateClassRequest struct {
 	Limitation  *int    `form:"limitation"`
 	Description *string `form:"description"`
 }
+
+// TransferOwnershipRequest クラスのオーナー変更リクエストDTO
+type TransferOwnershipRequest struct {
+	UID               uint         `json:"uid" binding:"required"` // 新しいオーナーのユーザID
+	PreviousOwnerRole *models.Role `json:"previous_owner_role"`    // 指定した場合、元のオーナーのロールを変更する (ADMIN, ASSISTANT, USER)
+}
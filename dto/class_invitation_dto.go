@@ -0,0 +1,39 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+)
+
+// InviteUserRequest 既存のユーザーをクラスに招待するリクエスト
+type InviteUserRequest struct {
+	UID       uint        `json:"uid" binding:"required"`  // 招待するユーザID
+	Role      models.Role `json:"role" binding:"required"` // 参加後のロール (USER または ASSISTANT)
+	ExpiresAt *time.Time  `json:"expires_at"`              // 指定しない場合は期限なし
+}
+
+// CreateInvitationLinkRequest 招待リンクの発行リクエスト
+type CreateInvitationLinkRequest struct {
+	Role      models.Role `json:"role" binding:"required"`       // 参加後のロール (USER または ASSISTANT)
+	ExpiresAt time.Time   `json:"expires_at" binding:"required"` // 有効期限
+}
+
+// ClassInvitationDTO クラスへの招待
+// InviteeID が null の場合は招待リンクです。
+type ClassInvitationDTO struct {
+	ID        uint        `json:"id"`
+	CID       uint        `json:"cid"`
+	InviterID uint        `json:"inviter_id"`
+	InviteeID *uint       `json:"invitee_id"`
+	Role      models.Role `json:"role"`
+	ExpiresAt *time.Time  `json:"expires_at"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// CreatedInvitationLinkDTO 発行した招待リンク
+// トークンは発行時にのみ返され、再表示はできません。
+type CreatedInvitationLinkDTO struct {
+	ClassInvitationDTO
+	Token string `json:"token"`
+}
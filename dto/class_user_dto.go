@@ -1,18 +1,53 @@
 package dto
 
+import (
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/pagination"
+)
+
 type UserClassInfoDTO struct {
-	ID          uint   `json:"id"`
-	Name        string `json:"name"`
-	Limitation  int    `json:"limitation"`
-	Description string `json:"description"`
-	Image       string `json:"image"`
-	IsFavorite  bool   `json:"is_favorite"`
-	Role        string `json:"role"`
+	ID          uint        `json:"id"`
+	Name        string      `json:"name"`
+	Limitation  int         `json:"limitation"`
+	Description string      `json:"description"`
+	Image       string      `json:"image"`
+	IsFavorite  bool        `json:"is_favorite"`
+	Role        models.Role `json:"role"`
 }
 
 type ClassMemberDTO struct {
-	Uid      uint   `json:"uid"`
-	Nickname string `json:"nickname"`
-	Role     string `json:"role"`
-	Image    string `json:"image"`
+	Uid      uint        `json:"uid"`
+	Nickname string      `json:"nickname"`
+	Role     models.Role `json:"role"`
+	Image    string      `json:"image"`
+	JoinedAt time.Time   `json:"joined_at"`
+}
+
+// ClassMemberQuery クラスのメンバーの検索条件
+// Role は複数指定でき、いずれかのロールのメンバーを返します。Cursor には前のページの next_cursor を指定します。
+type ClassMemberQuery struct {
+	Roles  []models.Role `form:"role"`
+	Search string        `form:"q" binding:"omitempty,max=50"`
+	Sort   string        `form:"sort" binding:"omitempty,oneof=nickname role joined_at"`
+	Order  string        `form:"order" binding:"omitempty,oneof=asc desc"`
+	Cursor string        `form:"cursor"`
+	Limit  int           `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// ClassMemberCursor メンバーの一覧のカーソル
+// 前のページの最後のメンバーの並び替えの値で、並び替えの列に応じて使用する値が決まります。
+type ClassMemberCursor struct {
+	Nickname string      `json:"n"`
+	Role     models.Role `json:"r"`
+	JoinedAt time.Time   `json:"j"`
+	UID      uint        `json:"u"`
+}
+
+// ClassMemberPageDTO クラスのメンバーの1ページ
+// 他の一覧と同じ pagination.Page に、条件に一致するメンバーの総数 Total を加えたものです。
+type ClassMemberPageDTO struct {
+	pagination.Page[ClassMemberDTO]
+	Total int64 `json:"total"`
 }
@@ -0,0 +1,8 @@
+package dto
+
+// DevLoginRequest 開発用ログインのリクエスト
+// UserIDを指定した場合は既存のユーザーで、指定しない場合はNameに対応する開発用ユーザーでログインします。
+type DevLoginRequest struct {
+	UserID uint   `json:"user_id"`
+	Name   string `json:"name"`
+}
@@ -1,7 +0,0 @@
-package dto
-
-type UserInput struct {
-	ID      string `json:"id"`
-	Picture string `json:"picture"`
-	Name    string `json:"name"`
-}
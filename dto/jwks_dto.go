@@ -0,0 +1,19 @@
+package dto
+
+// JSONWebKey 公開鍵を表すJWK (RFC 7517)
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet /.well-known/jwks.json で公開するJWKの一覧
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
@@ -0,0 +1,24 @@
+package dto
+
+import "github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+
+// MemberImportRow CSVから読み込んだ、クラスに追加するメンバーの1行
+// ユーザーは UID、またはログインプロバイダのアカウント (Provider と Subject) で指定します。
+type MemberImportRow struct {
+	Line     int         `json:"line"` // CSVの行番号 (ヘッダーが1行目)
+	UID      uint        `json:"uid,omitempty"`
+	Provider string      `json:"provider,omitempty"`
+	Subject  string      `json:"subject,omitempty"`
+	Nickname string      `json:"nickname"`
+	Role     models.Role `json:"role"`
+	Error    string      `json:"error,omitempty"` // 追加できない場合の理由
+}
+
+// MemberImportResultDTO メンバーの一括追加の結果
+// DryRun の場合、Imported は追加できる行数で、クラスは変更されていません。
+type MemberImportResultDTO struct {
+	DryRun   bool              `json:"dry_run"`
+	Imported int               `json:"imported"`
+	Failed   int               `json:"failed"`
+	Rows     []MemberImportRow `json:"rows"`
+}
@@ -0,0 +1,21 @@
+package dto
+
+import "time"
+
+// SessionInfo ログイン時に記録する端末の情報
+type SessionInfo struct {
+	Provider  string
+	UserAgent string
+	IP        string
+}
+
+// SessionDTO ログイン中のセッション
+type SessionDTO struct {
+	ID         string    `json:"id"`
+	Provider   string    `json:"provider"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
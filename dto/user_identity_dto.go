@@ -0,0 +1,24 @@
+package dto
+
+import "time"
+
+// UserIdentityDTO ユーザーに紐づくログインプロバイダのアカウント
+type UserIdentityDTO struct {
+	ID        uint      `json:"id"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LinkIdentityRequest ログインプロバイダのアカウントを紐づけるリクエスト
+type LinkIdentityRequest struct {
+	AuthCode string `json:"authCode" binding:"required"`
+	State    string `json:"state"`
+}
+
+// MergeUsersRequest 二つのアカウントを統合するリクエスト
+// SourceUserID のアカウントのデータを TargetUserID に移動し、SourceUserID は削除されます。
+type MergeUsersRequest struct {
+	SourceUserID uint `json:"source_user_id" binding:"required"`
+	TargetUserID uint `json:"target_user_id" binding:"required"`
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/docs"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/migration"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/pagination"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
@@ -39,11 +40,12 @@ var (
 func main() {
 	configureGinMode()
 	ensureEnvVariables()
+	ensurePaginationSecret()
 
 	db := initializeDatabase()
 	redisClient := initializeRedis()
 
-	jwtService := services.NewJWTService()
+	jwtService := services.NewJWTService(services.NewRedisRefreshTokenStore(redisClient), services.NewRedisTokenDenylist(redisClient), services.NewRedisSessionStore(redisClient))
 
 	services.NewRoomManager(redisClient)
 
@@ -87,12 +89,24 @@ func ensureEnvVariables() {
 	}
 }
 
+// ensurePaginationSecret カーソルの署名の鍵が設定されているか確認する
+// 起動ごとに鍵が変わると再起動や複数のインスタンスでカーソルが無効になるため、リリースモードでは起動しない
+func ensurePaginationSecret() {
+	if err := pagination.LoadSecret(); err != nil && gin.Mode() == gin.ReleaseMode {
+		log.Fatalf("環境変数 PAGINATION_SECRET または JWT_SECRET を設定してください: %v", err)
+	}
+}
+
 // initializeDatabase データベースを初期化する
+// RUN_MIGRATIONS=true の場合は起動時にスキーマとデータのマイグレーションを実行する
 func initializeDatabase() *gorm.DB {
 	db, err := migration.InitDB()
 	if err != nil {
 		log.Fatalf("データベースの初期化に失敗しました: %v", err)
 	}
+	if os.Getenv("RUN_MIGRATIONS") == "true" {
+		migration.Migrate(db)
+	}
 	return db
 }
 
@@ -118,9 +132,29 @@ func initializeRedis() *redis.Client {
 	return client
 }
 
+// initializeAuthProviders 環境変数または設定ファイルからログインプロバイダを初期化する
+func initializeAuthProviders(redisClient *redis.Client) *services.AuthProviderRegistry {
+	configs, err := services.LoadAuthProviderConfigs()
+	if err != nil {
+		log.Fatalf("ログインプロバイダの設定の読み込みに失敗しました: %v", err)
+	}
+
+	registry, err := services.NewAuthProviderRegistryFromConfigs(configs, func(provider string) services.OAuthStateStore {
+		return services.NewRedisOAuthStateStore(redisClient, provider)
+	})
+	if err != nil {
+		log.Fatalf("ログインプロバイダの初期化に失敗しました: %v", err)
+	}
+	log.Printf("ログインプロバイダ: %v", registry.Names())
+	return registry
+}
+
 // setupRouter ルーターをセットアップする
 func setupRouter(db *gorm.DB, jwtService services.JWTService) *gin.Engine {
 	router := gin.Default()
+	if err := middlewares.ConfigureTrustedProxies(router); err != nil {
+		log.Fatalf("信頼するプロキシの設定に失敗しました: %v", err)
+	}
 
 	allowedOrigins := []string{
 		"http://localhost:3000",
@@ -134,14 +168,23 @@ func setupRouter(db *gorm.DB, jwtService services.JWTService) *gin.Engine {
 
 	ignoredPaths := []string{
 		"/api/gin/swagger/",
+		"/.well-known/",
 	}
 
 	router.Use(globalErrorHandler)
 	router.Use(CORS(allowedOrigins, ignoredPaths))
 	initializeSwagger(router)
-	userController, classBoardController, classCodeController, classScheduleController, classUserController, attendanceController, googleAuthController, lineAuthController, createClassController, chatController := initializeControllers(db, redisClient)
-
-	setupRoutes(router, userController, classBoardController, classCodeController, classScheduleController, classUserController, attendanceController, googleAuthController, lineAuthController, createClassController, chatController, jwtService)
+	rateLimiter := services.NewRedisRateLimiter(redisClient)
+	authzService := services.NewAuthorizationService(repositories.NewClassUserRepository(db), repositories.NewClassResourceRepository(db))
+	apiKeyService := services.NewAPIKeyService(repositories.NewAPIKeyRepository(db), repositories.NewClassUserRepository(db))
+	userController, classBoardController, classCodeController, classScheduleController, classUserController, attendanceController, authProviderController, authController, createClassController, chatController, apiKeyController, classAuditController, classApplicationController, classInvitationController, classBanController := initializeControllers(db, redisClient, jwtService, apiKeyService, authzService)
+
+	setupRoutes(router, userController, classBoardController, classCodeController, classScheduleController, classUserController, attendanceController, authProviderController, authController, createClassController, chatController, apiKeyController, classAuditController, classApplicationController, classInvitationController, classBanController, jwtService, apiKeyService, rateLimiter, authzService)
+	if devLoginEnabled() {
+		log.Println("開発用ログインが有効です: POST /api/gin/auth/dev-login")
+		devLoginService := services.NewDevLoginService(repositories.NewUserRepository(db), repositories.NewAuthUserRepository(db))
+		setupDevLoginRoutes(router, controllers.NewDevLoginController(devLoginService, jwtService))
+	}
 	return router
 }
 
@@ -267,7 +310,7 @@ func startServer(router *gin.Engine) {
 }
 
 // initializeControllers コントローラーを初期化する
-func initializeControllers(db *gorm.DB, redisClient *redis.Client) (*controllers.UserController, *controllers.ClassBoardController, *controllers.ClassCodeController, *controllers.ClassScheduleController, *controllers.ClassUserController, *controllers.AttendanceController, *controllers.GoogleAuthController, *controllers.LINEAuthController, *controllers.ClassController, *controllers.ChatController) {
+func initializeControllers(db *gorm.DB, redisClient *redis.Client, jwtService services.JWTService, apiKeyService services.APIKeyService, authzService services.AuthorizationService) (*controllers.UserController, *controllers.ClassBoardController, *controllers.ClassCodeController, *controllers.ClassScheduleController, *controllers.ClassUserController, *controllers.AttendanceController, *controllers.AuthProviderController, *controllers.AuthController, *controllers.ClassController, *controllers.ChatController, *controllers.APIKeyController, *controllers.ClassAuditController, *controllers.ClassApplicationController, *controllers.ClassInvitationController, *controllers.ClassBanController) {
 	userRepo := repositories.NewUserRepository(db)
 	classRepo := repositories.NewClassRepository(db)
 	classBoardRepo := repositories.NewClassBoardRepository(db)
@@ -276,50 +319,63 @@ func initializeControllers(db *gorm.DB, redisClient *redis.Client) (*controllers
 	classUserRepo := repositories.NewClassUserRepository(db)
 	roleRepo := repositories.NewRoleRepository(db)
 	attendanceRepo := repositories.NewAttendanceRepository(db)
-	googleAuthRepo := repositories.NewGoogleAuthRepository(db)
-	lineAuthRepo := repositories.NewLINEAuthRepository(db)
+	authUserRepo := repositories.NewAuthUserRepository(db)
+	classAuditRepo := repositories.NewClassAuditRepository(db)
+	classApplicationRepo := repositories.NewClassApplicationRepository(db)
+	classInvitationRepo := repositories.NewClassInvitationRepository(db)
+	classBanRepo := repositories.NewClassBanRepository(db)
 
-	userService := services.NewCreateUserService(userRepo)
+	userService := services.NewCreateUserService(userRepo, jwtService)
 	classBoardService := services.NewClassBoardService(classBoardRepo)
-	classCodeService := services.NewClassCodeService(classCodeRepo)
+	classCodeService := services.NewClassCodeService(classCodeRepo, classUserRepo)
 	classUserService := services.NewClassUserService(classUserRepo, roleRepo)
 	classScheduleService := services.NewClassScheduleService(classScheduleRepo)
-	attendanceService := services.NewAttendanceService(attendanceRepo)
-	googleAuthService := services.NewGoogleAuthService(googleAuthRepo)
-	lineAuthService := services.NewLINEAuthService(lineAuthRepo)
-	jwtService := services.NewJWTService()
+	attendanceService := services.NewAttendanceService(attendanceRepo, classScheduleRepo)
+	authProviderService := services.NewAuthProviderService(initializeAuthProviders(redisClient), authUserRepo)
 	chatManager := services.NewRoomManager(redisClient)
 	go manageChatRooms(db, chatManager)
 
 	createClassService := services.NewCreateClassService(classRepo, classUserRepo, classCodeRepo, userRepo)
+	classAuditService := services.NewClassAuditService(classAuditRepo, classUserRepo)
+	classApplicationService := services.NewClassApplicationService(classApplicationRepo, classUserRepo)
+	classInvitationService := services.NewClassInvitationService(classInvitationRepo, classUserRepo)
+	classBanService := services.NewClassBanService(classBanRepo, classUserRepo)
+	go liftExpiredBans(classBanService)
 
 	uploader := utils.NewAwsUploader()
 	userController := controllers.NewCreateUserController(userService)
-	classBoardController := controllers.NewClassBoardController(classBoardService, uploader)
-	classCodeController := controllers.NewClassCodeController(classCodeService, classUserService)
+	classBoardController := controllers.NewClassBoardController(classBoardService, uploader, authzService)
+	classCodeController := controllers.NewClassCodeController(classCodeService)
 	classScheduleController := controllers.NewClassScheduleController(classScheduleService)
 	classUserController := controllers.NewClassUserController(classUserService)
 	attendanceController := controllers.NewAttendanceController(attendanceService)
-	googleAuthController := controllers.NewGoogleAuthController(googleAuthService, jwtService)
-	lineAuthController := controllers.NewLINEAuthController(lineAuthService, jwtService)
+	authProviderController := controllers.NewAuthProviderController(authProviderService, jwtService)
+	authController := controllers.NewAuthController(jwtService)
 	createClassController := controllers.NewCreateClassController(createClassService, uploader)
-	chatController := controllers.NewChatController(chatManager, redisClient)
-
-	return userController, classBoardController, classCodeController, classScheduleController, classUserController, attendanceController, googleAuthController, lineAuthController, createClassController, chatController
+	chatController := controllers.NewChatController(chatManager, redisClient, authzService)
+	apiKeyController := controllers.NewAPIKeyController(apiKeyService)
+	classAuditController := controllers.NewClassAuditController(classAuditService)
+	classApplicationController := controllers.NewClassApplicationController(classApplicationService)
+	classInvitationController := controllers.NewClassInvitationController(classInvitationService)
+	classBanController := controllers.NewClassBanController(classBanService)
+
+	return userController, classBoardController, classCodeController, classScheduleController, classUserController, attendanceController, authProviderController, authController, createClassController, chatController, apiKeyController, classAuditController, classApplicationController, classInvitationController, classBanController
 }
 
 // setupRoutes ルートをセットアップする
-func setupRoutes(router *gin.Engine, userController *controllers.UserController, classBoardController *controllers.ClassBoardController, classCodeController *controllers.ClassCodeController, classScheduleController *controllers.ClassScheduleController, classUserController *controllers.ClassUserController, attendanceController *controllers.AttendanceController, googleAuthController *controllers.GoogleAuthController, lineAuthController *controllers.LINEAuthController, createClassController *controllers.ClassController, chatController *controllers.ChatController, jwtService services.JWTService) {
+func setupRoutes(router *gin.Engine, userController *controllers.UserController, classBoardController *controllers.ClassBoardController, classCodeController *controllers.ClassCodeController, classScheduleController *controllers.ClassScheduleController, classUserController *controllers.ClassUserController, attendanceController *controllers.AttendanceController, authProviderController *controllers.AuthProviderController, authController *controllers.AuthController, createClassController *controllers.ClassController, chatController *controllers.ChatController, apiKeyController *controllers.APIKeyController, classAuditController *controllers.ClassAuditController, classApplicationController *controllers.ClassApplicationController, classInvitationController *controllers.ClassInvitationController, classBanController *controllers.ClassBanController, jwtService services.JWTService, apiKeyService services.APIKeyService, rateLimiter services.RateLimiter, authzService services.AuthorizationService) {
 	setupUserRoutes(router, userController, jwtService)
-	setupClassBoardRoutes(router, classBoardController, jwtService)
-	setupClassCodeRoutes(router, classCodeController, jwtService)
-	setupClassScheduleRoutes(router, classScheduleController, jwtService)
-	setupClassUserRoutes(router, classUserController, jwtService)
-	setupAttendanceRoutes(router, attendanceController, jwtService)
-	setupGoogleAuthRoutes(router, googleAuthController)
-	setupLINEAuthRoutes(router, lineAuthController)
-	setupCreateClassRoutes(router, createClassController, jwtService)
-	setupChatRoutes(router, chatController, jwtService)
+	setupClassBoardRoutes(router, classBoardController, jwtService, apiKeyService, authzService)
+	setupClassCodeRoutes(router, classCodeController, jwtService, rateLimiter, authzService)
+	setupClassScheduleRoutes(router, classScheduleController, jwtService, apiKeyService, authzService)
+	setupClassUserRoutes(router, classUserController, classApplicationController, classBanController, jwtService, authzService)
+	setupAttendanceRoutes(router, attendanceController, jwtService, apiKeyService, authzService)
+	setupAuthProviderRoutes(router, authProviderController, jwtService, rateLimiter)
+	setupAuthRoutes(router, authController, jwtService)
+	setupCreateClassRoutes(router, createClassController, classAuditController, classUserController, jwtService, authzService)
+	setupChatRoutes(router, chatController, jwtService, authzService)
+	setupAPIKeyRoutes(router, apiKeyController, jwtService, authzService)
+	setupClassInvitationRoutes(router, classInvitationController, jwtService, authzService)
 }
 
 // @securityDefinitions.apikey Bearer
@@ -330,9 +386,10 @@ func setupUserRoutes(router *gin.Engine, controller *controllers.UserController,
 	u := router.Group("/api/gin/u")
 	u.Use(middlewares.TokenAuthMiddleware(jwtService))
 	{
-		u.GET(":userID/applying-classes", controller.GetApplyingClasses)
+		u.GET(":userID/applying-classes", middlewares.OwnershipMiddleware("userID"), controller.GetApplyingClasses)
 		u.GET("search", controller.SearchByName)
-		u.DELETE(":userID/delete", controller.RemoveUserFromService)
+		u.DELETE(":userID/delete", middlewares.OwnershipMiddleware("userID"), controller.RemoveUserFromService)
+		u.POST("merge", middlewares.PlatformAdminMiddleware(), controller.MergeUsers)
 	}
 }
 
@@ -341,9 +398,9 @@ func setupUserRoutes(router *gin.Engine, controller *controllers.UserController,
 // @in header
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
-func setupClassBoardRoutes(router *gin.Engine, controller *controllers.ClassBoardController, jwtService services.JWTService) {
+func setupClassBoardRoutes(router *gin.Engine, controller *controllers.ClassBoardController, jwtService services.JWTService, apiKeyService services.APIKeyService, authzService services.AuthorizationService) {
 	cb := router.Group("/api/gin/cb")
-	cb.Use(middlewares.TokenAuthMiddleware(jwtService))
+	cb.Use(middlewares.ScopedAuthMiddleware(jwtService, apiKeyService), middlewares.ClassPermissionMiddleware(authzService))
 	{
 		cb.GET("", controller.GetAllClassBoards)
 		cb.GET(":id", controller.GetClassBoardByID)
@@ -351,7 +408,7 @@ func setupClassBoardRoutes(router *gin.Engine, controller *controllers.ClassBoar
 
 		// TODO: フロントエンド側の実装が完了したら、削除
 		cb.POST("", controller.CreateClassBoard)
-		cb.PATCH(":id/:cid/:uid", controller.UpdateClassBoard)
+		cb.PATCH(":id/:cid/:uid", middlewares.OwnershipMiddleware("uid"), controller.UpdateClassBoard)
 		cb.DELETE(":id", controller.DeleteClassBoard)
 
 		cb.GET("subscribe", controller.SubscribeClassBoardUpdates)
@@ -364,13 +421,30 @@ func setupClassBoardRoutes(router *gin.Engine, controller *controllers.ClassBoar
 // @in header
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
-func setupClassCodeRoutes(router *gin.Engine, controller *controllers.ClassCodeController, jwtService services.JWTService) {
+func setupClassCodeRoutes(router *gin.Engine, controller *controllers.ClassCodeController, jwtService services.JWTService, rateLimiter services.RateLimiter, authzService services.AuthorizationService) {
+	// クラスコードとシークレットの総当たりを防ぐため、IPアドレス、ユーザー、クラスコードごとに制限する
 	cc := router.Group("/api/gin/cc")
-	cc.Use(middlewares.TokenAuthMiddleware(jwtService))
+	cc.Use(
+		middlewares.RateLimitMiddleware(rateLimiter, "class_code_ip", services.RateLimitFromEnv("class_code_ip", services.RateLimit{Limit: 30, Window: time.Minute}), middlewares.RateLimitByIP),
+		middlewares.TokenAuthMiddleware(jwtService),
+		middlewares.RateLimitMiddleware(rateLimiter, "class_code_user", services.RateLimitFromEnv("class_code_user", services.RateLimit{Limit: 10, Window: time.Minute}), middlewares.RateLimitByUserID),
+		middlewares.RateLimitMiddleware(rateLimiter, "class_code", services.RateLimitFromEnv("class_code", services.RateLimit{Limit: 20, Window: 10 * time.Minute}), middlewares.RateLimitByQuery("code")),
+	)
 	{
 		cc.GET("checkSecretExists", controller.CheckSecretExists)
-		cc.GET("verifyClassCode", controller.VerifyClassCode)
-		cc.GET("verifyAndRequestAccess", controller.VerifyAndRequestAccess)
+		cc.GET("verifyClassCode", middlewares.OwnershipMiddleware("uid"), controller.VerifyClassCode)
+		cc.GET("verifyAndRequestAccess", middlewares.OwnershipMiddleware("uid"), controller.VerifyAndRequestAccess)
+	}
+
+	// クラスコードの管理はコードの総当たりの制限とは別に、クラスの権限で制御する
+	codes := router.Group("/api/gin/cc/class/:cid/codes")
+	codes.Use(middlewares.TokenAuthMiddleware(jwtService), middlewares.ClassPermissionMiddleware(authzService))
+	{
+		codes.GET("", controller.ListClassCodes)
+		codes.POST("", controller.CreateClassCode)
+		codes.POST(":id/regenerate", controller.RegenerateClassCode)
+		codes.DELETE(":id", controller.RevokeClassCode)
+		codes.PUT(":id/secret", controller.UpdateClassCodeSecret)
 	}
 }
 
@@ -379,9 +453,9 @@ func setupClassCodeRoutes(router *gin.Engine, controller *controllers.ClassCodeC
 // @in header
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
-func setupClassScheduleRoutes(router *gin.Engine, controller *controllers.ClassScheduleController, jwtService services.JWTService) {
+func setupClassScheduleRoutes(router *gin.Engine, controller *controllers.ClassScheduleController, jwtService services.JWTService, apiKeyService services.APIKeyService, authzService services.AuthorizationService) {
 	cs := router.Group("/api/gin/cs")
-	cs.Use(middlewares.TokenAuthMiddleware(jwtService))
+	cs.Use(middlewares.ScopedAuthMiddleware(jwtService, apiKeyService), middlewares.ClassPermissionMiddleware(authzService))
 	{
 		cs.GET("", controller.GetAllClassSchedules)
 		cs.GET(":id", controller.GetClassScheduleByID)
@@ -395,22 +469,58 @@ func setupClassScheduleRoutes(router *gin.Engine, controller *controllers.ClassS
 	}
 }
 
-// setupGoogleAuthRoutes GoogleLoginのルートをセットアップする
-func setupGoogleAuthRoutes(router *gin.Engine, controller *controllers.GoogleAuthController) {
-	g := router.Group("/api/gin/auth/google")
+// setupAuthProviderRoutes ログインプロバイダ (google, line など) のルートをセットアップする
+func setupAuthProviderRoutes(router *gin.Engine, controller *controllers.AuthProviderController, jwtService services.JWTService, rateLimiter services.RateLimiter) {
+	processLimit := middlewares.RateLimitMiddleware(rateLimiter, "auth_process", services.RateLimitFromEnv("auth_process", services.RateLimit{Limit: 10, Window: time.Minute}), middlewares.RateLimitByIP)
+
+	g := router.Group("/api/gin/auth/:provider")
 	{
-		g.GET("login", controller.GoogleLoginHandler)
-		g.POST("process", controller.ProcessAuthCode)
+		g.GET("login", controller.LoginHandler)
+		g.POST("process", processLimit, controller.ProcessAuthCode)
 		g.POST("refresh-token", controller.RefreshAccessTokenHandler)
 	}
+
+	i := router.Group("/api/gin/auth/identities")
+	i.Use(middlewares.TokenAuthMiddleware(jwtService))
+	{
+		i.GET("", controller.ListIdentities)
+		i.GET(":provider/url", controller.LinkURLHandler)
+		i.POST(":provider", processLimit, controller.LinkIdentity)
+		i.DELETE(":identityID", controller.UnlinkIdentity)
+	}
+}
+
+// devLoginEnabled 開発用ログインを有効にするかを返す
+// リリースモードでは環境変数に関わらず無効になる
+func devLoginEnabled() bool {
+	return gin.Mode() != gin.ReleaseMode && os.Getenv("DEV_LOGIN_ENABLED") == "true"
 }
 
-func setupLINEAuthRoutes(router *gin.Engine, controller *controllers.LINEAuthController) {
-	g := router.Group("/api/gin/auth/line")
+// setupDevLoginRoutes 開発用ログインのルートをセットアップする
+func setupDevLoginRoutes(router *gin.Engine, controller *controllers.DevLoginController) {
+	d := router.Group("/api/gin/auth/dev-login")
 	{
-		g.GET("login", controller.LINELoginHandler)
-		g.POST("process", controller.ProcessAuthCode)
-		g.POST("refresh-token", controller.RefreshAccessTokenHandler)
+		d.POST("", controller.Login)
+		d.POST("refresh-token", controller.RefreshAccessTokenHandler)
+	}
+}
+
+// setupAuthRoutes ログインプロバイダ共通の認証ルートをセットアップする
+// @securityDefinitions.apikey Bearer
+// @in header
+// @name Authorization
+// @description Type "Bearer" followed by a space and JWT token.
+func setupAuthRoutes(router *gin.Engine, controller *controllers.AuthController, jwtService services.JWTService) {
+	router.GET("/.well-known/jwks.json", controller.JWKS)
+
+	a := router.Group("/api/gin/auth")
+	a.Use(middlewares.TokenAuthMiddleware(jwtService))
+	{
+		a.POST("logout", controller.Logout)
+		a.POST("revoke-all", controller.RevokeAllTokens)
+		a.GET("sessions", controller.ListSessions)
+		a.DELETE("sessions/:id", controller.RevokeSession)
+		a.POST("users/:userID/revoke", middlewares.PlatformAdminMiddleware(), controller.RevokeUserTokens)
 	}
 }
 
@@ -419,14 +529,18 @@ func setupLINEAuthRoutes(router *gin.Engine, controller *controllers.LINEAuthCon
 // @in header
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
-func setupCreateClassRoutes(router *gin.Engine, controller *controllers.ClassController, jwtService services.JWTService) {
+func setupCreateClassRoutes(router *gin.Engine, controller *controllers.ClassController, auditController *controllers.ClassAuditController, memberController *controllers.ClassUserController, jwtService services.JWTService, authzService services.AuthorizationService) {
 	cl := router.Group("/api/gin/cl")
-	cl.Use(middlewares.TokenAuthMiddleware(jwtService))
+	cl.Use(middlewares.TokenAuthMiddleware(jwtService), middlewares.ClassPermissionMiddleware(authzService))
 	{
 		cl.GET(":cid", controller.GetClass)
 		cl.POST("create", controller.CreateClass)
-		cl.PATCH(":uid/:cid", controller.UpdateClass)
-		cl.DELETE(":uid/:cid", controller.DeleteClass)
+		cl.PATCH(":uid/:cid", middlewares.OwnershipMiddleware("uid"), controller.UpdateClass)
+		cl.DELETE(":uid/:cid", middlewares.OwnershipMiddleware("uid"), controller.DeleteClass)
+		cl.GET(":cid/audit", auditController.ListClassAuditEvents)
+		cl.POST(":cid/transfer", controller.TransferOwnership)
+		cl.POST(":cid/members/import", memberController.ImportMembers)
+		cl.GET(":cid/members/export", memberController.ExportMembers)
 	}
 }
 
@@ -435,24 +549,34 @@ func setupCreateClassRoutes(router *gin.Engine, controller *controllers.ClassCon
 // @in header
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
-func setupClassUserRoutes(router *gin.Engine, controller *controllers.ClassUserController, jwtService services.JWTService) {
+func setupClassUserRoutes(router *gin.Engine, controller *controllers.ClassUserController, applicationController *controllers.ClassApplicationController, banController *controllers.ClassBanController, jwtService services.JWTService, authzService services.AuthorizationService) {
 	cu := router.Group("/api/gin/cu")
-	cu.Use(middlewares.TokenAuthMiddleware(jwtService))
+	cu.Use(middlewares.TokenAuthMiddleware(jwtService), middlewares.ClassPermissionMiddleware(authzService))
 	{
 		// TODO: フロントエンド側の実装が完了したら、削除
 		cu.GET("class/:cid/members", controller.GetClassMembers)
-
+		cu.GET("class/:cid/applicants", applicationController.ListApplicants)
+		cu.GET("class/:cid/waitlist", applicationController.ListWaitlist)
+		cu.POST("class/:cid/applicants/approve", applicationController.ApproveApplicants)
+		cu.POST("class/:cid/applicants/reject", applicationController.RejectApplicants)
+		cu.GET("class/:cid/bans", banController.ListBans)
+		cu.POST("class/:cid/bans", banController.BanUser)
+		cu.DELETE("class/:cid/bans/:uid", banController.LiftBan)
+
+		// :uid は操作対象のユーザー。本人のみが扱えるルートは OwnershipMiddleware で、
+		// 他のユーザーを操作するルートは authorization.Routes の権限で確認する
 		userRoutes := cu.Group(":uid")
 		{
+			self := middlewares.OwnershipMiddleware("uid")
 			userRoutes.GET(":cid/info", controller.GetUserClassUserInfo)
-			userRoutes.GET("classes", controller.GetUserClasses)
-			userRoutes.GET("favorite-classes", controller.GetFavoriteClasses)
-			userRoutes.GET("classes/by-role", controller.GetUserClassesByRole)
+			userRoutes.GET("classes", self, controller.GetUserClasses)
+			userRoutes.GET("favorite-classes", self, controller.GetFavoriteClasses)
+			userRoutes.GET("classes/by-role", self, controller.GetUserClassesByRole)
 			userRoutes.PATCH(":cid/role/:roleName", controller.ChangeUserRole)
-			userRoutes.PATCH(":cid/toggle-favorite", controller.ToggleFavorite)
+			userRoutes.PATCH(":cid/toggle-favorite", self, controller.ToggleFavorite)
 			userRoutes.PUT(":cid/:rename", controller.UpdateUserName)
 			userRoutes.DELETE(":cid/remove", controller.RemoveUserFromClass)
-			userRoutes.GET("classes/search", controller.SearchUserClassesByName)
+			userRoutes.GET("classes/search", self, controller.SearchUserClassesByName)
 		}
 	}
 }
@@ -462,9 +586,9 @@ func setupClassUserRoutes(router *gin.Engine, controller *controllers.ClassUserC
 // @in header
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
-func setupAttendanceRoutes(router *gin.Engine, controller *controllers.AttendanceController, jwtService services.JWTService) {
+func setupAttendanceRoutes(router *gin.Engine, controller *controllers.AttendanceController, jwtService services.JWTService, apiKeyService services.APIKeyService, authzService services.AuthorizationService) {
 	at := router.Group("/api/gin/attendances")
-	at.Use(middlewares.TokenAuthMiddleware(jwtService))
+	at.Use(middlewares.ScopedAuthMiddleware(jwtService, apiKeyService), middlewares.ClassPermissionMiddleware(authzService))
 	{
 		at.POST("", controller.CreateOrUpdateAttendances)
 		at.GET("/class/:classId", controller.GetAttendancesByClass)
@@ -478,19 +602,37 @@ func setupAttendanceRoutes(router *gin.Engine, controller *controllers.Attendanc
 // @in header
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
-func setupChatRoutes(router *gin.Engine, chatController *controllers.ChatController, jwtService services.JWTService) {
+func setupChatRoutes(router *gin.Engine, chatController *controllers.ChatController, jwtService services.JWTService, authzService services.AuthorizationService) {
 	chat := router.Group("/api/gin/chat")
-	chat.Use(middlewares.TokenAuthMiddleware(jwtService))
+	chat.Use(middlewares.TokenAuthMiddleware(jwtService), middlewares.ClassPermissionMiddleware(authzService))
 	{
 		chat.POST("create-room/:scheduleId", chatController.CreateChatRoom)
-		chat.GET("room/:scheduleId/:userId", chatController.HandleChatRoom)
+		chat.GET("room/:scheduleId/:userId", middlewares.OwnershipMiddleware("userId"), chatController.HandleChatRoom)
 		chat.POST("room/:scheduleId", chatController.PostToChatRoom)
 		chat.DELETE("room/:scheduleId", chatController.DeleteChatRoom)
 		chat.GET("stream/:scheduleId", chatController.StreamChat)
 		chat.GET("messages/:roomid", chatController.GetChatMessages)
-		chat.POST("dm/:senderId/:receiverId", chatController.SendDirectMessage)
-		chat.GET("dm/:senderId/:receiverId", chatController.GetDirectMessages)
-		chat.DELETE("dm/:senderId/:receiverId", chatController.DeleteDirectMessages)
+		chat.POST("dm/:senderId/:receiverId", middlewares.OwnershipMiddleware("senderId"), chatController.SendDirectMessage)
+		chat.GET("dm/:senderId/:receiverId", middlewares.OwnershipMiddleware("senderId", "receiverId"), chatController.GetDirectMessages)
+		chat.DELETE("dm/:senderId/:receiverId", middlewares.OwnershipMiddleware("senderId"), chatController.DeleteDirectMessages)
+	}
+}
+
+// liftExpiredBans 期限が切れたクラスの利用停止を定期的に解除する
+func liftExpiredBans(classBanService services.ClassBanService) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		<-ticker.C
+		lifted, err := classBanService.LiftExpired()
+		if err != nil {
+			log.Printf("期限切れの利用停止の解除に失敗しました: %v", err)
+			continue
+		}
+		if lifted > 0 {
+			log.Printf("期限切れの利用停止を%d件解除しました", lifted)
+		}
 	}
 }
 
@@ -516,3 +658,49 @@ func manageChatRooms(db *gorm.DB, chatManager *services.Manager) {
 		}
 	}
 }
+
+// setupAPIKeyRoutes APIKeyのルートをセットアップする
+// サービス全体のキーはサービス管理者のみ、クラスのキーは api_keys.manage の権限を持つユーザーのみが管理できる
+// @securityDefinitions.apikey Bearer
+// @in header
+// @name Authorization
+// @description Type "Bearer" followed by a space and JWT token.
+func setupAPIKeyRoutes(router *gin.Engine, controller *controllers.APIKeyController, jwtService services.JWTService, authzService services.AuthorizationService) {
+	platform := router.Group("/api/gin/api-keys")
+	platform.Use(middlewares.TokenAuthMiddleware(jwtService), middlewares.PlatformAdminMiddleware())
+	{
+		platform.GET("", controller.ListAPIKeys)
+		platform.POST("", controller.CreateAPIKey)
+		platform.PATCH(":id", controller.UpdateAPIKey)
+		platform.DELETE(":id", controller.DeleteAPIKey)
+	}
+
+	class := router.Group("/api/gin/api-keys/class/:cid")
+	class.Use(middlewares.TokenAuthMiddleware(jwtService), middlewares.ClassPermissionMiddleware(authzService))
+	{
+		class.GET("", controller.ListAPIKeys)
+		class.POST("", controller.CreateAPIKey)
+		class.PATCH(":id", controller.UpdateAPIKey)
+		class.DELETE(":id", controller.DeleteAPIKey)
+	}
+}
+
+// setupClassInvitationRoutes ClassInvitationのルートをセットアップする
+// 招待の作成、一覧、取り消しは members.manage の権限を持つユーザーのみ、承諾と辞退は招待されたユーザーが行う
+// @securityDefinitions.apikey Bearer
+// @in header
+// @name Authorization
+// @description Type "Bearer" followed by a space and JWT token.
+func setupClassInvitationRoutes(router *gin.Engine, controller *controllers.ClassInvitationController, jwtService services.JWTService, authzService services.AuthorizationService) {
+	inv := router.Group("/api/gin/invitations")
+	inv.Use(middlewares.TokenAuthMiddleware(jwtService), middlewares.ClassPermissionMiddleware(authzService))
+	{
+		inv.GET("class/:cid", controller.ListInvitations)
+		inv.POST("class/:cid", controller.InviteUser)
+		inv.POST("class/:cid/links", controller.CreateInvitationLink)
+		inv.DELETE("class/:cid/:id", controller.RevokeInvitation)
+		inv.POST("class/:cid/accept", controller.AcceptInvitation)
+		inv.POST("class/:cid/decline", controller.DeclineInvitation)
+		inv.POST("links/:token/accept", controller.AcceptInvitationLink)
+	}
+}
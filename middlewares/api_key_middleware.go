@@ -0,0 +1,54 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/authorization"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeySchema はAPIキーを送るAuthorizationヘッダーのスキーム
+const apiKeySchema = "ApiKey "
+
+// ScopedAuthMiddleware はJWTまたはAPIキーで認証するミドルウェアです。
+// "Authorization: ApiKey <key>" の場合は、authorization.Routes に登録されたルートの権限に対応するスコープをキーが持っていることを確認します。
+// クラスのキーで他のクラスを操作できないことは ClassPermissionMiddleware で確認します。
+// APIキーで認証したリクエストでは、userID にキーを発行したユーザー、apiKey にキーを設定します。
+// それ以外のリクエストは TokenAuthMiddleware と同じくJWTで認証します。
+// APIキーで行えない操作のルートでは403を返します。
+func ScopedAuthMiddleware(jwtService services.JWTService, apiKeyService services.APIKeyService) gin.HandlerFunc {
+	tokenAuth := TokenAuthMiddleware(jwtService)
+
+	return func(ctx *gin.Context) {
+		header := ctx.GetHeader("Authorization")
+		if !strings.HasPrefix(header, apiKeySchema) {
+			tokenAuth(ctx)
+			return
+		}
+
+		key, err := apiKeyService.Authenticate(strings.TrimSpace(header[len(apiKeySchema):]))
+		if errors.Is(err, services.ErrInvalidAPIKey) {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": constants.InvalidAPIKey})
+			return
+		}
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check API key"})
+			return
+		}
+
+		rule, _ := authorization.RuleFor(ctx.Request.Method, ctx.FullPath())
+		scope, ok := services.ScopeForPermission(rule.Permission)
+		if !ok || !services.HasScope(key, scope) {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": constants.APIKeyScopeDenied})
+			return
+		}
+
+		ctx.Set("userID", key.UID)
+		ctx.Set("apiKey", key)
+		ctx.Next()
+	}
+}
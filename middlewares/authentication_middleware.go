@@ -2,7 +2,9 @@ package middlewares
 
 import (
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
@@ -10,57 +12,26 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-const (
-	AdminRole     = "ADMIN"
-	AssistantRole = "ASSISTANT"
-)
-
-// getUserInfoFromPath はクエリパラメータからユーザー情報を取得します。
-func getUserInfoFromPath(ctx *gin.Context) (uid uint, cid uint, err error) {
-	uidStr, cidStr := ctx.Query("uid"), ctx.Query("cid")
-	uidUint, uidErr := strconv.ParseUint(uidStr, 10, 32)
-	cidUint, cidErr := strconv.ParseUint(cidStr, 10, 32)
-	if uidErr != nil || cidErr != nil {
-		return 0, 0, err
+// PlatformAdminMiddleware は環境変数 ADMIN_USER_IDS に登録されたサービス管理者かどうかを確認するミドルウェアです。
+// TokenAuthMiddleware の後に使用してください。
+func PlatformAdminMiddleware() gin.HandlerFunc {
+	adminIDs := make(map[uint]bool)
+	for _, idStr := range strings.Split(os.Getenv("ADMIN_USER_IDS"), ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(idStr), 10, 32)
+		if err == nil {
+			adminIDs[uint(id)] = true
+		}
 	}
 
-	return uint(uidUint), uint(cidUint), nil
-}
-
-// ClassUserRoleMiddleware は指定された権限を持っているかどうかを確認するミドルウェアです。
-func ClassUserRoleMiddleware(roleService services.ClassUserService, requiredRoleName string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		uid, cid, err := getUserInfoFromPath(ctx)
-		if err != nil {
-			ctx.AbortWithStatusJSON(constants.StatusUnauthorized, gin.H{"error": "Unauthorized: invalid user or class ID"})
+		if !adminIDs[ctx.GetUint("userID")] {
+			ctx.AbortWithStatusJSON(constants.StatusForbidden, gin.H{"error": "Forbidden: platform admin only"})
 			return
 		}
-
-		roleName, err := roleService.GetRole(uid, cid)
-		if err != nil {
-			ctx.AbortWithStatusJSON(constants.StatusUnauthorized, gin.H{"error": "Unauthorized: role check failed"})
-			return
-		}
-
-		if roleName != requiredRoleName {
-			ctx.AbortWithStatusJSON(constants.StatusForbidden, gin.H{"error": "Forbidden: insufficient privileges"})
-			return
-		}
-
 		ctx.Next()
 	}
 }
 
-// AdminMiddleware は管理者権限を持っているかどうかを確認するミドルウェアです。
-func AdminMiddleware(roleService services.ClassUserService) gin.HandlerFunc {
-	return ClassUserRoleMiddleware(roleService, AdminRole)
-}
-
-// AssistantMiddleware はアシスタント権限を持っているかどうかを確認するミドルウェアです。
-func AssistantMiddleware(roleService services.ClassUserService) gin.HandlerFunc {
-	return ClassUserRoleMiddleware(roleService, AssistantRole)
-}
-
 func AuthMiddleware(authenticate func(token string) bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := c.GetHeader("Authorization")
@@ -90,8 +61,26 @@ func TokenAuthMiddleware(jwtService services.JWTService) gin.HandlerFunc {
 		}
 
 		claims := token.Claims.(jwt.MapClaims)
+		if claims["type"] == "refresh" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API token"})
+			return
+		}
+
+		revoked, err := jwtService.IsTokenRevoked(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check API token"})
+			return
+		}
+		if revoked {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API token has been revoked"})
+			return
+		}
+
+		jwtService.TouchSession(token)
+
 		userID := uint(claims["id"].(float64))
 		c.Set("userID", userID)
+		c.Set("accessToken", token)
 
 		c.Next()
 	}
@@ -0,0 +1,194 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/authorization"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// errInvalidClassID はリクエストからクラスIDを取得できない場合のエラー
+var errInvalidClassID = errors.New("invalid class id")
+
+// ClassPermissionMiddleware は authorization.Routes に登録されたルートの権限を確認するミドルウェアです。
+// 認証ミドルウェアの後に使用してください。登録されていないルートは403を返します。
+// サービス全体のAPIキーはスコープのみで認可し、クラスのAPIキーはキーのクラス以外を操作できません。
+func ClassPermissionMiddleware(authz services.AuthorizationService) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		rule, ok := authorization.RuleFor(ctx.Request.Method, ctx.FullPath())
+		if !ok {
+			log.Printf("権限が登録されていないルートです: %s %s", ctx.Request.Method, ctx.FullPath())
+			ctx.AbortWithStatusJSON(constants.StatusForbidden, gin.H{"error": constants.Forbidden})
+			return
+		}
+		if rule.Permission == "" {
+			ctx.Next()
+			return
+		}
+
+		// サービス全体のAPIキーはスコープのみで認可し、クラスのAPIキーはクラスを特定できないルートを使用できない
+		key := requestAPIKey(ctx)
+		if key != nil && key.CID == nil {
+			ctx.Next()
+			return
+		}
+		if rule.Class.Kind == authorization.NoClass {
+			if key != nil {
+				ctx.AbortWithStatusJSON(constants.StatusForbidden, gin.H{"error": constants.Forbidden})
+				return
+			}
+			ctx.Next()
+			return
+		}
+
+		userID := ctx.GetUint("userID")
+		if rule.Self != "" && ctx.Param(rule.Self) == strconv.FormatUint(uint64(userID), 10) {
+			ctx.Next()
+			return
+		}
+
+		classIDs, err := resolveClassIDs(ctx, authz, rule.Class)
+		switch {
+		case errors.Is(err, errInvalidClassID):
+			ctx.AbortWithStatusJSON(constants.StatusBadRequest, gin.H{"error": constants.InvalidRequest})
+			return
+		case errors.Is(err, services.ErrNotFound):
+			ctx.AbortWithStatusJSON(constants.StatusNotFound, gin.H{"error": constants.ResourceNotFound})
+			return
+		case err != nil:
+			ctx.AbortWithStatusJSON(constants.StatusInternalServerError, gin.H{"error": constants.InternalServerError})
+			return
+		}
+
+		for _, cid := range classIDs {
+			if key != nil && *key.CID != cid {
+				ctx.AbortWithStatusJSON(constants.StatusForbidden, gin.H{"error": constants.Forbidden})
+				return
+			}
+			err := authz.Authorize(userID, cid, rule.Permission)
+			if errors.Is(err, services.ErrForbidden) {
+				ctx.AbortWithStatusJSON(constants.StatusForbidden, gin.H{"error": constants.Forbidden})
+				return
+			}
+			if err != nil {
+				ctx.AbortWithStatusJSON(constants.StatusInternalServerError, gin.H{"error": constants.InternalServerError})
+				return
+			}
+		}
+
+		ctx.Next()
+	}
+}
+
+// requestAPIKey はAPIキーで認証されたリクエストの場合にキーを返します。
+func requestAPIKey(ctx *gin.Context) *models.APIKey {
+	value, exists := ctx.Get("apiKey")
+	if !exists {
+		return nil
+	}
+	return value.(*models.APIKey)
+}
+
+// resolveClassIDs はリクエストから操作対象のクラスIDを取得します。
+func resolveClassIDs(ctx *gin.Context, authz services.AuthorizationService, source authorization.ClassSource) ([]uint, error) {
+	switch source.Kind {
+	case authorization.FromParam:
+		return parseClassID(ctx.Param(source.Name))
+	case authorization.FromQuery:
+		return parseClassID(ctx.Query(source.Name))
+	case authorization.FromForm:
+		return parseClassID(ctx.PostForm(source.Name))
+	case authorization.FromJSON:
+		return classIDsFromJSON(ctx, source.Name)
+	case authorization.FromResource:
+		id, err := strconv.ParseUint(ctx.Param(source.Name), 10, 32)
+		if err != nil {
+			return nil, errInvalidClassID
+		}
+		cid, err := authz.ClassIDOf(source.Resource, uint(id))
+		if err != nil {
+			return nil, err
+		}
+		return []uint{cid}, nil
+	default:
+		return nil, fmt.Errorf("unknown class source %d", source.Kind)
+	}
+}
+
+func parseClassID(value string) ([]uint, error) {
+	cid, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || cid == 0 {
+		return nil, errInvalidClassID
+	}
+	return []uint{uint(cid)}, nil
+}
+
+// classIDsFromJSON はJSONボディ (オブジェクトまたはオブジェクトの配列) の name フィールドのクラスIDを返します。
+// encoding/json はフィールド名を大文字小文字を区別せずに照合するため、"cid" と "CID" のように
+// 大文字小文字だけが異なるキーが複数あるボディは、ハンドラーが別の値を読み込めないよう拒否します。
+// 確認したボディは gin.BodyBytesKey に保存するため、ハンドラーは ShouldBindBodyWith で同じボディを読み込んでください。
+func classIDsFromJSON(ctx *gin.Context, name string) ([]uint, error) {
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return nil, errInvalidClassID
+	}
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+	ctx.Set(gin.BodyBytesKey, body)
+
+	var objects []map[string]json.RawMessage
+	if len(bytes.TrimSpace(body)) > 0 && bytes.TrimSpace(body)[0] == '[' {
+		if err := json.Unmarshal(body, &objects); err != nil {
+			return nil, errInvalidClassID
+		}
+	} else {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(body, &object); err != nil {
+			return nil, errInvalidClassID
+		}
+		objects = append(objects, object)
+	}
+	if len(objects) == 0 {
+		return nil, errInvalidClassID
+	}
+
+	seen := make(map[uint]bool)
+	var classIDs []uint
+	for _, object := range objects {
+		value, ok := uniqueField(object, name)
+		if !ok {
+			return nil, errInvalidClassID
+		}
+		var cid uint
+		if err := json.Unmarshal(value, &cid); err != nil || cid == 0 {
+			return nil, errInvalidClassID
+		}
+		if !seen[cid] {
+			seen[cid] = true
+			classIDs = append(classIDs, cid)
+		}
+	}
+	return classIDs, nil
+}
+
+// uniqueField は大文字小文字を区別せずに name と一致するキーがちょうど1つの場合に、その値を返します。
+func uniqueField(object map[string]json.RawMessage, name string) (json.RawMessage, bool) {
+	var value json.RawMessage
+	found := 0
+	for key, raw := range object {
+		if strings.EqualFold(key, name) {
+			value = raw
+			found++
+		}
+	}
+	return value, found == 1
+}
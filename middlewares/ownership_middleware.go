@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"strconv"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/gin-gonic/gin"
+)
+
+// OwnershipMiddleware はリクエストで指定されたユーザーIDが、トークンのユーザーIDと一致するかを確認するミドルウェアです。
+// パスパラメータを優先し、存在しない場合はクエリパラメータを参照します。
+// 複数の名前を指定した場合は、いずれか一つが一致すれば許可します。TokenAuthMiddleware の後に使用してください。
+func OwnershipMiddleware(paramNames ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userID := ctx.GetUint("userID")
+
+		for _, name := range paramNames {
+			value := ctx.Param(name)
+			if value == "" {
+				value = ctx.Query(name)
+			}
+			if value == "" {
+				continue
+			}
+
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				ctx.AbortWithStatusJSON(constants.StatusBadRequest, gin.H{"error": constants.InvalidRequest})
+				return
+			}
+			if uint(id) == userID {
+				ctx.Next()
+				return
+			}
+		}
+
+		ctx.AbortWithStatusJSON(constants.StatusForbidden, gin.H{"error": constants.Forbidden})
+	}
+}
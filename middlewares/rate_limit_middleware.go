@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"log"
+	"math"
+	"strconv"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitKeyFunc はリクエストからレート制限のキーを取得する関数です。空文字を返した場合は制限しません。
+type RateLimitKeyFunc func(ctx *gin.Context) string
+
+// RateLimitByIP はクライアントのIPアドレスごとに制限します。
+func RateLimitByIP(ctx *gin.Context) string {
+	return ctx.ClientIP()
+}
+
+// RateLimitByUserID は認証されたユーザーごとに制限します。TokenAuthMiddleware の後に使用してください。
+func RateLimitByUserID(ctx *gin.Context) string {
+	userID := ctx.GetUint("userID")
+	if userID == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(userID), 10)
+}
+
+// RateLimitByQuery はクエリパラメータ (クラスコードなど) の値ごとに制限します。
+func RateLimitByQuery(name string) RateLimitKeyFunc {
+	return func(ctx *gin.Context) string {
+		return ctx.Query(name)
+	}
+}
+
+// RateLimitMiddleware は name ごとに、key が返すキーのリクエスト数を limit に制限するミドルウェアです。
+// 上限を超えた場合は429とRetry-Afterヘッダーを返します。
+// Redisに接続できない場合はリクエストを許可し、ログを出力します。
+func RateLimitMiddleware(limiter services.RateLimiter, name string, limit services.RateLimit, key RateLimitKeyFunc) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		value := key(ctx)
+		if value == "" {
+			ctx.Next()
+			return
+		}
+
+		allowed, retryAfter, err := limiter.Allow(name+":"+value, limit)
+		if err != nil {
+			log.Printf("レート制限の確認に失敗しました (%s): %v", name, err)
+			ctx.Next()
+			return
+		}
+		if !allowed {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			if seconds < 1 {
+				seconds = 1
+			}
+			ctx.Header("Retry-After", strconv.Itoa(seconds))
+			ctx.AbortWithStatusJSON(constants.StatusTooManyRequests, gin.H{"error": constants.TooManyRequests})
+			return
+		}
+
+		ctx.Next()
+	}
+}
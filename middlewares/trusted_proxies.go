@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigureTrustedProxies はクライアントのIPアドレスを X-Forwarded-For などのヘッダーから取得してよいプロキシを設定します。
+// TRUSTED_PROXIES (カンマ区切りのIPアドレスまたはCIDR) が未設定の場合はどのプロキシも信頼せず、接続元のアドレスを使用します。
+// クライアントが送信したヘッダーを信頼すると、値を変えるだけで RateLimitByIP の制限を回避できるためです。
+// プロキシの背後で動かす場合は設定が必要です (README のデプロイを参照)。
+// TRUSTED_PLATFORM に cloudflare、google、またはヘッダー名を指定した場合は、そのプラットフォームが設定するヘッダーを使用します。
+func ConfigureTrustedProxies(router *gin.Engine) error {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	if err := router.SetTrustedProxies(proxies); err != nil {
+		return err
+	}
+
+	switch platform := os.Getenv("TRUSTED_PLATFORM"); platform {
+	case "":
+	case "cloudflare":
+		router.TrustedPlatform = gin.PlatformCloudflare
+	case "google":
+		router.TrustedPlatform = gin.PlatformGoogleAppEngine
+	default:
+		router.TrustedPlatform = platform
+	}
+	return nil
+}
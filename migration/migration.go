@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
@@ -45,6 +46,14 @@ func InitDB() (*gorm.DB, error) {
 }
 
 func Migrate(db *gorm.DB) {
+	if err := migrateRoleEnum(db); err != nil {
+		log.Fatalf("failed to migrate role enum: %v", err)
+	}
+
+	if err := migrateClassUserJoinedAt(db); err != nil {
+		log.Fatalf("failed to migrate class user joined_at: %v", err)
+	}
+
 	err := db.AutoMigrate(
 		&models.User{},
 		&models.Class{},
@@ -53,8 +62,170 @@ func Migrate(db *gorm.DB) {
 		&models.ClassCode{},
 		&models.ClassSchedule{},
 		&models.Attendance{},
+		&models.UserIdentity{},
+		&models.APIKey{},
+		&models.ClassAuditEvent{},
+		&models.ClassApplication{},
+		&models.ClassInvitation{},
+		&models.ClassBan{},
 	)
 	if err != nil {
 		log.Fatalf("failed to migrate database: %v", err)
 	}
+
+	if err := migrateAuditTrigger(db); err != nil {
+		log.Fatalf("failed to migrate audit trigger: %v", err)
+	}
+
+	if err := migratePendingApplications(db); err != nil {
+		log.Fatalf("failed to migrate pending applications: %v", err)
+	}
+
+	if err := migrateClassBans(db); err != nil {
+		log.Fatalf("failed to migrate class bans: %v", err)
+	}
+}
+
+// migrateRoleEnum はロールの列挙型を作成し、既存の class_users.role を列挙型に変換します。
+// 変換できないロールの行は起動を止めないように quarantined_class_users に移動します。
+// AutoMigrate は文字列の列を列挙型に変換できないため、AutoMigrate の前に実行します。
+func migrateRoleEnum(db *gorm.DB) error {
+	values := make([]string, len(models.Roles))
+	for i, role := range models.Roles {
+		values[i] = fmt.Sprintf("'%s'", role)
+	}
+
+	createType := fmt.Sprintf(`DO $$ BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = '%s') THEN
+		CREATE TYPE %s AS ENUM (%s);
+	END IF;
+END $$`, models.RoleEnumName, models.RoleEnumName, strings.Join(values, ", "))
+	if err := db.Exec(createType).Error; err != nil {
+		return err
+	}
+
+	// 後から追加されたロールを既存の列挙型に追加する
+	for _, role := range models.Roles {
+		if err := db.Exec(fmt.Sprintf("ALTER TYPE %s ADD VALUE IF NOT EXISTS '%s'", models.RoleEnumName, role)).Error; err != nil {
+			return err
+		}
+	}
+
+	if !db.Migrator().HasTable(&models.ClassUser{}) {
+		return nil
+	}
+	var udtName string
+	if err := db.Raw("SELECT udt_name FROM information_schema.columns WHERE table_name = 'class_users' AND column_name = 'role'").Scan(&udtName).Error; err != nil {
+		return err
+	}
+	if udtName == "" || udtName == models.RoleEnumName {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// 小文字や前後の空白を含むロールを列挙型の値に合わせる
+		if err := tx.Exec("ALTER TABLE class_users ALTER COLUMN role TYPE text USING role::text").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("UPDATE class_users SET role = UPPER(TRIM(role)) WHERE role <> UPPER(TRIM(role))").Error; err != nil {
+			return err
+		}
+		if err := quarantineInvalidRoles(tx, values); err != nil {
+			return err
+		}
+		return tx.Exec(fmt.Sprintf("ALTER TABLE class_users ALTER COLUMN role TYPE %s USING role::%s", models.RoleEnumName, models.RoleEnumName)).Error
+	})
+}
+
+// quarantineInvalidRoles は列挙型に変換できないロールの class_users を quarantined_class_users に移動します。
+// 移動した行はログに出力し、確認してロールを直してから class_users に戻せるように残します。
+func quarantineInvalidRoles(tx *gorm.DB, values []string) error {
+	if err := tx.Exec("CREATE TABLE IF NOT EXISTS quarantined_class_users (LIKE class_users, quarantined_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP)").Error; err != nil {
+		return err
+	}
+
+	var invalid []struct {
+		CID  uint
+		UID  uint
+		Role string
+	}
+	inValues := strings.Join(values, ", ")
+	if err := tx.Raw(fmt.Sprintf("SELECT cid, uid, role FROM class_users WHERE role NOT IN (%s)", inValues)).Scan(&invalid).Error; err != nil {
+		return err
+	}
+	if len(invalid) == 0 {
+		return nil
+	}
+	for _, row := range invalid {
+		log.Printf("ロールを変換できないため quarantined_class_users に移動します: cid=%d uid=%d role=%q", row.CID, row.UID, row.Role)
+	}
+
+	columns := "cid, uid, nickname, is_favorite, role"
+	if tx.Migrator().HasColumn(&models.ClassUser{}, "JoinedAt") {
+		columns += ", joined_at"
+	}
+	return tx.Exec(fmt.Sprintf(`WITH moved AS (DELETE FROM class_users WHERE role NOT IN (%s) RETURNING %s)
+	INSERT INTO quarantined_class_users (%s) SELECT %s FROM moved`, inValues, columns, columns, columns)).Error
+}
+
+// migrateClassUserJoinedAt は既存の class_users に joined_at を追加し、ユーザーの登録日時で埋めます。
+// 既存の行には参加日時の記録がないため、参加日時より前になることがないユーザーの登録日時を使います。
+// AutoMigrate は NOT NULL の列を既存の行に追加できないため、AutoMigrate の前に実行します。
+func migrateClassUserJoinedAt(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.ClassUser{}) || db.Migrator().HasColumn(&models.ClassUser{}, "JoinedAt") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("ALTER TABLE class_users ADD COLUMN joined_at timestamptz").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("UPDATE class_users cu SET joined_at = u.created_at FROM users u WHERE u.id = cu.uid").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("UPDATE class_users SET joined_at = NOW() WHERE joined_at IS NULL").Error; err != nil {
+			return err
+		}
+		return tx.Exec("ALTER TABLE class_users ALTER COLUMN joined_at SET DEFAULT CURRENT_TIMESTAMP, ALTER COLUMN joined_at SET NOT NULL").Error
+	})
+}
+
+// migrateAuditTrigger は class_audit_events を追記のみにするため、更新と削除を拒否するトリガーを作成します。
+func migrateAuditTrigger(db *gorm.DB) error {
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION reject_class_audit_event_change() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'class_audit_events is append-only';
+END
+$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS class_audit_events_append_only ON class_audit_events`,
+		`CREATE TRIGGER class_audit_events_append_only BEFORE UPDATE OR DELETE ON class_audit_events
+	FOR EACH ROW EXECUTE PROCEDURE reject_class_audit_event_change()`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migratePendingApplications は申請の記録を導入する前から APPLICANT のユーザーに、申請中の申請を作成します。
+func migratePendingApplications(db *gorm.DB) error {
+	return db.Exec(`INSERT INTO class_applications (cid, uid, status, created_at)
+	SELECT cu.cid, cu.uid, ?, NOW() FROM class_users cu
+	WHERE cu.role = ? AND NOT EXISTS (
+		SELECT 1 FROM class_applications ca WHERE ca.cid = cu.cid AND ca.uid = cu.uid AND ca.status = ?
+	)`, models.ApplicationPending, models.RoleApplicant, models.ApplicationPending).Error
+}
+
+// migrateClassBans は利用停止の記録を導入する前から BLACKLIST のユーザーに、期限のない利用停止を作成します。
+// 設定したユーザーは記録されていないため、クラスのオーナーの操作とします。
+func migrateClassBans(db *gorm.DB) error {
+	return db.Exec(`INSERT INTO class_bans (cid, uid, banned_by, created_at)
+	SELECT cu.cid, cu.uid, c.uid, NOW() FROM class_users cu
+	JOIN classes c ON c.id = cu.cid
+	WHERE cu.role = ? AND NOT EXISTS (
+		SELECT 1 FROM class_bans cb WHERE cb.cid = cu.cid AND cb.uid = cu.uid AND cb.lifted_at IS NULL
+	)`, models.RoleBlacklist).Error
 }
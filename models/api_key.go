@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// APIKey 人のログインを伴わないクライアント (出欠端末、集計ジョブなど) 用のAPIキー
+// キーそのものは保存せず、SHA-256のハッシュのみを保存する。CIDがnilの場合はサービス全体のキーになる。
+type APIKey struct {
+	ID         uint   `gorm:"primaryKey"`
+	Name       string `gorm:"size:100;not null"`
+	Prefix     string `gorm:"size:16;not null"`
+	KeyHash    string `gorm:"size:64;not null;uniqueIndex"`
+	Scopes     string `gorm:"size:255;not null"`
+	CID        *uint  `gorm:"column:cid;index"`
+	UID        uint   `gorm:"column:uid;not null"` // キーを発行したユーザー
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time `gorm:"not null;"`
+	Class      *Class    `gorm:"foreignKey:CID;constraint:OnDelete:CASCADE"`
+	User       User      `gorm:"foreignKey:UID;constraint:OnDelete:CASCADE"`
+}
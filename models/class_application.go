@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// ApplicationStatus はクラスへの参加申請の状態
+type ApplicationStatus string
+
+const (
+	ApplicationPending    ApplicationStatus = "pending"
+	ApplicationApproved   ApplicationStatus = "approved"
+	ApplicationRejected   ApplicationStatus = "rejected"
+	ApplicationWaitlisted ApplicationStatus = "waitlisted"
+)
+
+// ClassApplication はクラスへの参加申請と、その承認または却下の記録
+// 申請中 (pending) の申請はクラスとユーザーの組み合わせごとに1件までです。
+// 定員に達していたため承認を待っている申請は waitlisted になり、WaitlistedAt の順に空きができると承認されます。
+type ClassApplication struct {
+	ID           uint              `gorm:"primaryKey;autoIncrement"`
+	CID          uint              `gorm:"column:cid;not null;index;uniqueIndex:idx_class_applications_pending,where:status = 'pending'"`
+	UID          uint              `gorm:"column:uid;not null;index;uniqueIndex:idx_class_applications_pending,where:status = 'pending'"`
+	Status       ApplicationStatus `gorm:"size:20;not null;default:pending"`
+	Reason       *string           `gorm:"size:255"`
+	DecidedBy    *uint             `gorm:"column:decided_by"`
+	DecidedAt    *time.Time
+	WaitlistedAt *time.Time `gorm:"index"`
+	CreatedAt    time.Time  `gorm:"not null"`
+	Class        Class      `gorm:"foreignKey:CID;constraint:OnDelete:CASCADE"`
+	User         User       `gorm:"foreignKey:UID;constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// AuditAction はクラスのメンバーに対する操作の種類
+type AuditAction string
+
+const (
+	AuditMemberJoined    AuditAction = "member.joined"
+	AuditRoleChanged     AuditAction = "member.role_changed"
+	AuditMemberRemoved   AuditAction = "member.removed"
+	AuditFavoriteToggled AuditAction = "member.favorite_toggled"
+
+	AuditOwnershipTransferred AuditAction = "class.ownership_transferred"
+)
+
+// ClassAuditEvent はクラスのメンバーに対する操作の履歴
+// 追記のみのテーブルで、更新と削除はデータベースのトリガーで拒否されます。
+// クラスやユーザーが削除されても履歴を残すため、外部キーは設定しません。
+type ClassAuditEvent struct {
+	ID        uint        `gorm:"primaryKey;autoIncrement"`
+	CID       uint        `gorm:"column:cid;not null;index"`
+	ActorID   uint        `gorm:"column:actor_id;not null;index"`
+	TargetID  uint        `gorm:"column:target_id;not null;index"`
+	Action    AuditAction `gorm:"size:50;not null"`
+	OldValue  string      `gorm:"size:255"`
+	NewValue  string      `gorm:"size:255"`
+	CreatedAt time.Time   `gorm:"not null"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// ClassBan はクラスでの利用停止の記録
+// 利用停止中のユーザーは BLACKLIST のロールでクラスに所属し、有効な (LiftedAt がない) 利用停止はクラスとユーザーの組み合わせごとに1件までです。
+// ExpiresAt を過ぎた利用停止はバックグラウンドのジョブで解除され、LiftedBy は空になります。
+type ClassBan struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement"`
+	CID       uint       `gorm:"column:cid;not null;index;uniqueIndex:idx_class_bans_active,where:lifted_at IS NULL"`
+	UID       uint       `gorm:"column:uid;not null;index;uniqueIndex:idx_class_bans_active,where:lifted_at IS NULL"`
+	BannedBy  uint       `gorm:"column:banned_by;not null"`
+	Reason    *string    `gorm:"size:255"`
+	ExpiresAt *time.Time `gorm:"index"`
+	LiftedBy  *uint      `gorm:"column:lifted_by"`
+	LiftedAt  *time.Time
+	CreatedAt time.Time `gorm:"not null"`
+	Class     Class     `gorm:"foreignKey:CID;constraint:OnDelete:CASCADE"`
+	User      User      `gorm:"foreignKey:UID;constraint:OnDelete:CASCADE"`
+}
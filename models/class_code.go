@@ -1,11 +1,28 @@
 package models
 
+import "time"
+
+// ClassCode はクラスへの参加コード
+// クラスは複数のコードを持つことができ、コードごとに参加後のロール (APPLICANT の場合は参加申請) を指定します。
+// 無効にしたコード、期限切れのコード、使用回数が MaxUses に達したコードは使用できません。
+// Secret はシークレットの bcrypt ハッシュで、平文は保存しません。
 type ClassCode struct {
-	ID     uint    `gorm:"primaryKey;size:255;autoIncrement"`
-	Code   string  `gorm:"size:10;not null"`
-	Secret *string `gorm:"size:20"`
-	CID    uint    `gorm:"column:cid;not null;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	UID    uint    `gorm:"column:uid;not null"` // User ID
-	Class  Class   `gorm:"foreignKey:CID;constraint:OnDelete:CASCADE"`
-	User   User    `gorm:"foreignKey:UID"`
+	ID        uint    `gorm:"primaryKey;size:255;autoIncrement"`
+	Code      string  `gorm:"size:10;not null;uniqueIndex"`
+	Secret    *string `gorm:"size:60"`
+	Role      Role    `gorm:"type:class_role;not null;default:APPLICANT"`
+	ExpiresAt *time.Time
+	MaxUses   *int
+	Uses      int       `gorm:"not null;default:0"`
+	Enabled   bool      `gorm:"not null;default:true"`
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	CID       uint      `gorm:"column:cid;not null;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	UID       uint      `gorm:"column:uid;not null"` // User ID
+	Class     Class     `gorm:"foreignKey:CID;constraint:OnDelete:CASCADE"`
+	User      User      `gorm:"foreignKey:UID"`
+}
+
+// Usable はコードが参加に使用できる状態かを返します。
+func (c ClassCode) Usable(now time.Time) bool {
+	return c.Enabled && (c.ExpiresAt == nil || now.Before(*c.ExpiresAt)) && (c.MaxUses == nil || c.Uses < *c.MaxUses)
 }
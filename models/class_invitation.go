@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// InvitationStatus はクラスへの招待の状態
+type InvitationStatus string
+
+const (
+	InvitationPending  InvitationStatus = "pending"
+	InvitationAccepted InvitationStatus = "accepted"
+	InvitationDeclined InvitationStatus = "declined"
+	InvitationRevoked  InvitationStatus = "revoked"
+)
+
+// ClassInvitation はクラスへの招待
+// InviteeID がある場合は既存のユーザーへの招待で、招待中のユーザーは INVITE のロールでクラスに所属します。
+// InviteeID がない場合は招待リンクで、リンクのトークンは保存せずSHA-256のハッシュのみを保存します。どちらも1回だけ使用できます。
+type ClassInvitation struct {
+	ID         uint             `gorm:"primaryKey;autoIncrement"`
+	CID        uint             `gorm:"column:cid;not null;index"`
+	InviterID  uint             `gorm:"column:inviter_id;not null"`
+	InviteeID  *uint            `gorm:"column:invitee_id;index"`
+	Role       Role             `gorm:"type:class_role;not null"`
+	TokenHash  *string          `gorm:"size:64;uniqueIndex"`
+	Status     InvitationStatus `gorm:"size:20;not null;default:pending"`
+	ExpiresAt  *time.Time
+	AcceptedBy *uint `gorm:"column:accepted_by"`
+	DecidedAt  *time.Time
+	CreatedAt  time.Time `gorm:"not null"`
+	Class      Class     `gorm:"foreignKey:CID;constraint:OnDelete:CASCADE"`
+	Invitee    *User     `gorm:"foreignKey:InviteeID;constraint:OnDelete:CASCADE"`
+}
+
+// Usable は招待が承諾できる状態 (未使用で期限内) かを返します。
+func (i ClassInvitation) Usable(now time.Time) bool {
+	return i.Status == InvitationPending && (i.ExpiresAt == nil || now.Before(*i.ExpiresAt))
+}
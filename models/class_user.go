@@ -1,11 +1,16 @@
 package models
 
+import "time"
+
+// ClassUser はクラスへの所属
+// JoinedAt はクラスに追加された (申請、招待を含む) 日時です。
 type ClassUser struct {
-	CID        uint   `gorm:"column:cid;primaryKey"`
-	UID        uint   `gorm:"column:uid;primaryKey"`
-	Nickname   string `gorm:"size:50;not null"`
-	IsFavorite bool   `gorm:"not null;default:false"`
-	Role       string `gorm:"type:Role;not null"`
-	Class      Class  `gorm:"foreignKey:CID;constraint:OnDelete:CASCADE"`
-	User       User   `gorm:"foreignKey:UID"`
+	CID        uint      `gorm:"column:cid;primaryKey"`
+	UID        uint      `gorm:"column:uid;primaryKey"`
+	Nickname   string    `gorm:"size:50;not null"`
+	IsFavorite bool      `gorm:"not null;default:false"`
+	Role       Role      `gorm:"type:class_role;not null"`
+	JoinedAt   time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	Class      Class     `gorm:"foreignKey:CID;constraint:OnDelete:CASCADE"`
+	User       User      `gorm:"foreignKey:UID"`
 }
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidRole は存在しないロールの場合のエラー
+var ErrInvalidRole = errors.New("invalid role")
+
+// RoleEnumName はロールを保存するPostgreSQLの列挙型の名前
+const RoleEnumName = "class_role"
+
+// Role はクラス内のロール
+type Role string
+
+const (
+	RoleAdmin     Role = "ADMIN"
+	RoleAssistant Role = "ASSISTANT"
+	RoleUser      Role = "USER"
+	RoleApplicant Role = "APPLICANT"
+	RoleBlacklist Role = "BLACKLIST"
+	RoleInvite    Role = "INVITE"
+)
+
+// Roles は全てのロール。列挙型の値の順序でもあるため、追加する場合は末尾に追加してください。
+var Roles = []Role{RoleAdmin, RoleAssistant, RoleUser, RoleApplicant, RoleBlacklist, RoleInvite}
+
+// ParseRole は文字列をロールに変換します。存在しないロールの場合は ErrInvalidRole を返します。
+func ParseRole(value string) (Role, error) {
+	role := Role(value)
+	if !role.Valid() {
+		return "", fmt.Errorf("%w: %q", ErrInvalidRole, value)
+	}
+	return role, nil
+}
+
+// Valid はロールが存在するかを返します。
+func (r Role) Valid() bool {
+	for _, role := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Active は承認済みのメンバーのロール (ADMIN, ASSISTANT, USER) かを返します。クラスの定員はこのロールのユーザー数で数えます。
+func (r Role) Active() bool {
+	return r == RoleAdmin || r == RoleAssistant || r == RoleUser
+}
+
+// Value は存在しないロールを保存しないように確認してから値を返します。
+func (r Role) Value() (driver.Value, error) {
+	if !r.Valid() {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidRole, string(r))
+	}
+	return string(r), nil
+}
+
+// Scan はデータベースの値をロールに変換します。NULLは空のロールになります。
+func (r *Role) Scan(src interface{}) error {
+	var value string
+	switch v := src.(type) {
+	case nil:
+		*r = ""
+		return nil
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Role", src)
+	}
+	role, err := ParseRole(value)
+	if err != nil {
+		return err
+	}
+	*r = role
+	return nil
+}
+
+// UnmarshalJSON は存在しないロールを拒否します。
+func (r *Role) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	role, err := ParseRole(value)
+	if err != nil {
+		return err
+	}
+	*r = role
+	return nil
+}
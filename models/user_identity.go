@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// UserIdentity ユーザーに紐づくログインプロバイダのアカウント
+// 同じプロバイダのアカウント (provider + subject) は一人のユーザーにのみ紐づけられる。
+type UserIdentity struct {
+	ID        uint      `gorm:"primaryKey"`
+	UID       uint      `gorm:"column:uid;not null;index"`
+	Provider  string    `gorm:"size:50;not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Subject   string    `gorm:"size:255;not null;uniqueIndex:idx_user_identities_provider_subject"`
+	CreatedAt time.Time `gorm:"not null;"`
+	User      User      `gorm:"foreignKey:UID;constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,177 @@
+// Package pagination は一覧のカーソルページングを提供します。
+// カーソルは署名付きの不透明な文字列で、発行した一覧 (scope) 以外では使用できません。
+// 互換性のため、カーソルを指定しない場合は従来の page と limit でも取得できます。
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultLimit は件数が指定されなかった場合の1ページの件数
+	DefaultLimit = 10
+	// MaxLimit は1ページの最大件数
+	MaxLimit = 100
+)
+
+// ErrInvalidCursor はカーソルが改ざんされている、または別の一覧のカーソルの場合のエラー
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Query は一覧の取得条件
+// Cursor がある場合は Page を無視します。
+type Query struct {
+	Cursor string
+	Page   int
+	Limit  int
+}
+
+// Window はリポジトリで取得する範囲
+// AfterID が0でない場合は並び順で AfterID の次から、0の場合は Offset 件を飛ばして取得します。
+// 次のページがあるかを判定するため、Limit はページの件数より1件多くなっています。
+type Window struct {
+	AfterID uint
+	Offset  int
+	Limit   int
+}
+
+// Page は一覧の1ページ
+// 次のページがない場合 NextCursor は null です。
+type Page[T any] struct {
+	Items      []T     `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+	HasMore    bool    `json:"has_more"`
+}
+
+// idKey はIDで並ぶ一覧のカーソルの値
+type idKey struct {
+	ID uint `json:"id"`
+}
+
+// NewWindow はクエリを取得範囲に変換します。scope はカーソルを発行した一覧の名前です。
+func NewWindow(scope string, query Query) (Window, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	window := Window{Limit: limit + 1}
+	if query.Cursor != "" {
+		var key idKey
+		if err := Decode(scope, query.Cursor, &key); err != nil || key.ID == 0 {
+			return Window{}, ErrInvalidCursor
+		}
+		window.AfterID = key.ID
+		return window, nil
+	}
+	if query.Page > 1 {
+		window.Offset = (query.Page - 1) * limit
+	}
+	return window, nil
+}
+
+// NewPage は window の範囲で取得した items をページにします。id は並び順のキーとなるIDを返します。
+func NewPage[T any](scope string, window Window, items []T, id func(T) uint) Page[T] {
+	page := Page[T]{Items: items}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	if limit := window.Limit - 1; len(items) > limit {
+		page.Items = items[:limit]
+		next := Encode(scope, idKey{ID: id(items[limit-1])})
+		page.NextCursor = &next
+		page.HasMore = true
+	}
+	return page
+}
+
+// payload は署名するカーソルの内容
+type payload struct {
+	Scope string          `json:"s"`
+	Key   json.RawMessage `json:"k"`
+}
+
+// Encode は key を scope の一覧のカーソルにします。
+func Encode(scope string, key interface{}) string {
+	k, _ := json.Marshal(key)
+	data, _ := json.Marshal(payload{Scope: scope, Key: k})
+	body := base64.RawURLEncoding.EncodeToString(data)
+	return body + "." + base64.RawURLEncoding.EncodeToString(sign(body))
+}
+
+// Decode は Encode で作成したカーソルの署名と scope を確認し、key に戻します。
+func Decode(scope string, cursor string, key interface{}) error {
+	body, signature, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return ErrInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, sign(body)) {
+		return ErrInvalidCursor
+	}
+	data, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	var p payload
+	if err := json.Unmarshal(data, &p); err != nil || p.Scope != scope {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(p.Key, key); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+// ErrNoSecret は PAGINATION_SECRET と JWT_SECRET のどちらも設定されていない場合のエラー
+var ErrNoSecret = errors.New("neither PAGINATION_SECRET nor JWT_SECRET is set")
+
+var (
+	secretOnce sync.Once
+	secret     []byte
+	secretErr  error
+)
+
+// LoadSecret はカーソルの署名の鍵を読み込みます。起動時に呼び出して設定を確認してください。
+// 鍵は PAGINATION_SECRET、未設定の場合は JWT_SECRET から導出します。どちらもない場合は起動ごとに生成した鍵を使用して ErrNoSecret を返します。
+// 生成した鍵では再起動前や他のインスタンスが発行したカーソルを使用できません。
+func LoadSecret() error {
+	secretOnce.Do(func() {
+		for _, name := range []string{"PAGINATION_SECRET", "JWT_SECRET"} {
+			if value := os.Getenv(name); value != "" {
+				// JWTの署名と同じ鍵にならないよう、用途ごとの鍵を導出する
+				mac := hmac.New(sha256.New, []byte(value))
+				mac.Write([]byte("pagination"))
+				secret = mac.Sum(nil)
+				return
+			}
+		}
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("failed to generate pagination secret: %v", err)
+		}
+		secretErr = ErrNoSecret
+		log.Println("警告: PAGINATION_SECRET と JWT_SECRET が未設定のため、カーソルの署名に起動ごとに生成した鍵を使用します。再起動や他のインスタンスではカーソルが無効になります。")
+	})
+	return secretErr
+}
+
+// sign はカーソルの署名を返します。
+func sign(body string) []byte {
+	_ = LoadSecret()
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"gorm.io/gorm"
+)
+
+// APIKeyRepository APIキーのリポジトリ
+type APIKeyRepository interface {
+	Create(key *models.APIKey) error
+	FindByHash(keyHash string) (*models.APIKey, error)
+	FindByID(id uint) (*models.APIKey, error)
+	FindAll(cid *uint) ([]models.APIKey, error)
+	Update(key *models.APIKey) error
+	Delete(id uint) error
+	UpdateLastUsedAt(id uint, at time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+// Create APIキーを保存する
+func (r *apiKeyRepository) Create(key *models.APIKey) error {
+	return r.db.Omit("Class", "User").Create(key).Error
+}
+
+// FindByHash キーのハッシュに一致するAPIキーを返す
+func (r *apiKeyRepository) FindByHash(keyHash string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.Where("key_hash = ?", keyHash).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// FindByID IDに一致するAPIキーを返す
+func (r *apiKeyRepository) FindByID(id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.First(&key, id).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// FindAll クラスのAPIキーを返す。cidがnilの場合はサービス全体のAPIキーを返す
+func (r *apiKeyRepository) FindAll(cid *uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	query := r.db.Order("id")
+	if cid == nil {
+		query = query.Where("cid IS NULL")
+	} else {
+		query = query.Where("cid = ?", *cid)
+	}
+	err := query.Find(&keys).Error
+	return keys, err
+}
+
+// Update APIキーの名前、スコープ、有効期限を更新する
+func (r *apiKeyRepository) Update(key *models.APIKey) error {
+	return r.db.Model(key).Select("name", "scopes", "expires_at").Updates(key).Error
+}
+
+// Delete APIキーを削除する
+func (r *apiKeyRepository) Delete(id uint) error {
+	return r.db.Delete(&models.APIKey{}, id).Error
+}
+
+// UpdateLastUsedAt APIキーの最終使用日時を更新する
+func (r *apiKeyRepository) UpdateLastUsedAt(id uint, at time.Time) error {
+	return r.db.Model(&models.APIKey{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
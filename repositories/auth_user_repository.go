@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"gorm.io/gorm"
+)
+
+// AuthUserRepository ログインプロバイダで認証されたユーザーと、ユーザーに紐づくアカウントを保存するリポジトリ
+type AuthUserRepository interface {
+	UpdateOrCreateUser(userInfo dto.ExternalUserInfo) (models.User, error)
+	FindIdentity(provider string, subject string) (*models.UserIdentity, error)
+	FindIdentities(userID uint) ([]models.UserIdentity, error)
+	CreateIdentity(identity *models.UserIdentity) error
+	DeleteIdentity(userID uint, identityID uint) (bool, error)
+	CountIdentities(userID uint) (int64, error)
+}
+
+type authUserRepository struct {
+	db *gorm.DB
+}
+
+func NewAuthUserRepository(db *gorm.DB) AuthUserRepository {
+	return &authUserRepository{db: db}
+}
+
+// legacyPIDProviders はアカウントの紐づけを導入する前に users.p_id にアカウントのIDを保存していたプロバイダ
+var legacyPIDProviders = map[string]bool{"google": true, "line": true}
+
+// UpdateOrCreateUser プロバイダのアカウントに紐づくユーザーを返し、存在しない場合は作成する
+// google と line のアカウントは、紐づけがない既存のユーザーの users.p_id が一致する場合にそのユーザーへ紐づける
+// それ以外のプロバイダは p_id で既存のユーザーに紐づけず、新しいユーザーを作成する
+func (repo *authUserRepository) UpdateOrCreateUser(userInfo dto.ExternalUserInfo) (models.User, error) {
+	var user models.User
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var identity models.UserIdentity
+		err := tx.Where("provider = ? AND subject = ?", userInfo.Provider, userInfo.Subject).First(&identity).Error
+		if err == nil {
+			return tx.First(&user, identity.UID).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		err = gorm.ErrRecordNotFound
+		if legacyPIDProviders[userInfo.Provider] {
+			err = tx.Where("p_id = ?", userInfo.Subject).
+				Where("NOT EXISTS (SELECT 1 FROM user_identities WHERE user_identities.uid = users.id AND user_identities.provider = ?)", userInfo.Provider).
+				First(&user).Error
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			user = newProviderUser(userInfo)
+			err = tx.Create(&user).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&models.UserIdentity{UID: user.ID, Provider: userInfo.Provider, Subject: userInfo.Subject}).Error
+	})
+	return user, err
+}
+
+// newProviderUser はプロバイダのアカウントから新しいユーザーを作成する
+// google と line 以外のプロバイダは、google と line の p_id の照合に一致しないよう p_id にプロバイダ名を付ける
+func newProviderUser(userInfo dto.ExternalUserInfo) models.User {
+	pidPrefix := userInfo.Subject
+	if len(pidPrefix) > 4 {
+		pidPrefix = pidPrefix[:4]
+	}
+	pid := userInfo.Subject
+	if !legacyPIDProviders[userInfo.Provider] {
+		pid = userInfo.Provider + ":" + userInfo.Subject
+	}
+	return models.User{
+		PID:   pid,
+		Name:  fmt.Sprintf("%s#%s", userInfo.Name, pidPrefix),
+		Image: userInfo.Picture,
+	}
+}
+
+// FindIdentity プロバイダのアカウントを取得する
+func (repo *authUserRepository) FindIdentity(provider string, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := repo.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// FindIdentities ユーザーに紐づく全てのアカウントを取得する
+func (repo *authUserRepository) FindIdentities(userID uint) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	err := repo.db.Where("uid = ?", userID).Order("id").Find(&identities).Error
+	return identities, err
+}
+
+// CreateIdentity ユーザーにアカウントを紐づける
+func (repo *authUserRepository) CreateIdentity(identity *models.UserIdentity) error {
+	return repo.db.Create(identity).Error
+}
+
+// DeleteIdentity ユーザーに紐づくアカウントを削除し、削除したかどうかを返す
+func (repo *authUserRepository) DeleteIdentity(userID uint, identityID uint) (bool, error) {
+	result := repo.db.Where("id = ? AND uid = ?", identityID, userID).Delete(&models.UserIdentity{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountIdentities ユーザーに紐づくアカウントの数を取得する
+func (repo *authUserRepository) CountIdentities(userID uint) (int64, error) {
+	var count int64
+	err := repo.db.Model(&models.UserIdentity{}).Where("uid = ?", userID).Count(&count).Error
+	return count, err
+}
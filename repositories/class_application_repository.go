@@ -0,0 +1,153 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ClassApplicationRepository はクラスへの参加申請のリポジトリ
+// 申請はロールの変更と同じトランザクションで ClassUserRepository が作成、更新します。
+type ClassApplicationRepository interface {
+	// FindPending はクラスの申請中の申請を古い順に返します。
+	FindPending(cid uint) ([]models.ClassApplication, error)
+	// FindWaitlisted はクラスのキャンセル待ちの申請を承認される順に返します。
+	FindWaitlisted(cid uint) ([]models.ClassApplication, error)
+	// Decide は actorID の操作として uids の申請を承認または却下し、キャンセル待ちになったユーザーを返します。
+	// 承認したユーザーは USER になり、定員に達している場合はキャンセル待ちになります。却下したユーザーはクラスから削除されます。
+	// 申請中ではないユーザーが含まれる場合は gorm.ErrRecordNotFound を返し、何も変更しません。
+	Decide(actorID uint, cid uint, uids []uint, approve bool, reason *string) ([]uint, error)
+}
+
+type classApplicationRepository struct {
+	db *gorm.DB
+}
+
+func NewClassApplicationRepository(db *gorm.DB) ClassApplicationRepository {
+	return &classApplicationRepository{db: db}
+}
+
+func (r *classApplicationRepository) FindPending(cid uint) ([]models.ClassApplication, error) {
+	var applications []models.ClassApplication
+	err := r.db.Preload("User").
+		Where("cid = ? AND status = ?", cid, models.ApplicationPending).
+		Order("id").
+		Find(&applications).Error
+	return applications, err
+}
+
+func (r *classApplicationRepository) FindWaitlisted(cid uint) ([]models.ClassApplication, error) {
+	var applications []models.ClassApplication
+	err := r.db.Preload("User").
+		Where("cid = ? AND status = ?", cid, models.ApplicationWaitlisted).
+		Order("waitlisted_at, id").
+		Find(&applications).Error
+	return applications, err
+}
+
+func (r *classApplicationRepository) Decide(actorID uint, cid uint, uids []uint, approve bool, reason *string) ([]uint, error) {
+	status := models.ApplicationRejected
+	if approve {
+		status = models.ApplicationApproved
+	}
+
+	waitlisted := []uint{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		class, err := lockClass(tx, cid)
+		if err != nil {
+			return err
+		}
+
+		for _, uid := range uids {
+			var classUser models.ClassUser
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("role").First(&classUser, "uid = ? AND cid = ?", uid, cid).Error
+			if err != nil {
+				return err
+			}
+			if classUser.Role != models.RoleApplicant {
+				return gorm.ErrRecordNotFound
+			}
+
+			if approve {
+				ok, err := hasVacancy(tx, class)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					if err := waitlistApplication(tx, actorID, uid, cid, reason); err != nil {
+						return err
+					}
+					waitlisted = append(waitlisted, uid)
+					continue
+				}
+			}
+
+			// ロールの変更でも申請は閉じられるが、理由を残すため先に閉じる
+			if err := closeApplication(tx, actorID, uid, cid, status, reason); err != nil {
+				return err
+			}
+			if approve {
+				err = updateUserRole(tx, actorID, uid, cid, models.RoleUser)
+			} else {
+				err = deleteClassUser(tx, actorID, uid, cid)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return waitlisted, nil
+}
+
+// openApplication はトランザクション内で申請中の申請を作成します。
+func openApplication(tx *gorm.DB, uid uint, cid uint) error {
+	return tx.Create(&models.ClassApplication{CID: cid, UID: uid, Status: models.ApplicationPending}).Error
+}
+
+// waitlistApplication はトランザクション内で申請中の申請をキャンセル待ちにします。既にキャンセル待ちの場合は順番を変えません。
+func waitlistApplication(tx *gorm.DB, actorID uint, uid uint, cid uint, reason *string) error {
+	return tx.Model(&models.ClassApplication{}).
+		Where("cid = ? AND uid = ? AND status = ?", cid, uid, models.ApplicationPending).
+		Updates(map[string]interface{}{
+			"status":        models.ApplicationWaitlisted,
+			"reason":        reason,
+			"decided_by":    actorID,
+			"waitlisted_at": time.Now(),
+		}).Error
+}
+
+// closeApplication はトランザクション内で申請中またはキャンセル待ちの申請を status に更新します。該当する申請がない場合は何もしません。
+func closeApplication(tx *gorm.DB, actorID uint, uid uint, cid uint, status models.ApplicationStatus, reason *string) error {
+	return tx.Model(&models.ClassApplication{}).
+		Where("cid = ? AND uid = ? AND status IN ?", cid, uid, []models.ApplicationStatus{models.ApplicationPending, models.ApplicationWaitlisted}).
+		Updates(map[string]interface{}{
+			"status":     status,
+			"reason":     reason,
+			"decided_by": actorID,
+			"decided_at": time.Now(),
+		}).Error
+}
+
+// syncApplication はロールが from から to に変わったときに申請の状態を合わせます。
+// 申請者になった場合は申請を作成し、申請者でなくなった場合は BLACKLIST または削除 (to が空) なら却下、それ以外は承認とします。
+func syncApplication(tx *gorm.DB, actorID uint, uid uint, cid uint, from models.Role, to models.Role) error {
+	switch {
+	case from == to:
+		return nil
+	case to == models.RoleApplicant:
+		return openApplication(tx, uid, cid)
+	case from == models.RoleApplicant:
+		status := models.ApplicationApproved
+		if to == "" || to == models.RoleBlacklist {
+			status = models.ApplicationRejected
+		}
+		return closeApplication(tx, actorID, uid, cid, status, nil)
+	}
+	return nil
+}
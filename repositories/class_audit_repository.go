@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/pagination"
+	"gorm.io/gorm"
+)
+
+// ClassAuditRepository はクラスの操作履歴を参照するリポジトリ
+// 履歴は変更と同じトランザクションで各リポジトリが記録するため、追加のメソッドはありません。
+type ClassAuditRepository interface {
+	// FindByClass は条件に一致する履歴を新しい順に window の範囲で返します。
+	FindByClass(cid uint, query dto.ClassAuditQuery, window pagination.Window) ([]models.ClassAuditEvent, error)
+}
+
+type classAuditRepository struct {
+	db *gorm.DB
+}
+
+func NewClassAuditRepository(db *gorm.DB) ClassAuditRepository {
+	return &classAuditRepository{db: db}
+}
+
+func (r *classAuditRepository) FindByClass(cid uint, query dto.ClassAuditQuery, window pagination.Window) ([]models.ClassAuditEvent, error) {
+	db := r.db.Where("cid = ?", cid)
+	if query.Action != "" {
+		db = db.Where("action = ?", query.Action)
+	}
+	if query.ActorID != nil {
+		db = db.Where("actor_id = ?", *query.ActorID)
+	}
+	if query.TargetID != nil {
+		db = db.Where("target_id = ?", *query.TargetID)
+	}
+	if query.From != nil {
+		db = db.Where("created_at >= ?", *query.From)
+	}
+	if query.To != nil {
+		db = db.Where("created_at < ?", *query.To)
+	}
+
+	var events []models.ClassAuditEvent
+	if err := applyWindow(db, "id", true, window).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// recordAuditEvent はトランザクション内でクラスの操作履歴を記録します。
+func recordAuditEvent(tx *gorm.DB, event models.ClassAuditEvent) error {
+	return tx.Create(&event).Error
+}
@@ -0,0 +1,212 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrBanOwner はクラスのオーナーを利用停止にしようとした場合のエラー
+var ErrBanOwner = errors.New("cannot ban the class owner")
+
+// ErrBannedMember は利用停止中のユーザーを利用停止の解除なしにクラスから削除しようとした場合のエラー
+var ErrBannedMember = errors.New("cannot remove a banned member without lifting the ban")
+
+// ClassBanRepository はクラスでの利用停止のリポジトリ
+type ClassBanRepository interface {
+	// Ban は ban.BannedBy の操作として ban.UID を BLACKLIST にし、理由と期限を記録します。
+	// 既に利用停止中の場合は理由と期限を更新します。ユーザーが存在しない場合は gorm.ErrRecordNotFound を、
+	// クラスのオーナーの場合は ErrBanOwner を、最後の管理者の場合は ErrLastAdmin を返します。
+	Ban(ban *models.ClassBan) error
+	// FindActive はクラスの有効な利用停止を新しい順に返します。
+	FindActive(cid uint) ([]models.ClassBan, error)
+	// Lift は actorID の操作として uid の利用停止を解除し、クラスから削除します。利用停止中でない場合は gorm.ErrRecordNotFound を返します。
+	Lift(actorID uint, uid uint, cid uint) error
+	// LiftExpired は now までに期限が切れた利用停止を解除し、解除した件数を返します。
+	// 解除は利用停止を設定したユーザーの操作として履歴に記録されます。
+	LiftExpired(now time.Time) (int, error)
+}
+
+type classBanRepository struct {
+	db *gorm.DB
+}
+
+func NewClassBanRepository(db *gorm.DB) ClassBanRepository {
+	return &classBanRepository{db: db}
+}
+
+func (r *classBanRepository) Ban(ban *models.ClassBan) error {
+	var user models.User
+	if err := r.db.First(&user, ban.UID).Error; err != nil {
+		return err
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// オーナーの移譲と同時に実行されないようにクラスをロックしてから確認する
+		class, err := lockClass(tx, ban.CID)
+		if err != nil {
+			return err
+		}
+		if class.UID == ban.UID {
+			return ErrBanOwner
+		}
+
+		var classUser models.ClassUser
+		err = tx.Select("role").First(&classUser, "uid = ? AND cid = ?", ban.UID, ban.CID).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			err = createClassUser(tx, ban.BannedBy, &models.ClassUser{CID: ban.CID, UID: ban.UID, Nickname: user.Name, Role: models.RoleBlacklist})
+		case err == nil:
+			err = updateUserRole(tx, ban.BannedBy, ban.UID, ban.CID, models.RoleBlacklist)
+		}
+		if err != nil {
+			return err
+		}
+		if err := ensureBan(tx, ban.BannedBy, ban.UID, ban.CID); err != nil {
+			return err
+		}
+
+		err = activeBan(tx, ban.UID, ban.CID).Updates(map[string]interface{}{
+			"banned_by":  ban.BannedBy,
+			"reason":     ban.Reason,
+			"expires_at": ban.ExpiresAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return activeBan(tx, ban.UID, ban.CID).Preload("User").First(ban).Error
+	})
+}
+
+func (r *classBanRepository) FindActive(cid uint) ([]models.ClassBan, error) {
+	var bans []models.ClassBan
+	err := r.db.Preload("User").
+		Where("cid = ? AND lifted_at IS NULL", cid).
+		Order("id DESC").
+		Find(&bans).Error
+	return bans, err
+}
+
+func (r *classBanRepository) Lift(actorID uint, uid uint, cid uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var ban models.ClassBan
+		if err := activeBan(tx, uid, cid).Clauses(clause.Locking{Strength: "UPDATE"}).First(&ban).Error; err != nil {
+			return err
+		}
+		if err := liftBan(tx, &actorID, uid, cid); err != nil {
+			return err
+		}
+		return deleteBannedUser(tx, actorID, uid, cid)
+	})
+}
+
+func (r *classBanRepository) LiftExpired(now time.Time) (int, error) {
+	var expired []models.ClassBan
+	err := r.db.Where("lifted_at IS NULL AND expires_at <= ?", now).Find(&expired).Error
+	if err != nil {
+		return 0, err
+	}
+
+	lifted := 0
+	for _, ban := range expired {
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			// 取得後に解除または更新された利用停止は対象外にする
+			var current models.ClassBan
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id = ? AND lifted_at IS NULL AND expires_at <= ?", ban.ID, now).
+				First(&current).Error
+			if err != nil {
+				return err
+			}
+			if err := liftBan(tx, nil, current.UID, current.CID); err != nil {
+				return err
+			}
+			return deleteBannedUser(tx, current.BannedBy, current.UID, current.CID)
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return lifted, err
+		}
+		lifted++
+	}
+	return lifted, nil
+}
+
+// activeBan は uid のクラスでの有効な利用停止を対象にしたクエリを返します。
+func activeBan(tx *gorm.DB, uid uint, cid uint) *gorm.DB {
+	return tx.Model(&models.ClassBan{}).Where("cid = ? AND uid = ? AND lifted_at IS NULL", cid, uid)
+}
+
+// ensureBan はトランザクション内で、uid に有効な利用停止がなければ作成し、未使用の招待を取り消します。
+func ensureBan(tx *gorm.DB, actorID uint, uid uint, cid uint) error {
+	var count int64
+	if err := activeBan(tx, uid, cid).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		if err := tx.Omit(clause.Associations).Create(&models.ClassBan{CID: cid, UID: uid, BannedBy: actorID}).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(&models.ClassInvitation{}).
+		Where("cid = ? AND invitee_id = ? AND status = ?", cid, uid, models.InvitationPending).
+		Updates(map[string]interface{}{
+			"status":     models.InvitationRevoked,
+			"decided_at": time.Now(),
+		}).Error
+}
+
+// liftBan はトランザクション内で uid の有効な利用停止を解除します。actorID が nil の場合は期限切れによる解除です。
+func liftBan(tx *gorm.DB, actorID *uint, uid uint, cid uint) error {
+	return activeBan(tx, uid, cid).Updates(map[string]interface{}{
+		"lifted_by": actorID,
+		"lifted_at": time.Now(),
+	}).Error
+}
+
+// deleteBannedUser はトランザクション内で、利用停止中 (BLACKLIST) のユーザーのみをクラスから削除します。
+func deleteBannedUser(tx *gorm.DB, actorID uint, uid uint, cid uint) error {
+	var classUser models.ClassUser
+	err := tx.Select("role").First(&classUser, "uid = ? AND cid = ?", uid, cid).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && classUser.Role != models.RoleBlacklist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return deleteClassUser(tx, actorID, uid, cid)
+}
+
+// syncBan はロールが from から to に変わったときに利用停止の記録を合わせます。
+// BLACKLIST になった場合は利用停止を作成し、BLACKLIST でなくなった場合は解除します。
+func syncBan(tx *gorm.DB, actorID uint, uid uint, cid uint, from models.Role, to models.Role) error {
+	switch {
+	case from == to:
+		return nil
+	case to == models.RoleBlacklist:
+		return ensureBan(tx, actorID, uid, cid)
+	case from == models.RoleBlacklist:
+		return liftBan(tx, &actorID, uid, cid)
+	}
+	return nil
+}
+
+// mergeClassBan は統合元ユーザーのクラスでの有効な利用停止を統合先ユーザーに移動します。
+// 統合後のロールが BLACKLIST でない場合、または統合先が既に利用停止中の場合は統合元の利用停止を解除します。
+func mergeClassBan(tx *gorm.DB, cid uint, sourceID uint, targetID uint, role models.Role) error {
+	if role == models.RoleBlacklist {
+		var count int64
+		if err := activeBan(tx, targetID, cid).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return activeBan(tx, sourceID, cid).Update("uid", targetID).Error
+		}
+	}
+	return liftBan(tx, &targetID, sourceID, cid)
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/pagination"
 	"gorm.io/gorm"
 )
 
@@ -9,7 +10,7 @@ import (
 type ClassBoardRepository interface {
 	InsertClassBoard(b *models.ClassBoard) (*models.ClassBoard, error)
 	FindByID(id uint) (*models.ClassBoard, error)
-	FindAllPaged(cid uint, limit int, offset int) ([]models.ClassBoard, error)
+	FindAllPaged(cid uint, window pagination.Window) ([]models.ClassBoard, error)
 	FindAnnounced(isAnnounced bool, cid uint) ([]models.ClassBoard, error)
 	UpdateClassBoard(b *models.ClassBoard) error
 	DeleteClassBoard(id uint) error
@@ -39,10 +40,10 @@ func (repo *classBoardRepository) FindByID(id uint) (*models.ClassBoard, error)
 	return &classBoard, err
 }
 
-// FindAllPaged 全てのグループ掲示板を取得
-func (repo *classBoardRepository) FindAllPaged(cid uint, limit int, offset int) ([]models.ClassBoard, error) {
+// FindAllPaged グループ掲示板を新しい順に window の範囲で取得
+func (repo *classBoardRepository) FindAllPaged(cid uint, window pagination.Window) ([]models.ClassBoard, error) {
 	var classBoards []models.ClassBoard
-	err := repo.db.Where("cid = ?", cid).Offset(offset).Limit(limit).Find(&classBoards).Error
+	err := applyWindow(repo.db.Where("cid = ?", cid), "id", true, window).Find(&classBoards).Error
 	return classBoards, err
 }
 
@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrClassFull はクラスの定員に達しているため、メンバーを追加できない場合のエラー
+var ErrClassFull = errors.New("class is full")
+
+// lockClass はトランザクション内でクラスの行をロックして取得します。
+// メンバー数を変更する処理は、同時に実行されても定員を超えないよう最初にクラスの行をロックします。
+func lockClass(tx *gorm.DB, cid uint) (models.Class, error) {
+	var class models.Class
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&class, cid).Error
+	return class, err
+}
+
+// hasVacancy はロックしたクラスに承認済みのメンバーを1人追加できるかを返します。Limitation が未設定の場合は制限しません。
+func hasVacancy(tx *gorm.DB, class models.Class) (bool, error) {
+	if class.Limitation == nil {
+		return true, nil
+	}
+	var count int64
+	err := tx.Model(&models.ClassUser{}).
+		Where("cid = ? AND role IN ?", class.ID, []models.Role{models.RoleAdmin, models.RoleAssistant, models.RoleUser}).
+		Count(&count).Error
+	return count < int64(*class.Limitation), err
+}
+
+// ensureVacancy はロールが from から to に変わることで承認済みのメンバーが増える場合に、定員に空きがあることを確認します。
+// 空きがない場合は ErrClassFull を返します。
+func ensureVacancy(tx *gorm.DB, class models.Class, from models.Role, to models.Role) error {
+	if from.Active() || !to.Active() {
+		return nil
+	}
+	ok, err := hasVacancy(tx, class)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrClassFull
+	}
+	return nil
+}
+
+// promoteWaitlist はロックしたクラスに空きがある間、キャンセル待ちの申請を古い順に承認します。
+// 承認の操作者はキャンセル待ちにしたユーザーとして記録します。
+func promoteWaitlist(tx *gorm.DB, class models.Class) error {
+	for {
+		ok, err := hasVacancy(tx, class)
+		if err != nil || !ok {
+			return err
+		}
+
+		var application models.ClassApplication
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("cid = ? AND status = ?", class.ID, models.ApplicationWaitlisted).
+			Order("waitlisted_at, id").
+			First(&application).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var actorID uint
+		if application.DecidedBy != nil {
+			actorID = *application.DecidedBy
+		}
+		if err := tx.Model(&application).Update("status", models.ApplicationApproved).Error; err != nil {
+			return err
+		}
+		if err := updateUserRole(tx, actorID, application.UID, class.ID, models.RoleUser); err != nil {
+			return err
+		}
+	}
+}
@@ -4,15 +4,35 @@ import (
 	"errors"
 	"log"
 	"strconv"
+	"time"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"gorm.io/gorm"
 )
 
+// ErrAlreadyMember は既にクラスに所属しているため、参加できない場合のエラー
+var ErrAlreadyMember = errors.New("user is already a member of the class")
+
 type ClassCodeRepository interface {
 	FindByCode(code string) (*models.ClassCode, error)
+	// FindByClassID はクラスの使用できるコードのうち、最初に作成されたものを取得します。
 	FindByClassID(cid uint) (*models.ClassCode, error)
+	// FindAllByClassID はクラスの全てのコードを、無効にしたものも含めて作成順に取得します。
+	FindAllByClassID(cid uint) ([]models.ClassCode, error)
 	SaveClassCode(classCode *models.ClassCode) error
+	// Regenerate はクラスのコードの値を code に変更し、使用回数をリセットして有効にします。
+	Regenerate(cid uint, id uint, code string) (*models.ClassCode, error)
+	// Revoke はクラスのコードを無効にします。
+	Revoke(cid uint, id uint) error
+	// UpdateSecret はクラスのコードのシークレットを secret (ハッシュ) に変更します。nil の場合はシークレットを削除します。
+	UpdateSecret(cid uint, id uint, secret *string) (*models.ClassCode, error)
+	// FindAllWithSecret はシークレットのある全てのコードを取得します。
+	FindAllWithSecret() ([]models.ClassCode, error)
+	// Redeem はコードの使用回数を1増やし、同じトランザクションで uid をコードのロールでクラスに追加します。
+	// 申請中のユーザーは、コードのロールが承認済みのメンバーの場合にそのロールになります。
+	// コードが使用できない場合は gorm.ErrRecordNotFound、既に所属している場合は ErrAlreadyMember、
+	// 定員に達している場合は ErrClassFull を返し、使用回数は増えません。
+	Redeem(uid uint, codeID uint) error
 }
 
 // ClassCodeRepository はグループコードのリポジトリです。
@@ -43,7 +63,7 @@ func (r *classCodeRepository) FindByCode(code string) (*models.ClassCode, error)
 // FindByClassID は指定されたクラスIDのクラスコードを取得します。
 func (r *classCodeRepository) FindByClassID(cid uint) (*models.ClassCode, error) {
 	var classCode models.ClassCode
-	result := r.db.Where("cid = ?", cid).First(&classCode)
+	result := usableClassCodes(r.db).Where("cid = ?", cid).Order("id").First(&classCode)
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 		log.Printf("ClassCode not found for ClassID: %d", cid)
 		return nil, nil
@@ -58,6 +78,12 @@ func (r *classCodeRepository) FindByClassID(cid uint) (*models.ClassCode, error)
 	return &classCode, nil
 }
 
+func (r *classCodeRepository) FindAllByClassID(cid uint) ([]models.ClassCode, error) {
+	var classCodes []models.ClassCode
+	err := r.db.Where("cid = ?", cid).Order("id").Find(&classCodes).Error
+	return classCodes, err
+}
+
 func (r *classCodeRepository) SaveClassCode(classCode *models.ClassCode) error {
 	var class models.Class
 	if err := r.db.First(&class, "id = ?", classCode.CID).Error; err != nil {
@@ -71,3 +97,101 @@ func (r *classCodeRepository) SaveClassCode(classCode *models.ClassCode) error {
 
 	return r.db.Create(classCode).Error
 }
+
+func (r *classCodeRepository) Regenerate(cid uint, id uint, code string) (*models.ClassCode, error) {
+	var classCode models.ClassCode
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ClassCode{}).Where("id = ? AND cid = ?", id, cid).Updates(map[string]interface{}{
+			"code":    code,
+			"uses":    0,
+			"enabled": true,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.First(&classCode, id).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &classCode, nil
+}
+
+func (r *classCodeRepository) Revoke(cid uint, id uint) error {
+	result := r.db.Model(&models.ClassCode{}).Where("id = ? AND cid = ?", id, cid).Update("enabled", false)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *classCodeRepository) UpdateSecret(cid uint, id uint, secret *string) (*models.ClassCode, error) {
+	var classCode models.ClassCode
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ClassCode{}).Where("id = ? AND cid = ?", id, cid).Update("secret", secret)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.First(&classCode, id).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &classCode, nil
+}
+
+func (r *classCodeRepository) FindAllWithSecret() ([]models.ClassCode, error) {
+	var classCodes []models.ClassCode
+	err := r.db.Where("secret IS NOT NULL").Order("id").Find(&classCodes).Error
+	return classCodes, err
+}
+
+func (r *classCodeRepository) Redeem(uid uint, codeID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// 条件付きの UPDATE で、同時に使用された場合も MaxUses を超えないようにする
+		result := usableClassCodes(tx.Model(&models.ClassCode{})).
+			Where("id = ?", codeID).
+			UpdateColumn("uses", gorm.Expr("uses + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		var classCode models.ClassCode
+		if err := tx.First(&classCode, codeID).Error; err != nil {
+			return err
+		}
+
+		var classUser models.ClassUser
+		err := tx.Select("role").First(&classUser, "uid = ? AND cid = ?", uid, classCode.CID).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			var user models.User
+			if err := tx.First(&user, uid).Error; err != nil {
+				return err
+			}
+			return createClassUser(tx, uid, &models.ClassUser{CID: classCode.CID, UID: uid, Nickname: user.Name, Role: classCode.Role})
+		case err != nil:
+			return err
+		case classUser.Role == models.RoleApplicant && classCode.Role.Active():
+			return updateUserRole(tx, uid, uid, classCode.CID, classCode.Role)
+		default:
+			return ErrAlreadyMember
+		}
+	})
+}
+
+// usableClassCodes は使用できる (有効で期限内、使用回数が上限未満の) コードに絞り込みます。
+func usableClassCodes(db *gorm.DB) *gorm.DB {
+	return db.Where("enabled AND (expires_at IS NULL OR expires_at > ?) AND (max_uses IS NULL OR uses < max_uses)", time.Now())
+}
@@ -0,0 +1,220 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ClassInvitationRepository はクラスへの招待のリポジトリ
+type ClassInvitationRepository interface {
+	// InviteUser は招待を保存し、同じトランザクションで招待するユーザーを INVITE のロールでクラスに追加します。
+	InviteUser(invitation *models.ClassInvitation) error
+	// CreateLink は招待リンクを保存します。
+	CreateLink(invitation *models.ClassInvitation) error
+	// FindPending はクラスの未使用で期限内の招待を新しい順に返します。
+	FindPending(cid uint) ([]models.ClassInvitation, error)
+	// FindPendingForUser はユーザーへのクラスの未使用の招待を返します。
+	FindPendingForUser(uid uint, cid uint) (*models.ClassInvitation, error)
+	// FindByTokenHash は招待リンクをトークンのハッシュで取得します。
+	FindByTokenHash(tokenHash string) (*models.ClassInvitation, error)
+	// Accept は uid が招待を承諾し、招待のロールでクラスに所属させます。招待リンクの場合、申請中のユーザーも承諾できます。
+	// 招待が使用済みか期限切れ、または uid が招待を承諾できない状態 (既にメンバー、利用停止中) の場合は gorm.ErrRecordNotFound を返します。
+	// 定員に達している場合は ErrClassFull を返し、招待は使用されません。
+	Accept(uid uint, invitationID uint) error
+	// Decline は uid への招待を辞退し、INVITE のロールを削除します。
+	Decline(uid uint, invitationID uint) error
+	// Revoke は actorID の操作としてクラスの招待を取り消します。既存のユーザーへの招待の場合は INVITE のロールも削除します。
+	Revoke(actorID uint, cid uint, invitationID uint) error
+}
+
+type classInvitationRepository struct {
+	db *gorm.DB
+}
+
+func NewClassInvitationRepository(db *gorm.DB) ClassInvitationRepository {
+	return &classInvitationRepository{db: db}
+}
+
+func (r *classInvitationRepository) InviteUser(invitation *models.ClassInvitation) error {
+	var user models.User
+	if err := r.db.First(&user, *invitation.InviteeID).Error; err != nil {
+		return err
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		classUser := models.ClassUser{
+			CID:      invitation.CID,
+			UID:      user.ID,
+			Nickname: user.Name,
+			Role:     models.RoleInvite,
+		}
+		if err := createClassUser(tx, invitation.InviterID, &classUser); err != nil {
+			return err
+		}
+		return tx.Omit(clause.Associations).Create(invitation).Error
+	})
+}
+
+func (r *classInvitationRepository) CreateLink(invitation *models.ClassInvitation) error {
+	return r.db.Omit(clause.Associations).Create(invitation).Error
+}
+
+func (r *classInvitationRepository) FindPending(cid uint) ([]models.ClassInvitation, error) {
+	var invitations []models.ClassInvitation
+	err := r.db.Where("cid = ? AND status = ? AND (expires_at IS NULL OR expires_at > ?)", cid, models.InvitationPending, time.Now()).
+		Order("id DESC").
+		Find(&invitations).Error
+	return invitations, err
+}
+
+func (r *classInvitationRepository) FindPendingForUser(uid uint, cid uint) (*models.ClassInvitation, error) {
+	var invitation models.ClassInvitation
+	err := r.db.Where("cid = ? AND invitee_id = ? AND status = ?", cid, uid, models.InvitationPending).
+		Order("id DESC").
+		First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *classInvitationRepository) FindByTokenHash(tokenHash string) (*models.ClassInvitation, error) {
+	var invitation models.ClassInvitation
+	if err := r.db.Where("token_hash = ?", tokenHash).First(&invitation).Error; err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *classInvitationRepository) Accept(uid uint, invitationID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		invitation, err := lockInvitation(tx, invitationID)
+		if err != nil {
+			return err
+		}
+		if !invitation.Usable(time.Now()) || (invitation.InviteeID != nil && *invitation.InviteeID != uid) {
+			return gorm.ErrRecordNotFound
+		}
+
+		var classUser models.ClassUser
+		err = tx.Select("role").First(&classUser, "uid = ? AND cid = ?", uid, invitation.CID).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound) && invitation.InviteeID == nil:
+			var user models.User
+			if err := tx.First(&user, uid).Error; err != nil {
+				return err
+			}
+			err = createClassUser(tx, uid, &models.ClassUser{CID: invitation.CID, UID: uid, Nickname: user.Name, Role: invitation.Role})
+		case err != nil:
+			return err
+		case classUser.Role == models.RoleInvite, classUser.Role == models.RoleApplicant && invitation.InviteeID == nil:
+			err = updateUserRole(tx, uid, uid, invitation.CID, invitation.Role)
+		default:
+			return gorm.ErrRecordNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		// 招待リンクで参加した場合も、同じユーザーへの招待は使用済みにする
+		return tx.Model(&models.ClassInvitation{}).
+			Where("id = ? OR (cid = ? AND invitee_id = ? AND status = ?)", invitation.ID, invitation.CID, uid, models.InvitationPending).
+			Updates(map[string]interface{}{
+				"status":      models.InvitationAccepted,
+				"accepted_by": uid,
+				"decided_at":  time.Now(),
+			}).Error
+	})
+}
+
+func (r *classInvitationRepository) Decline(uid uint, invitationID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		invitation, err := lockInvitation(tx, invitationID)
+		if err != nil {
+			return err
+		}
+		if invitation.Status != models.InvitationPending || invitation.InviteeID == nil || *invitation.InviteeID != uid {
+			return gorm.ErrRecordNotFound
+		}
+		if err := closeInvitation(tx, invitation.ID, models.InvitationDeclined); err != nil {
+			return err
+		}
+		return deleteInvitedUser(tx, uid, uid, invitation.CID)
+	})
+}
+
+func (r *classInvitationRepository) Revoke(actorID uint, cid uint, invitationID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		invitation, err := lockInvitation(tx, invitationID)
+		if err != nil {
+			return err
+		}
+		if invitation.CID != cid || invitation.Status != models.InvitationPending {
+			return gorm.ErrRecordNotFound
+		}
+		if err := closeInvitation(tx, invitation.ID, models.InvitationRevoked); err != nil {
+			return err
+		}
+		if invitation.InviteeID == nil {
+			return nil
+		}
+		return deleteInvitedUser(tx, actorID, *invitation.InviteeID, cid)
+	})
+}
+
+// lockInvitation はトランザクション内で招待の行をロックして取得します。
+func lockInvitation(tx *gorm.DB, invitationID uint) (models.ClassInvitation, error) {
+	var invitation models.ClassInvitation
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&invitation, invitationID).Error
+	return invitation, err
+}
+
+// closeInvitation はトランザクション内で招待を辞退または取り消しにします。
+func closeInvitation(tx *gorm.DB, invitationID uint, status models.InvitationStatus) error {
+	return tx.Model(&models.ClassInvitation{}).Where("id = ?", invitationID).Updates(map[string]interface{}{
+		"status":     status,
+		"decided_at": time.Now(),
+	}).Error
+}
+
+// deleteInvitedUser はトランザクション内で、招待中 (INVITE) のユーザーのみをクラスから削除します。
+func deleteInvitedUser(tx *gorm.DB, actorID uint, uid uint, cid uint) error {
+	var classUser models.ClassUser
+	err := tx.Select("role").First(&classUser, "uid = ? AND cid = ?", uid, cid).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && classUser.Role != models.RoleInvite) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return deleteClassUser(tx, actorID, uid, cid)
+}
+
+// mergeClassInvitations はアカウント統合時に、統合元ユーザーへのクラスの招待を統合先ユーザーに移動します。
+// role は統合後のロールです。INVITE の場合は統合先の未使用の招待を優先して一つだけ残し、
+// それ以外の場合は招待中ではなくなるため、両方のユーザーへの未使用の招待を取り消します。
+func mergeClassInvitations(tx *gorm.DB, cid uint, sourceID uint, targetID uint, role models.Role) error {
+	pending := func(uids ...uint) *gorm.DB {
+		return tx.Model(&models.ClassInvitation{}).Where("cid = ? AND invitee_id IN ? AND status = ?", cid, uids, models.InvitationPending)
+	}
+	revoke := map[string]interface{}{"status": models.InvitationRevoked, "decided_at": time.Now()}
+
+	if role == models.RoleInvite {
+		var count int64
+		if err := pending(targetID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			if err := pending(sourceID).Updates(revoke).Error; err != nil {
+				return err
+			}
+		}
+	} else if err := pending(sourceID, targetID).Updates(revoke).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.ClassInvitation{}).Where("cid = ? AND invitee_id = ?", cid, sourceID).Update("invitee_id", targetID).Error
+}
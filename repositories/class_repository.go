@@ -2,11 +2,16 @@ package repositories
 
 import (
 	"errors"
+	"strconv"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrInvalidOwner は承認済みのメンバーではないユーザーにクラスのオーナーを変更しようとした場合のエラー
+var ErrInvalidOwner = errors.New("new owner must be an active member of the class")
+
 type ClassRepository interface {
 	GetByID(classID uint) (*models.Class, error)
 	Create(class *models.Class) error
@@ -14,6 +19,7 @@ type ClassRepository interface {
 	UpdateClassImage(classID uint, imageUrl string) error
 	Update(class *models.Class) error
 	Delete(classID uint) error
+	TransferOwnership(actorID uint, classID uint, fromUID uint, toUID uint, previousOwnerRole models.Role) error
 }
 
 type classRepository struct {
@@ -51,10 +57,65 @@ func (r *classRepository) UpdateClassImage(classID uint, imageUrl string) error
 	return r.db.Model(&models.Class{}).Where("id = ?", classID).Update("image", imageUrl).Error
 }
 
+// Update はクラスを更新します。定員が増えて空きができた場合はキャンセル待ちを繰り上げます。
 func (r *classRepository) Update(class *models.Class) error {
-	return r.db.Save(class).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockClass(tx, class.ID); err != nil {
+			return err
+		}
+		if err := tx.Save(class).Error; err != nil {
+			return err
+		}
+		return promoteWaitlist(tx, *class)
+	})
 }
 
 func (r *classRepository) Delete(classID uint) error {
 	return r.db.Delete(&models.Class{}, classID).Error
 }
+
+// TransferOwnership は actorID の操作としてクラスのオーナーを fromUID から toUID に変更し、toUID を管理者にします。
+// previousOwnerRole が空でない場合は fromUID のロールも変更します。
+// 全ての変更と履歴の記録は一つのトランザクションで行います。オーナーが既に変更されていた場合は gorm.ErrRecordNotFound を、
+// toUID が承認済みのメンバー (ADMIN, ASSISTANT, USER) ではない場合は ErrInvalidOwner を返します。
+func (r *classRepository) TransferOwnership(actorID uint, classID uint, fromUID uint, toUID uint, previousOwnerRole models.Role) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// ロールの変更や利用停止と同時に実行されないようにクラスをロックしてから確認する
+		class, err := lockClass(tx, classID)
+		if err != nil {
+			return err
+		}
+		if class.UID != fromUID {
+			return gorm.ErrRecordNotFound
+		}
+		var classUser models.ClassUser
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("role").First(&classUser, "uid = ? AND cid = ?", toUID, classID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !classUser.Role.Active()) {
+			return ErrInvalidOwner
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Class{}).Where("id = ?", classID).Update("uid", toUID).Error; err != nil {
+			return err
+		}
+
+		if err := updateUserRole(tx, actorID, toUID, classID, models.RoleAdmin); err != nil {
+			return err
+		}
+		if previousOwnerRole != "" {
+			if err := updateUserRole(tx, actorID, fromUID, classID, previousOwnerRole); err != nil {
+				return err
+			}
+		}
+		return recordAuditEvent(tx, models.ClassAuditEvent{
+			CID:      classID,
+			ActorID:  actorID,
+			TargetID: toUID,
+			Action:   models.AuditOwnershipTransferred,
+			OldValue: strconv.FormatUint(uint64(fromUID), 10),
+			NewValue: strconv.FormatUint(uint64(toUID), 10),
+		})
+	})
+}
@@ -0,0 +1,30 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+)
+
+// ClassResourceRepository はクラスに属するリソース (掲示板、スケジュール、出欠) のクラスIDを取得するリポジトリ
+type ClassResourceRepository interface {
+	FindClassID(table string, id uint) (uint, error)
+}
+
+type classResourceRepository struct {
+	db *gorm.DB
+}
+
+func NewClassResourceRepository(db *gorm.DB) ClassResourceRepository {
+	return &classResourceRepository{db: db}
+}
+
+// FindClassID はテーブルのレコードが属するクラスのIDを返す。存在しない場合は gorm.ErrRecordNotFound を返す
+func (r *classResourceRepository) FindClassID(table string, id uint) (uint, error) {
+	var cids []uint
+	if err := r.db.Table(table).Where("id = ?", id).Limit(1).Pluck("cid", &cids).Error; err != nil {
+		return 0, err
+	}
+	if len(cids) == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return cids[0], nil
+}
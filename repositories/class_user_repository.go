@@ -2,31 +2,44 @@ package repositories
 
 import (
 	"errors"
+	"strconv"
+	"strings"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/pagination"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrLastAdmin はクラスの最後の管理者を降格または削除しようとした場合のエラー
+var ErrLastAdmin = errors.New("class must keep at least one admin")
+
 type ClassUserRepository interface {
-	GetClassMembers(cid uint, roles ...string) ([]dto.ClassMemberDTO, error)
+	GetClassMembers(cid uint, roles ...models.Role) ([]dto.ClassMemberDTO, error)
+	// FindClassMembers は条件に一致するメンバーを query.Sort の順に、after の次から最大 limit 件返します。
+	// 2つ目の戻り値は after と limit に関係なく、条件に一致するメンバーの総数です。
+	FindClassMembers(cid uint, query dto.ClassMemberQuery, after *dto.ClassMemberCursor, limit int) ([]dto.ClassMemberDTO, int64, error)
 	GetClassUserInfo(uid uint, cid uint) (dto.ClassMemberDTO, error)
-	GetUserClasses(uid uint, page int, limit int) ([]dto.UserClassInfoDTO, error)
-	GetUserClassesByRole(uid uint, role string, page int, limit int) ([]dto.UserClassInfoDTO, error)
-	GetRole(uid uint, cid uint) (string, error)
-	UpdateUserRole(uid uint, cid uint, newRole string) error
+	// GetUserClasses, GetUserClassesByRole, GetFavoriteClasses はユーザーのクラスをクラスIDの順に window の範囲で返します。
+	GetUserClasses(uid uint, window pagination.Window) ([]dto.UserClassInfoDTO, error)
+	GetUserClassesByRole(uid uint, role models.Role, window pagination.Window) ([]dto.UserClassInfoDTO, error)
+	GetRole(uid uint, cid uint) (models.Role, error)
+	UpdateUserRole(actorID uint, uid uint, cid uint, newRole models.Role) error
 	UpdateUserName(uid uint, cid uint, newName string) error
 	ToggleFavorite(uid uint, cid uint) error
-	DeleteClassUser(uid uint, cid uint) error
+	DeleteClassUser(actorID uint, uid uint, cid uint) error
 	Save(classUser *models.ClassUser) error
-	GetFavoriteClasses(uid uint, page int, limit int) ([]dto.UserClassInfoDTO, error)
-	IsAdmin(uid uint, cid uint) (bool, error)
+	GetFavoriteClasses(uid uint, window pagination.Window) ([]dto.UserClassInfoDTO, error)
 	IsMember(uid uint, cid uint) (bool, error)
 	SearchUserClassesByName(uid uint, name string) ([]dto.UserClassInfoDTO, error)
 	RoleExists(uid uint, cid uint) (bool, error)
-	CreateUserRole(uid uint, cid uint, role string) error
+	CreateUserRole(actorID uint, uid uint, cid uint, role models.Role) error
+	// ImportMembers は actorID の操作として rows のユーザーをまとめてクラスに追加し、追加できない行の Error に理由を設定します。
+	// Error が設定済みの行は無視します。追加できる行は1つのトランザクションで追加し、dryRun の場合は追加せずに結果のみを設定します。
+	ImportMembers(actorID uint, cid uint, rows []dto.MemberImportRow, dryRun bool) error
 }
 
 type classUserRepository struct {
@@ -50,17 +63,14 @@ func (r *classUserRepository) GetClassUserInfo(uid uint, cid uint) (dto.ClassMem
 	return toClassMemberDTO(classUser), nil
 }
 
-func (r *classUserRepository) GetUserClasses(uid uint, page int, limit int) ([]dto.UserClassInfoDTO, error) {
+func (r *classUserRepository) GetUserClasses(uid uint, window pagination.Window) ([]dto.UserClassInfoDTO, error) {
 	var userClassesInfo []dto.UserClassInfoDTO
-	offset := (page - 1) * limit
 
-	err := r.db.Table("classes").
+	query := r.db.Table("classes").
 		Select("classes.id, classes.name, classes.limitation, classes.description, classes.image, class_users.is_favorite, class_users.role").
 		Joins("INNER JOIN class_users ON classes.id = class_users.cid").
-		Where("class_users.uid = ?", uid).
-		Offset(offset).
-		Limit(limit).
-		Scan(&userClassesInfo).Error
+		Where("class_users.uid = ?", uid)
+	err := applyWindow(query, "classes.id", false, window).Scan(&userClassesInfo).Error
 
 	if err != nil {
 		return nil, err
@@ -70,16 +80,16 @@ func (r *classUserRepository) GetUserClasses(uid uint, page int, limit int) ([]d
 }
 
 // GetClassMembers はクラスのメンバー情報を取得します。
-func (r *classUserRepository) GetClassMembers(cid uint, roles ...string) ([]dto.ClassMemberDTO, error) {
+func (r *classUserRepository) GetClassMembers(cid uint, roles ...models.Role) ([]dto.ClassMemberDTO, error) {
 	var members []dto.ClassMemberDTO
 
 	query := r.db.Table("class_users").
-		Select("class_users.uid, class_users.nickname, class_users.role, users.image").
+		Select("class_users.uid, class_users.nickname, class_users.role, class_users.joined_at, users.image").
 		Joins("join users on class_users.uid = users.id").
 		Where("class_users.cid = ?", cid)
 
 	if len(roles) > 0 {
-		query = query.Where("class_users.role = ?", roles[0])
+		query = query.Where("class_users.role IN ?", roles)
 	}
 
 	if err := query.Scan(&members).Error; err != nil {
@@ -92,16 +102,74 @@ func (r *classUserRepository) GetClassMembers(cid uint, roles ...string) ([]dto.
 	return members, nil
 }
 
-func (r *classUserRepository) GetUserClassesByRole(uid uint, role string, page int, limit int) ([]dto.UserClassInfoDTO, error) {
+// memberSortColumns は並び替えの名前と列
+var memberSortColumns = map[string]string{
+	"nickname":  "class_users.nickname",
+	"role":      "class_users.role",
+	"joined_at": "class_users.joined_at",
+}
+
+func (r *classUserRepository) FindClassMembers(cid uint, query dto.ClassMemberQuery, after *dto.ClassMemberCursor, limit int) ([]dto.ClassMemberDTO, int64, error) {
+	db := r.db.Table("class_users").
+		Joins("JOIN users ON class_users.uid = users.id").
+		Where("class_users.cid = ?", cid)
+	if len(query.Roles) > 0 {
+		db = db.Where("class_users.role IN ?", query.Roles)
+	}
+	if query.Search != "" {
+		db = db.Where("class_users.nickname ILIKE ?", "%"+escapeLike(query.Search)+"%")
+	}
+
+	var total int64
+	if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	column, ok := memberSortColumns[query.Sort]
+	if !ok {
+		column = memberSortColumns["nickname"]
+	}
+	direction, operator := "ASC", ">"
+	if query.Order == "desc" {
+		direction, operator = "DESC", "<"
+	}
+	if after != nil {
+		var value interface{}
+		switch column {
+		case memberSortColumns["role"]:
+			value = gorm.Expr("CAST(? AS "+models.RoleEnumName+")", after.Role)
+		case memberSortColumns["joined_at"]:
+			value = after.JoinedAt
+		default:
+			value = after.Nickname
+		}
+		db = db.Where("("+column+", class_users.uid) "+operator+" (?, ?)", value, after.UID)
+	}
+
+	var members []dto.ClassMemberDTO
+	err := db.Select("class_users.uid, class_users.nickname, class_users.role, class_users.joined_at, users.image").
+		Order(column + " " + direction).
+		Order("class_users.uid " + direction).
+		Limit(limit).
+		Scan(&members).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return members, total, nil
+}
+
+// escapeLike は LIKE のパターンで特別な意味を持つ文字をエスケープします。
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+func (r *classUserRepository) GetUserClassesByRole(uid uint, role models.Role, window pagination.Window) ([]dto.UserClassInfoDTO, error) {
 	var userClassesInfo []dto.UserClassInfoDTO
-	offset := (page - 1) * limit
-	err := r.db.Table("classes").
+	query := r.db.Table("classes").
 		Select("classes.id, classes.name, classes.limitation, classes.description, classes.image, class_users.is_favorite, class_users.role").
 		Joins("INNER JOIN class_users ON classes.id = class_users.cid").
-		Where("class_users.uid = ? AND class_users.role = ?", uid, role).
-		Offset(offset).
-		Limit(limit).
-		Scan(&userClassesInfo).Error
+		Where("class_users.uid = ? AND class_users.role = ?", uid, role)
+	err := applyWindow(query, "classes.id", false, window).Scan(&userClassesInfo).Error
 
 	if err != nil {
 		return nil, err
@@ -111,7 +179,7 @@ func (r *classUserRepository) GetUserClassesByRole(uid uint, role string, page i
 }
 
 // GetRole はユーザーのロールを取得します。
-func (r *classUserRepository) GetRole(uid uint, cid uint) (string, error) {
+func (r *classUserRepository) GetRole(uid uint, cid uint) (models.Role, error) {
 	var classUser models.ClassUser
 	result := r.db.Select("role").First(&classUser, "uid = ? AND cid = ?", uid, cid)
 
@@ -124,9 +192,75 @@ func (r *classUserRepository) GetRole(uid uint, cid uint) (string, error) {
 	return classUser.Role, nil
 }
 
-// UpdateUserRole はユーザーのロールを更新します。
-func (r *classUserRepository) UpdateUserRole(uid uint, cid uint, newRole string) error {
-	return r.db.Model(&models.ClassUser{}).Where("uid = ? AND cid = ?", uid, cid).Update("role", newRole).Error
+// UpdateUserRole は actorID の操作としてユーザーのロールを更新し、同じトランザクションで履歴を記録します。
+// 承認済みのメンバーにする場合、定員に達していれば ErrClassFull を、最後の管理者のロールを変更する場合は ErrLastAdmin を返します。
+func (r *classUserRepository) UpdateUserRole(actorID uint, uid uint, cid uint, newRole models.Role) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return updateUserRole(tx, actorID, uid, cid, newRole)
+	})
+}
+
+// updateUserRole はトランザクション内でユーザーのロールを更新し、変更があった場合に履歴を記録します。
+// 承認済みのメンバーになる場合は定員を確認し、承認済みのメンバーでなくなる場合はキャンセル待ちを繰り上げます。
+// 最後の管理者のロールを変更する場合は ErrLastAdmin を返します。
+func updateUserRole(tx *gorm.DB, actorID uint, uid uint, cid uint, newRole models.Role) error {
+	class, err := lockClass(tx, cid)
+	if err != nil {
+		return err
+	}
+	var classUser models.ClassUser
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("role").First(&classUser, "uid = ? AND cid = ?", uid, cid).Error; err != nil {
+		return err
+	}
+	if classUser.Role == newRole {
+		return nil
+	}
+	if err := ensureAdminRemains(tx, cid, classUser.Role, newRole); err != nil {
+		return err
+	}
+	if err := ensureVacancy(tx, class, classUser.Role, newRole); err != nil {
+		return err
+	}
+	if err := tx.Model(&models.ClassUser{}).Where("uid = ? AND cid = ?", uid, cid).Update("role", newRole).Error; err != nil {
+		return err
+	}
+	if err := syncApplication(tx, actorID, uid, cid, classUser.Role, newRole); err != nil {
+		return err
+	}
+	if err := syncBan(tx, actorID, uid, cid, classUser.Role, newRole); err != nil {
+		return err
+	}
+	err = recordAuditEvent(tx, models.ClassAuditEvent{
+		CID:      cid,
+		ActorID:  actorID,
+		TargetID: uid,
+		Action:   models.AuditRoleChanged,
+		OldValue: string(classUser.Role),
+		NewValue: string(newRole),
+	})
+	if err != nil {
+		return err
+	}
+	if classUser.Role.Active() && !newRole.Active() {
+		return promoteWaitlist(tx, class)
+	}
+	return nil
+}
+
+// ensureAdminRemains はロールが from から to に変わることで管理者が減る場合に、他の管理者が残ることを確認します。
+// 管理者の数はクラスの行をロックした後に数えるため、管理者同士が同時に降格や削除をしても管理者がいなくなることはありません。
+func ensureAdminRemains(tx *gorm.DB, cid uint, from models.Role, to models.Role) error {
+	if from != models.RoleAdmin || to == models.RoleAdmin {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&models.ClassUser{}).Where("cid = ? AND role = ?", cid, models.RoleAdmin).Count(&count).Error; err != nil {
+		return err
+	}
+	if count <= 1 {
+		return ErrLastAdmin
+	}
+	return nil
 }
 
 // UpdateUserName はユーザーの名前を更新します。
@@ -149,34 +283,138 @@ func toClassMemberDTO(classUser models.ClassUser) dto.ClassMemberDTO {
 		Nickname: classUser.Nickname,
 		Role:     classUser.Role,
 		Image:    classUser.User.Image,
+		JoinedAt: classUser.JoinedAt,
 	}
 }
 
+// ToggleFavorite はお気に入りを切り替え、同じトランザクションで履歴を記録します。
 func (r *classUserRepository) ToggleFavorite(uid uint, cid uint) error {
-	var classUser models.ClassUser
-	err := r.db.Model(&classUser).Where("uid = ? AND cid = ?", uid, cid).UpdateColumn("is_favorite", gorm.Expr("NOT is_favorite")).Error
-	return err
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var classUser models.ClassUser
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("is_favorite").First(&classUser, "uid = ? AND cid = ?", uid, cid).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ClassUser{}).Where("uid = ? AND cid = ?", uid, cid).UpdateColumn("is_favorite", !classUser.IsFavorite).Error; err != nil {
+			return err
+		}
+		return recordAuditEvent(tx, models.ClassAuditEvent{
+			CID:      cid,
+			ActorID:  uid,
+			TargetID: uid,
+			Action:   models.AuditFavoriteToggled,
+			OldValue: strconv.FormatBool(classUser.IsFavorite),
+			NewValue: strconv.FormatBool(!classUser.IsFavorite),
+		})
+	})
+}
+
+// DeleteClassUser は actorID の操作としてユーザーをクラスから削除し、同じトランザクションで履歴を記録します。
+// 所属していない場合は何もしません。最後の管理者を削除する場合は ErrLastAdmin を、
+// 利用停止中のユーザーを削除する場合は ErrBannedMember を返します。
+func (r *classUserRepository) DeleteClassUser(actorID uint, uid uint, cid uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return deleteClassUser(tx, actorID, uid, cid)
+	})
 }
 
-func (r *classUserRepository) DeleteClassUser(uid uint, cid uint) error {
-	return r.db.Where("uid = ? AND cid = ?", uid, cid).Delete(&models.ClassUser{}).Error
+// deleteClassUser はトランザクション内でユーザーをクラスから削除し、履歴を記録します。
+// 申請中の場合は申請を却下し、承認済みのメンバーの場合はキャンセル待ちを繰り上げます。
+// 最後の管理者を削除する場合は ErrLastAdmin を、有効な利用停止が残っている場合は ErrBannedMember を返します。
+// 利用停止は削除では解除されないため、先に liftBan で解除する必要があります。
+func deleteClassUser(tx *gorm.DB, actorID uint, uid uint, cid uint) error {
+	class, err := lockClass(tx, cid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var classUser models.ClassUser
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("role").First(&classUser, "uid = ? AND cid = ?", uid, cid).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := ensureAdminRemains(tx, cid, classUser.Role, ""); err != nil {
+		return err
+	}
+	if classUser.Role == models.RoleBlacklist {
+		// 行を削除すると再参加できてしまうため、利用停止の解除 (Lift または期限切れ) を経た場合のみ削除する
+		var count int64
+		if err := activeBan(tx, uid, cid).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrBannedMember
+		}
+	}
+	if err := tx.Where("uid = ? AND cid = ?", uid, cid).Delete(&models.ClassUser{}).Error; err != nil {
+		return err
+	}
+	if err := syncApplication(tx, actorID, uid, cid, classUser.Role, ""); err != nil {
+		return err
+	}
+	err = recordAuditEvent(tx, models.ClassAuditEvent{
+		CID:      cid,
+		ActorID:  actorID,
+		TargetID: uid,
+		Action:   models.AuditMemberRemoved,
+		OldValue: string(classUser.Role),
+	})
+	if err != nil {
+		return err
+	}
+	if classUser.Role.Active() {
+		return promoteWaitlist(tx, class)
+	}
+	return nil
 }
 
+// Save はクラスユーザーを作成し、本人の操作として履歴を記録します。
 func (r *classUserRepository) Save(classUser *models.ClassUser) error {
-	return r.db.Create(classUser).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return createClassUser(tx, classUser.UID, classUser)
+	})
+}
+
+// createClassUser はトランザクション内でクラスユーザーを作成し、履歴を記録します。
+// 承認済みのメンバーとして追加する場合は定員を確認し、空きがなければ ErrClassFull を返します。
+func createClassUser(tx *gorm.DB, actorID uint, classUser *models.ClassUser) error {
+	class, err := lockClass(tx, classUser.CID)
+	if err != nil {
+		return err
+	}
+	if err := ensureVacancy(tx, class, "", classUser.Role); err != nil {
+		return err
+	}
+	if err := tx.Create(classUser).Error; err != nil {
+		return err
+	}
+	if err := syncApplication(tx, actorID, classUser.UID, classUser.CID, "", classUser.Role); err != nil {
+		return err
+	}
+	if err := syncBan(tx, actorID, classUser.UID, classUser.CID, "", classUser.Role); err != nil {
+		return err
+	}
+	return recordAuditEvent(tx, models.ClassAuditEvent{
+		CID:      classUser.CID,
+		ActorID:  actorID,
+		TargetID: classUser.UID,
+		Action:   models.AuditMemberJoined,
+		NewValue: string(classUser.Role),
+	})
 }
 
-func (r *classUserRepository) GetFavoriteClasses(uid uint, page int, limit int) ([]dto.UserClassInfoDTO, error) {
+func (r *classUserRepository) GetFavoriteClasses(uid uint, window pagination.Window) ([]dto.UserClassInfoDTO, error) {
 	var favoriteClasses []dto.UserClassInfoDTO
-	offset := (page - 1) * limit
 
 	query := r.db.Table("classes").
 		Select("classes.id, classes.name, classes.description, classes.image, class_users.is_favorite").
 		Joins("join class_users on classes.id = class_users.cid").
-		Where("class_users.uid = ? AND class_users.is_favorite = ?", uid, true).
-		Offset(offset).
-		Limit(limit).
-		Scan(&favoriteClasses)
+		Where("class_users.uid = ? AND class_users.is_favorite = ?", uid, true)
+	query = applyWindow(query, "classes.id", false, window).Scan(&favoriteClasses)
 
 	if query.Error != nil {
 		return nil, query.Error
@@ -184,15 +422,6 @@ func (r *classUserRepository) GetFavoriteClasses(uid uint, page int, limit int)
 	return favoriteClasses, nil
 }
 
-// IsAdmin はユーザーが管理者かどうかを確認します。
-func (r *classUserRepository) IsAdmin(uid uint, cid uint) (bool, error) {
-	role, err := r.GetRole(uid, cid)
-	if err != nil {
-		return false, err
-	}
-	return role == "ADMIN", nil
-}
-
 func (r *classUserRepository) IsMember(uid uint, cid uint) (bool, error) {
 	var count int64
 	r.db.Model(&models.ClassUser{}).Where("uid = ? AND cid = ?", uid, cid).Count(&count)
@@ -219,7 +448,9 @@ func (r *classUserRepository) RoleExists(uid uint, cid uint) (bool, error) {
 	return count > 0, err
 }
 
-func (r *classUserRepository) CreateUserRole(uid uint, cid uint, role string) error {
+// CreateUserRole は actorID の操作としてユーザーをクラスに追加し、同じトランザクションで履歴を記録します。
+// 承認済みのメンバーとして追加する場合、定員に達していれば ErrClassFull を返します。
+func (r *classUserRepository) CreateUserRole(actorID uint, uid uint, cid uint, role models.Role) error {
 	var user models.User
 	if err := r.db.First(&user, uid).Error; err != nil {
 		return err
@@ -231,5 +462,88 @@ func (r *classUserRepository) CreateUserRole(uid uint, cid uint, role string) er
 		Role:     role,
 		Nickname: user.Name,
 	}
-	return r.db.Create(&newUserRole).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return createClassUser(tx, actorID, &newUserRole)
+	})
+}
+
+// errDryRun はドライランのトランザクションをロールバックするためのエラー
+var errDryRun = errors.New("dry run")
+
+func (r *classUserRepository) ImportMembers(actorID uint, cid uint, rows []dto.MemberImportRow, dryRun bool) error {
+	// ドライランでも定員の確認を含めて実際に追加し、最後にロールバックする
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockClass(tx, cid); err != nil {
+			return err
+		}
+
+		seen := make(map[uint]bool)
+		for i := range rows {
+			row := &rows[i]
+			if row.Error != "" {
+				continue
+			}
+			user, err := findImportUser(tx, *row)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				row.Error = constants.UserNotFound
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			row.UID = user.ID
+			if seen[user.ID] {
+				row.Error = constants.DuplicateImportRow
+				continue
+			}
+			seen[user.ID] = true
+			if row.Nickname == "" {
+				row.Nickname = user.Name
+			}
+
+			var existing models.ClassUser
+			err = tx.Select("role").First(&existing, "uid = ? AND cid = ?", user.ID, cid).Error
+			switch {
+			case err == nil && existing.Role == models.RoleBlacklist:
+				row.Error = constants.BannedUser
+				continue
+			case err == nil:
+				row.Error = constants.AlreadyMember
+				continue
+			case !errors.Is(err, gorm.ErrRecordNotFound):
+				return err
+			}
+
+			err = createClassUser(tx, actorID, &models.ClassUser{CID: cid, UID: user.ID, Nickname: row.Nickname, Role: row.Role})
+			if errors.Is(err, ErrClassFull) {
+				row.Error = constants.ClassFull
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		return nil
+	}
+	return err
+}
+
+// findImportUser は行のユーザーIDまたはログインプロバイダのアカウントでユーザーを取得します。
+func findImportUser(tx *gorm.DB, row dto.MemberImportRow) (models.User, error) {
+	var user models.User
+	if row.UID != 0 {
+		err := tx.First(&user, row.UID).Error
+		return user, err
+	}
+	err := tx.Joins("JOIN user_identities ON user_identities.uid = users.id").
+		Where("user_identities.provider = ? AND user_identities.subject = ?", row.Provider, row.Subject).
+		First(&user).Error
+	return user, err
 }
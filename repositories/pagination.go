@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/pagination"
+	"gorm.io/gorm"
+)
+
+// applyWindow は一意の列 column の順で window の範囲を取得する条件をクエリに追加します。desc の場合は降順です。
+func applyWindow(db *gorm.DB, column string, desc bool, window pagination.Window) *gorm.DB {
+	direction, operator := "ASC", ">"
+	if desc {
+		direction, operator = "DESC", "<"
+	}
+	if window.AfterID != 0 {
+		db = db.Where(column+" "+operator+" ?", window.AfterID)
+	} else if window.Offset > 0 {
+		db = db.Offset(window.Offset)
+	}
+	return db.Order(column + " " + direction).Limit(window.Limit)
+}
@@ -1,16 +1,30 @@
 package repositories
 
 import (
+	"errors"
+
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// mergeRoleRank はアカウント統合時に両方のアカウントが同じクラスに所属している場合、どちらのロールを残すかを決める順位
+// 管理者が失われないよう ADMIN を最優先し、次に BLACKLIST を優先して統合によって利用停止が解除されないようにする
+var mergeRoleRank = map[models.Role]int{
+	models.RoleAdmin:     5,
+	models.RoleBlacklist: 4,
+	models.RoleAssistant: 3,
+	models.RoleUser:      2,
+	models.RoleApplicant: 1,
+}
+
 type UserRepository interface {
-	GetApplyingClasses(userID uint) ([]models.ClassUser, error)
+	GetApplyingClasses(userID uint) ([]models.ClassApplication, error)
 	UserExists(userID uint) (bool, error)
 	FindByName(name string) ([]models.User, error)
 	DeleteUser(userID uint) error
 	FindByID(userID uint) (*models.User, error)
+	MergeUsers(sourceID uint, targetID uint) error
 }
 
 type userRepository struct {
@@ -21,11 +35,15 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
-// GetApplyingClasses はユーザーが申請中のクラスを取得します。
-func (r *userRepository) GetApplyingClasses(userID uint) ([]models.ClassUser, error) {
-	var classUsers []models.ClassUser
-	err := r.db.Preload("Class").Preload("User").Where("uid = ? AND role = ?", userID, "APPLICANT").Find(&classUsers).Error
-	return classUsers, err
+// GetApplyingClasses はユーザーのクラスごとの最新の申請のうち、申請中、キャンセル待ち、または却下された申請を新しい順に取得します。
+func (r *userRepository) GetApplyingClasses(userID uint) ([]models.ClassApplication, error) {
+	var applications []models.ClassApplication
+	latest := r.db.Model(&models.ClassApplication{}).Select("MAX(id)").Where("uid = ?", userID).Group("cid")
+	err := r.db.Preload("Class").
+		Where("uid = ? AND status IN ? AND id IN (?)", userID, []models.ApplicationStatus{models.ApplicationPending, models.ApplicationWaitlisted, models.ApplicationRejected}, latest).
+		Order("id DESC").
+		Find(&applications).Error
+	return applications, err
 }
 
 // UserExists はユーザーが存在するかを確認します。
@@ -54,3 +72,115 @@ func (r *userRepository) FindByID(userID uint) (*models.User, error) {
 	}
 	return &user, nil
 }
+
+// MergeUsers は統合元ユーザーのクラス所属、参加申請、招待、出欠、掲示板、クラスコード、作成したクラス、ログインアカウント、APIキーを
+// 統合先ユーザーに移動し、統合元ユーザーを削除します。全ての処理は一つのトランザクションで行います。
+func (r *userRepository) MergeUsers(sourceID uint, targetID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var memberships []models.ClassUser
+		if err := tx.Where("uid = ?", sourceID).Find(&memberships).Error; err != nil {
+			return err
+		}
+
+		for _, membership := range memberships {
+			if err := mergeClassUser(tx, membership, targetID); err != nil {
+				return err
+			}
+		}
+
+		moves := []interface{}{
+			&models.Attendance{},
+			&models.ClassBoard{},
+			&models.ClassCode{},
+			&models.Class{},
+			&models.UserIdentity{},
+			&models.APIKey{},
+			&models.ClassApplication{},
+			&models.ClassBan{},
+		}
+		for _, model := range moves {
+			if err := tx.Model(model).Where("uid = ?", sourceID).Update("uid", targetID).Error; err != nil {
+				return err
+			}
+		}
+		// 所属していないクラスの招待 (辞退、取り消し済み) と、招待した、招待リンクを使用した記録を移動する
+		for _, column := range []string{"invitee_id", "inviter_id", "accepted_by"} {
+			if err := tx.Model(&models.ClassInvitation{}).Where(column+" = ?", sourceID).Update(column, targetID).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(&models.User{}, sourceID).Error
+	})
+}
+
+// mergeClassUser は統合元ユーザーのクラス所属を統合先ユーザーに移動します。
+// 出欠は (cid, uid) でクラス所属を参照しているため、統合先の所属を作成してから出欠を移動し、最後に統合元の所属を削除します。
+func mergeClassUser(tx *gorm.DB, source models.ClassUser, targetID uint) error {
+	var target models.ClassUser
+	err := tx.Where("cid = ? AND uid = ?", source.CID, targetID).First(&target).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		target = models.ClassUser{
+			CID:        source.CID,
+			UID:        targetID,
+			Nickname:   source.Nickname,
+			IsFavorite: source.IsFavorite,
+			Role:       source.Role,
+			JoinedAt:   source.JoinedAt,
+		}
+		if err := tx.Omit(clause.Associations).Create(&target).Error; err != nil {
+			return err
+		}
+		if err := mergeClassBan(tx, source.CID, source.UID, targetID, target.Role); err != nil {
+			return err
+		}
+		if err := mergeClassInvitations(tx, source.CID, source.UID, targetID, target.Role); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	default:
+		role := target.Role
+		if mergeRoleRank[source.Role] > mergeRoleRank[target.Role] {
+			role = source.Role
+		}
+		err := tx.Model(&models.ClassUser{}).
+			Where("cid = ? AND uid = ?", source.CID, targetID).
+			Updates(map[string]interface{}{"role": role, "is_favorite": target.IsFavorite || source.IsFavorite}).Error
+		if err != nil {
+			return err
+		}
+		if err := mergeClassBan(tx, source.CID, source.UID, targetID, role); err != nil {
+			return err
+		}
+		if err := mergeClassInvitations(tx, source.CID, source.UID, targetID, role); err != nil {
+			return err
+		}
+		if err := syncApplication(tx, targetID, targetID, source.CID, target.Role, role); err != nil {
+			return err
+		}
+		if err := syncBan(tx, targetID, targetID, source.CID, target.Role, role); err != nil {
+			return err
+		}
+
+		// 統合先が既に所属している場合は統合先の申請の状態を残し、統合元の申請中の申請は移動しない
+		err = tx.Where("cid = ? AND uid = ? AND status = ?", source.CID, source.UID, models.ApplicationPending).Delete(&models.ClassApplication{}).Error
+		if err != nil {
+			return err
+		}
+
+		// 統合先に同じ授業の出欠がある場合は、統合先の記録を残す
+		targetSchedules := tx.Model(&models.Attendance{}).Select("csid").Where("cid = ? AND uid = ?", source.CID, targetID)
+		err = tx.Where("cid = ? AND uid = ? AND csid IN (?)", source.CID, source.UID, targetSchedules).Delete(&models.Attendance{}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Model(&models.Attendance{}).Where("cid = ? AND uid = ?", source.CID, source.UID).Update("uid", targetID).Error
+	if err != nil {
+		return err
+	}
+	return tx.Where("cid = ? AND uid = ?", source.CID, source.UID).Delete(&models.ClassUser{}).Error
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/authorization"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"gorm.io/gorm"
+)
+
+// APIキーのスコープ
+const (
+	ScopeAttendanceRead  = "attendance:read"
+	ScopeAttendanceWrite = "attendance:write"
+	ScopeScheduleRead    = "schedule:read"
+	ScopeScheduleWrite   = "schedule:write"
+	ScopeBoardRead       = "board:read"
+	ScopeBoardWrite      = "board:write"
+)
+
+// permissionScopes はAPIキーで許可できる権限と、そのために必要なスコープ
+var permissionScopes = map[authorization.Permission]string{
+	authorization.AttendanceRead:   ScopeAttendanceRead,
+	authorization.AttendanceManage: ScopeAttendanceWrite,
+	authorization.ScheduleRead:     ScopeScheduleRead,
+	authorization.ScheduleWrite:    ScopeScheduleWrite,
+	authorization.BoardRead:        ScopeBoardRead,
+	authorization.BoardWrite:       ScopeBoardWrite,
+}
+
+var apiKeyScopes = map[string]bool{
+	ScopeAttendanceRead:  true,
+	ScopeAttendanceWrite: true,
+	ScopeScheduleRead:    true,
+	ScopeScheduleWrite:   true,
+	ScopeBoardRead:       true,
+	ScopeBoardWrite:      true,
+}
+
+const (
+	// apiKeyPrefix は発行するAPIキーの接頭辞
+	apiKeyPrefix = "mk_"
+	// apiKeyDisplayLength は一覧で表示するキーの先頭部分の長さ
+	apiKeyDisplayLength = 11
+	// apiKeyTouchInterval はAPIキーの最終使用日時を更新する最短間隔
+	apiKeyTouchInterval = time.Minute
+)
+
+// APIKeyService はAPIキーの発行、管理、認証を行うサービス
+// cidがnilの場合はサービス全体のキーを扱い、サービス管理者の確認はルートで行います。
+// cidが指定された場合は、actorIDがクラス内で api_keys.manage の権限を持っていることを確認します。
+type APIKeyService interface {
+	Create(actorID uint, cid *uint, request dto.CreateAPIKeyRequest) (dto.CreatedAPIKeyDTO, error)
+	List(actorID uint, cid *uint) ([]dto.APIKeyDTO, error)
+	Update(actorID uint, cid *uint, id uint, request dto.UpdateAPIKeyRequest) (dto.APIKeyDTO, error)
+	Delete(actorID uint, cid *uint, id uint) error
+	Authenticate(rawKey string) (*models.APIKey, error)
+}
+
+type apiKeyServiceImpl struct {
+	repo          repositories.APIKeyRepository
+	classUserRepo repositories.ClassUserRepository
+}
+
+// NewAPIKeyService はAPIKeyServiceを生成します。
+func NewAPIKeyService(repo repositories.APIKeyRepository, classUserRepo repositories.ClassUserRepository) APIKeyService {
+	return &apiKeyServiceImpl{
+		repo:          repo,
+		classUserRepo: classUserRepo,
+	}
+}
+
+// Create はAPIキーを発行します。キーはハッシュのみを保存し、発行時にのみ返します。
+func (s *apiKeyServiceImpl) Create(actorID uint, cid *uint, request dto.CreateAPIKeyRequest) (dto.CreatedAPIKeyDTO, error) {
+	if err := s.requireManager(actorID, cid); err != nil {
+		return dto.CreatedAPIKeyDTO{}, err
+	}
+	scopes, err := normalizeScopes(request.Scopes)
+	if err != nil {
+		return dto.CreatedAPIKeyDTO{}, err
+	}
+
+	rawKey, err := generateAPIKey()
+	if err != nil {
+		return dto.CreatedAPIKeyDTO{}, err
+	}
+
+	key := models.APIKey{
+		Name:      request.Name,
+		Prefix:    rawKey[:apiKeyDisplayLength],
+		KeyHash:   hashAPIKey(rawKey),
+		Scopes:    strings.Join(scopes, ","),
+		CID:       cid,
+		UID:       actorID,
+		ExpiresAt: request.ExpiresAt,
+	}
+	if err := s.repo.Create(&key); err != nil {
+		return dto.CreatedAPIKeyDTO{}, err
+	}
+	return dto.CreatedAPIKeyDTO{APIKeyDTO: toAPIKeyDTO(key), Key: rawKey}, nil
+}
+
+// List はクラス、またはサービス全体のAPIキーを返します。
+func (s *apiKeyServiceImpl) List(actorID uint, cid *uint) ([]dto.APIKeyDTO, error) {
+	if err := s.requireManager(actorID, cid); err != nil {
+		return nil, err
+	}
+	keys, err := s.repo.FindAll(cid)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]dto.APIKeyDTO, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, toAPIKeyDTO(key))
+	}
+	return result, nil
+}
+
+// Update はAPIキーの名前、スコープ、有効期限を更新します。
+func (s *apiKeyServiceImpl) Update(actorID uint, cid *uint, id uint, request dto.UpdateAPIKeyRequest) (dto.APIKeyDTO, error) {
+	key, err := s.find(actorID, cid, id)
+	if err != nil {
+		return dto.APIKeyDTO{}, err
+	}
+
+	if request.Name != nil {
+		key.Name = *request.Name
+	}
+	if request.Scopes != nil {
+		scopes, err := normalizeScopes(request.Scopes)
+		if err != nil {
+			return dto.APIKeyDTO{}, err
+		}
+		key.Scopes = strings.Join(scopes, ",")
+	}
+	if request.ExpiresAt != nil {
+		key.ExpiresAt = request.ExpiresAt
+	}
+
+	if err := s.repo.Update(key); err != nil {
+		return dto.APIKeyDTO{}, err
+	}
+	return toAPIKeyDTO(*key), nil
+}
+
+// Delete はAPIキーを削除します。
+func (s *apiKeyServiceImpl) Delete(actorID uint, cid *uint, id uint) error {
+	if _, err := s.find(actorID, cid, id); err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
+
+// Authenticate はAPIキーを検証し、有効なキーを返します。
+// 存在しない、または期限切れのキーの場合は ErrInvalidAPIKey を返します。
+func (s *apiKeyServiceImpl) Authenticate(rawKey string) (*models.APIKey, error) {
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.FindByHash(hashAPIKey(rawKey))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.repo.UpdateLastUsedAt(key.ID, now); err != nil {
+			return nil, err
+		}
+		key.LastUsedAt = &now
+	}
+	return key, nil
+}
+
+// find はcidに属するAPIキーを返します。他のクラスのキーの場合は ErrNotFound を返します。
+func (s *apiKeyServiceImpl) find(actorID uint, cid *uint, id uint) (*models.APIKey, error) {
+	if err := s.requireManager(actorID, cid); err != nil {
+		return nil, err
+	}
+
+	key, err := s.repo.FindByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if (key.CID == nil) != (cid == nil) || (cid != nil && *key.CID != *cid) {
+		return nil, ErrNotFound
+	}
+	return key, nil
+}
+
+// requireManager はクラスのキーの場合に actorID がクラス内で api_keys.manage の権限を持っていることを確認します。
+func (s *apiKeyServiceImpl) requireManager(actorID uint, cid *uint) error {
+	if cid == nil {
+		return nil
+	}
+	return authorize(s.classUserRepo, actorID, *cid, authorization.APIKeysManage)
+}
+
+// ScopeForPermission は権限に対応するAPIキーのスコープを返します。
+// APIキーで行えない操作の場合は false を返します。
+func ScopeForPermission(permission authorization.Permission) (string, bool) {
+	scope, ok := permissionScopes[permission]
+	return scope, ok
+}
+
+// HasScope はAPIキーがスコープを持っているかを返します。
+func HasScope(key *models.APIKey, scope string) bool {
+	for _, s := range strings.Split(key.Scopes, ",") {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeScopes はスコープを検証し、重複を除いて返します。
+func normalizeScopes(scopes []string) ([]string, error) {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !apiKeyScopes[scope] {
+			return nil, ErrInvalidScope
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	if len(result) == 0 {
+		return nil, ErrInvalidScope
+	}
+	return result, nil
+}
+
+// generateAPIKey はランダムなAPIキーを生成します。
+func generateAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashAPIKey はAPIキーのSHA-256ハッシュを返します。
+// キーは十分なエントロピーを持つため、ソルトなしのハッシュで検索できるようにしています。
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func toAPIKeyDTO(key models.APIKey) dto.APIKeyDTO {
+	return dto.APIKeyDTO{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     strings.Split(key.Scopes, ","),
+		CID:        key.CID,
+		UID:        key.UID,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
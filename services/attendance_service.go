@@ -9,6 +9,8 @@ import (
 )
 
 type AttendanceService interface {
+	// CreateOrUpdateAttendance はスケジュール csid の出欠を登録または更新します。
+	// スケジュールがクラス cid に属していない場合は ErrNotFound を返します。
 	CreateOrUpdateAttendance(cid uint, uid uint, csid uint, status string) error
 	GetAllAttendancesByCID(cid uint) ([]models.Attendance, error)
 	GetAllAttendancesByCSID(csid uint) ([]models.Attendance, error)
@@ -16,14 +18,27 @@ type AttendanceService interface {
 }
 
 type attendanceService struct {
-	repo repositories.AttendanceRepository
+	repo         repositories.AttendanceRepository
+	scheduleRepo repositories.ClassScheduleRepository
 }
 
-func NewAttendanceService(repo repositories.AttendanceRepository) AttendanceService {
-	return &attendanceService{repo: repo}
+func NewAttendanceService(repo repositories.AttendanceRepository, scheduleRepo repositories.ClassScheduleRepository) AttendanceService {
+	return &attendanceService{repo: repo, scheduleRepo: scheduleRepo}
 }
 
 func (s *attendanceService) CreateOrUpdateAttendance(cid uint, uid uint, csid uint, status string) error {
+	// 権限を確認したクラス以外のスケジュールの出欠を変更できないようにする
+	schedule, err := s.scheduleRepo.GetClassScheduleByID(csid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if schedule.CID != cid {
+		return ErrNotFound
+	}
+
 	attendance, err := s.repo.GetAttendanceByUIDAndCSID(uid, csid)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"golang.org/x/oauth2"
+)
+
+// AuthProvider はログインプロバイダのインターフェース
+// 認可URLの生成と、認可コードから認証されたユーザー情報を取得する処理を提供します。
+type AuthProvider interface {
+	// Name は /api/gin/auth/:provider で使用するプロバイダ名を返します。
+	Name() string
+	// AuthCodeURL は flow のstateとPKCEのcode_challengeを含む認可URLを生成し、URLとstateを返します。
+	AuthCodeURL(flow OAuthFlow) (string, string, error)
+	// Authenticate はstateが flow で発行されたことを検証して認可コードを交換し、ユーザー情報を返します。
+	Authenticate(code string, state string, flow OAuthFlow) (dto.ExternalUserInfo, error)
+}
+
+// oidcProvider はid_tokenをローカルで検証するOpenID Connectプロバイダ
+type oidcProvider struct {
+	name            string
+	oauthConfig     *oauth2.Config
+	stateStore      OAuthStateStore
+	idTokenVerifier IDTokenVerifier
+}
+
+// NewOIDCProvider はOpenID Connectプロバイダを生成します。
+// ユーザー情報はプロフィールAPIを呼び出さず、検証済みのid_tokenのクレーム (sub, name, picture) から取得します。
+func NewOIDCProvider(name string, config *oauth2.Config, stateStore OAuthStateStore, idTokenVerifier IDTokenVerifier) AuthProvider {
+	return &oidcProvider{
+		name:            name,
+		oauthConfig:     config,
+		stateStore:      stateStore,
+		idTokenVerifier: idTokenVerifier,
+	}
+}
+
+func (p *oidcProvider) Name() string {
+	return p.name
+}
+
+func (p *oidcProvider) AuthCodeURL(flow OAuthFlow) (string, string, error) {
+	return newAuthCodeURL(p.oauthConfig, p.stateStore, flow)
+}
+
+func (p *oidcProvider) Authenticate(code string, state string, flow OAuthFlow) (dto.ExternalUserInfo, error) {
+	token, data, err := exchangeAuthCode(p.oauthConfig, p.stateStore, code, state, flow)
+	if err != nil {
+		return dto.ExternalUserInfo{}, err
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return dto.ExternalUserInfo{}, fmt.Errorf("%w: id_token is missing", ErrInvalidIDToken)
+	}
+	claims, err := p.idTokenVerifier.Verify(rawIDToken, data.Nonce)
+	if err != nil {
+		return dto.ExternalUserInfo{}, err
+	}
+
+	return dto.ExternalUserInfo{
+		Provider: p.name,
+		Subject:  claims.Subject,
+		Name:     claims.Name,
+		Picture:  claims.Picture,
+	}, nil
+}
+
+// githubProvider はGitHubのOAuthアプリによるログインプロバイダ
+// GitHubはid_tokenを発行しないため、アクセストークンでユーザーAPIを呼び出します。
+type githubProvider struct {
+	name        string
+	oauthConfig *oauth2.Config
+	stateStore  OAuthStateStore
+	userInfoURL string
+}
+
+// githubUser はGitHubのユーザーAPIのレスポンス
+type githubUser struct {
+	ID        int64  `json:"id"`
+	Login     string `json:"login"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// NewGitHubProvider はGitHubのログインプロバイダを生成します。
+func NewGitHubProvider(name string, config *oauth2.Config, stateStore OAuthStateStore, userInfoURL string) AuthProvider {
+	return &githubProvider{
+		name:        name,
+		oauthConfig: config,
+		stateStore:  stateStore,
+		userInfoURL: userInfoURL,
+	}
+}
+
+func (p *githubProvider) Name() string {
+	return p.name
+}
+
+func (p *githubProvider) AuthCodeURL(flow OAuthFlow) (string, string, error) {
+	return newAuthCodeURL(p.oauthConfig, p.stateStore, flow)
+}
+
+func (p *githubProvider) Authenticate(code string, state string, flow OAuthFlow) (dto.ExternalUserInfo, error) {
+	token, _, err := exchangeAuthCode(p.oauthConfig, p.stateStore, code, state, flow)
+	if err != nil {
+		return dto.ExternalUserInfo{}, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, p.userInfoURL, nil)
+	if err != nil {
+		return dto.ExternalUserInfo{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := p.oauthConfig.Client(context.Background(), token).Do(req)
+	if err != nil {
+		return dto.ExternalUserInfo{}, fmt.Errorf("Failed to Get UserInfo: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return dto.ExternalUserInfo{}, fmt.Errorf("Failed to Get UserInfo: status %d", resp.StatusCode)
+	}
+
+	var user githubUser
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return dto.ExternalUserInfo{}, fmt.Errorf("Failed to decode UserInfo: %w", err)
+	}
+	if user.ID == 0 {
+		return dto.ExternalUserInfo{}, fmt.Errorf("Failed to Get UserInfo: id is missing")
+	}
+
+	name := user.Name
+	if name == "" {
+		name = user.Login
+	}
+	return dto.ExternalUserInfo{
+		Provider: p.name,
+		Subject:  strconv.FormatInt(user.ID, 10),
+		Name:     name,
+		Picture:  user.AvatarURL,
+	}, nil
+}
+
+// AuthProviderRegistry はプロバイダ名からログインプロバイダを引くレジストリ
+type AuthProviderRegistry struct {
+	providers map[string]AuthProvider
+}
+
+// NewAuthProviderRegistry は指定したプロバイダを登録したレジストリを生成します。
+func NewAuthProviderRegistry(providers ...AuthProvider) *AuthProviderRegistry {
+	registry := &AuthProviderRegistry{providers: make(map[string]AuthProvider, len(providers))}
+	for _, provider := range providers {
+		registry.providers[provider.Name()] = provider
+	}
+	return registry
+}
+
+// Get はプロバイダ名に対応するログインプロバイダを返します。
+// 登録されていない場合は ErrUnknownAuthProvider を返します。
+func (r *AuthProviderRegistry) Get(name string) (AuthProvider, error) {
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAuthProvider, name)
+	}
+	return provider, nil
+}
+
+// Names は登録されているプロバイダ名を名前順で返します。
+func (r *AuthProviderRegistry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
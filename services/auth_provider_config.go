@@ -0,0 +1,229 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// ログインプロバイダの種類
+const (
+	AuthProviderTypeOIDC   = "oidc"
+	AuthProviderTypeGitHub = "github"
+)
+
+// defaultAuthProviders はAUTH_PROVIDERSが未設定の場合に有効にするプロバイダ
+const defaultAuthProviders = "google,line"
+
+// AuthProviderConfig はログインプロバイダの設定
+// 組み込みのプロバイダ (google, line, microsoft, github) はエンドポイントが設定済みのため、
+// クライアントIDなどの値のみを環境変数または設定ファイルで指定します。
+type AuthProviderConfig struct {
+	Name         string   `json:"name"`
+	Type         string   `json:"type"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	RedirectURL  string   `json:"redirect_url"`
+	AuthURL      string   `json:"auth_url"`
+	TokenURL     string   `json:"token_url"`
+	Scopes       []string `json:"scopes"`
+	Issuers      []string `json:"issuers"`
+	JWKSURL      string   `json:"jwks_url"`
+	UserInfoURL  string   `json:"user_info_url"`
+	// IDTokenHS256 が true の場合、HS256 で署名されたid_tokenをクライアントシークレットで検証します (LINEログイン)。
+	IDTokenHS256 bool `json:"id_token_hs256"`
+}
+
+// builtinAuthProviderConfig は組み込みのプロバイダの設定を返します。
+func builtinAuthProviderConfig(name string) (AuthProviderConfig, bool) {
+	switch name {
+	case "google":
+		return AuthProviderConfig{
+			Name:     name,
+			Type:     AuthProviderTypeOIDC,
+			AuthURL:  google.Endpoint.AuthURL,
+			TokenURL: google.Endpoint.TokenURL,
+			Scopes:   []string{"openid", "https://www.googleapis.com/auth/userinfo.profile"},
+			Issuers:  []string{"https://accounts.google.com", "accounts.google.com"},
+			JWKSURL:  "https://www.googleapis.com/oauth2/v3/certs",
+		}, true
+	case "line":
+		return AuthProviderConfig{
+			Name:     name,
+			Type:     AuthProviderTypeOIDC,
+			AuthURL:  "https://access.line.me/oauth2/v2.1/authorize",
+			TokenURL: "https://api.line.me/oauth2/v2.1/token",
+			Scopes:   []string{"profile", "openid"},
+			Issuers:  []string{"https://access.line.me"},
+			JWKSURL:  "https://api.line.me/oauth2/v2.1/certs",
+			// ウェブアプリのid_tokenはチャネルシークレットを使用した HS256 で署名される
+			IDTokenHS256: true,
+		}, true
+	case "microsoft":
+		// Microsoft Entra ID はテナントごとに発行者が異なるため、MICROSOFT_TENANT_ID が必要
+		// 未設定の場合はエンドポイントが空になり、NewAuthProvider でエラーになる
+		tenant := os.Getenv("MICROSOFT_TENANT_ID")
+		if tenant == "" {
+			return AuthProviderConfig{Name: name, Type: AuthProviderTypeOIDC}, true
+		}
+		base := "https://login.microsoftonline.com/" + tenant
+		return AuthProviderConfig{
+			Name:     name,
+			Type:     AuthProviderTypeOIDC,
+			AuthURL:  base + "/oauth2/v2.0/authorize",
+			TokenURL: base + "/oauth2/v2.0/token",
+			Scopes:   []string{"openid", "profile"},
+			Issuers:  []string{base + "/v2.0"},
+			JWKSURL:  base + "/discovery/v2.0/keys",
+		}, true
+	case "github":
+		return AuthProviderConfig{
+			Name:        name,
+			Type:        AuthProviderTypeGitHub,
+			AuthURL:     "https://github.com/login/oauth/authorize",
+			TokenURL:    "https://github.com/login/oauth/access_token",
+			Scopes:      []string{"read:user"},
+			UserInfoURL: "https://api.github.com/user",
+		}, true
+	default:
+		return AuthProviderConfig{}, false
+	}
+}
+
+// LoadAuthProviderConfigs はログインプロバイダの設定を読み込みます。
+// AUTH_PROVIDERS_FILE が設定されている場合はJSON形式の設定ファイル (設定の配列) を読み込み、
+// 値の中の ${VAR} は環境変数で置き換えます。
+// 設定されていない場合は AUTH_PROVIDERS (カンマ区切り、既定値は google,line) の組み込みプロバイダを使用します。
+// どちらの場合も <NAME>_CLIENT_ID, <NAME>_CLIENT_SECRET, <NAME>_REDIRECT_URL の環境変数が既定値になります。
+func LoadAuthProviderConfigs() ([]AuthProviderConfig, error) {
+	if path := os.Getenv("AUTH_PROVIDERS_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var fileConfigs []AuthProviderConfig
+		if err := json.Unmarshal([]byte(os.ExpandEnv(string(data))), &fileConfigs); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+
+		configs := make([]AuthProviderConfig, 0, len(fileConfigs))
+		for _, fileConfig := range fileConfigs {
+			config, _ := builtinAuthProviderConfig(fileConfig.Name)
+			config = mergeAuthProviderConfig(withEnvAuthProviderConfig(config, fileConfig.Name), fileConfig)
+			configs = append(configs, config)
+		}
+		return configs, nil
+	}
+
+	names := os.Getenv("AUTH_PROVIDERS")
+	if names == "" {
+		names = defaultAuthProviders
+	}
+	var configs []AuthProviderConfig
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		config, ok := builtinAuthProviderConfig(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown auth provider %s: use AUTH_PROVIDERS_FILE to configure custom providers", name)
+		}
+		configs = append(configs, withEnvAuthProviderConfig(config, name))
+	}
+	return configs, nil
+}
+
+// withEnvAuthProviderConfig は <NAME>_CLIENT_ID などの環境変数の値を設定します。
+func withEnvAuthProviderConfig(config AuthProviderConfig, name string) AuthProviderConfig {
+	prefix := strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+	config.Name = name
+	config.ClientID = os.Getenv(prefix + "CLIENT_ID")
+	config.ClientSecret = os.Getenv(prefix + "CLIENT_SECRET")
+	config.RedirectURL = os.Getenv(prefix + "REDIRECT_URL")
+	return config
+}
+
+// mergeAuthProviderConfig は override で空でない値を base に上書きします。
+func mergeAuthProviderConfig(base AuthProviderConfig, override AuthProviderConfig) AuthProviderConfig {
+	pick := func(base, override string) string {
+		if override != "" {
+			return override
+		}
+		return base
+	}
+	base.Type = pick(base.Type, override.Type)
+	base.ClientID = pick(base.ClientID, override.ClientID)
+	base.ClientSecret = pick(base.ClientSecret, override.ClientSecret)
+	base.RedirectURL = pick(base.RedirectURL, override.RedirectURL)
+	base.AuthURL = pick(base.AuthURL, override.AuthURL)
+	base.TokenURL = pick(base.TokenURL, override.TokenURL)
+	base.JWKSURL = pick(base.JWKSURL, override.JWKSURL)
+	base.UserInfoURL = pick(base.UserInfoURL, override.UserInfoURL)
+	if len(override.Scopes) > 0 {
+		base.Scopes = override.Scopes
+	}
+	if len(override.Issuers) > 0 {
+		base.Issuers = override.Issuers
+	}
+	base.IDTokenHS256 = base.IDTokenHS256 || override.IDTokenHS256
+	return base
+}
+
+// NewAuthProvider は設定からログインプロバイダを生成します。
+func NewAuthProvider(config AuthProviderConfig, stateStore OAuthStateStore) (AuthProvider, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("auth provider name is not set")
+	}
+	if config.AuthURL == "" || config.TokenURL == "" {
+		return nil, fmt.Errorf("auth provider %s: auth_url and token_url are required", config.Name)
+	}
+
+	oauthConfig := &oauth2.Config{
+		RedirectURL:  config.RedirectURL,
+		ClientID:     config.ClientID,
+		ClientSecret: config.ClientSecret,
+		Scopes:       config.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  config.AuthURL,
+			TokenURL: config.TokenURL,
+		},
+	}
+
+	switch config.Type {
+	case AuthProviderTypeOIDC:
+		if len(config.Issuers) == 0 || config.JWKSURL == "" {
+			return nil, fmt.Errorf("auth provider %s: issuers and jwks_url are required", config.Name)
+		}
+		verifier := NewIDTokenVerifier(config.Issuers, config.ClientID, config.JWKSURL)
+		if config.IDTokenHS256 && config.ClientSecret != "" {
+			verifier = NewHS256IDTokenVerifier(config.Issuers, config.ClientID, config.JWKSURL, config.ClientSecret)
+		}
+		return NewOIDCProvider(config.Name, oauthConfig, stateStore, verifier), nil
+	case AuthProviderTypeGitHub:
+		if config.UserInfoURL == "" {
+			return nil, fmt.Errorf("auth provider %s: user_info_url is required", config.Name)
+		}
+		return NewGitHubProvider(config.Name, oauthConfig, stateStore, config.UserInfoURL), nil
+	default:
+		return nil, fmt.Errorf("auth provider %s: unsupported type %q", config.Name, config.Type)
+	}
+}
+
+// NewAuthProviderRegistryFromConfigs は設定からレジストリを生成します。
+// stateはプロバイダごとに分けて保存するため、newStateStore にはプロバイダ名が渡されます。
+func NewAuthProviderRegistryFromConfigs(configs []AuthProviderConfig, newStateStore func(provider string) OAuthStateStore) (*AuthProviderRegistry, error) {
+	providers := make([]AuthProvider, 0, len(configs))
+	for _, config := range configs {
+		provider, err := NewAuthProvider(config, newStateStore(config.Name))
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+	return NewAuthProviderRegistry(providers...), nil
+}
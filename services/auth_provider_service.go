@@ -0,0 +1,152 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"gorm.io/gorm"
+)
+
+// AuthProviderService はログインプロバイダによるログイン処理を行うサービス
+type AuthProviderService interface {
+	AuthCodeURL(provider string) (string, string, error)
+	LinkURL(userID uint, provider string) (string, error)
+	Login(provider string, code string, state string) (models.User, error)
+	HasProvider(provider string) bool
+	ListIdentities(userID uint) ([]dto.UserIdentityDTO, error)
+	LinkIdentity(userID uint, provider string, code string, state string) (dto.UserIdentityDTO, error)
+	UnlinkIdentity(userID uint, identityID uint) error
+}
+
+type authProviderServiceImpl struct {
+	registry *AuthProviderRegistry
+	repo     repositories.AuthUserRepository
+}
+
+// NewAuthProviderService はAuthProviderServiceを生成します。
+func NewAuthProviderService(registry *AuthProviderRegistry, repo repositories.AuthUserRepository) AuthProviderService {
+	return &authProviderServiceImpl{
+		registry: registry,
+		repo:     repo,
+	}
+}
+
+// AuthCodeURL はプロバイダのログインの認可URLを生成し、URLとstateを返します。
+// stateはログインを開始したブラウザに保存し、認可コードの処理時に照合してください。
+func (s *authProviderServiceImpl) AuthCodeURL(provider string) (string, string, error) {
+	p, err := s.registry.Get(provider)
+	if err != nil {
+		return "", "", err
+	}
+	return p.AuthCodeURL(LoginFlow())
+}
+
+// LinkURL はプロバイダのアカウントを userID に紐づけるための認可URLを生成します。
+// URLのstateは LinkIdentity で同じユーザーが使用した場合のみ受け付けます。
+func (s *authProviderServiceImpl) LinkURL(userID uint, provider string) (string, error) {
+	p, err := s.registry.Get(provider)
+	if err != nil {
+		return "", err
+	}
+	url, _, err := p.AuthCodeURL(LinkFlow(userID))
+	return url, err
+}
+
+// Login は認可コードを検証し、プロバイダのユーザー情報に対応するユーザーを返します。
+// ユーザーが存在しない場合は作成します。
+func (s *authProviderServiceImpl) Login(provider string, code string, state string) (models.User, error) {
+	p, err := s.registry.Get(provider)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	userInfo, err := p.Authenticate(code, state, LoginFlow())
+	if err != nil {
+		return models.User{}, err
+	}
+	return s.repo.UpdateOrCreateUser(userInfo)
+}
+
+// HasProvider はプロバイダが登録されているかを返します。
+func (s *authProviderServiceImpl) HasProvider(provider string) bool {
+	_, err := s.registry.Get(provider)
+	return err == nil
+}
+
+// ListIdentities はユーザーに紐づくログインプロバイダのアカウントを返します。
+func (s *authProviderServiceImpl) ListIdentities(userID uint) ([]dto.UserIdentityDTO, error) {
+	identities, err := s.repo.FindIdentities(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]dto.UserIdentityDTO, 0, len(identities))
+	for _, identity := range identities {
+		result = append(result, toUserIdentityDTO(identity))
+	}
+	return result, nil
+}
+
+// LinkIdentity は認可コードを検証し、プロバイダのアカウントをユーザーに紐づけます。
+// stateは LinkURL で同じユーザーに発行されたものでなければならず、ログインや他のユーザーのstateの場合は ErrInvalidOAuthState を返します。
+// 既に他のユーザーに紐づいているアカウントの場合は ErrIdentityAlreadyLinked を返します。
+func (s *authProviderServiceImpl) LinkIdentity(userID uint, provider string, code string, state string) (dto.UserIdentityDTO, error) {
+	p, err := s.registry.Get(provider)
+	if err != nil {
+		return dto.UserIdentityDTO{}, err
+	}
+
+	userInfo, err := p.Authenticate(code, state, LinkFlow(userID))
+	if err != nil {
+		return dto.UserIdentityDTO{}, err
+	}
+
+	existing, err := s.repo.FindIdentity(userInfo.Provider, userInfo.Subject)
+	if err == nil {
+		if existing.UID != userID {
+			return dto.UserIdentityDTO{}, ErrIdentityAlreadyLinked
+		}
+		return toUserIdentityDTO(*existing), nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return dto.UserIdentityDTO{}, err
+	}
+
+	identity := models.UserIdentity{UID: userID, Provider: userInfo.Provider, Subject: userInfo.Subject}
+	if err := s.repo.CreateIdentity(&identity); err != nil {
+		return dto.UserIdentityDTO{}, err
+	}
+	return toUserIdentityDTO(identity), nil
+}
+
+// UnlinkIdentity はユーザーに紐づくアカウントの紐づけを解除します。
+// ログインできなくなるため、最後の一つのアカウントは解除できません。
+func (s *authProviderServiceImpl) UnlinkIdentity(userID uint, identityID uint) error {
+	count, err := s.repo.CountIdentities(userID)
+	if err != nil {
+		return err
+	}
+	if count <= 1 {
+		return ErrLastIdentity
+	}
+
+	deleted, err := s.repo.DeleteIdentity(userID, identityID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func toUserIdentityDTO(identity models.UserIdentity) dto.UserIdentityDTO {
+	return dto.UserIdentityDTO{
+		ID:        identity.ID,
+		Provider:  identity.Provider,
+		Subject:   identity.Subject,
+		CreatedAt: identity.CreatedAt,
+	}
+}
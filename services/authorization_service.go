@@ -0,0 +1,82 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/authorization"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"gorm.io/gorm"
+)
+
+// resourceTables はリソースが保存されているテーブル
+var resourceTables = map[authorization.Resource]string{
+	authorization.ResourceClassBoard:    "class_boards",
+	authorization.ResourceClassSchedule: "class_schedules",
+	authorization.ResourceAttendance:    "attendances",
+}
+
+// AuthorizationService はクラス内の権限を確認するサービス
+type AuthorizationService interface {
+	// Role はユーザーのクラス内のロールを返します。所属していない場合は空文字を返します。
+	Role(uid uint, cid uint) (models.Role, error)
+	// Authorize はユーザーがクラス内で権限を持っているかを確認し、持っていない場合は ErrForbidden を返します。
+	Authorize(uid uint, cid uint, permission authorization.Permission) error
+	// ClassIDOf はリソースが属するクラスのIDを返します。存在しない場合は ErrNotFound を返します。
+	ClassIDOf(resource authorization.Resource, id uint) (uint, error)
+}
+
+type authorizationServiceImpl struct {
+	classUserRepo repositories.ClassUserRepository
+	resourceRepo  repositories.ClassResourceRepository
+}
+
+// NewAuthorizationService はAuthorizationServiceを生成します。
+func NewAuthorizationService(classUserRepo repositories.ClassUserRepository, resourceRepo repositories.ClassResourceRepository) AuthorizationService {
+	return &authorizationServiceImpl{
+		classUserRepo: classUserRepo,
+		resourceRepo:  resourceRepo,
+	}
+}
+
+func (s *authorizationServiceImpl) Role(uid uint, cid uint) (models.Role, error) {
+	return classRole(s.classUserRepo, uid, cid)
+}
+
+func (s *authorizationServiceImpl) Authorize(uid uint, cid uint, permission authorization.Permission) error {
+	return authorize(s.classUserRepo, uid, cid, permission)
+}
+
+func (s *authorizationServiceImpl) ClassIDOf(resource authorization.Resource, id uint) (uint, error) {
+	table, ok := resourceTables[resource]
+	if !ok {
+		return 0, fmt.Errorf("unknown resource %q", resource)
+	}
+	cid, err := s.resourceRepo.FindClassID(table, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrNotFound
+	}
+	return cid, err
+}
+
+// classRole はユーザーのクラス内のロールを返します。所属していない場合は空文字を返します。
+func classRole(classUserRepo repositories.ClassUserRepository, uid uint, cid uint) (models.Role, error) {
+	role, err := classUserRepo.GetRole(uid, cid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return role, err
+}
+
+// authorize はユーザーがクラス内で権限を持っているかを確認し、持っていない場合は ErrForbidden を返します。
+func authorize(classUserRepo repositories.ClassUserRepository, uid uint, cid uint, permission authorization.Permission) error {
+	role, err := classRole(classUserRepo, uid, cid)
+	if err != nil {
+		return err
+	}
+	if !authorization.Can(role, permission) {
+		return fmt.Errorf("role %q does not have %s: %w", role, permission, ErrForbidden)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/authorization"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"gorm.io/gorm"
+)
+
+// ClassApplicationService はクラスへの参加申請と、その承認、却下のサービス
+type ClassApplicationService interface {
+	// Apply は uid をクラスの申請者にします。既に申請中の場合は何もしません。
+	// 利用停止中の場合は ErrForbidden、既に所属している場合は ErrAlreadyMember を返します。
+	Apply(uid uint, cid uint) error
+	// ListPending は actorID が members.approve の権限を持っていることを確認し、申請中のユーザーを返します。
+	ListPending(actorID uint, cid uint) ([]dto.ApplicantDTO, error)
+	// ListWaitlist は actorID が members.approve の権限を持っていることを確認し、キャンセル待ちのユーザーを承認される順に返します。
+	ListWaitlist(actorID uint, cid uint) ([]dto.ApplicantDTO, error)
+	// Decide は actorID が members.approve の権限を持っていることを確認し、申請をまとめて承認または却下します。
+	// 定員に達したため承認できなかったユーザーはキャンセル待ちになり、そのユーザーIDを返します。
+	// 申請中ではないユーザーが含まれる場合は ErrNotFound を返し、何も変更しません。
+	Decide(actorID uint, cid uint, request dto.DecideApplicationsRequest, approve bool) ([]uint, error)
+}
+
+type classApplicationServiceImpl struct {
+	repo          repositories.ClassApplicationRepository
+	classUserRepo repositories.ClassUserRepository
+}
+
+// NewClassApplicationService はClassApplicationServiceを生成します。
+func NewClassApplicationService(repo repositories.ClassApplicationRepository, classUserRepo repositories.ClassUserRepository) ClassApplicationService {
+	return &classApplicationServiceImpl{
+		repo:          repo,
+		classUserRepo: classUserRepo,
+	}
+}
+
+func (s *classApplicationServiceImpl) Apply(uid uint, cid uint) error {
+	current, err := classRole(s.classUserRepo, uid, cid)
+	if err != nil {
+		return err
+	}
+	switch current {
+	case "":
+		return s.classUserRepo.CreateUserRole(uid, uid, cid, models.RoleApplicant)
+	case models.RoleApplicant:
+		return nil
+	case models.RoleBlacklist:
+		return ErrForbidden
+	default:
+		return ErrAlreadyMember
+	}
+}
+
+func (s *classApplicationServiceImpl) ListPending(actorID uint, cid uint) ([]dto.ApplicantDTO, error) {
+	if err := authorize(s.classUserRepo, actorID, cid, authorization.MembersApprove); err != nil {
+		return nil, err
+	}
+
+	applications, err := s.repo.FindPending(cid)
+	if err != nil {
+		return nil, err
+	}
+	return toApplicantDTOs(applications), nil
+}
+
+func (s *classApplicationServiceImpl) ListWaitlist(actorID uint, cid uint) ([]dto.ApplicantDTO, error) {
+	if err := authorize(s.classUserRepo, actorID, cid, authorization.MembersApprove); err != nil {
+		return nil, err
+	}
+
+	applications, err := s.repo.FindWaitlisted(cid)
+	if err != nil {
+		return nil, err
+	}
+	return toApplicantDTOs(applications), nil
+}
+
+func toApplicantDTOs(applications []models.ClassApplication) []dto.ApplicantDTO {
+	applicants := make([]dto.ApplicantDTO, 0, len(applications))
+	for _, application := range applications {
+		applicants = append(applicants, dto.ApplicantDTO{
+			UID:       application.UID,
+			Name:      application.User.Name,
+			Image:     application.User.Image,
+			AppliedAt: application.CreatedAt,
+		})
+	}
+	return applicants
+}
+
+func (s *classApplicationServiceImpl) Decide(actorID uint, cid uint, request dto.DecideApplicationsRequest, approve bool) ([]uint, error) {
+	if err := authorize(s.classUserRepo, actorID, cid, authorization.MembersApprove); err != nil {
+		return nil, err
+	}
+
+	uids := make([]uint, 0, len(request.UIDs))
+	seen := make(map[uint]bool, len(request.UIDs))
+	for _, uid := range request.UIDs {
+		if !seen[uid] {
+			seen[uid] = true
+			uids = append(uids, uid)
+		}
+	}
+
+	waitlisted, err := s.repo.Decide(actorID, cid, uids, approve, request.Reason)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return waitlisted, err
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/authorization"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/pagination"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+)
+
+// defaultAuditPageSize は件数が指定されなかった場合の1ページの件数
+const defaultAuditPageSize = 20
+
+// ClassAuditService はクラスの操作履歴を参照するサービス
+type ClassAuditService interface {
+	// List は actorID がクラス内で audit.read の権限を持っていることを確認し、条件に一致する履歴を新しい順に返します。
+	// カーソルが不正な場合は ErrInvalidCursor を返します。
+	List(actorID uint, cid uint, query dto.ClassAuditQuery) (pagination.Page[dto.ClassAuditEventDTO], error)
+}
+
+type classAuditServiceImpl struct {
+	repo          repositories.ClassAuditRepository
+	classUserRepo repositories.ClassUserRepository
+}
+
+// NewClassAuditService はClassAuditServiceを生成します。
+func NewClassAuditService(repo repositories.ClassAuditRepository, classUserRepo repositories.ClassUserRepository) ClassAuditService {
+	return &classAuditServiceImpl{
+		repo:          repo,
+		classUserRepo: classUserRepo,
+	}
+}
+
+func (s *classAuditServiceImpl) List(actorID uint, cid uint, query dto.ClassAuditQuery) (pagination.Page[dto.ClassAuditEventDTO], error) {
+	if err := authorize(s.classUserRepo, actorID, cid, authorization.AuditRead); err != nil {
+		return pagination.Page[dto.ClassAuditEventDTO]{}, err
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultAuditPageSize
+	}
+	scope := fmt.Sprintf("class_audit:%d", cid)
+	window, err := pagination.NewWindow(scope, pagination.Query{Cursor: query.Cursor, Limit: limit})
+	if err != nil {
+		return pagination.Page[dto.ClassAuditEventDTO]{}, err
+	}
+
+	events, err := s.repo.FindByClass(cid, query, window)
+	if err != nil {
+		return pagination.Page[dto.ClassAuditEventDTO]{}, err
+	}
+	items := make([]dto.ClassAuditEventDTO, 0, len(events))
+	for _, event := range events {
+		items = append(items, toClassAuditEventDTO(event))
+	}
+	return pagination.NewPage(scope, window, items, func(event dto.ClassAuditEventDTO) uint { return event.ID }), nil
+}
+
+func toClassAuditEventDTO(event models.ClassAuditEvent) dto.ClassAuditEventDTO {
+	return dto.ClassAuditEventDTO{
+		ID:        event.ID,
+		CID:       event.CID,
+		ActorID:   event.ActorID,
+		TargetID:  event.TargetID,
+		Action:    string(event.Action),
+		OldValue:  event.OldValue,
+		NewValue:  event.NewValue,
+		CreatedAt: event.CreatedAt,
+	}
+}
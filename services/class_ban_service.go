@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/authorization"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"gorm.io/gorm"
+)
+
+// ClassBanService はクラスでの利用停止のサービス
+// 利用停止中のユーザーはクラス内の権限を持たず、クラスコードや招待でも参加できません。
+type ClassBanService interface {
+	// Ban は actorID が members.manage の権限を持っていることを確認し、uid を利用停止にします。
+	// クラスに所属していないユーザーも利用停止にでき、既に利用停止中の場合は理由と期限を更新します。
+	// クラスのオーナーは利用停止にできず ErrBanOwner を、最後の管理者の場合は ErrLastAdmin を返します。
+	Ban(actorID uint, cid uint, request dto.BanUserRequest) (dto.ClassBanDTO, error)
+	// List は actorID が members.manage の権限を持っていることを確認し、利用停止中のユーザーを返します。
+	List(actorID uint, cid uint) ([]dto.ClassBanDTO, error)
+	// Lift は actorID が members.manage の権限を持っていることを確認し、uid の利用停止を解除します。
+	// 解除されたユーザーはクラスから削除され、再度参加できるようになります。
+	Lift(actorID uint, uid uint, cid uint) error
+	// LiftExpired は期限が切れた利用停止を解除し、解除した件数を返します。
+	LiftExpired() (int, error)
+}
+
+type classBanServiceImpl struct {
+	repo          repositories.ClassBanRepository
+	classUserRepo repositories.ClassUserRepository
+}
+
+// NewClassBanService はClassBanServiceを生成します。
+func NewClassBanService(repo repositories.ClassBanRepository, classUserRepo repositories.ClassUserRepository) ClassBanService {
+	return &classBanServiceImpl{
+		repo:          repo,
+		classUserRepo: classUserRepo,
+	}
+}
+
+func (s *classBanServiceImpl) Ban(actorID uint, cid uint, request dto.BanUserRequest) (dto.ClassBanDTO, error) {
+	if err := authorize(s.classUserRepo, actorID, cid, authorization.MembersManage); err != nil {
+		return dto.ClassBanDTO{}, err
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		return dto.ClassBanDTO{}, ErrInvalidExpiry
+	}
+
+	ban := models.ClassBan{
+		CID:       cid,
+		UID:       request.UID,
+		BannedBy:  actorID,
+		Reason:    request.Reason,
+		ExpiresAt: request.ExpiresAt,
+	}
+	err := s.repo.Ban(&ban)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return dto.ClassBanDTO{}, ErrNotFound
+	}
+	if err != nil {
+		return dto.ClassBanDTO{}, err
+	}
+	return toClassBanDTO(ban), nil
+}
+
+func (s *classBanServiceImpl) List(actorID uint, cid uint) ([]dto.ClassBanDTO, error) {
+	if err := authorize(s.classUserRepo, actorID, cid, authorization.MembersManage); err != nil {
+		return nil, err
+	}
+
+	bans, err := s.repo.FindActive(cid)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.ClassBanDTO, 0, len(bans))
+	for _, ban := range bans {
+		result = append(result, toClassBanDTO(ban))
+	}
+	return result, nil
+}
+
+func (s *classBanServiceImpl) Lift(actorID uint, uid uint, cid uint) error {
+	if err := authorize(s.classUserRepo, actorID, cid, authorization.MembersManage); err != nil {
+		return err
+	}
+	err := s.repo.Lift(actorID, uid, cid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *classBanServiceImpl) LiftExpired() (int, error) {
+	return s.repo.LiftExpired(time.Now())
+}
+
+func toClassBanDTO(ban models.ClassBan) dto.ClassBanDTO {
+	return dto.ClassBanDTO{
+		UID:       ban.UID,
+		Name:      ban.User.Name,
+		Image:     ban.User.Image,
+		BannedBy:  ban.BannedBy,
+		Reason:    ban.Reason,
+		ExpiresAt: ban.ExpiresAt,
+		BannedAt:  ban.CreatedAt,
+	}
+}
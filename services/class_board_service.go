@@ -1,11 +1,13 @@
 package services
 
 import (
+	"fmt"
 	"net/http"
 	"sync"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/pagination"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
 )
@@ -13,7 +15,7 @@ import (
 // ClassBoardService インタフェース
 type ClassBoardService interface {
 	CreateClassBoard(b dto.ClassBoardCreateDTO) (*models.ClassBoard, error)
-	GetAllClassBoards(cid uint, page int, pageSize int) ([]models.ClassBoard, error)
+	GetAllClassBoards(cid uint, query pagination.Query) (pagination.Page[models.ClassBoard], error)
 	GetClassBoardByID(id uint) (*models.ClassBoard, error)
 	GetAnnouncedClassBoards(cid uint) ([]models.ClassBoard, error)
 	UpdateClassBoard(id uint, b dto.ClassBoardUpdateDTO, imageUrl string) (*models.ClassBoard, error) // Added imageUrl parameter
@@ -61,10 +63,18 @@ func (s *classBoardService) CreateClassBoard(b dto.ClassBoardCreateDTO) (*models
 	return s.repo.InsertClassBoard(&classBoard)
 }
 
-// GetAllClassBoards 全てのグループ掲示板を取得
-func (s *classBoardService) GetAllClassBoards(cid uint, page int, pageSize int) ([]models.ClassBoard, error) {
-	offset := (page - 1) * pageSize
-	return s.repo.FindAllPaged(cid, pageSize, offset)
+// GetAllClassBoards グループ掲示板を新しい順に1ページずつ取得
+func (s *classBoardService) GetAllClassBoards(cid uint, query pagination.Query) (pagination.Page[models.ClassBoard], error) {
+	scope := fmt.Sprintf("class_boards:%d", cid)
+	window, err := pagination.NewWindow(scope, query)
+	if err != nil {
+		return pagination.Page[models.ClassBoard]{}, err
+	}
+	boards, err := s.repo.FindAllPaged(cid, window)
+	if err != nil {
+		return pagination.Page[models.ClassBoard]{}, err
+	}
+	return pagination.NewPage(scope, window, boards, func(board models.ClassBoard) uint { return board.ID }), nil
 }
 
 // GetClassBoardByID IDでグループ掲示板を取得
@@ -109,20 +119,32 @@ func (s *classBoardService) DeleteClassBoard(id uint) error {
 	return s.repo.DeleteClassBoard(id)
 }
 
+// BoardSubscriber はクラス掲示板の更新を購読するクライアント
+type BoardSubscriber struct {
+	CID    uint
+	Writer http.ResponseWriter
+}
+
+// BoardUpdate はクラス掲示板の更新の通知。CID のクラスを購読しているクライアントにだけ送信します。
+type BoardUpdate struct {
+	CID     uint
+	Message []byte
+}
+
 type UpdateNotifier struct {
-	Register   chan http.ResponseWriter
+	Register   chan BoardSubscriber
 	Unregister chan http.ResponseWriter
-	Broadcast  chan []byte
-	clients    map[http.ResponseWriter]struct{}
+	Broadcast  chan BoardUpdate
+	clients    map[http.ResponseWriter]uint
 	mu         sync.Mutex
 }
 
 func NewUpdateNotifier() *UpdateNotifier {
 	notifier := &UpdateNotifier{
-		Register:   make(chan http.ResponseWriter),
+		Register:   make(chan BoardSubscriber),
 		Unregister: make(chan http.ResponseWriter),
-		Broadcast:  make(chan []byte),
-		clients:    make(map[http.ResponseWriter]struct{}),
+		Broadcast:  make(chan BoardUpdate),
+		clients:    make(map[http.ResponseWriter]uint),
 	}
 	go notifier.run()
 	return notifier
@@ -133,16 +155,19 @@ func (u *UpdateNotifier) run() {
 		select {
 		case s := <-u.Register:
 			u.mu.Lock()
-			u.clients[s] = struct{}{}
+			u.clients[s.Writer] = s.CID
 			u.mu.Unlock()
 		case s := <-u.Unregister:
 			u.mu.Lock()
 			delete(u.clients, s)
 			u.mu.Unlock()
-		case msg := <-u.Broadcast:
+		case update := <-u.Broadcast:
 			u.mu.Lock()
-			for s := range u.clients {
-				_, _ = s.Write(msg)
+			for s, cid := range u.clients {
+				if cid != update.CID {
+					continue
+				}
+				_, _ = s.Write(update.Message)
 				if f, ok := s.(http.Flusher); ok {
 					f.Flush()
 				}
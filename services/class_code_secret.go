@@ -0,0 +1,77 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxClassCodeSecretLength はクラスコードのシークレットの最大文字数
+const maxClassCodeSecretLength = 20
+
+// hashClassCodeSecret はシークレットを bcrypt でハッシュにします。空の場合はシークレットなしとして nil を返します。
+func hashClassCodeSecret(secret *string) (*string, error) {
+	if secret == nil || *secret == "" {
+		return nil, nil
+	}
+	if len([]rune(*secret)) > maxClassCodeSecretLength {
+		return nil, ErrInvalidSecret
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(*secret), bcrypt.DefaultCost)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return nil, ErrInvalidSecret
+	}
+	if err != nil {
+		return nil, err
+	}
+	hashed := string(hash)
+	return &hashed, nil
+}
+
+// isHashedSecret は保存されている値が bcrypt のハッシュかを返します。
+func isHashedSecret(stored string) bool {
+	_, err := bcrypt.Cost([]byte(stored))
+	return err == nil
+}
+
+// matchClassCodeSecret はコードのシークレットと secret が一致するかを一定時間で比較します。
+// コードにシークレットがない場合は false を返します。
+func matchClassCodeSecret(classCode *models.ClassCode, secret string) bool {
+	if classCode.Secret == nil || *classCode.Secret == "" {
+		return false
+	}
+	stored := *classCode.Secret
+	if isHashedSecret(stored) {
+		return bcrypt.CompareHashAndPassword([]byte(stored), []byte(secret)) == nil
+	}
+	// hashclasscodesecrets コマンドで移行する前の平文のシークレット
+	return subtle.ConstantTimeCompare([]byte(stored), []byte(secret)) == 1
+}
+
+// HashClassCodeSecrets は平文で保存されているクラスコードのシークレットをハッシュに置き換え、置き換えた件数を返します。
+// 空のシークレットはシークレットなしとして削除します。既にハッシュのシークレットは変更しないため、繰り返し実行できます。
+func HashClassCodeSecrets(repo repositories.ClassCodeRepository) (int, error) {
+	classCodes, err := repo.FindAllWithSecret()
+	if err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for _, classCode := range classCodes {
+		if isHashedSecret(*classCode.Secret) {
+			continue
+		}
+		hash, err := hashClassCodeSecret(classCode.Secret)
+		if err != nil {
+			return migrated, err
+		}
+		if _, err := repo.UpdateSecret(classCode.CID, classCode.ID, hash); err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+	return migrated, nil
+}
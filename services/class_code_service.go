@@ -1,29 +1,59 @@
 package services
 
 import (
+	"crypto/rand"
 	"errors"
+	"math/big"
+	"time"
 
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/authorization"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"gorm.io/gorm"
 )
 
 const ErrClassNotFound = "class not found"
 
+// classCodeRoles はクラスコードで参加できるロール。APPLICANT の場合は参加申請になります。
+var classCodeRoles = []models.Role{models.RoleApplicant, models.RoleUser, models.RoleAssistant}
+
 // ClassCodeService はグループコードのサービスです。
+// コードの一覧、作成、再発行、無効化には members.manage の権限が必要です。
 type ClassCodeService interface {
 	CheckSecretExists(code string) (bool, error)
 	VerifyClassCode(code, secret string) (bool, error)
+	// VerifySecret はコードのシークレットと secret が一致するかを一定時間で比較します。コードにシークレットがない場合は false です。
+	VerifySecret(classCode *models.ClassCode, secret string) bool
+	// FindClassCode は使用できるコードを取得します。無効、期限切れ、使用回数の上限に達したコードは見つからない扱いです。
 	FindClassCode(code string) (*models.ClassCode, error)
+	// Redeem は uid がコードを使用してクラスに参加します。使用回数は参加と同じトランザクションで増やします。
+	// 利用停止中の場合は ErrForbidden、既に所属している場合は ErrAlreadyMember、コードが使用できなくなった場合は ErrNotFound を返します。
+	Redeem(uid uint, classCode *models.ClassCode) error
+	// List はクラスの全てのコードを作成順に返します。
+	List(actorID uint, cid uint) ([]dto.ClassCodeDTO, error)
+	// Create はクラスのコードを作成します。
+	Create(actorID uint, cid uint, request dto.CreateClassCodeRequest) (dto.ClassCodeDTO, error)
+	// Regenerate はコードの値を新しくし、使用回数をリセットします。以前の値は使用できなくなります。
+	Regenerate(actorID uint, cid uint, id uint) (dto.ClassCodeDTO, error)
+	// Revoke はコードを無効にします。
+	Revoke(actorID uint, cid uint, id uint) error
+	// UpdateSecret はコードの値を変えずにシークレットを変更します。secret が nil または空の場合はシークレットを削除します。
+	UpdateSecret(actorID uint, cid uint, id uint, secret *string) (dto.ClassCodeDTO, error)
 }
 
 // classCodeServiceImpl はClassCodeServiceの実装です。
 type classCodeServiceImpl struct {
-	repo repositories.ClassCodeRepository
+	repo          repositories.ClassCodeRepository
+	classUserRepo repositories.ClassUserRepository
 }
 
 // NewClassCodeService はClassCodeServiceを生成します。
-func NewClassCodeService(repo repositories.ClassCodeRepository) ClassCodeService {
-	return &classCodeServiceImpl{repo: repo}
+func NewClassCodeService(repo repositories.ClassCodeRepository, classUserRepo repositories.ClassUserRepository) ClassCodeService {
+	return &classCodeServiceImpl{
+		repo:          repo,
+		classUserRepo: classUserRepo,
+	}
 }
 
 // FindClassCode findClassCode は指定されたグループコードを取得します。
@@ -32,7 +62,7 @@ func (s *classCodeServiceImpl) FindClassCode(code string) (*models.ClassCode, er
 	if err != nil {
 		return nil, err
 	}
-	if classCode == nil {
+	if classCode == nil || !classCode.Usable(time.Now()) {
 		return nil, errors.New(ErrClassNotFound)
 	}
 	return classCode, nil
@@ -61,9 +91,170 @@ func (s *classCodeServiceImpl) VerifyClassCode(code string, secret string) (bool
 		return false, err
 	}
 
-	if classCode.Secret == nil || *classCode.Secret != secret {
-		return false, nil
+	return s.VerifySecret(classCode, secret), nil
+}
+
+func (s *classCodeServiceImpl) VerifySecret(classCode *models.ClassCode, secret string) bool {
+	return matchClassCodeSecret(classCode, secret)
+}
+
+func (s *classCodeServiceImpl) Redeem(uid uint, classCode *models.ClassCode) error {
+	current, err := classRole(s.classUserRepo, uid, classCode.CID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case current == models.RoleBlacklist:
+		return ErrForbidden
+	case current == models.RoleApplicant && classCode.Role == models.RoleApplicant:
+		// 既に申請中の場合は使用回数を増やさない
+		return nil
+	case current != "" && current != models.RoleApplicant:
+		return ErrAlreadyMember
 	}
 
-	return true, nil
+	err = s.repo.Redeem(uid, classCode.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *classCodeServiceImpl) List(actorID uint, cid uint) ([]dto.ClassCodeDTO, error) {
+	if err := authorize(s.classUserRepo, actorID, cid, authorization.MembersManage); err != nil {
+		return nil, err
+	}
+
+	classCodes, err := s.repo.FindAllByClassID(cid)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.ClassCodeDTO, 0, len(classCodes))
+	for _, classCode := range classCodes {
+		result = append(result, toClassCodeDTO(classCode))
+	}
+	return result, nil
+}
+
+func (s *classCodeServiceImpl) Create(actorID uint, cid uint, request dto.CreateClassCodeRequest) (dto.ClassCodeDTO, error) {
+	if err := authorize(s.classUserRepo, actorID, cid, authorization.MembersManage); err != nil {
+		return dto.ClassCodeDTO{}, err
+	}
+	if !containsRole(classCodeRoles, request.Role) {
+		return dto.ClassCodeDTO{}, ErrInvalidRole
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		return dto.ClassCodeDTO{}, ErrInvalidExpiry
+	}
+	secret, err := hashClassCodeSecret(request.Secret)
+	if err != nil {
+		return dto.ClassCodeDTO{}, err
+	}
+
+	code, err := generateClassCode(s.repo)
+	if err != nil {
+		return dto.ClassCodeDTO{}, err
+	}
+	classCode := models.ClassCode{
+		Code:      code,
+		Secret:    secret,
+		Role:      request.Role,
+		ExpiresAt: request.ExpiresAt,
+		MaxUses:   request.MaxUses,
+		Enabled:   true,
+		CID:       cid,
+		UID:       actorID,
+	}
+	if err := s.repo.SaveClassCode(&classCode); err != nil {
+		return dto.ClassCodeDTO{}, err
+	}
+	return toClassCodeDTO(classCode), nil
+}
+
+func (s *classCodeServiceImpl) Regenerate(actorID uint, cid uint, id uint) (dto.ClassCodeDTO, error) {
+	if err := authorize(s.classUserRepo, actorID, cid, authorization.MembersManage); err != nil {
+		return dto.ClassCodeDTO{}, err
+	}
+
+	code, err := generateClassCode(s.repo)
+	if err != nil {
+		return dto.ClassCodeDTO{}, err
+	}
+	classCode, err := s.repo.Regenerate(cid, id, code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return dto.ClassCodeDTO{}, ErrNotFound
+	}
+	if err != nil {
+		return dto.ClassCodeDTO{}, err
+	}
+	return toClassCodeDTO(*classCode), nil
+}
+
+func (s *classCodeServiceImpl) Revoke(actorID uint, cid uint, id uint) error {
+	if err := authorize(s.classUserRepo, actorID, cid, authorization.MembersManage); err != nil {
+		return err
+	}
+	err := s.repo.Revoke(cid, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *classCodeServiceImpl) UpdateSecret(actorID uint, cid uint, id uint, secret *string) (dto.ClassCodeDTO, error) {
+	if err := authorize(s.classUserRepo, actorID, cid, authorization.MembersManage); err != nil {
+		return dto.ClassCodeDTO{}, err
+	}
+
+	hash, err := hashClassCodeSecret(secret)
+	if err != nil {
+		return dto.ClassCodeDTO{}, err
+	}
+	classCode, err := s.repo.UpdateSecret(cid, id, hash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return dto.ClassCodeDTO{}, ErrNotFound
+	}
+	if err != nil {
+		return dto.ClassCodeDTO{}, err
+	}
+	return toClassCodeDTO(*classCode), nil
+}
+
+// generateClassCode は既存のコードと重複しない新しいコードを生成します。
+// コードを知っていれば参加できるため、推測されないように crypto/rand で生成します。
+func generateClassCode(repo repositories.ClassCodeRepository) (string, error) {
+	max := big.NewInt(int64(len(letters)))
+	for {
+		code := make([]byte, 6)
+		for i := range code {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", err
+			}
+			code[i] = letters[n.Int64()]
+		}
+		existingCode, err := repo.FindByCode(string(code))
+		if err != nil {
+			return "", err
+		}
+		if existingCode == nil {
+			return string(code), nil
+		}
+	}
+}
+
+func toClassCodeDTO(classCode models.ClassCode) dto.ClassCodeDTO {
+	return dto.ClassCodeDTO{
+		ID:        classCode.ID,
+		CID:       classCode.CID,
+		Code:      classCode.Code,
+		Role:      classCode.Role,
+		HasSecret: classCode.Secret != nil && *classCode.Secret != "",
+		ExpiresAt: classCode.ExpiresAt,
+		MaxUses:   classCode.MaxUses,
+		Uses:      classCode.Uses,
+		Enabled:   classCode.Enabled,
+		Usable:    classCode.Usable(time.Now()),
+		CreatedAt: classCode.CreatedAt,
+	}
 }
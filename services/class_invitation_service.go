@@ -0,0 +1,245 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/authorization"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"gorm.io/gorm"
+)
+
+// invitationTokenPrefix は発行する招待リンクのトークンの接頭辞
+const invitationTokenPrefix = "inv_"
+
+// ClassInvitationService はクラスへの招待を管理するサービス
+// 招待の作成、一覧、取り消しには members.manage の権限が必要です。
+// 招待は申請の承認を省略しますが、承諾時に定員を確認し、利用停止中のユーザーは招待、承諾できません。
+type ClassInvitationService interface {
+	// Invite は既存のユーザーを招待します。招待されたユーザーは承諾するまで INVITE のロールになります。
+	Invite(actorID uint, cid uint, request dto.InviteUserRequest) (dto.ClassInvitationDTO, error)
+	// CreateLink は1回だけ使用できる招待リンクを発行します。トークンは発行時にのみ返します。
+	CreateLink(actorID uint, cid uint, request dto.CreateInvitationLinkRequest) (dto.CreatedInvitationLinkDTO, error)
+	// List はクラスの未使用で期限内の招待を返します。
+	List(actorID uint, cid uint) ([]dto.ClassInvitationDTO, error)
+	// Revoke は未使用の招待を取り消します。
+	Revoke(actorID uint, cid uint, id uint) error
+	// Accept は uid が自分への招待を承諾します。
+	Accept(uid uint, cid uint) error
+	// Decline は uid が自分への招待を辞退します。
+	Decline(uid uint, cid uint) error
+	// AcceptLink は uid が招待リンクで参加し、参加したクラスのIDを返します。
+	AcceptLink(uid uint, token string) (uint, error)
+}
+
+type classInvitationServiceImpl struct {
+	repo          repositories.ClassInvitationRepository
+	classUserRepo repositories.ClassUserRepository
+}
+
+// NewClassInvitationService はClassInvitationServiceを生成します。
+func NewClassInvitationService(repo repositories.ClassInvitationRepository, classUserRepo repositories.ClassUserRepository) ClassInvitationService {
+	return &classInvitationServiceImpl{
+		repo:          repo,
+		classUserRepo: classUserRepo,
+	}
+}
+
+func (s *classInvitationServiceImpl) Invite(actorID uint, cid uint, request dto.InviteUserRequest) (dto.ClassInvitationDTO, error) {
+	if err := s.validate(actorID, cid, request.Role, request.ExpiresAt); err != nil {
+		return dto.ClassInvitationDTO{}, err
+	}
+	if err := s.ensureInvitable(request.UID, cid); err != nil {
+		return dto.ClassInvitationDTO{}, err
+	}
+
+	invitation := models.ClassInvitation{
+		CID:       cid,
+		InviterID: actorID,
+		InviteeID: &request.UID,
+		Role:      request.Role,
+		Status:    models.InvitationPending,
+		ExpiresAt: request.ExpiresAt,
+	}
+	err := s.repo.InviteUser(&invitation)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return dto.ClassInvitationDTO{}, ErrNotFound
+	}
+	if err != nil {
+		return dto.ClassInvitationDTO{}, err
+	}
+	return toClassInvitationDTO(invitation), nil
+}
+
+func (s *classInvitationServiceImpl) CreateLink(actorID uint, cid uint, request dto.CreateInvitationLinkRequest) (dto.CreatedInvitationLinkDTO, error) {
+	if err := s.validate(actorID, cid, request.Role, &request.ExpiresAt); err != nil {
+		return dto.CreatedInvitationLinkDTO{}, err
+	}
+
+	token, err := generateInvitationToken()
+	if err != nil {
+		return dto.CreatedInvitationLinkDTO{}, err
+	}
+	tokenHash := hashInvitationToken(token)
+	invitation := models.ClassInvitation{
+		CID:       cid,
+		InviterID: actorID,
+		Role:      request.Role,
+		TokenHash: &tokenHash,
+		Status:    models.InvitationPending,
+		ExpiresAt: &request.ExpiresAt,
+	}
+	if err := s.repo.CreateLink(&invitation); err != nil {
+		return dto.CreatedInvitationLinkDTO{}, err
+	}
+	return dto.CreatedInvitationLinkDTO{ClassInvitationDTO: toClassInvitationDTO(invitation), Token: token}, nil
+}
+
+func (s *classInvitationServiceImpl) List(actorID uint, cid uint) ([]dto.ClassInvitationDTO, error) {
+	if err := authorize(s.classUserRepo, actorID, cid, authorization.MembersManage); err != nil {
+		return nil, err
+	}
+
+	invitations, err := s.repo.FindPending(cid)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.ClassInvitationDTO, 0, len(invitations))
+	for _, invitation := range invitations {
+		result = append(result, toClassInvitationDTO(invitation))
+	}
+	return result, nil
+}
+
+func (s *classInvitationServiceImpl) Revoke(actorID uint, cid uint, id uint) error {
+	if err := authorize(s.classUserRepo, actorID, cid, authorization.MembersManage); err != nil {
+		return err
+	}
+	err := s.repo.Revoke(actorID, cid, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *classInvitationServiceImpl) Accept(uid uint, cid uint) error {
+	invitation, err := s.repo.FindPendingForUser(uid, cid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidInvitation
+	}
+	if err != nil {
+		return err
+	}
+	return s.accept(uid, invitation.ID)
+}
+
+func (s *classInvitationServiceImpl) Decline(uid uint, cid uint) error {
+	invitation, err := s.repo.FindPendingForUser(uid, cid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidInvitation
+	}
+	if err != nil {
+		return err
+	}
+	err = s.repo.Decline(uid, invitation.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidInvitation
+	}
+	return err
+}
+
+func (s *classInvitationServiceImpl) AcceptLink(uid uint, token string) (uint, error) {
+	invitation, err := s.repo.FindByTokenHash(hashInvitationToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrInvalidInvitation
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !invitation.Usable(time.Now()) {
+		return 0, ErrInvalidInvitation
+	}
+
+	current, err := classRole(s.classUserRepo, uid, invitation.CID)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case current == models.RoleBlacklist:
+		return 0, ErrForbidden
+	case current.Active():
+		return 0, ErrAlreadyMember
+	}
+	return invitation.CID, s.accept(uid, invitation.ID)
+}
+
+func (s *classInvitationServiceImpl) accept(uid uint, invitationID uint) error {
+	err := s.repo.Accept(uid, invitationID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidInvitation
+	}
+	return err
+}
+
+// validate は actorID が招待を作成できること、招待のロールと期限が有効であることを確認します。
+func (s *classInvitationServiceImpl) validate(actorID uint, cid uint, role models.Role, expiresAt *time.Time) error {
+	if err := authorize(s.classUserRepo, actorID, cid, authorization.MembersManage); err != nil {
+		return err
+	}
+	if role != models.RoleUser && role != models.RoleAssistant {
+		return ErrInvalidRole
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return ErrInvalidExpiry
+	}
+	return nil
+}
+
+// ensureInvitable は uid がクラスに所属しておらず、招待できることを確認します。
+func (s *classInvitationServiceImpl) ensureInvitable(uid uint, cid uint) error {
+	current, err := classRole(s.classUserRepo, uid, cid)
+	if err != nil {
+		return err
+	}
+	switch current {
+	case "":
+		return nil
+	case models.RoleBlacklist:
+		return ErrForbidden
+	default:
+		return ErrAlreadyMember
+	}
+}
+
+// generateInvitationToken はランダムな招待リンクのトークンを生成します。
+func generateInvitationToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return invitationTokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashInvitationToken は招待リンクのトークンのSHA-256ハッシュを返します。
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func toClassInvitationDTO(invitation models.ClassInvitation) dto.ClassInvitationDTO {
+	return dto.ClassInvitationDTO{
+		ID:        invitation.ID,
+		CID:       invitation.CID,
+		InviterID: invitation.InviterID,
+		InviteeID: invitation.InviteeID,
+		Role:      invitation.Role,
+		ExpiresAt: invitation.ExpiresAt,
+		CreatedAt: invitation.CreatedAt,
+	}
+}
@@ -2,14 +2,13 @@ package services
 
 import (
 	"errors"
-	"fmt"
 	"log"
-	"math/rand"
-	"time"
 
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/authorization"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"gorm.io/gorm"
 )
 
 type ClassService interface {
@@ -19,6 +18,7 @@ type ClassService interface {
 	UpdateClassImage(classID uint, imageUrl string) error
 	UpdateClass(classID uint, userID uint, request dto.UpdateClassRequest) error
 	DeleteClass(classID uint, userID uint) error
+	TransferOwnership(classID uint, userID uint, request dto.TransferOwnershipRequest) error
 	GenerateClassCode() (string, error)
 }
 
@@ -45,10 +45,6 @@ func NewCreateClassService(
 
 const letters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
-func init() {
-	rand.Seed(time.Now().UnixNano())
-}
-
 func (s *classServiceImpl) GetClass(classID uint) (*models.Class, error) {
 	return s.classRepo.GetByID(classID)
 }
@@ -102,7 +98,7 @@ func (s *classServiceImpl) CreateClass(request dto.CreateClassRequest) (uint, er
 		UID:        request.UID,
 		Nickname:   user.Name,
 		IsFavorite: false,
-		Role:       "ADMIN",
+		Role:       models.RoleAdmin,
 	}
 	err = s.classUserRepo.Save(&classUser)
 	if err != nil {
@@ -113,11 +109,18 @@ func (s *classServiceImpl) CreateClass(request dto.CreateClassRequest) (uint, er
 	if err != nil {
 		return 0, err
 	}
+	secret, err := hashClassCodeSecret(request.Secret)
+	if err != nil {
+		return 0, err
+	}
+	// 作成時のコードは参加申請のコード
 	classCode := models.ClassCode{
-		Code:   code,
-		CID:    classID,
-		UID:    request.UID,
-		Secret: request.Secret,
+		Code:    code,
+		CID:     classID,
+		UID:     request.UID,
+		Secret:  secret,
+		Role:    models.RoleApplicant,
+		Enabled: true,
 	}
 	if err := s.classCodeRepo.SaveClassCode(&classCode); err != nil {
 		return 0, err
@@ -131,9 +134,8 @@ func (s *classServiceImpl) UpdateClassImage(classID uint, imageUrl string) error
 }
 
 func (s *classServiceImpl) UpdateClass(classID uint, userID uint, request dto.UpdateClassRequest) error {
-	isAdmin, err := s.IsAdmin(userID, classID)
-	if err != nil || !isAdmin {
-		return errors.New("unauthorized: user is not an admin")
+	if err := authorize(s.classUserRepo, userID, classID, authorization.ClassManage); err != nil {
+		return err
 	}
 
 	class, err := s.GetClass(classID)
@@ -154,39 +156,55 @@ func (s *classServiceImpl) UpdateClass(classID uint, userID uint, request dto.Up
 	return s.classRepo.Update(class)
 }
 
-func (s *classServiceImpl) IsAdmin(userID uint, classID uint) (bool, error) {
-	role, err := s.classUserRepo.GetRole(userID, classID)
-	if err != nil {
-		return false, err
+func (s *classServiceImpl) DeleteClass(classID uint, userID uint) error {
+	if err := authorize(s.classUserRepo, userID, classID, authorization.ClassManage); err != nil {
+		return err
 	}
-	return role == "ADMIN", nil
+
+	return s.classRepo.Delete(classID)
 }
 
-func (s *classServiceImpl) DeleteClass(classID uint, userID uint) error {
-	role, err := s.classUserRepo.GetRole(userID, classID)
+// previousOwnerRoles はオーナーを変更する際に元のオーナーに指定できるロール
+var previousOwnerRoles = []models.Role{models.RoleAdmin, models.RoleAssistant, models.RoleUser}
+
+// TransferOwnership はクラスのオーナーである userID が、クラスのオーナーを request.UID に変更します。
+// 新しいオーナーは管理者になります。新しいオーナーは承認済みのメンバーである必要があります。
+// 元のオーナーのロールは管理者、アシスタント、ユーザーのいずれかにのみ変更できます。
+func (s *classServiceImpl) TransferOwnership(classID uint, userID uint, request dto.TransferOwnershipRequest) error {
+	if err := authorize(s.classUserRepo, userID, classID, authorization.ClassManage); err != nil {
+		return err
+	}
+
+	class, err := s.classRepo.GetByID(classID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
 	if err != nil {
 		return err
 	}
+	// 他の管理者がオーナーからクラスを移すことはできない
+	if class.UID != userID {
+		return ErrForbidden
+	}
+	if class.UID == request.UID {
+		return ErrInvalidOwner
+	}
 
-	if role != "ADMIN" {
-		return errors.New(fmt.Sprintf("unauthorized access: role %s", role))
+	var previousOwnerRole models.Role
+	if request.PreviousOwnerRole != nil {
+		if !containsRole(previousOwnerRoles, *request.PreviousOwnerRole) {
+			return ErrInvalidRole
+		}
+		previousOwnerRole = *request.PreviousOwnerRole
 	}
 
-	return s.classRepo.Delete(classID)
+	err = s.classRepo.TransferOwnership(userID, classID, class.UID, request.UID, previousOwnerRole)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
 }
 
 func (s *classServiceImpl) GenerateClassCode() (string, error) {
-	for {
-		code := make([]byte, 6)
-		for i := range code {
-			code[i] = letters[rand.Intn(len(letters))]
-		}
-		existingCode, err := s.classCodeRepo.FindByCode(string(code))
-		if err != nil {
-			return "", err
-		}
-		if existingCode == nil {
-			return string(code), nil
-		}
-	}
+	return generateClassCode(s.classCodeRepo)
 }
@@ -1,28 +1,59 @@
 package services
 
 import (
+	"encoding/csv"
 	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/authorization"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/pagination"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
 	"gorm.io/gorm"
 )
 
 // ClassUserService はグループコードのサービスです。
 type ClassUserService interface {
-	GetClassMembers(cid uint, roleNames ...string) ([]dto.ClassMemberDTO, error)
+	GetClassMembers(cid uint, roles ...models.Role) ([]dto.ClassMemberDTO, error)
+	// ExportMembers は ImportMembers で読み込めるロール (ADMIN, ASSISTANT, USER) のメンバーを返します。
+	// role を指定した場合はそのロールのメンバーだけを返し、読み込めないロールの場合は ErrInvalidRole を返します。
+	ExportMembers(cid uint, role models.Role) ([]dto.ClassMemberDTO, error)
+	// ListClassMembers は条件に一致するクラスのメンバーを1ページずつ返します。
+	// ロールが不正な場合は ErrInvalidRole、カーソルが不正な場合は ErrInvalidCursor を返します。
+	ListClassMembers(cid uint, query dto.ClassMemberQuery) (dto.ClassMemberPageDTO, error)
 	GetClassUserInfo(uid uint, cid uint) (dto.ClassMemberDTO, error)
-	GetUserClasses(uid uint, page int, limit int) ([]dto.UserClassInfoDTO, error)
-	GetRole(uid uint, cid uint) (string, error)
-	GetFavoriteClasses(uid uint, page int, limit int) ([]dto.UserClassInfoDTO, error)
-	GetUserClassesByRole(uid uint, roleName string, page int, limit int) ([]dto.UserClassInfoDTO, error)
-	AssignRole(uid uint, cid uint, roleName string) error
-	UpdateUserName(uid uint, cid uint, newName string) error
+	GetUserClasses(uid uint, query pagination.Query) (pagination.Page[dto.UserClassInfoDTO], error)
+	GetRole(uid uint, cid uint) (models.Role, error)
+	GetFavoriteClasses(uid uint, query pagination.Query) (pagination.Page[dto.UserClassInfoDTO], error)
+	GetUserClassesByRole(uid uint, role models.Role, query pagination.Query) (pagination.Page[dto.UserClassInfoDTO], error)
+	AssignRole(actorID uint, uid uint, cid uint, role models.Role) error
+	ChangeUserRole(actorID uint, uid uint, cid uint, role models.Role) error
+	UpdateUserName(actorID uint, uid uint, cid uint, newName string) error
 	ToggleFavorite(uid uint, cid uint) error
-	RemoveUserFromClass(uid uint, cid uint) error
+	RemoveUserFromClass(actorID uint, uid uint, cid uint) error
 	SearchUserClassesByName(uid uint, name string) ([]dto.UserClassInfoDTO, error)
+	// ImportMembers は actorID が members.manage の権限を持っていることを確認し、CSVのユーザーをまとめてクラスに追加します。
+	// CSVのヘッダーは uid, provider, subject, nickname, role で、ユーザーは uid または provider と subject で指定します。
+	// 全ての行を検証し、エラーのない行のみを追加します。dryRun の場合は検証のみを行います。
+	// ヘッダーが不正な場合は ErrInvalidCSV、行数が多すぎる場合は ErrTooManyImportRows を返します。
+	ImportMembers(actorID uint, cid uint, r io.Reader, dryRun bool) (dto.MemberImportResultDTO, error)
 }
 
+// maxImportRows は一度に追加できるメンバーの行数
+const maxImportRows = 1000
+
+// defaultMemberPageSize は件数が指定されなかった場合のメンバーの一覧の1ページの件数
+const defaultMemberPageSize = 50
+
+// importRoles はCSVで追加できるロール。ロールを省略した場合は USER になります。
+var importRoles = []models.Role{models.RoleAdmin, models.RoleAssistant, models.RoleUser}
+
 // classUserServiceImpl はClassCodeServiceの実装です。
 type classUserServiceImpl struct {
 	roleRepo      repositories.RoleRepository
@@ -40,46 +71,169 @@ func (s *classUserServiceImpl) GetClassUserInfo(uid uint, cid uint) (dto.ClassMe
 	return s.classUserRepo.GetClassUserInfo(uid, cid)
 }
 
-func (s *classUserServiceImpl) GetUserClasses(uid uint, page int, limit int) ([]dto.UserClassInfoDTO, error) {
-	return s.classUserRepo.GetUserClasses(uid, page, limit)
+func (s *classUserServiceImpl) GetUserClasses(uid uint, query pagination.Query) (pagination.Page[dto.UserClassInfoDTO], error) {
+	scope := fmt.Sprintf("user_classes:%d", uid)
+	return pageUserClasses(scope, query, func(window pagination.Window) ([]dto.UserClassInfoDTO, error) {
+		return s.classUserRepo.GetUserClasses(uid, window)
+	})
+}
+
+func (s *classUserServiceImpl) GetClassMembers(cid uint, roles ...models.Role) ([]dto.ClassMemberDTO, error) {
+	for _, role := range roles {
+		if !role.Valid() {
+			return nil, ErrInvalidRole
+		}
+	}
+	return s.classUserRepo.GetClassMembers(cid, roles...)
+}
+
+func (s *classUserServiceImpl) ExportMembers(cid uint, role models.Role) ([]dto.ClassMemberDTO, error) {
+	if role == "" {
+		return s.classUserRepo.GetClassMembers(cid, importRoles...)
+	}
+	if !containsRole(importRoles, role) {
+		return nil, ErrInvalidRole
+	}
+	return s.classUserRepo.GetClassMembers(cid, role)
 }
 
-func (s *classUserServiceImpl) GetClassMembers(cid uint, roleNames ...string) ([]dto.ClassMemberDTO, error) {
-	if len(roleNames) > 0 {
-		return s.classUserRepo.GetClassMembers(cid, roleNames[0])
+func (s *classUserServiceImpl) ListClassMembers(cid uint, query dto.ClassMemberQuery) (dto.ClassMemberPageDTO, error) {
+	for _, role := range query.Roles {
+		if !role.Valid() {
+			return dto.ClassMemberPageDTO{}, ErrInvalidRole
+		}
+	}
+
+	if query.Sort == "" {
+		query.Sort = "nickname"
+	}
+	if query.Order == "" {
+		query.Order = "asc"
+	}
+	// カーソルは同じクラス、同じ並び順の一覧でのみ使用できる
+	scope := fmt.Sprintf("class_members:%d:%s:%s", cid, query.Sort, query.Order)
+
+	var after *dto.ClassMemberCursor
+	if query.Cursor != "" {
+		var cursor dto.ClassMemberCursor
+		if err := pagination.Decode(scope, query.Cursor, &cursor); err != nil || cursor.UID == 0 {
+			return dto.ClassMemberPageDTO{}, ErrInvalidCursor
+		}
+		after = &cursor
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultMemberPageSize
+	}
+
+	// 次のページがあるかを判定するため1件多く取得する
+	members, total, err := s.classUserRepo.FindClassMembers(cid, query, after, limit+1)
+	if err != nil {
+		return dto.ClassMemberPageDTO{}, err
+	}
+
+	page := dto.ClassMemberPageDTO{Page: pagination.Page[dto.ClassMemberDTO]{Items: members}, Total: total}
+	if page.Items == nil {
+		page.Items = []dto.ClassMemberDTO{}
+	}
+	if len(members) > limit {
+		page.Items = members[:limit]
+		last := members[limit-1]
+		next := pagination.Encode(scope, dto.ClassMemberCursor{
+			Nickname: last.Nickname,
+			Role:     last.Role,
+			JoinedAt: last.JoinedAt,
+			UID:      last.Uid,
+		})
+		page.NextCursor = &next
+		page.HasMore = true
 	}
-	return s.classUserRepo.GetClassMembers(cid)
+	return page, nil
 }
 
-func (s *classUserServiceImpl) GetFavoriteClasses(uid uint, page int, limit int) ([]dto.UserClassInfoDTO, error) {
-	return s.classUserRepo.GetFavoriteClasses(uid, page, limit)
+func (s *classUserServiceImpl) GetFavoriteClasses(uid uint, query pagination.Query) (pagination.Page[dto.UserClassInfoDTO], error) {
+	scope := fmt.Sprintf("favorite_classes:%d", uid)
+	return pageUserClasses(scope, query, func(window pagination.Window) ([]dto.UserClassInfoDTO, error) {
+		return s.classUserRepo.GetFavoriteClasses(uid, window)
+	})
+}
+
+func (s *classUserServiceImpl) GetUserClassesByRole(uid uint, role models.Role, query pagination.Query) (pagination.Page[dto.UserClassInfoDTO], error) {
+	if !role.Valid() {
+		return pagination.Page[dto.UserClassInfoDTO]{}, ErrInvalidRole
+	}
+	scope := fmt.Sprintf("user_classes:%d:%s", uid, role)
+	return pageUserClasses(scope, query, func(window pagination.Window) ([]dto.UserClassInfoDTO, error) {
+		return s.classUserRepo.GetUserClassesByRole(uid, role, window)
+	})
 }
 
-func (s *classUserServiceImpl) GetUserClassesByRole(uid uint, roleName string, page int, limit int) ([]dto.UserClassInfoDTO, error) {
-	return s.classUserRepo.GetUserClassesByRole(uid, roleName, page, limit)
+// pageUserClasses は find で取得したユーザーのクラスをクラスIDのカーソルでページにします。
+func pageUserClasses(scope string, query pagination.Query, find func(pagination.Window) ([]dto.UserClassInfoDTO, error)) (pagination.Page[dto.UserClassInfoDTO], error) {
+	window, err := pagination.NewWindow(scope, query)
+	if err != nil {
+		return pagination.Page[dto.UserClassInfoDTO]{}, err
+	}
+	classes, err := find(window)
+	if err != nil {
+		return pagination.Page[dto.UserClassInfoDTO]{}, err
+	}
+	return pagination.NewPage(scope, window, classes, func(class dto.UserClassInfoDTO) uint { return class.ID }), nil
 }
 
-func (s *classUserServiceImpl) GetRole(uid uint, cid uint) (string, error) {
-	roleName, err := s.classUserRepo.GetRole(uid, cid)
+func (s *classUserServiceImpl) GetRole(uid uint, cid uint) (models.Role, error) {
+	role, err := s.classUserRepo.GetRole(uid, cid)
 	if err != nil {
 		return "", err
 	}
-	return roleName, nil
+	return role, nil
 }
 
-func (s *classUserServiceImpl) AssignRole(uid uint, cid uint, roleName string) error {
-	exists, err := s.classUserRepo.RoleExists(uid, cid)
+// AssignRole は actorID の操作として uid のロールを設定します。存在しないロールの場合は ErrInvalidRole を返します。
+// 権限は確認しないため、呼び出し元で確認してください。
+// 最後の管理者のロールを変更する場合は ErrLastAdmin を返します。
+func (s *classUserServiceImpl) AssignRole(actorID uint, uid uint, cid uint, role models.Role) error {
+	if !role.Valid() {
+		return ErrInvalidRole
+	}
+	current, err := classRole(s.classUserRepo, uid, cid)
 	if err != nil {
 		return err
 	}
-	if exists {
-		return s.classUserRepo.UpdateUserRole(uid, cid, roleName)
-	} else {
-		return s.classUserRepo.CreateUserRole(uid, cid, roleName)
+	if current == "" {
+		return s.classUserRepo.CreateUserRole(actorID, uid, cid, role)
 	}
+	return s.classUserRepo.UpdateUserRole(actorID, uid, cid, role)
 }
 
-func (s *classUserServiceImpl) UpdateUserName(uid uint, cid uint, newName string) error {
+// ChangeUserRole は actorID が uid のロールを変更します。
+// 申請中のユーザーの承認 (APPLICANT から USER) は members.approve、それ以外の変更は members.manage の権限が必要です。
+func (s *classUserServiceImpl) ChangeUserRole(actorID uint, uid uint, cid uint, role models.Role) error {
+	if !role.Valid() {
+		return ErrInvalidRole
+	}
+
+	permission := authorization.MembersManage
+	current, err := classRole(s.classUserRepo, uid, cid)
+	if err != nil {
+		return err
+	}
+	if current == models.RoleApplicant && role == models.RoleUser {
+		permission = authorization.MembersApprove
+	}
+
+	if err := authorize(s.classUserRepo, actorID, cid, permission); err != nil {
+		return err
+	}
+	return s.AssignRole(actorID, uid, cid, role)
+}
+
+// UpdateUserName は本人またはクラス管理者である actorID が、uid のニックネームを変更します。
+func (s *classUserServiceImpl) UpdateUserName(actorID uint, uid uint, cid uint, newName string) error {
+	if err := s.requireSelfOr(actorID, uid, cid, authorization.MembersManage); err != nil {
+		return err
+	}
 	return s.classUserRepo.UpdateUserName(uid, cid, newName)
 }
 
@@ -95,10 +249,146 @@ func (s *classUserServiceImpl) ToggleFavorite(uid uint, cid uint) error {
 	return nil
 }
 
-func (s *classUserServiceImpl) RemoveUserFromClass(uid uint, cid uint) error {
-	return s.classUserRepo.DeleteClassUser(uid, cid)
+// RemoveUserFromClass は本人 (退会) またはクラス管理者である actorID が、uid をクラスから削除します。
+// 最後の管理者を削除する場合は ErrLastAdmin を、利用停止中のユーザーを削除する場合は ErrBannedMember を返します。
+func (s *classUserServiceImpl) RemoveUserFromClass(actorID uint, uid uint, cid uint) error {
+	if err := s.requireSelfOr(actorID, uid, cid, authorization.MembersManage); err != nil {
+		return err
+	}
+	return s.classUserRepo.DeleteClassUser(actorID, uid, cid)
 }
 
 func (s *classUserServiceImpl) SearchUserClassesByName(uid uint, name string) ([]dto.UserClassInfoDTO, error) {
 	return s.classUserRepo.SearchUserClassesByName(uid, name)
 }
+
+// requireSelfOr は actorID が uid 本人、またはクラス内で permission を持っていることを確認します。
+func (s *classUserServiceImpl) requireSelfOr(actorID uint, uid uint, cid uint, permission authorization.Permission) error {
+	if actorID == uid {
+		return nil
+	}
+	return authorize(s.classUserRepo, actorID, cid, permission)
+}
+
+func (s *classUserServiceImpl) ImportMembers(actorID uint, cid uint, r io.Reader, dryRun bool) (dto.MemberImportResultDTO, error) {
+	if err := authorize(s.classUserRepo, actorID, cid, authorization.MembersManage); err != nil {
+		return dto.MemberImportResultDTO{}, err
+	}
+
+	rows, err := parseMemberImportCSV(r)
+	if err != nil {
+		return dto.MemberImportResultDTO{}, err
+	}
+	if err := s.classUserRepo.ImportMembers(actorID, cid, rows, dryRun); err != nil {
+		return dto.MemberImportResultDTO{}, err
+	}
+
+	result := dto.MemberImportResultDTO{DryRun: dryRun, Rows: rows}
+	for _, row := range rows {
+		if row.Error == "" {
+			result.Imported++
+		} else {
+			result.Failed++
+		}
+	}
+	return result, nil
+}
+
+// parseMemberImportCSV はCSVを読み込み、各行を検証します。行の値が不正な場合は行の Error に理由を設定します。
+func parseMemberImportCSV(r io.Reader) ([]dto.MemberImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, ErrInvalidCSV
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		// Excelで保存したCSVの先頭のBOMを取り除く
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[name] = i
+	}
+	_, hasUID := columns["uid"]
+	_, hasProvider := columns["provider"]
+	_, hasSubject := columns["subject"]
+	if !hasUID && !(hasProvider && hasSubject) {
+		return nil, ErrInvalidCSV
+	}
+
+	var rows []dto.MemberImportRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, ErrInvalidCSV
+		}
+		if len(rows) == maxImportRows {
+			return nil, ErrTooManyImportRows
+		}
+		value := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		rows = append(rows, parseMemberImportRow(line, value))
+	}
+	return rows, nil
+}
+
+// parseMemberImportRow はCSVの1行を検証します。
+func parseMemberImportRow(line int, value func(name string) string) dto.MemberImportRow {
+	row := dto.MemberImportRow{
+		Line:     line,
+		Provider: value("provider"),
+		Subject:  value("subject"),
+		Nickname: unescapeCSVCell(value("nickname")),
+		Role:     models.RoleUser,
+	}
+
+	if uid := value("uid"); uid != "" {
+		parsed, err := strconv.ParseUint(uid, 10, 32)
+		if err != nil || parsed == 0 {
+			row.Error = constants.InvalidImportUser
+			return row
+		}
+		row.UID = uint(parsed)
+	} else if row.Provider == "" || row.Subject == "" {
+		row.Error = constants.InvalidImportUser
+		return row
+	}
+
+	if role := value("role"); role != "" {
+		parsed, err := models.ParseRole(strings.ToUpper(role))
+		if err != nil || !containsRole(importRoles, parsed) {
+			row.Error = constants.InvalidRole
+			return row
+		}
+		row.Role = parsed
+	}
+	if utf8.RuneCountInString(row.Nickname) > 50 {
+		row.Error = constants.NicknameTooLong
+	}
+	return row
+}
+
+// unescapeCSVCell はエクスポートしたCSVで数式として扱われないように先頭に付けた ' を取り除きます。
+func unescapeCSVCell(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+func containsRole(roles []models.Role, role models.Role) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"gorm.io/gorm"
+)
+
+const (
+	// DevLoginProvider は開発用ログインで作成するアカウントのプロバイダ名です。
+	DevLoginProvider = "dev"
+	defaultDevUser   = "developer"
+)
+
+// DevLoginService は外部のログインプロバイダを使わずにログインする開発用のサービス
+type DevLoginService interface {
+	Login(request dto.DevLoginRequest) (models.User, error)
+}
+
+type devLoginServiceImpl struct {
+	userRepo     repositories.UserRepository
+	authUserRepo repositories.AuthUserRepository
+}
+
+// NewDevLoginService はDevLoginServiceを生成します。
+func NewDevLoginService(userRepo repositories.UserRepository, authUserRepo repositories.AuthUserRepository) DevLoginService {
+	return &devLoginServiceImpl{
+		userRepo:     userRepo,
+		authUserRepo: authUserRepo,
+	}
+}
+
+// Login はUserIDが指定された場合はそのユーザーを、指定されていない場合はNameに対応する開発用ユーザーを返します。
+// 開発用ユーザーが存在しない場合は作成します。
+func (s *devLoginServiceImpl) Login(request dto.DevLoginRequest) (models.User, error) {
+	if request.UserID != 0 {
+		user, err := s.userRepo.FindByID(request.UserID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.User{}, ErrNotFound
+		}
+		if err != nil {
+			return models.User{}, err
+		}
+		return *user, nil
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		name = defaultDevUser
+	}
+	return s.authUserRepo.UpdateOrCreateUser(dto.ExternalUserInfo{
+		Provider: DevLoginProvider,
+		Subject:  name,
+		Name:     name,
+	})
+}
@@ -1,9 +1,51 @@
 package services
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/pagination"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+)
 
 var (
 	ErrNotFound     = errors.New("not found")
 	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
 	ErrDatabase     = errors.New("database error")
+
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+
+	ErrInvalidOAuthState = errors.New("invalid or expired oauth state")
+	ErrOAuthCodeExchange = errors.New("failed to exchange oauth authorization code")
+	ErrInvalidIDToken    = errors.New("invalid id token")
+
+	ErrUnknownAuthProvider = errors.New("unknown auth provider")
+
+	ErrIdentityAlreadyLinked = errors.New("identity is already linked to another user")
+	ErrLastIdentity          = errors.New("cannot unlink the last identity")
+	ErrSameUser              = errors.New("source and target users must be different")
+
+	ErrInvalidRole  = models.ErrInvalidRole
+	ErrLastAdmin    = repositories.ErrLastAdmin
+	ErrInvalidOwner = repositories.ErrInvalidOwner
+	ErrBanOwner     = repositories.ErrBanOwner
+
+	ErrBannedMember = repositories.ErrBannedMember
+
+	ErrAlreadyMember = repositories.ErrAlreadyMember
+	ErrClassFull     = repositories.ErrClassFull
+
+	ErrInvalidInvitation = errors.New("invitation is invalid, used or expired")
+	ErrInvalidExpiry     = errors.New("expiry must be in the future")
+	ErrInvalidSecret     = errors.New("secret must be at most 20 characters")
+
+	ErrInvalidCSV        = errors.New("invalid csv")
+	ErrTooManyImportRows = errors.New("too many rows to import")
+
+	ErrInvalidCursor = pagination.ErrInvalidCursor
+
+	ErrInvalidAPIKey = errors.New("invalid or expired api key")
+	ErrInvalidScope  = errors.New("invalid api key scope")
 )
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	// defaultJWKSCacheTTL はCache-Controlが返されない場合にJWKSをキャッシュする時間
+	defaultJWKSCacheTTL = time.Hour
+	// jwksRefreshInterval は未知のkidを受け取った場合にJWKSを再取得する最短間隔
+	jwksRefreshInterval = time.Minute
+	// idTokenClockSkew はexpとiatの検証で許容する時刻のずれ
+	idTokenClockSkew = time.Minute
+)
+
+// IDTokenClaims は検証済みのOpenID Connect id_tokenのクレーム
+type IDTokenClaims struct {
+	jwt.StandardClaims
+	Nonce   string `json:"nonce"`
+	Name    string `json:"name"`
+	Picture string `json:"picture"`
+}
+
+// IDTokenVerifier はプロバイダが発行したid_tokenを検証するインターフェース
+type IDTokenVerifier interface {
+	// Verify は署名、発行者、対象者、nonce、有効期限を検証し、クレームを返します。
+	Verify(rawIDToken string, nonce string) (*IDTokenClaims, error)
+}
+
+// jwksIDTokenVerifier はプロバイダのJWKSを取得してキャッシュし、id_tokenをローカルで検証する実装
+type jwksIDTokenVerifier struct {
+	issuers  []string
+	audience string
+	jwksURL  string
+	// clientSecret が設定されている場合は、HS256 で署名されたid_tokenをクライアントシークレットで検証する
+	clientSecret []byte
+	client       *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]interface{}
+	expiresAt time.Time
+	fetchedAt time.Time
+}
+
+// NewIDTokenVerifier はJWKSのURLを指定してIDTokenVerifierを生成します。
+// issuers にはプロバイダが使用する発行者を、audience にはクライアントIDを指定します。
+func NewIDTokenVerifier(issuers []string, audience string, jwksURL string) IDTokenVerifier {
+	return &jwksIDTokenVerifier{
+		issuers:  issuers,
+		audience: audience,
+		jwksURL:  jwksURL,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NewHS256IDTokenVerifier はJWKSの公開鍵に加えて、クライアントシークレットを使用した HS256 の署名も受け付けるIDTokenVerifierを生成します。
+// LINEログインのウェブアプリで発行されるid_tokenは、チャネルシークレットを使用した HS256 で署名されます。
+func NewHS256IDTokenVerifier(issuers []string, audience string, jwksURL string, clientSecret string) IDTokenVerifier {
+	return &jwksIDTokenVerifier{
+		issuers:      issuers,
+		audience:     audience,
+		jwksURL:      jwksURL,
+		clientSecret: []byte(clientSecret),
+		client:       &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (v *jwksIDTokenVerifier) Verify(rawIDToken string, nonce string) (*IDTokenClaims, error) {
+	claims := &IDTokenClaims{}
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	_, err := parser.ParseWithClaims(rawIDToken, claims, v.keyFunc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	now := time.Now()
+	if claims.ExpiresAt == 0 || now.Add(-idTokenClockSkew).Unix() > claims.ExpiresAt {
+		return nil, fmt.Errorf("%w: token is expired", ErrInvalidIDToken)
+	}
+	if claims.IssuedAt > now.Add(idTokenClockSkew).Unix() {
+		return nil, fmt.Errorf("%w: token used before issued", ErrInvalidIDToken)
+	}
+	if !v.validIssuer(claims.Issuer) {
+		return nil, fmt.Errorf("%w: unexpected issuer %s", ErrInvalidIDToken, claims.Issuer)
+	}
+	if claims.Audience != v.audience {
+		return nil, fmt.Errorf("%w: unexpected audience %s", ErrInvalidIDToken, claims.Audience)
+	}
+	if nonce == "" || claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: subject is missing", ErrInvalidIDToken)
+	}
+	return claims, nil
+}
+
+func (v *jwksIDTokenVerifier) validIssuer(issuer string) bool {
+	for _, expected := range v.issuers {
+		if issuer == expected {
+			return true
+		}
+	}
+	return false
+}
+
+// keyFunc はid_tokenのkidに対応する公開鍵を返します。
+// 公開鍵の種類と一致しない署名方式 (HS256やnoneなど) は受け付けません。
+// HS256 はクライアントシークレットが設定されている場合のみ、クライアントシークレットで検証します。
+func (v *jwksIDTokenVerifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if token.Method == jwt.SigningMethodHS256 && len(v.clientSecret) > 0 {
+			return v.clientSecret, nil
+		}
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, err := v.key(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey:
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); ok {
+			return key, nil
+		}
+	case *ecdsa.PublicKey:
+		if _, ok := token.Method.(*jwt.SigningMethodECDSA); ok {
+			return key, nil
+		}
+	case ed25519.PublicKey:
+		if token.Method == SigningMethodEdDSA {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+}
+
+// key はキャッシュからkidに対応する公開鍵を返します。
+// キャッシュが期限切れの場合、または未知のkidの場合はJWKSを再取得します。
+func (v *jwksIDTokenVerifier) key(kid string) (interface{}, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := time.Now()
+	key, ok := v.keys[kid]
+	if ok && now.Before(v.expiresAt) {
+		return key, nil
+	}
+	if v.keys == nil || now.After(v.expiresAt) || now.Sub(v.fetchedAt) >= jwksRefreshInterval {
+		if err := v.fetch(now); err != nil {
+			if ok {
+				// 再取得に失敗した場合は期限切れのキャッシュを使用する
+				return key, nil
+			}
+			return nil, err
+		}
+		key, ok = v.keys[kid]
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %s", kid)
+	}
+	return key, nil
+}
+
+func (v *jwksIDTokenVerifier) fetch(now time.Time) error {
+	v.fetchedAt = now
+
+	resp, err := v.client.Get(v.jwksURL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	var keySet dto.JSONWebKeySet
+	if err := json.NewDecoder(resp.Body).Decode(&keySet); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(keySet.Keys))
+	for _, jwk := range keySet.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := parseJSONWebKey(jwk)
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	v.keys = keys
+	v.expiresAt = now.Add(cacheMaxAge(resp.Header.Get("Cache-Control")))
+	return nil
+}
+
+// cacheMaxAge はCache-Controlヘッダのmax-ageを返します。
+func cacheMaxAge(cacheControl string) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.TrimSpace(directive)
+		if !strings.HasPrefix(directive, "max-age=") {
+			continue
+		}
+		seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+		if err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return defaultJWKSCacheTTL
+}
+
+// parseJSONWebKey はRSA、EC (P-256)、Ed25519のJWKを公開鍵に変換します。
+func parseJSONWebKey(jwk dto.JSONWebKey) (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve: %s", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("invalid EC public key")
+		}
+		return key, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve: %s", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 public key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", jwk.Kty)
+	}
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/dgrijalva/jwt-go"
+)
+
+// 署名方式の設定値 (JWT_SIGNING_ALG)
+const (
+	SigningAlgHS256 = "HS256"
+	SigningAlgRS256 = "RS256"
+	SigningAlgEdDSA = "EdDSA"
+)
+
+// SigningMethodEdDSA はEd25519によるJWT署名方式
+// jwt-go v3 はEdDSAに対応していないため、ここで登録する。
+var SigningMethodEdDSA = &signingMethodEdDSA{}
+
+type signingMethodEdDSA struct{}
+
+func init() {
+	jwt.RegisterSigningMethod(SigningMethodEdDSA.Alg(), func() jwt.SigningMethod {
+		return SigningMethodEdDSA
+	})
+}
+
+func (m *signingMethodEdDSA) Alg() string {
+	return SigningAlgEdDSA
+}
+
+func (m *signingMethodEdDSA) Verify(signingString, signature string, key interface{}) error {
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+		return jwt.ErrSignatureInvalid
+	}
+	return nil
+}
+
+func (m *signingMethodEdDSA) Sign(signingString string, key interface{}) (string, error) {
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+	return jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString))), nil
+}
+
+// signingKey はkidで識別される署名鍵
+type signingKey struct {
+	kid        string
+	method     jwt.SigningMethod
+	privateKey crypto.Signer
+	publicKey  crypto.PublicKey
+}
+
+// jwtKeySet は検証に使用する全ての鍵と、新しいトークンの署名に使用する鍵を保持する
+// 鍵のローテーション時は新しい鍵を追加してJWT_ACTIVE_KIDを切り替え、
+// 古い鍵は発行済みトークンが全て期限切れになるまで検証用に残しておく。
+type jwtKeySet struct {
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+// loadJWTKeySet はJWT_KEYS_DIR内のPEM形式の秘密鍵を読み込みます。
+// ファイル名 (拡張子を除く) がkidになり、JWT_ACTIVE_KIDが未設定の場合は名前順で最後の鍵を署名に使用します。
+func loadJWTKeySet(alg string, dir string, activeKid string) (*jwtKeySet, error) {
+	if dir == "" {
+		return nil, errors.New("JWT_KEYS_DIR is not set")
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	keySet := &jwtKeySet{keys: make(map[string]*signingKey)}
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := loadSigningKey(path, kid)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load signing key %s: %w", path, err)
+		}
+		if key.method.Alg() != alg {
+			return nil, fmt.Errorf("signing key %s is not a %s key", kid, alg)
+		}
+		keySet.keys[kid] = key
+		keySet.active = key
+	}
+
+	if len(keySet.keys) == 0 {
+		return nil, fmt.Errorf("no signing keys found in %s", dir)
+	}
+	if activeKid != "" {
+		key, ok := keySet.keys[activeKid]
+		if !ok {
+			return nil, fmt.Errorf("active signing key %s not found", activeKid)
+		}
+		keySet.active = key
+	}
+	return keySet, nil
+}
+
+// loadSigningKey はPEMファイルからRSAまたはEd25519の秘密鍵を読み込みます。
+func loadSigningKey(path string, kid string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type: %s", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodRS256, privateKey: key, publicKey: &key.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &signingKey{kid: kid, method: SigningMethodEdDSA, privateKey: key, publicKey: key.Public()}, nil
+	default:
+		return nil, errors.New("unsupported private key type")
+	}
+}
+
+// jwks は公開鍵をJWK Setとして返します。
+func (k *jwtKeySet) jwks() dto.JSONWebKeySet {
+	kids := make([]string, 0, len(k.keys))
+	for kid := range k.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	keySet := dto.JSONWebKeySet{Keys: make([]dto.JSONWebKey, 0, len(kids))}
+	for _, kid := range kids {
+		key := k.keys[kid]
+		jwk := dto.JSONWebKey{Kid: kid, Use: "sig", Alg: key.method.Alg()}
+		switch publicKey := key.publicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		}
+		keySet.Keys = append(keySet.Keys, jwk)
+	}
+	return keySet
+}
+
+// GenerateSigningKeyPEM は指定した方式の新しい秘密鍵をPKCS#8のPEM形式で生成します。
+// 鍵のローテーション時にJWT_KEYS_DIRへ配置するファイルの作成に使用します。
+func GenerateSigningKeyPEM(alg string) ([]byte, error) {
+	var key interface{}
+	var err error
+	switch alg {
+	case SigningAlgRS256:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case SigningAlgEdDSA:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
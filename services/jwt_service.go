@@ -0,0 +1,472 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	accessTokenTTL  = 3 * time.Hour
+	refreshTokenTTL = 24 * time.Hour * 7
+	// sessionTouchInterval はセッションの最終利用時刻を更新する最短間隔
+	sessionTouchInterval = time.Minute
+)
+
+type JWTService interface {
+	ValidateToken(tokenString string) (*jwt.Token, error)
+	RefreshAccessToken(refreshToken string) (*jwt.Token, string, error)
+	RevokeRefreshToken(refreshToken string, userID uint) error
+	RevokeToken(token *jwt.Token) error
+	RevokeAllUserTokens(userID uint) error
+	IsTokenRevoked(token *jwt.Token) (bool, error)
+	JWKS() dto.JSONWebKeySet
+	StartSession(userID uint, info dto.SessionInfo) (string, string, error)
+	ListSessions(userID uint, currentSessionID string) ([]dto.SessionDTO, error)
+	RevokeSession(userID uint, sessionID string) error
+	TouchSession(token *jwt.Token)
+}
+
+type JWTServiceImpl struct {
+	secretKey    []byte
+	keySet       *jwtKeySet
+	refreshStore RefreshTokenStore
+	denylist     TokenDenylist
+	sessionStore SessionStore
+	// lastTouched はセッションごとに最後に最終利用時刻を更新した時刻
+	lastTouched sync.Map
+	// lastSwept は lastTouched から古い時刻を最後に削除した時刻 (UnixNano)
+	lastSwept atomic.Int64
+}
+
+// NewJWTService はJWTサービスを生成します。
+// JWT_SIGNING_ALG に RS256 または EdDSA を指定すると JWT_KEYS_DIR の鍵で署名し、
+// 未指定または HS256 の場合は従来どおり JWT_SECRET で署名します。
+// 非対称鍵モードでも JWT_SECRET が設定されていれば、移行期間中の HS256 トークンを検証できます。
+func NewJWTService(refreshStore RefreshTokenStore, denylist TokenDenylist, sessionStore SessionStore) *JWTServiceImpl {
+	service := &JWTServiceImpl{
+		refreshStore: refreshStore,
+		denylist:     denylist,
+		sessionStore: sessionStore,
+	}
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		service.secretKey = []byte(secret)
+	}
+
+	alg := os.Getenv("JWT_SIGNING_ALG")
+	switch alg {
+	case "", SigningAlgHS256:
+		if service.secretKey == nil {
+			panic("JWT secret is not set")
+		}
+	case SigningAlgRS256, SigningAlgEdDSA:
+		keySet, err := loadJWTKeySet(alg, os.Getenv("JWT_KEYS_DIR"), os.Getenv("JWT_ACTIVE_KID"))
+		if err != nil {
+			panic(fmt.Sprintf("failed to load JWT signing keys: %v", err))
+		}
+		service.keySet = keySet
+	default:
+		panic(fmt.Sprintf("unsupported JWT signing algorithm: %s", alg))
+	}
+	return service
+}
+
+// sign は設定された署名方式でクレームに署名します。
+func (s *JWTServiceImpl) sign(claims jwt.MapClaims) (string, error) {
+	if s.keySet == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secretKey)
+	}
+
+	key := s.keySet.active
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.privateKey)
+}
+
+// verificationKey はトークンのalgとkidに対応する検証鍵を返します。
+func (s *JWTServiceImpl) verificationKey(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if s.secretKey == nil {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return s.secretKey, nil
+	}
+
+	if s.keySet == nil {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.keySet.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %v", token.Header["kid"])
+	}
+	if key.method.Alg() != token.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.publicKey, nil
+}
+
+// generateAccessToken はアクセストークンを発行します。sessionID が指定された場合は sid クレームに含めます。
+func (s *JWTServiceImpl) generateAccessToken(userID uint, sessionID string) (string, error) {
+	jti, err := generateTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"id":  userID,
+		"jti": jti,
+		// 一括失効と同じ秒に発行されたトークンを区別できるように、発行時刻はミリ秒まで含める
+		"iat": float64(now.UnixMilli()) / 1000,
+		"exp": now.Add(accessTokenTTL).Unix(),
+	}
+	if sessionID != "" {
+		claims["sid"] = sessionID
+	}
+	return s.sign(claims)
+}
+
+// StartSession はログイン時にセッションを作成し、そのセッションのアクセストークンとリフレッシュトークンを発行します。
+// セッションIDにはリフレッシュトークンのファミリーを使用します。
+func (s *JWTServiceImpl) StartSession(userID uint, info dto.SessionInfo) (string, string, error) {
+	sessionID, err := generateTokenID()
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	session := Session{
+		ID:         sessionID,
+		UserID:     userID,
+		Provider:   info.Provider,
+		UserAgent:  info.UserAgent,
+		IP:         info.IP,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	if err := s.sessionStore.Create(session, refreshTokenTTL); err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err := s.issueRefreshToken(userID, sessionID)
+	if err != nil {
+		return "", "", err
+	}
+	accessToken, err := s.generateAccessToken(userID, sessionID)
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
+}
+
+// ListSessions はユーザーのセッションを最終利用時刻の新しい順に返します。
+func (s *JWTServiceImpl) ListSessions(userID uint, currentSessionID string) ([]dto.SessionDTO, error) {
+	sessions, err := s.sessionStore.List(userID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+
+	result := make([]dto.SessionDTO, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, dto.SessionDTO{
+			ID:         session.ID,
+			Provider:   session.Provider,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+	return result, nil
+}
+
+// RevokeSession はユーザーのセッションを失効させます。
+// セッションのリフレッシュトークンは使用できなくなり、アクセストークンも次のリクエストから拒否されます。
+func (s *JWTServiceImpl) RevokeSession(userID uint, sessionID string) error {
+	session, err := s.sessionStore.Get(sessionID)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.UserID != userID {
+		return ErrNotFound
+	}
+	return s.revokeSession(sessionID)
+}
+
+// revokeSession はセッションとそのリフレッシュトークンのファミリーを失効させます。
+func (s *JWTServiceImpl) revokeSession(sessionID string) error {
+	if err := s.refreshStore.RevokeFamily(sessionID); err != nil {
+		return err
+	}
+	s.lastTouched.Delete(sessionID)
+	return s.sessionStore.Delete(sessionID)
+}
+
+// TouchSession はアクセストークンのセッションの最終利用時刻を更新します。
+// リクエストごとにストアへ書き込まないよう、同じセッションの更新は sessionTouchInterval に一度までにします。
+func (s *JWTServiceImpl) TouchSession(token *jwt.Token) {
+	sessionID := tokenSessionID(token)
+	if sessionID == "" {
+		return
+	}
+
+	now := time.Now()
+	if last, ok := s.lastTouched.Load(sessionID); ok && now.Sub(last.(time.Time)) < sessionTouchInterval {
+		return
+	}
+	s.lastTouched.Store(sessionID, now)
+	s.sweepLastTouched(now)
+
+	if err := s.sessionStore.Touch(sessionID, now); err != nil {
+		log.Printf("failed to update session last seen: %v", err)
+	}
+}
+
+// sweepLastTouched は sessionTouchInterval 以上前の時刻を lastTouched から削除します。
+// 削除した時刻は次の更新を抑える役目を終えているため、期限切れや別のサーバーで失効したセッションが残り続けないようにします。
+// 削除は sessionTouchInterval に一度までにします。
+func (s *JWTServiceImpl) sweepLastTouched(now time.Time) {
+	last := s.lastSwept.Load()
+	if now.UnixNano()-last < int64(sessionTouchInterval) || !s.lastSwept.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	s.lastTouched.Range(func(key, value interface{}) bool {
+		if now.Sub(value.(time.Time)) >= sessionTouchInterval {
+			s.lastTouched.Delete(key)
+		}
+		return true
+	})
+}
+
+// tokenSessionID はアクセストークンの sid クレームを返します。
+func tokenSessionID(token *jwt.Token) string {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+	sessionID, _ := claims["sid"].(string)
+	return sessionID
+}
+
+// issueRefreshToken は指定したファミリーに属するリフレッシュトークンを発行し、ストアに保存します。
+func (s *JWTServiceImpl) issueRefreshToken(userID uint, family string) (string, error) {
+	jti, err := generateTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	signed, err := s.sign(jwt.MapClaims{
+		"id":   userID,
+		"exp":  time.Now().Add(refreshTokenTTL).Unix(),
+		"type": "refresh",
+		"jti":  jti,
+		"fam":  family,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.refreshStore.Save(jti, family, userID, refreshTokenTTL); err != nil {
+		return "", err
+	}
+	return signed, nil
+}
+
+func (s *JWTServiceImpl) ValidateToken(tokenString string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, s.verificationKey)
+}
+
+// JWKS はトークン検証用の公開鍵を返します。HS256モードでは空の鍵セットを返します。
+func (s *JWTServiceImpl) JWKS() dto.JSONWebKeySet {
+	if s.keySet == nil {
+		return dto.JSONWebKeySet{Keys: []dto.JSONWebKey{}}
+	}
+	return s.keySet.jwks()
+}
+
+// RefreshAccessToken はリフレッシュトークンをローテーションし、新しいアクセストークンとリフレッシュトークンを返します。
+// 既にローテーション済みのトークンが再利用された場合は、そのファミリー全体を失効させます。
+func (s *JWTServiceImpl) RefreshAccessToken(refreshToken string) (*jwt.Token, string, error) {
+	claims, err := s.parseRefreshToken(refreshToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	family, err := s.refreshStore.Consume(claims.jti, refreshTokenTTL)
+	if errors.Is(err, ErrRefreshTokenReused) {
+		if revokeErr := s.revokeSession(claims.family); revokeErr != nil {
+			return nil, "", revokeErr
+		}
+		return nil, "", err
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if family != claims.family {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	active, err := s.refreshStore.FamilyActive(family)
+	if err != nil {
+		return nil, "", err
+	}
+	if !active {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	newRefreshToken, err := s.issueRefreshToken(claims.userID, family)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// セッションが作成される前に発行されたファミリーは、セッションなしのアクセストークンを発行する
+	sessionID := ""
+	if session, err := s.sessionStore.Get(family); err != nil {
+		return nil, "", err
+	} else if session != nil {
+		if err := s.sessionStore.Extend(family, refreshTokenTTL); err != nil {
+			return nil, "", err
+		}
+		sessionID = family
+	}
+
+	newAccessToken, err := s.generateAccessToken(claims.userID, sessionID)
+	if err != nil {
+		return nil, "", err
+	}
+	accessToken, err := s.ValidateToken(newAccessToken)
+	if err != nil {
+		return nil, "", err
+	}
+	return accessToken, newRefreshToken, nil
+}
+
+// RevokeRefreshToken はリフレッシュトークンが属するファミリーを失効させます。
+func (s *JWTServiceImpl) RevokeRefreshToken(refreshToken string, userID uint) error {
+	claims, err := s.parseRefreshToken(refreshToken)
+	if err != nil {
+		return err
+	}
+	if claims.userID != userID {
+		return ErrUnauthorized
+	}
+	return s.revokeSession(claims.family)
+}
+
+// RevokeToken はアクセストークンを有効期限まで拒否リストに追加します。
+func (s *JWTServiceImpl) RevokeToken(token *jwt.Token) error {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return ErrUnauthorized
+	}
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return nil
+	}
+	exp, _ := claims["exp"].(float64)
+	return s.denylist.Deny(jti, time.Until(time.Unix(int64(exp), 0)))
+}
+
+// RevokeAllUserTokens はユーザーに発行済みの全てのアクセストークンとリフレッシュトークンを失効させます。
+func (s *JWTServiceImpl) RevokeAllUserTokens(userID uint) error {
+	if err := s.denylist.RevokeUser(userID, time.Now(), accessTokenTTL); err != nil {
+		return err
+	}
+	if err := s.refreshStore.RevokeUser(userID); err != nil {
+		return err
+	}
+	return s.sessionStore.DeleteUser(userID)
+}
+
+// IsTokenRevoked はアクセストークンが個別に、セッション単位で、またはユーザー単位で失効されているかを確認します。
+func (s *JWTServiceImpl) IsTokenRevoked(token *jwt.Token) (bool, error) {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return true, nil
+	}
+
+	if sessionID, ok := claims["sid"].(string); ok && sessionID != "" {
+		exists, err := s.sessionStore.Exists(sessionID)
+		if err != nil || !exists {
+			return !exists, err
+		}
+	}
+
+	if jti, ok := claims["jti"].(string); ok && jti != "" {
+		denied, err := s.denylist.IsDenied(jti)
+		if err != nil || denied {
+			return denied, err
+		}
+	}
+
+	id, ok := claims["id"].(float64)
+	if !ok {
+		return true, nil
+	}
+	revokedAt, revoked, err := s.denylist.UserRevokedAt(uint(id))
+	if err != nil || !revoked {
+		return false, err
+	}
+
+	// iatを持たない古いトークンは有効期限から発行時刻を推定する
+	issuedAt, ok := claims["iat"].(float64)
+	if !ok {
+		exp, _ := claims["exp"].(float64)
+		issuedAt = exp - accessTokenTTL.Seconds()
+	}
+	return int64(math.Round(issuedAt*1000)) <= revokedAt.UnixMilli(), nil
+}
+
+// refreshTokenClaims はリフレッシュトークンから取り出したクレーム
+type refreshTokenClaims struct {
+	userID uint
+	jti    string
+	family string
+}
+
+// parseRefreshToken はリフレッシュトークンの署名と有効期限を検証し、クレームを取り出します。
+func (s *JWTServiceImpl) parseRefreshToken(refreshToken string) (*refreshTokenClaims, error) {
+	token, err := s.ValidateToken(refreshToken)
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["type"] != "refresh" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	id, idOK := claims["id"].(float64)
+	jti, jtiOK := claims["jti"].(string)
+	family, famOK := claims["fam"].(string)
+	if !idOK || !jtiOK || !famOK || jti == "" || family == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return &refreshTokenClaims{userID: uint(id), jti: jti, family: family}, nil
+}
+
+// generateTokenID はトークンIDとして利用するランダムな文字列を生成します。
+func generateTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
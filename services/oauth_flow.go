@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// OAuthStateTTL はログイン開始から認可コードの処理までに許容する時間
+const OAuthStateTTL = 10 * time.Minute
+
+// newAuthCodeURL はstate、PKCEのcode_verifier、nonceを生成してフローとともに保存し、認可URLとstateを返します。
+func newAuthCodeURL(config *oauth2.Config, stateStore OAuthStateStore, flow OAuthFlow) (string, string, error) {
+	state, err := randomURLString()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomURLString()
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	if err := stateStore.Save(state, OAuthState{OAuthFlow: flow, CodeVerifier: verifier, Nonce: nonce}, OAuthStateTTL); err != nil {
+		return "", "", err
+	}
+	return config.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier), oauth2.SetAuthURLParam("nonce", nonce)), state, nil
+}
+
+// exchangeAuthCode はstateを検証し、保存されたcode_verifierを使って認可コードをトークンに交換します。
+// stateが flow と異なる目的、または異なるユーザーで発行された場合は ErrInvalidOAuthState を返します。
+// id_tokenの検証に使用するため、ログイン開始時の情報も返します。
+func exchangeAuthCode(config *oauth2.Config, stateStore OAuthStateStore, code string, state string, flow OAuthFlow) (*oauth2.Token, OAuthState, error) {
+	if state == "" {
+		return nil, OAuthState{}, ErrInvalidOAuthState
+	}
+	data, err := stateStore.Consume(state)
+	if err != nil {
+		return nil, OAuthState{}, err
+	}
+	if data.OAuthFlow != flow {
+		return nil, OAuthState{}, ErrInvalidOAuthState
+	}
+
+	token, err := config.Exchange(context.Background(), code, oauth2.VerifierOption(data.CodeVerifier))
+	if err != nil {
+		return nil, OAuthState{}, fmt.Errorf("%w: %v", ErrOAuthCodeExchange, err)
+	}
+	return token, data, nil
+}
+
+// randomURLString はURLに使用できるランダムな文字列を生成します。
+func randomURLString() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const oauthStateKeyPrefix = "oauth_state:"
+
+// OAuthPurpose はstateを発行した目的
+type OAuthPurpose string
+
+const (
+	// OAuthPurposeLogin はログイン
+	OAuthPurposeLogin OAuthPurpose = "login"
+	// OAuthPurposeLink はログイン中のユーザーへのアカウントの紐づけ
+	OAuthPurposeLink OAuthPurpose = "link"
+)
+
+// OAuthFlow はstateを発行した目的と、紐づけの場合にフローを開始したユーザー
+// 認可コードを処理する際に一致しないstateは拒否し、他人が開始したフローのアカウントを紐づけられないようにします。
+type OAuthFlow struct {
+	Purpose OAuthPurpose `json:"purpose"`
+	UserID  uint         `json:"user_id,omitempty"`
+}
+
+// LoginFlow はログインのフローを返します。
+func LoginFlow() OAuthFlow {
+	return OAuthFlow{Purpose: OAuthPurposeLogin}
+}
+
+// LinkFlow は userID へのアカウントの紐づけのフローを返します。
+func LinkFlow(userID uint) OAuthFlow {
+	return OAuthFlow{Purpose: OAuthPurposeLink, UserID: userID}
+}
+
+// OAuthState はstateに紐づけて保存するログイン開始時の情報
+type OAuthState struct {
+	OAuthFlow
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+}
+
+// OAuthStateStore はOAuthのstateとPKCEのcode_verifier、OpenID Connectのnonceを保持するストア
+// 複数のレプリカで同じstateを検証できるよう、サーバー側の共有ストアに保存します。
+type OAuthStateStore interface {
+	// Save はstateと対応するログイン開始時の情報を保存します。
+	Save(state string, data OAuthState, ttl time.Duration) error
+	// Consume はstateを一度だけ使用済みにし、対応するログイン開始時の情報を返します。
+	// 存在しない、期限切れ、または使用済みのstateの場合は ErrInvalidOAuthState を返します。
+	Consume(state string) (OAuthState, error)
+}
+
+// redisOAuthStateStore はRedisを利用したOAuthStateStoreの実装
+type redisOAuthStateStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisOAuthStateStore はRedisを利用したOAuthStateStoreを生成します。
+// providerごとにキーを分け、他のプロバイダで発行されたstateを受け付けないようにします。
+func NewRedisOAuthStateStore(client *redis.Client, provider string) OAuthStateStore {
+	return &redisOAuthStateStore{client: client, prefix: oauthStateKeyPrefix + provider + ":"}
+}
+
+func (s *redisOAuthStateStore) Save(state string, data OAuthState, ttl time.Duration) error {
+	value, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(context.Background(), s.prefix+state, value, ttl).Err()
+}
+
+func (s *redisOAuthStateStore) Consume(state string) (OAuthState, error) {
+	ctx := context.Background()
+
+	// GETとDELをトランザクション内で実行し、同じstateが二重に使用されないようにする
+	var get *redis.StringCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, s.prefix+state)
+		pipe.Del(ctx, s.prefix+state)
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return OAuthState{}, err
+	}
+
+	value, err := get.Bytes()
+	if errors.Is(err, redis.Nil) {
+		return OAuthState{}, ErrInvalidOAuthState
+	}
+	if err != nil {
+		return OAuthState{}, err
+	}
+
+	var data OAuthState
+	if err := json.Unmarshal(value, &data); err != nil {
+		return OAuthState{}, err
+	}
+	return data, nil
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const rateLimitKeyPrefix = "rate_limit:"
+
+// RateLimit は window の間に許可するリクエスト数
+type RateLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+// RateLimiter はキーごとにリクエスト数を制限するリミッター
+type RateLimiter interface {
+	// Allow はリクエストを許可するかを返します。許可しない場合は再試行できるまでの時間を返します。
+	Allow(key string, limit RateLimit) (bool, time.Duration, error)
+}
+
+// slidingWindowScript は直近 window の間のリクエストをソート済みセットに記録するスライディングウィンドウ
+// 上限に達している場合は、最も古いリクエストが window から外れるまでのミリ秒を返す
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], 0, now - window)
+if redis.call("ZCARD", KEYS[1]) < tonumber(ARGV[3]) then
+	redis.call("ZADD", KEYS[1], now, ARGV[4])
+	redis.call("PEXPIRE", KEYS[1], window)
+	return {1, 0}
+end
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+return {0, tonumber(oldest[2]) + window - now}
+`)
+
+// redisRateLimiter はRedisを利用したスライディングウィンドウ方式のRateLimiterの実装
+// 全てのレプリカで同じRedisを参照するため、レプリカ数に関わらず制限が共有されます。
+type redisRateLimiter struct {
+	client redis.Scripter
+	now    func() time.Time
+}
+
+// NewRedisRateLimiter はRedisを利用したRateLimiterを生成します。
+// テストでは redis.Scripter のモックを渡すことができます。
+func NewRedisRateLimiter(client redis.Scripter) RateLimiter {
+	return &redisRateLimiter{client: client, now: time.Now}
+}
+
+func (l *redisRateLimiter) Allow(key string, limit RateLimit) (bool, time.Duration, error) {
+	now := l.now().UnixMilli()
+	member, err := randomURLString()
+	if err != nil {
+		return false, 0, err
+	}
+
+	result, err := slidingWindowScript.Run(context.Background(), l.client, []string{rateLimitKeyPrefix + key},
+		now, limit.Window.Milliseconds(), limit.Limit, fmt.Sprintf("%d-%s", now, member)).Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	if len(result) != 2 {
+		return false, 0, fmt.Errorf("unexpected rate limit result: %v", result)
+	}
+
+	allowed, _ := result[0].(int64)
+	retryAfter, _ := result[1].(int64)
+	return allowed == 1, time.Duration(retryAfter) * time.Millisecond, nil
+}
+
+// ParseRateLimit は "10/1m" の形式の文字列をRateLimitに変換します。
+func ParseRateLimit(value string) (RateLimit, error) {
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: expected <limit>/<window>", value)
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || limit <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: limit must be a positive integer", value)
+	}
+	window, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || window <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: window must be a positive duration", value)
+	}
+	return RateLimit{Limit: limit, Window: window}, nil
+}
+
+// RateLimitFromEnv は環境変数 RATE_LIMIT_<NAME> ("10/1m" の形式) のRateLimitを返します。
+// 設定されていない、または不正な場合は def を返します。
+func RateLimitFromEnv(name string, def RateLimit) RateLimit {
+	value := os.Getenv("RATE_LIMIT_" + strings.ToUpper(name))
+	if value == "" {
+		return def
+	}
+	limit, err := ParseRateLimit(value)
+	if err != nil {
+		log.Printf("RATE_LIMIT_%s を無視します: %v", strings.ToUpper(name), err)
+		return def
+	}
+	return limit
+}
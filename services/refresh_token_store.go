@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	refreshTokenKeyPrefix     = "refresh_token:"
+	usedRefreshTokenKeyPrefix = "refresh_token_used:"
+	refreshFamilyKeyPrefix    = "refresh_family:"
+	userFamiliesKeyPrefix     = "refresh_user_families:"
+)
+
+// RefreshTokenStore はリフレッシュトークンの状態をサーバー側で保持するストア
+type RefreshTokenStore interface {
+	// Save は発行したリフレッシュトークンを保存し、ファミリーの有効期限を延長します。
+	Save(jti string, family string, userID uint, ttl time.Duration) error
+	// Consume はリフレッシュトークンを一度だけ使用済みにし、所属するファミリーを返します。
+	// 既に使用済みのトークンの場合は ErrRefreshTokenReused を返します。
+	Consume(jti string, ttl time.Duration) (string, error)
+	// FamilyActive はファミリーが失効していないかを確認します。
+	FamilyActive(family string) (bool, error)
+	// RevokeFamily はファミリーに属する全てのリフレッシュトークンを失効させます。
+	RevokeFamily(family string) error
+	// RevokeUser はユーザーの全てのファミリーを失効させます。
+	RevokeUser(userID uint) error
+}
+
+// consumeRefreshTokenScript はリフレッシュトークンを削除し、同じ操作の中で使用済みの印を ttl ミリ秒保存する
+// 未使用のトークンは {1, ファミリー}、使用済みのトークンは {0, ファミリー} を返し、どちらでもない場合は nil を返す
+// 印の保存までをまとめて実行するため、削除の直後に同じトークンが使用されても再利用として検出できる
+var consumeRefreshTokenScript = redis.NewScript(`
+local family = redis.call("GET", KEYS[1])
+if family then
+	redis.call("DEL", KEYS[1])
+	redis.call("SET", KEYS[2], family, "PX", ARGV[1])
+	return {1, family}
+end
+local used = redis.call("GET", KEYS[2])
+if used then
+	return {0, used}
+end
+return false
+`)
+
+// redisRefreshTokenStore はRedisを利用したRefreshTokenStoreの実装
+type redisRefreshTokenStore struct {
+	client *redis.Client
+}
+
+// NewRedisRefreshTokenStore はRedisを利用したRefreshTokenStoreを生成します。
+func NewRedisRefreshTokenStore(client *redis.Client) RefreshTokenStore {
+	return &redisRefreshTokenStore{client: client}
+}
+
+func (s *redisRefreshTokenStore) Save(jti string, family string, userID uint, ttl time.Duration) error {
+	ctx := context.Background()
+	userKey := userFamiliesKeyPrefix + strconv.FormatUint(uint64(userID), 10)
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, refreshTokenKeyPrefix+jti, family, ttl)
+		pipe.Set(ctx, refreshFamilyKeyPrefix+family, strconv.FormatUint(uint64(userID), 10), ttl)
+		pipe.SAdd(ctx, userKey, family)
+		pipe.Expire(ctx, userKey, ttl)
+		return nil
+	})
+	return err
+}
+
+func (s *redisRefreshTokenStore) Consume(jti string, ttl time.Duration) (string, error) {
+	result, err := consumeRefreshTokenScript.Run(context.Background(), s.client,
+		[]string{refreshTokenKeyPrefix + jti, usedRefreshTokenKeyPrefix + jti}, ttl.Milliseconds()).Slice()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(result) != 2 {
+		return "", fmt.Errorf("unexpected refresh token result: %v", result)
+	}
+
+	consumed, _ := result[0].(int64)
+	family, _ := result[1].(string)
+	if consumed != 1 {
+		return family, ErrRefreshTokenReused
+	}
+	return family, nil
+}
+
+func (s *redisRefreshTokenStore) FamilyActive(family string) (bool, error) {
+	n, err := s.client.Exists(context.Background(), refreshFamilyKeyPrefix+family).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (s *redisRefreshTokenStore) RevokeFamily(family string) error {
+	return s.client.Del(context.Background(), refreshFamilyKeyPrefix+family).Err()
+}
+
+func (s *redisRefreshTokenStore) RevokeUser(userID uint) error {
+	ctx := context.Background()
+	userKey := userFamiliesKeyPrefix + strconv.FormatUint(uint64(userID), 10)
+
+	families, err := s.client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(families)+1)
+	for _, family := range families {
+		keys = append(keys, refreshFamilyKeyPrefix+family)
+	}
+	keys = append(keys, userKey)
+	return s.client.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	sessionKeyPrefix      = "session:"
+	userSessionsKeyPrefix = "user_sessions:"
+)
+
+// touchSessionScript はセッションが存在する場合のみ最終利用時刻を更新する
+// 削除されたセッションを再作成しないように、存在の確認と更新をまとめて実行する
+var touchSessionScript = redis.NewScript(`if redis.call("EXISTS", KEYS[1]) == 1 then return redis.call("HSET", KEYS[1], "last_seen_at", ARGV[1]) end return 0`)
+
+// Session はログインごとに作成されるセッション
+// セッションIDはリフレッシュトークンのファミリーと同じ値を使用します。
+type Session struct {
+	ID         string
+	UserID     uint
+	Provider   string
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+}
+
+// SessionStore はセッションを保持するストア
+// 認証のたびに参照されるため、データベースではなくRedisに保存します。
+type SessionStore interface {
+	// Create はセッションを保存します。
+	Create(session Session, ttl time.Duration) error
+	// Get はセッションを返します。存在しない場合は nil を返します。
+	Get(id string) (*Session, error)
+	// List はユーザーの全てのセッションを返します。
+	List(userID uint) ([]Session, error)
+	// Exists はセッションが失効していないかを確認します。
+	Exists(id string) (bool, error)
+	// Touch はセッションの最終利用時刻を更新します。
+	Touch(id string, at time.Time) error
+	// Extend はセッションの有効期限を延長します。
+	Extend(id string, ttl time.Duration) error
+	// Delete はセッションを削除します。
+	Delete(id string) error
+	// DeleteUser はユーザーの全てのセッションを削除します。
+	DeleteUser(userID uint) error
+}
+
+// redisSessionStore はRedisを利用したSessionStoreの実装
+type redisSessionStore struct {
+	client *redis.Client
+}
+
+// NewRedisSessionStore はRedisを利用したSessionStoreを生成します。
+func NewRedisSessionStore(client *redis.Client) SessionStore {
+	return &redisSessionStore{client: client}
+}
+
+func userSessionsKey(userID uint) string {
+	return userSessionsKeyPrefix + strconv.FormatUint(uint64(userID), 10)
+}
+
+func (s *redisSessionStore) Create(session Session, ttl time.Duration) error {
+	ctx := context.Background()
+	userKey := userSessionsKey(session.UserID)
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, sessionKeyPrefix+session.ID, map[string]interface{}{
+			"uid":          session.UserID,
+			"provider":     session.Provider,
+			"user_agent":   session.UserAgent,
+			"ip":           session.IP,
+			"created_at":   session.CreatedAt.Unix(),
+			"last_seen_at": session.LastSeenAt.Unix(),
+		})
+		pipe.Expire(ctx, sessionKeyPrefix+session.ID, ttl)
+		pipe.SAdd(ctx, userKey, session.ID)
+		pipe.Expire(ctx, userKey, ttl)
+		return nil
+	})
+	return err
+}
+
+func (s *redisSessionStore) Get(id string) (*Session, error) {
+	values, err := s.client.HGetAll(context.Background(), sessionKeyPrefix+id).Result()
+	if err != nil {
+		return nil, err
+	}
+	return parseSession(id, values), nil
+}
+
+func (s *redisSessionStore) List(userID uint) ([]Session, error) {
+	ctx := context.Background()
+	userKey := userSessionsKey(userID)
+	ids, err := s.client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	cmds := make([]*redis.StringStringMapCmd, len(ids))
+	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range ids {
+			cmds[i] = pipe.HGetAll(ctx, sessionKeyPrefix+id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, 0, len(ids))
+	for i, id := range ids {
+		session := parseSession(id, cmds[i].Val())
+		if session == nil {
+			// 期限切れのセッションは一覧から取り除く
+			s.client.SRem(ctx, userKey, id)
+			continue
+		}
+		sessions = append(sessions, *session)
+	}
+	return sessions, nil
+}
+
+func (s *redisSessionStore) Exists(id string) (bool, error) {
+	n, err := s.client.Exists(context.Background(), sessionKeyPrefix+id).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (s *redisSessionStore) Touch(id string, at time.Time) error {
+	return touchSessionScript.Run(context.Background(), s.client, []string{sessionKeyPrefix + id}, at.Unix()).Err()
+}
+
+func (s *redisSessionStore) Extend(id string, ttl time.Duration) error {
+	ctx := context.Background()
+	session, err := s.Get(id)
+	if err != nil || session == nil {
+		return err
+	}
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Expire(ctx, sessionKeyPrefix+id, ttl)
+		pipe.Expire(ctx, userSessionsKey(session.UserID), ttl)
+		return nil
+	})
+	return err
+}
+
+func (s *redisSessionStore) Delete(id string) error {
+	ctx := context.Background()
+	session, err := s.Get(id)
+	if err != nil || session == nil {
+		return err
+	}
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, sessionKeyPrefix+id)
+		pipe.SRem(ctx, userSessionsKey(session.UserID), id)
+		return nil
+	})
+	return err
+}
+
+func (s *redisSessionStore) DeleteUser(userID uint) error {
+	ctx := context.Background()
+	userKey := userSessionsKey(userID)
+	ids, err := s.client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, id := range ids {
+			pipe.Del(ctx, sessionKeyPrefix+id)
+		}
+		pipe.Del(ctx, userKey)
+		return nil
+	})
+	return err
+}
+
+// parseSession はRedisのハッシュをセッションに変換します。ハッシュが空の場合は nil を返します。
+func parseSession(id string, values map[string]string) *Session {
+	if len(values) == 0 {
+		return nil
+	}
+	uid, _ := strconv.ParseUint(values["uid"], 10, 64)
+	createdAt, _ := strconv.ParseInt(values["created_at"], 10, 64)
+	lastSeenAt, _ := strconv.ParseInt(values["last_seen_at"], 10, 64)
+	return &Session{
+		ID:         id,
+		UserID:     uint(uid),
+		Provider:   values["provider"],
+		UserAgent:  values["user_agent"],
+		IP:         values["ip"],
+		CreatedAt:  time.Unix(createdAt, 0),
+		LastSeenAt: time.Unix(lastSeenAt, 0),
+	}
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	accessDenylistKeyPrefix    = "access_denylist:"
+	accessUserRevokedKeyPrefix = "access_revoked_user:"
+)
+
+// TokenDenylist は失効させたアクセストークンを保持するストア
+type TokenDenylist interface {
+	// Deny は指定したjtiのトークンを有効期限まで拒否します。
+	Deny(jti string, ttl time.Duration) error
+	// IsDenied はjtiが拒否リストに含まれているかを確認します。
+	IsDenied(jti string) (bool, error)
+	// RevokeUser は指定時刻以前に発行されたユーザーの全てのトークンを拒否します。
+	RevokeUser(userID uint, revokedAt time.Time, ttl time.Duration) error
+	// UserRevokedAt はユーザーのトークンが一括失効された時刻を返します。
+	UserRevokedAt(userID uint) (time.Time, bool, error)
+}
+
+// redisTokenDenylist はRedisを利用したTokenDenylistの実装
+type redisTokenDenylist struct {
+	client *redis.Client
+}
+
+// NewRedisTokenDenylist はRedisを利用したTokenDenylistを生成します。
+func NewRedisTokenDenylist(client *redis.Client) TokenDenylist {
+	return &redisTokenDenylist{client: client}
+}
+
+func (d *redisTokenDenylist) Deny(jti string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	return d.client.Set(context.Background(), accessDenylistKeyPrefix+jti, "1", ttl).Err()
+}
+
+func (d *redisTokenDenylist) IsDenied(jti string) (bool, error) {
+	n, err := d.client.Exists(context.Background(), accessDenylistKeyPrefix+jti).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (d *redisTokenDenylist) RevokeUser(userID uint, revokedAt time.Time, ttl time.Duration) error {
+	key := accessUserRevokedKeyPrefix + strconv.FormatUint(uint64(userID), 10)
+	return d.client.Set(context.Background(), key, revokedAt.UnixMilli(), ttl).Err()
+}
+
+func (d *redisTokenDenylist) UserRevokedAt(userID uint) (time.Time, bool, error) {
+	key := accessUserRevokedKeyPrefix + strconv.FormatUint(uint64(userID), 10)
+	value, err := d.client.Get(context.Background(), key).Int64()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.UnixMilli(value), true, nil
+}
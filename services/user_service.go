@@ -3,6 +3,7 @@ package services
 import (
 	"errors"
 
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
 )
@@ -10,22 +11,26 @@ import (
 const ErrUserNotFound = "user not found"
 
 type UserService interface {
-	GetApplyingClasses(userID uint) ([]models.ClassUser, error)
+	GetApplyingClasses(userID uint) ([]dto.ClassApplicationDTO, error)
 	SearchUsersByName(name string) ([]models.User, error)
 	RemoveUserFromService(userID uint) error
+	MergeUsers(sourceID uint, targetID uint) error
 }
 
 type userServiceImpl struct {
-	userRepo repositories.UserRepository
+	userRepo   repositories.UserRepository
+	jwtService JWTService
 }
 
-func NewCreateUserService(userRepo repositories.UserRepository) UserService {
+func NewCreateUserService(userRepo repositories.UserRepository, jwtService JWTService) UserService {
 	return &userServiceImpl{
-		userRepo: userRepo,
+		userRepo:   userRepo,
+		jwtService: jwtService,
 	}
 }
 
-func (s *userServiceImpl) GetApplyingClasses(userID uint) ([]models.ClassUser, error) {
+// GetApplyingClasses はユーザーの申請中、または却下されたクラスへの参加申請を返します。
+func (s *userServiceImpl) GetApplyingClasses(userID uint) ([]dto.ClassApplicationDTO, error) {
 	exists, err := s.userRepo.UserExists(userID)
 	if err != nil {
 		return nil, err
@@ -34,13 +39,55 @@ func (s *userServiceImpl) GetApplyingClasses(userID uint) ([]models.ClassUser, e
 		return nil, errors.New(ErrUserNotFound)
 	}
 
-	return s.userRepo.GetApplyingClasses(userID)
+	applications, err := s.userRepo.GetApplyingClasses(userID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.ClassApplicationDTO, 0, len(applications))
+	for _, application := range applications {
+		result = append(result, dto.ClassApplicationDTO{
+			CID:       application.CID,
+			ClassName: application.Class.Name,
+			Image:     application.Class.Image,
+			Status:    string(application.Status),
+			Reason:    application.Reason,
+			AppliedAt: application.CreatedAt,
+			DecidedAt: application.DecidedAt,
+		})
+	}
+	return result, nil
 }
 
 func (s *userServiceImpl) SearchUsersByName(name string) ([]models.User, error) {
 	return s.userRepo.FindByName(name)
 }
 
+// RemoveUserFromService はユーザーを削除し、発行済みの全てのトークンを失効させます。
 func (s *userServiceImpl) RemoveUserFromService(userID uint) error {
-	return s.userRepo.DeleteUser(userID)
+	if err := s.userRepo.DeleteUser(userID); err != nil {
+		return err
+	}
+	return s.jwtService.RevokeAllUserTokens(userID)
+}
+
+// MergeUsers は統合元ユーザーのデータを統合先ユーザーに移動して統合元ユーザーを削除し、
+// 統合元ユーザーに発行済みの全てのトークンを失効させます。
+func (s *userServiceImpl) MergeUsers(sourceID uint, targetID uint) error {
+	if sourceID == targetID {
+		return ErrSameUser
+	}
+	for _, userID := range []uint{sourceID, targetID} {
+		exists, err := s.userRepo.UserExists(userID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrNotFound
+		}
+	}
+
+	if err := s.userRepo.MergeUsers(sourceID, targetID); err != nil {
+		return err
+	}
+	return s.jwtService.RevokeAllUserTokens(sourceID)
 }
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/tests/fakes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	repo := fakes.NewAPIKeyRepository()
+	classUserRepo := &fakes.ClassUserRepository{Roles: map[uint]map[uint]models.Role{10: {1: models.RoleAdmin}, 11: {2: models.RoleAdmin}}}
+	apiKeyService := services.NewAPIKeyService(repo, classUserRepo)
+	authzService := services.NewAuthorizationService(classUserRepo, nil)
+	jwtService := newTestJWTService(t)
+	classID := uint(10)
+
+	platformKey, err := apiKeyService.Create(1, nil, dto.CreateAPIKeyRequest{Name: "report", Scopes: []string{services.ScopeAttendanceRead}})
+	assert.NoError(t, err)
+	kioskKey, err := apiKeyService.Create(1, &classID, dto.CreateAPIKeyRequest{Name: "kiosk", Scopes: []string{services.ScopeAttendanceWrite, services.ScopeAttendanceRead}})
+	assert.NoError(t, err)
+	expired := time.Now().Add(-time.Hour)
+	expiredKey, err := apiKeyService.Create(1, nil, dto.CreateAPIKeyRequest{Name: "expired", Scopes: []string{services.ScopeAttendanceRead}, ExpiresAt: &expired})
+	assert.NoError(t, err)
+	accessToken, _ := startTestSession(t, jwtService, 2)
+
+	t.Run("Management", func(t *testing.T) {
+		_, err := apiKeyService.Create(1, nil, dto.CreateAPIKeyRequest{Name: "bad", Scopes: []string{"class:delete"}})
+		assert.ErrorIs(t, err, services.ErrInvalidScope)
+
+		_, err = apiKeyService.Create(2, &classID, dto.CreateAPIKeyRequest{Name: "kiosk", Scopes: []string{services.ScopeAttendanceWrite}})
+		assert.ErrorIs(t, err, services.ErrForbidden)
+
+		keys, err := apiKeyService.List(1, &classID)
+		assert.NoError(t, err)
+		assert.Len(t, keys, 1)
+		assert.Equal(t, kioskKey.Prefix, keys[0].Prefix)
+		assert.NotContains(t, repo.Keys[kioskKey.ID].KeyHash, kioskKey.Key)
+
+		_, err = apiKeyService.Update(1, &classID, platformKey.ID, dto.UpdateAPIKeyRequest{})
+		assert.ErrorIs(t, err, services.ErrNotFound)
+	})
+
+	ok := func(ctx *gin.Context) { ctx.JSON(http.StatusOK, gin.H{"userID": ctx.GetUint("userID")}) }
+	router := gin.New()
+	attendances := router.Group("/api/gin/attendances", middlewares.ScopedAuthMiddleware(jwtService, apiKeyService), middlewares.ClassPermissionMiddleware(authzService))
+	attendances.POST("", ok)
+	attendances.GET("/class/:classId", ok)
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		body          string
+		authorization string
+		status        int
+	}{
+		{"Read with platform key", http.MethodGet, "/api/gin/attendances/class/11", "", "ApiKey " + platformKey.Key, http.StatusOK},
+		{"Write without scope", http.MethodPost, "/api/gin/attendances", `[{"cid":10}]`, "ApiKey " + platformKey.Key, http.StatusForbidden},
+		{"Write with class key", http.MethodPost, "/api/gin/attendances", `[{"cid":10}]`, "ApiKey " + kioskKey.Key, http.StatusOK},
+		{"Write other class with class key", http.MethodPost, "/api/gin/attendances", `[{"cid":10},{"cid":11}]`, "ApiKey " + kioskKey.Key, http.StatusForbidden},
+		{"Read own class with class key", http.MethodGet, "/api/gin/attendances/class/10", "", "ApiKey " + kioskKey.Key, http.StatusOK},
+		{"Read other class with class key", http.MethodGet, "/api/gin/attendances/class/11", "", "ApiKey " + kioskKey.Key, http.StatusForbidden},
+		{"Expired key", http.MethodGet, "/api/gin/attendances/class/11", "", "ApiKey " + expiredKey.Key, http.StatusUnauthorized},
+		{"Unknown key", http.MethodGet, "/api/gin/attendances/class/11", "", "ApiKey mk_unknown", http.StatusUnauthorized},
+		{"JWT class admin", http.MethodPost, "/api/gin/attendances", `[{"cid":11}]`, "Bearer " + accessToken, http.StatusOK},
+		{"JWT non-member", http.MethodPost, "/api/gin/attendances", `[{"cid":10}]`, "Bearer " + accessToken, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", tt.authorization)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+
+	assert.NotNil(t, repo.Keys[kioskKey.ID].LastUsedAt)
+
+	assert.NoError(t, apiKeyService.Delete(1, &classID, kioskKey.ID))
+	_, err = apiKeyService.Authenticate(kioskKey.Key)
+	assert.ErrorIs(t, err, services.ErrInvalidAPIKey)
+}
@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockAttendanceRepository は出欠の登録と更新のみを実装したテスト用のAttendanceRepository
+type MockAttendanceRepository struct {
+	repositories.AttendanceRepository
+	mock.Mock
+}
+
+func (m *MockAttendanceRepository) GetAttendanceByUIDAndCSID(uid uint, csid uint) (*models.Attendance, error) {
+	args := m.Called(uid, csid)
+	attendance, _ := args.Get(0).(*models.Attendance)
+	return attendance, args.Error(1)
+}
+
+func (m *MockAttendanceRepository) CreateAttendance(attendance *models.Attendance) error {
+	return m.Called(attendance).Error(0)
+}
+
+func (m *MockAttendanceRepository) UpdateAttendance(attendance *models.Attendance) error {
+	return m.Called(attendance).Error(0)
+}
+
+// MockClassScheduleRepository はスケジュールの取得のみを実装したテスト用のClassScheduleRepository
+type MockClassScheduleRepository struct {
+	repositories.ClassScheduleRepository
+	mock.Mock
+}
+
+func (m *MockClassScheduleRepository) GetClassScheduleByID(id uint) (*models.ClassSchedule, error) {
+	args := m.Called(id)
+	schedule, _ := args.Get(0).(*models.ClassSchedule)
+	return schedule, args.Error(1)
+}
+
+func TestCreateOrUpdateAttendanceScheduleClass(t *testing.T) {
+	attendanceRepo := new(MockAttendanceRepository)
+	scheduleRepo := new(MockClassScheduleRepository)
+	scheduleRepo.On("GetClassScheduleByID", uint(1)).Return(&models.ClassSchedule{ID: 1, CID: 10}, nil)
+	scheduleRepo.On("GetClassScheduleByID", uint(2)).Return(&models.ClassSchedule{ID: 2, CID: 11}, nil)
+	scheduleRepo.On("GetClassScheduleByID", uint(3)).Return(nil, gorm.ErrRecordNotFound)
+	attendanceRepo.On("GetAttendanceByUIDAndCSID", uint(5), uint(1)).Return(&models.Attendance{ID: 7, CID: 10, UID: 5, CSID: 1}, nil)
+	attendanceRepo.On("UpdateAttendance", &models.Attendance{ID: 7, CID: 10, UID: 5, CSID: 1, IsAttendance: models.TardyStatus}).Return(nil)
+	service := services.NewAttendanceService(attendanceRepo, scheduleRepo)
+
+	assert.NoError(t, service.CreateOrUpdateAttendance(10, 5, 1, string(models.TardyStatus)))
+	// 権限を確認したクラス以外のスケジュールの出欠は変更できない
+	assert.ErrorIs(t, service.CreateOrUpdateAttendance(10, 5, 2, string(models.TardyStatus)), services.ErrNotFound)
+	assert.ErrorIs(t, service.CreateOrUpdateAttendance(10, 5, 3, string(models.TardyStatus)), services.ErrNotFound)
+
+	attendanceRepo.AssertExpectations(t)
+	attendanceRepo.AssertNotCalled(t, "GetAttendanceByUIDAndCSID", uint(5), uint(2))
+	scheduleRepo.AssertExpectations(t)
+}
@@ -0,0 +1,301 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/authorization"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAuthorizationService はクラス10のロールを固定したテスト用のAuthorizationService
+type fakeAuthorizationService struct {
+	role models.Role
+}
+
+func (s *fakeAuthorizationService) Role(uid uint, cid uint) (models.Role, error) {
+	if cid != 10 {
+		return "", nil
+	}
+	return s.role, nil
+}
+
+func (s *fakeAuthorizationService) Authorize(uid uint, cid uint, permission authorization.Permission) error {
+	role, _ := s.Role(uid, cid)
+	if !authorization.Can(role, permission) {
+		return services.ErrForbidden
+	}
+	return nil
+}
+
+func (s *fakeAuthorizationService) ClassIDOf(resource authorization.Resource, id uint) (uint, error) {
+	if id != 5 {
+		return 0, services.ErrNotFound
+	}
+	return 10, nil
+}
+
+var routeParamPattern = regexp.MustCompile(`:[A-Za-z]+`)
+
+// requestForRoute はルートのパスパラメータとクラスIDを埋めたリクエストを生成します。
+// クラスIDは10、リソースIDは5、ユーザーIDは認証ユーザー (1) 以外の2を使用します。
+func requestForRoute(method string, fullPath string, rule authorization.Rule) *http.Request {
+	path := routeParamPattern.ReplaceAllStringFunc(fullPath, func(param string) string {
+		switch param {
+		case ":cid", ":classId":
+			return "10"
+		case ":uid", ":userId", ":senderId", ":receiverId":
+			return "2"
+		case ":roleName":
+			return string(models.RoleUser)
+		case ":rename":
+			return "nickname"
+		default:
+			return "5"
+		}
+	})
+
+	var body string
+	contentType := ""
+	switch rule.Class.Kind {
+	case authorization.FromQuery:
+		path += "?" + rule.Class.Name + "=10"
+	case authorization.FromForm:
+		body = url.Values{rule.Class.Name: {"10"}}.Encode()
+		contentType = "application/x-www-form-urlencoded"
+	case authorization.FromJSON:
+		body = `[{"` + rule.Class.Name + `":10}]`
+		contentType = "application/json"
+	}
+
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req
+}
+
+func TestClassPermissionMatrix(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	admin := []models.Role{models.RoleAdmin}
+	staff := []models.Role{models.RoleAdmin, models.RoleAssistant}
+	members := []models.Role{models.RoleAdmin, models.RoleAssistant, models.RoleUser}
+	joining := []models.Role{models.RoleAdmin, models.RoleAssistant, models.RoleUser, models.RoleApplicant, models.RoleInvite}
+	everyone := append([]models.Role{models.RoleBlacklist, ""}, joining...)
+
+	expected := map[string][]models.Role{
+		"GET /api/gin/cb":                 members,
+		"GET /api/gin/cb/:id":             members,
+		"GET /api/gin/cb/announced":       members,
+		"POST /api/gin/cb":                staff,
+		"PATCH /api/gin/cb/:id/:cid/:uid": staff,
+		"DELETE /api/gin/cb/:id":          staff,
+		"GET /api/gin/cb/subscribe":       members,
+		"GET /api/gin/cb/search":          members,
+
+		"GET /api/gin/cs":        members,
+		"GET /api/gin/cs/:id":    members,
+		"POST /api/gin/cs":       staff,
+		"PATCH /api/gin/cs/:id":  staff,
+		"DELETE /api/gin/cs/:id": staff,
+		"GET /api/gin/cs/live":   members,
+		"GET /api/gin/cs/date":   members,
+
+		"POST /api/gin/attendances":                     staff,
+		"GET /api/gin/attendances/class/:classId":       staff,
+		"GET /api/gin/attendances/schedule/:scheduleId": staff,
+		"DELETE /api/gin/attendances/:id":               staff,
+
+		"GET /api/gin/cl/:cid":                 joining,
+		"POST /api/gin/cl/create":              everyone,
+		"PATCH /api/gin/cl/:uid/:cid":          admin,
+		"DELETE /api/gin/cl/:uid/:cid":         admin,
+		"GET /api/gin/cl/:cid/audit":           admin,
+		"POST /api/gin/cl/:cid/transfer":       admin,
+		"POST /api/gin/cl/:cid/members/import": admin,
+		"GET /api/gin/cl/:cid/members/export":  members,
+
+		"GET /api/gin/cu/class/:cid/members":             members,
+		"GET /api/gin/cu/class/:cid/applicants":          staff,
+		"GET /api/gin/cu/class/:cid/waitlist":            staff,
+		"POST /api/gin/cu/class/:cid/applicants/approve": staff,
+		"POST /api/gin/cu/class/:cid/applicants/reject":  staff,
+		"GET /api/gin/cu/class/:cid/bans":                admin,
+		"POST /api/gin/cu/class/:cid/bans":               admin,
+		"DELETE /api/gin/cu/class/:cid/bans/:uid":        admin,
+		"GET /api/gin/cu/:uid/:cid/info":                 members,
+		"GET /api/gin/cu/:uid/classes":                   everyone,
+		"GET /api/gin/cu/:uid/favorite-classes":          everyone,
+		"GET /api/gin/cu/:uid/classes/by-role":           everyone,
+		"PATCH /api/gin/cu/:uid/:cid/role/:roleName":     staff,
+		"PATCH /api/gin/cu/:uid/:cid/toggle-favorite":    everyone,
+		"PUT /api/gin/cu/:uid/:cid/:rename":              admin,
+		"DELETE /api/gin/cu/:uid/:cid/remove":            admin,
+		"GET /api/gin/cu/:uid/classes/search":            everyone,
+
+		"POST /api/gin/chat/create-room/:scheduleId":    staff,
+		"GET /api/gin/chat/room/:scheduleId/:userId":    members,
+		"POST /api/gin/chat/room/:scheduleId":           members,
+		"DELETE /api/gin/chat/room/:scheduleId":         staff,
+		"GET /api/gin/chat/stream/:scheduleId":          members,
+		"GET /api/gin/chat/messages/:roomid":            members,
+		"POST /api/gin/chat/dm/:senderId/:receiverId":   everyone,
+		"GET /api/gin/chat/dm/:senderId/:receiverId":    everyone,
+		"DELETE /api/gin/chat/dm/:senderId/:receiverId": everyone,
+
+		"GET /api/gin/api-keys/class/:cid":        admin,
+		"POST /api/gin/api-keys/class/:cid":       admin,
+		"PATCH /api/gin/api-keys/class/:cid/:id":  admin,
+		"DELETE /api/gin/api-keys/class/:cid/:id": admin,
+
+		"GET /api/gin/cc/class/:cid/codes":                 admin,
+		"POST /api/gin/cc/class/:cid/codes":                admin,
+		"POST /api/gin/cc/class/:cid/codes/:id/regenerate": admin,
+		"DELETE /api/gin/cc/class/:cid/codes/:id":          admin,
+		"PUT /api/gin/cc/class/:cid/codes/:id/secret":      admin,
+
+		"GET /api/gin/invitations/class/:cid":           admin,
+		"POST /api/gin/invitations/class/:cid":          admin,
+		"POST /api/gin/invitations/class/:cid/links":    admin,
+		"DELETE /api/gin/invitations/class/:cid/:id":    admin,
+		"POST /api/gin/invitations/class/:cid/accept":   everyone,
+		"POST /api/gin/invitations/class/:cid/decline":  everyone,
+		"POST /api/gin/invitations/links/:token/accept": everyone,
+	}
+	assert.Len(t, expected, len(authorization.Routes), "全てのルートの期待値を定義してください")
+
+	ok := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+	for key, rule := range authorization.Routes {
+		allowed, defined := expected[key]
+		if !assert.True(t, defined, key) {
+			continue
+		}
+		method, fullPath, _ := strings.Cut(key, " ")
+
+		for _, role := range append([]models.Role{""}, models.Roles...) {
+			authz := &fakeAuthorizationService{role: role}
+			router := gin.New()
+			router.Handle(method, fullPath, func(ctx *gin.Context) { ctx.Set("userID", uint(1)) }, middlewares.ClassPermissionMiddleware(authz), ok)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, requestForRoute(method, fullPath, rule))
+
+			status := http.StatusForbidden
+			for _, r := range allowed {
+				if r == role {
+					status = http.StatusOK
+				}
+			}
+			assert.Equal(t, status, w.Code, "%s as %q", key, role)
+		}
+	}
+}
+
+func TestClassPermissionMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ok := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+	router := gin.New()
+	api := router.Group("/api/gin", func(ctx *gin.Context) { ctx.Set("userID", uint(2)) }, middlewares.ClassPermissionMiddleware(&fakeAuthorizationService{role: models.RoleUser}))
+	api.GET("/cb/:id", ok)
+	api.GET("/cb", ok)
+	api.PUT("/cu/:uid/:cid/:rename", ok)
+	api.GET("/unregistered", ok)
+
+	tests := []struct {
+		name   string
+		path   string
+		method string
+		status int
+		error  string
+	}{
+		{"Rename self", "/api/gin/cu/2/10/nickname", http.MethodPut, http.StatusOK, ""},
+		{"Rename another member", "/api/gin/cu/3/10/nickname", http.MethodPut, http.StatusForbidden, ""},
+		{"Unknown resource", "/api/gin/cb/6", http.MethodGet, http.StatusNotFound, constants.ResourceNotFound},
+		{"Invalid class ID", "/api/gin/cb?cid=abc", http.MethodGet, http.StatusBadRequest, ""},
+		{"Other class", "/api/gin/cb?cid=11", http.MethodGet, http.StatusForbidden, ""},
+		{"Unregistered route", "/api/gin/unregistered", http.MethodGet, http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.error != "" {
+				assert.Contains(t, w.Body.String(), tt.error)
+			}
+		})
+	}
+}
+
+func TestClassPermissionJSONBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var bound []controllers.AttendanceInput
+	router := gin.New()
+	api := router.Group("/api/gin", func(ctx *gin.Context) { ctx.Set("userID", uint(2)) }, middlewares.ClassPermissionMiddleware(&fakeAuthorizationService{role: models.RoleAdmin}))
+	api.POST("/attendances", func(ctx *gin.Context) {
+		bound = nil
+		if err := ctx.ShouldBindBodyWith(&bound, binding.JSON); err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return
+		}
+		ctx.Status(http.StatusOK)
+	})
+
+	// encoding/json はキーを大文字小文字を区別せずに照合するため、権限を確認したクラスと別のクラスが読み込まれないようにする
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"Own class", `[{"cid":10,"uid":3}]`, http.StatusOK},
+		{"Differently cased duplicate", `[{"cid":10,"CID":11}]`, http.StatusBadRequest},
+		{"Differently cased key", `[{"CID":11}]`, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/api/gin/attendances", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, []controllers.AttendanceInput{{CID: 10, UID: 3}}, bound)
+			}
+		})
+	}
+}
+
+func TestUpdateNotifierClassScoped(t *testing.T) {
+	notifier := services.NewUpdateNotifier()
+	member := httptest.NewRecorder()
+	other := httptest.NewRecorder()
+	notifier.Register <- services.BoardSubscriber{CID: 10, Writer: member}
+	notifier.Register <- services.BoardSubscriber{CID: 11, Writer: other}
+
+	notifier.Broadcast <- services.BoardUpdate{CID: 10, Message: []byte("data: updated\n\n")}
+	// 送信が終わってから登録解除が処理される
+	notifier.Unregister <- member
+	notifier.Unregister <- other
+
+	assert.Equal(t, "data: updated\n\n", member.Body.String())
+	assert.Empty(t, other.Body.String())
+}
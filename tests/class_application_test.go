@@ -0,0 +1,181 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/tests/fakes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// memoryClassApplicationRepository は fakes.ClassUserRepository のロールを使うテスト用のClassApplicationRepository
+// limit が0より大きい場合、承認済みのメンバーが limit 人以上いれば承認したユーザーをキャンセル待ちにします。
+type memoryClassApplicationRepository struct {
+	classUserRepo *fakes.ClassUserRepository
+	reasons       map[uint]*string
+	limit         int
+	waitlist      []uint
+}
+
+func (r *memoryClassApplicationRepository) FindPending(cid uint) ([]models.ClassApplication, error) {
+	var applications []models.ClassApplication
+	for uid, role := range r.classUserRepo.Roles[cid] {
+		if role == models.RoleApplicant {
+			applications = append(applications, models.ClassApplication{CID: cid, UID: uid, User: models.User{ID: uid}})
+		}
+	}
+	return applications, nil
+}
+
+func (r *memoryClassApplicationRepository) FindWaitlisted(cid uint) ([]models.ClassApplication, error) {
+	var applications []models.ClassApplication
+	for _, uid := range r.waitlist {
+		applications = append(applications, models.ClassApplication{CID: cid, UID: uid, User: models.User{ID: uid}})
+	}
+	return applications, nil
+}
+
+func (r *memoryClassApplicationRepository) Decide(actorID uint, cid uint, uids []uint, approve bool, reason *string) ([]uint, error) {
+	for _, uid := range uids {
+		if r.classUserRepo.Roles[cid][uid] != models.RoleApplicant {
+			return nil, gorm.ErrRecordNotFound
+		}
+	}
+	waitlisted := []uint{}
+	for _, uid := range uids {
+		r.reasons[uid] = reason
+		switch {
+		case approve && r.limit > 0 && r.activeMembers(cid) >= r.limit:
+			r.waitlist = append(r.waitlist, uid)
+			waitlisted = append(waitlisted, uid)
+		case approve:
+			r.classUserRepo.Roles[cid][uid] = models.RoleUser
+		default:
+			delete(r.classUserRepo.Roles[cid], uid)
+		}
+	}
+	return waitlisted, nil
+}
+
+func (r *memoryClassApplicationRepository) activeMembers(cid uint) int {
+	count := 0
+	for _, role := range r.classUserRepo.Roles[cid] {
+		if role.Active() {
+			count++
+		}
+	}
+	return count
+}
+
+func TestClassApplications(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	classUserRepo := fakes.NewClassUserRepository(10, map[uint]models.Role{
+		1: models.RoleAdmin,
+		2: models.RoleUser,
+		3: models.RoleApplicant,
+		4: models.RoleApplicant,
+		5: models.RoleBlacklist,
+	})
+	repo := &memoryClassApplicationRepository{classUserRepo: classUserRepo, reasons: map[uint]*string{}}
+	service := services.NewClassApplicationService(repo, classUserRepo)
+
+	t.Run("Apply", func(t *testing.T) {
+		assert.NoError(t, service.Apply(6, 10))
+		assert.Equal(t, models.RoleApplicant, classUserRepo.Roles[10][6])
+		assert.NoError(t, service.Apply(6, 10))
+		assert.ErrorIs(t, service.Apply(2, 10), services.ErrAlreadyMember)
+		assert.ErrorIs(t, service.Apply(5, 10), services.ErrForbidden)
+		delete(classUserRepo.Roles[10], 6)
+	})
+
+	controller := controllers.NewClassApplicationController(service)
+	router := gin.New()
+	setUser := func(ctx *gin.Context) {
+		uid, _ := strconv.Atoi(ctx.GetHeader("X-User-ID"))
+		ctx.Set("userID", uint(uid))
+	}
+	router.GET("/cu/class/:cid/applicants", setUser, controller.ListApplicants)
+	router.GET("/cu/class/:cid/waitlist", setUser, controller.ListWaitlist)
+	router.POST("/cu/class/:cid/applicants/approve", setUser, controller.ApproveApplicants)
+	router.POST("/cu/class/:cid/applicants/reject", setUser, controller.RejectApplicants)
+
+	request := func(userID uint, method string, path string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", strconv.Itoa(int(userID)))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("List", func(t *testing.T) {
+		w := request(1, http.MethodGet, "/cu/class/10/applicants", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data []dto.ApplicantDTO `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Len(t, response.Data, 2)
+
+		assert.Equal(t, http.StatusForbidden, request(2, http.MethodGet, "/cu/class/10/applicants", "").Code)
+	})
+
+	t.Run("Decide", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			userID uint
+			path   string
+			body   string
+			status int
+		}{
+			{"Empty", 1, "/cu/class/10/applicants/approve", `{"uids":[]}`, http.StatusBadRequest},
+			{"Not an applicant", 1, "/cu/class/10/applicants/approve", `{"uids":[3,2]}`, http.StatusNotFound},
+			{"Not allowed", 2, "/cu/class/10/applicants/approve", `{"uids":[3]}`, http.StatusForbidden},
+			{"Approve", 1, "/cu/class/10/applicants/approve", `{"uids":[3,3]}`, http.StatusOK},
+			{"Reject", 1, "/cu/class/10/applicants/reject", `{"uids":[4],"reason":"定員に達しました"}`, http.StatusOK},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				assert.Equal(t, tt.status, request(tt.userID, http.MethodPost, tt.path, tt.body).Code)
+			})
+		}
+
+		assert.Equal(t, models.RoleUser, classUserRepo.Roles[10][3])
+		assert.Nil(t, repo.reasons[3])
+		assert.NotContains(t, classUserRepo.Roles[10], uint(4))
+		assert.Equal(t, "定員に達しました", *repo.reasons[4])
+	})
+
+	t.Run("Waitlist", func(t *testing.T) {
+		repo.limit = 3
+		classUserRepo.Roles[10][7] = models.RoleApplicant
+
+		w := request(1, http.MethodPost, "/cu/class/10/applicants/approve", `{"uids":[7]}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data struct {
+				Waitlisted []uint `json:"waitlisted"`
+			} `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, []uint{7}, response.Data.Waitlisted)
+		assert.Equal(t, models.RoleApplicant, classUserRepo.Roles[10][7])
+
+		w = request(1, http.MethodGet, "/cu/class/10/waitlist", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"uid":7`)
+		assert.Equal(t, http.StatusForbidden, request(2, http.MethodGet, "/cu/class/10/waitlist", "").Code)
+	})
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/pagination"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/tests/fakes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockClassAuditRepository はテスト用のClassAuditRepository
+type MockClassAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockClassAuditRepository) FindByClass(cid uint, query dto.ClassAuditQuery, window pagination.Window) ([]models.ClassAuditEvent, error) {
+	args := m.Called(cid, query, window)
+	events, _ := args.Get(0).([]models.ClassAuditEvent)
+	return events, args.Error(1)
+}
+
+func TestClassAuditEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	events := map[uint]models.ClassAuditEvent{}
+	for i := 1; i <= 5; i++ {
+		events[uint(i)] = models.ClassAuditEvent{
+			ID:        uint(i),
+			CID:       10,
+			ActorID:   1,
+			TargetID:  uint(i%2 + 2),
+			Action:    models.AuditRoleChanged,
+			OldValue:  string(models.RoleApplicant),
+			NewValue:  string(models.RoleUser),
+			CreatedAt: time.Now(),
+		}
+	}
+	target := uint(3)
+
+	// リポジトリは次のページがあるかを判定するため1件多く取得する
+	repo := new(MockClassAuditRepository)
+	repo.On("FindByClass", uint(10), mock.Anything, pagination.Window{Limit: 3}).
+		Return([]models.ClassAuditEvent{events[5], events[4], events[3]}, nil)
+	repo.On("FindByClass", uint(10), mock.Anything, pagination.Window{AfterID: 4, Limit: 3}).
+		Return([]models.ClassAuditEvent{events[3], events[2], events[1]}, nil)
+	repo.On("FindByClass", uint(10), mock.Anything, pagination.Window{AfterID: 2, Limit: 3}).
+		Return([]models.ClassAuditEvent{events[1]}, nil)
+	repo.On("FindByClass", uint(10), dto.ClassAuditQuery{Action: string(models.AuditRoleChanged), TargetID: &target}, pagination.Window{Limit: 21}).
+		Return([]models.ClassAuditEvent{events[5], events[3], events[1]}, nil)
+
+	service := services.NewClassAuditService(repo, fakes.NewClassUserRepository(10, map[uint]models.Role{1: models.RoleAdmin}))
+	controller := controllers.NewClassAuditController(service)
+
+	router := gin.New()
+	router.GET("/cl/:cid/audit", func(ctx *gin.Context) {
+		uid, _ := strconv.Atoi(ctx.GetHeader("X-User-ID"))
+		ctx.Set("userID", uint(uid))
+	}, controller.ListClassAuditEvents)
+
+	get := func(userID uint, query string) (*httptest.ResponseRecorder, pagination.Page[dto.ClassAuditEventDTO]) {
+		req, _ := http.NewRequest(http.MethodGet, "/cl/10/audit"+query, nil)
+		req.Header.Set("X-User-ID", strconv.Itoa(int(userID)))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response struct {
+			Data pagination.Page[dto.ClassAuditEventDTO] `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response.Data
+	}
+
+	t.Run("Pagination", func(t *testing.T) {
+		w, page := get(1, "?limit=2")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, page.Items, 2)
+		assert.Equal(t, uint(5), page.Items[0].ID)
+		assert.True(t, page.HasMore)
+
+		_, page = get(1, "?limit=2&cursor="+*page.NextCursor)
+		assert.Equal(t, []uint{3, 2}, []uint{page.Items[0].ID, page.Items[1].ID})
+
+		_, page = get(1, "?limit=2&cursor="+*page.NextCursor)
+		assert.Len(t, page.Items, 1)
+		assert.False(t, page.HasMore)
+		assert.Nil(t, page.NextCursor)
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		_, page := get(1, "?target_id=3&action=member.role_changed")
+		assert.Len(t, page.Items, 3)
+		for _, event := range page.Items {
+			assert.Equal(t, uint(3), event.TargetID)
+		}
+	})
+
+	t.Run("Invalid query", func(t *testing.T) {
+		for _, query := range []string{"?limit=1000", "?cursor=4"} {
+			w, _ := get(1, query)
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+	})
+
+	t.Run("Not an admin", func(t *testing.T) {
+		w, _ := get(2, "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/tests/fakes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// memoryClassBanRepository は fakes.ClassUserRepository のロールを使うテスト用のClassBanRepository
+type memoryClassBanRepository struct {
+	classUserRepo *fakes.ClassUserRepository
+	owner         uint
+	bans          map[uint]models.ClassBan // uid -> 有効な利用停止
+}
+
+func (r *memoryClassBanRepository) Ban(ban *models.ClassBan) error {
+	if ban.UID == r.owner {
+		return repositories.ErrBanOwner
+	}
+	if r.classUserRepo.LastAdmin(ban.UID, ban.CID, models.RoleBlacklist) {
+		return repositories.ErrLastAdmin
+	}
+	r.classUserRepo.Roles[ban.CID][ban.UID] = models.RoleBlacklist
+	r.bans[ban.UID] = *ban
+	return nil
+}
+
+func (r *memoryClassBanRepository) FindActive(cid uint) ([]models.ClassBan, error) {
+	var bans []models.ClassBan
+	for _, ban := range r.bans {
+		if ban.CID == cid {
+			bans = append(bans, ban)
+		}
+	}
+	return bans, nil
+}
+
+func (r *memoryClassBanRepository) Lift(actorID uint, uid uint, cid uint) error {
+	if _, ok := r.bans[uid]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.bans, uid)
+	delete(r.classUserRepo.Roles[cid], uid)
+	return nil
+}
+
+func (r *memoryClassBanRepository) LiftExpired(now time.Time) (int, error) {
+	lifted := 0
+	for uid, ban := range r.bans {
+		if ban.ExpiresAt != nil && !ban.ExpiresAt.After(now) {
+			if err := r.Lift(ban.BannedBy, uid, ban.CID); err != nil {
+				return lifted, err
+			}
+			lifted++
+		}
+	}
+	return lifted, nil
+}
+
+func TestClassBans(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	classUserRepo := fakes.NewClassUserRepository(10, map[uint]models.Role{
+		1: models.RoleAdmin,
+		2: models.RoleAssistant,
+		3: models.RoleUser,
+		5: models.RoleAssistant,
+	})
+	// オーナーではない管理者が1人だけの場合 (オーナーが退出した場合など) も最後の管理者は利用停止にできない
+	repo := &memoryClassBanRepository{classUserRepo: classUserRepo, owner: 5, bans: map[uint]models.ClassBan{}}
+	service := services.NewClassBanService(repo, classUserRepo)
+	controller := controllers.NewClassBanController(service)
+
+	router := gin.New()
+	setUser := func(ctx *gin.Context) {
+		uid, _ := strconv.Atoi(ctx.GetHeader("X-User-ID"))
+		ctx.Set("userID", uint(uid))
+	}
+	router.GET("/cu/class/:cid/bans", setUser, controller.ListBans)
+	router.POST("/cu/class/:cid/bans", setUser, controller.BanUser)
+	router.DELETE("/cu/class/:cid/bans/:uid", setUser, controller.LiftBan)
+
+	request := func(userID uint, method string, path string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", strconv.Itoa(int(userID)))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Ban", func(t *testing.T) {
+		past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+		tests := []struct {
+			name   string
+			userID uint
+			body   string
+			status int
+			error  string
+		}{
+			{"Not allowed", 2, `{"uid":3}`, http.StatusForbidden, ""},
+			{"Expired", 1, `{"uid":3,"expires_at":"` + past + `"}`, http.StatusBadRequest, ""},
+			{"Last admin", 1, `{"uid":1}`, http.StatusConflict, constants.LastAdmin},
+			{"Class owner", 1, `{"uid":5}`, http.StatusConflict, constants.BanOwner},
+			{"Ban member", 1, `{"uid":3,"reason":"荒らし行為"}`, http.StatusOK, ""},
+			{"Ban non-member", 1, `{"uid":4}`, http.StatusOK, ""},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := request(tt.userID, http.MethodPost, "/cu/class/10/bans", tt.body)
+				assert.Equal(t, tt.status, w.Code)
+				assert.Contains(t, w.Body.String(), tt.error)
+			})
+		}
+		assert.Equal(t, models.RoleBlacklist, classUserRepo.Roles[10][3])
+		assert.Equal(t, "荒らし行為", *repo.bans[3].Reason)
+
+		w := request(1, http.MethodGet, "/cu/class/10/bans", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data []dto.ClassBanDTO `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Len(t, response.Data, 2)
+	})
+
+	t.Run("Join refused", func(t *testing.T) {
+		applications := services.NewClassApplicationService(&memoryClassApplicationRepository{classUserRepo: classUserRepo}, classUserRepo)
+		assert.ErrorIs(t, applications.Apply(4, 10), services.ErrForbidden)
+
+		invitations := services.NewClassInvitationService(&memoryClassInvitationRepository{classUserRepo: classUserRepo}, classUserRepo)
+		_, err := invitations.Invite(1, 10, dto.InviteUserRequest{UID: 4, Role: models.RoleUser})
+		assert.ErrorIs(t, err, services.ErrForbidden)
+	})
+
+	t.Run("Lift", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, request(2, http.MethodDelete, "/cu/class/10/bans/4", "").Code)
+		assert.Equal(t, http.StatusOK, request(1, http.MethodDelete, "/cu/class/10/bans/4", "").Code)
+		assert.Equal(t, http.StatusNotFound, request(1, http.MethodDelete, "/cu/class/10/bans/4", "").Code)
+		assert.NotContains(t, classUserRepo.Roles[10], uint(4))
+	})
+
+	t.Run("Lift expired", func(t *testing.T) {
+		expired := time.Now().Add(-time.Minute)
+		ban := repo.bans[3]
+		ban.ExpiresAt = &expired
+		repo.bans[3] = ban
+
+		lifted, err := service.LiftExpired()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, lifted)
+		assert.NotContains(t, classUserRepo.Roles[10], uint(3))
+	})
+}
@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/tests/fakes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassCodes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	classUserRepo := fakes.NewClassUserRepository(10, map[uint]models.Role{
+		1: models.RoleAdmin,
+		2: models.RoleAssistant,
+		3: models.RoleApplicant,
+		4: models.RoleBlacklist,
+	})
+	repo := &fakes.ClassCodeRepository{ClassUserRepo: classUserRepo}
+	service := services.NewClassCodeService(repo, classUserRepo)
+
+	controller := controllers.NewClassCodeController(service)
+	router := gin.New()
+	setUser := func(ctx *gin.Context) {
+		uid, _ := strconv.Atoi(ctx.GetHeader("X-User-ID"))
+		ctx.Set("userID", uint(uid))
+	}
+	router.GET("/cc/class/:cid/codes", setUser, controller.ListClassCodes)
+	router.POST("/cc/class/:cid/codes", setUser, controller.CreateClassCode)
+	router.POST("/cc/class/:cid/codes/:id/regenerate", setUser, controller.RegenerateClassCode)
+	router.DELETE("/cc/class/:cid/codes/:id", setUser, controller.RevokeClassCode)
+	router.PUT("/cc/class/:cid/codes/:id/secret", setUser, controller.UpdateClassCodeSecret)
+
+	request := func(userID uint, method string, path string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", strconv.Itoa(int(userID)))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	create := func(body string) dto.ClassCodeDTO {
+		w := request(1, http.MethodPost, "/cc/class/10/codes", body)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response struct {
+			Data dto.ClassCodeDTO `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Data
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+		tests := []struct {
+			name   string
+			userID uint
+			body   string
+			status int
+		}{
+			{"Not allowed", 2, `{"role":"USER"}`, http.StatusForbidden},
+			{"Admin role", 1, `{"role":"ADMIN"}`, http.StatusBadRequest},
+			{"Expired", 1, `{"role":"USER","expires_at":"` + past + `"}`, http.StatusBadRequest},
+			{"No uses", 1, `{"role":"USER","max_uses":0}`, http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				assert.Equal(t, tt.status, request(tt.userID, http.MethodPost, "/cc/class/10/codes", tt.body).Code)
+			})
+		}
+
+		code := create(`{"role":"ASSISTANT","secret":"ta","max_uses":1}`)
+		assert.Equal(t, models.RoleAssistant, code.Role)
+		assert.True(t, code.HasSecret)
+		assert.True(t, code.Usable)
+		assert.Len(t, code.Code, 6)
+		assert.NotContains(t, request(1, http.MethodGet, "/cc/class/10/codes", "").Body.String(), `"ta"`)
+	})
+
+	t.Run("Redeem", func(t *testing.T) {
+		student := create(`{"role":"APPLICANT"}`)
+		ta := create(`{"role":"ASSISTANT","max_uses":1}`)
+
+		redeem := func(uid uint, code string) error {
+			classCode, err := service.FindClassCode(code)
+			if err != nil {
+				return err
+			}
+			return service.Redeem(uid, classCode)
+		}
+
+		assert.NoError(t, redeem(5, student.Code))
+		assert.Equal(t, models.RoleApplicant, classUserRepo.Roles[10][5])
+		// 申請中のユーザーが申請のコードを使用しても回数は増えない
+		assert.NoError(t, redeem(5, student.Code))
+		assert.Equal(t, 1, repo.Find(10, student.ID).Uses)
+
+		// 申請中のユーザーはアシスタントのコードで直接参加できる
+		assert.NoError(t, redeem(3, ta.Code))
+		assert.Equal(t, models.RoleAssistant, classUserRepo.Roles[10][3])
+		assert.Error(t, redeem(6, ta.Code), "使用回数の上限に達したコードは使用できない")
+
+		assert.ErrorIs(t, redeem(4, student.Code), services.ErrForbidden)
+		assert.ErrorIs(t, redeem(2, student.Code), services.ErrAlreadyMember)
+	})
+
+	t.Run("Revoke and regenerate", func(t *testing.T) {
+		code := create(`{"role":"USER"}`)
+		path := "/cc/class/10/codes/" + strconv.Itoa(int(code.ID))
+
+		assert.Equal(t, http.StatusForbidden, request(2, http.MethodDelete, path, "").Code)
+		assert.Equal(t, http.StatusNotFound, request(1, http.MethodDelete, "/cc/class/10/codes/999", "").Code)
+		assert.Equal(t, http.StatusOK, request(1, http.MethodDelete, path, "").Code)
+		_, err := service.FindClassCode(code.Code)
+		assert.Error(t, err)
+
+		w := request(1, http.MethodPost, path+"/regenerate", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data dto.ClassCodeDTO `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.NotEqual(t, code.Code, response.Data.Code)
+		assert.True(t, response.Data.Enabled)
+		_, err = service.FindClassCode(response.Data.Code)
+		assert.NoError(t, err)
+	})
+	t.Run("Secret", func(t *testing.T) {
+		code := create(`{"role":"USER","secret":"pass"}`)
+		stored := repo.Find(10, code.ID).Secret
+		assert.NotEqual(t, "pass", *stored, "シークレットは平文で保存しない")
+
+		valid, err := service.VerifyClassCode(code.Code, "pass")
+		assert.NoError(t, err)
+		assert.True(t, valid)
+		valid, err = service.VerifyClassCode(code.Code, "wrong")
+		assert.NoError(t, err)
+		assert.False(t, valid)
+
+		// コードを変えずにシークレットを変更・削除できる
+		path := "/cc/class/10/codes/" + strconv.Itoa(int(code.ID)) + "/secret"
+		assert.Equal(t, http.StatusForbidden, request(2, http.MethodPut, path, `{"secret":"new"}`).Code)
+		assert.Equal(t, http.StatusNotFound, request(1, http.MethodPut, "/cc/class/10/codes/999/secret", `{"secret":"new"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, request(1, http.MethodPut, path, `{"secret":"`+strings.Repeat("a", 21)+`"}`).Code)
+		assert.Equal(t, http.StatusOK, request(1, http.MethodPut, path, `{"secret":"new"}`).Code)
+		classCode, err := service.FindClassCode(code.Code)
+		assert.NoError(t, err)
+		assert.False(t, service.VerifySecret(classCode, "pass"))
+		assert.True(t, service.VerifySecret(classCode, "new"))
+
+		w := request(1, http.MethodPut, path, `{"secret":null}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data dto.ClassCodeDTO `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, code.Code, response.Data.Code)
+		assert.False(t, response.Data.HasSecret)
+	})
+
+	t.Run("Hash plaintext secrets", func(t *testing.T) {
+		plaintext := "legacy"
+		legacy := models.ClassCode{Code: "LEGACY", CID: 10, Role: models.RoleUser, Enabled: true, Secret: &plaintext}
+		assert.NoError(t, repo.SaveClassCode(&legacy))
+
+		// 移行前の平文のシークレットも検証できる
+		classCode, err := service.FindClassCode("LEGACY")
+		assert.NoError(t, err)
+		assert.True(t, service.VerifySecret(classCode, "legacy"))
+
+		migrated, err := services.HashClassCodeSecrets(repo)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, migrated)
+		assert.NotEqual(t, "legacy", *repo.Find(10, legacy.ID).Secret)
+		classCode, err = service.FindClassCode("LEGACY")
+		assert.NoError(t, err)
+		assert.True(t, service.VerifySecret(classCode, "legacy"))
+		assert.False(t, service.VerifySecret(classCode, "wrong"))
+
+		migrated, err = services.HashClassCodeSecrets(repo)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, migrated, "ハッシュのシークレットは変更しない")
+	})
+}
@@ -0,0 +1,207 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/tests/fakes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// memoryClassInvitationRepository は fakes.ClassUserRepository のロールを使うテスト用のClassInvitationRepository
+type memoryClassInvitationRepository struct {
+	classUserRepo *fakes.ClassUserRepository
+	invitations   []models.ClassInvitation
+}
+
+func (r *memoryClassInvitationRepository) InviteUser(invitation *models.ClassInvitation) error {
+	r.classUserRepo.Roles[invitation.CID][*invitation.InviteeID] = models.RoleInvite
+	return r.CreateLink(invitation)
+}
+
+func (r *memoryClassInvitationRepository) CreateLink(invitation *models.ClassInvitation) error {
+	invitation.ID = uint(len(r.invitations) + 1)
+	r.invitations = append(r.invitations, *invitation)
+	return nil
+}
+
+func (r *memoryClassInvitationRepository) FindPending(cid uint) ([]models.ClassInvitation, error) {
+	var invitations []models.ClassInvitation
+	for _, invitation := range r.invitations {
+		if invitation.CID == cid && invitation.Usable(time.Now()) {
+			invitations = append(invitations, invitation)
+		}
+	}
+	return invitations, nil
+}
+
+func (r *memoryClassInvitationRepository) FindPendingForUser(uid uint, cid uint) (*models.ClassInvitation, error) {
+	for i, invitation := range r.invitations {
+		if invitation.CID == cid && invitation.InviteeID != nil && *invitation.InviteeID == uid && invitation.Status == models.InvitationPending {
+			return &r.invitations[i], nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryClassInvitationRepository) FindByTokenHash(tokenHash string) (*models.ClassInvitation, error) {
+	for i, invitation := range r.invitations {
+		if invitation.TokenHash != nil && *invitation.TokenHash == tokenHash {
+			return &r.invitations[i], nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryClassInvitationRepository) Accept(uid uint, invitationID uint) error {
+	invitation := &r.invitations[invitationID-1]
+	if !invitation.Usable(time.Now()) {
+		return gorm.ErrRecordNotFound
+	}
+	r.classUserRepo.Roles[invitation.CID][uid] = invitation.Role
+	invitation.Status = models.InvitationAccepted
+	return nil
+}
+
+func (r *memoryClassInvitationRepository) Decline(uid uint, invitationID uint) error {
+	invitation := &r.invitations[invitationID-1]
+	delete(r.classUserRepo.Roles[invitation.CID], uid)
+	invitation.Status = models.InvitationDeclined
+	return nil
+}
+
+func (r *memoryClassInvitationRepository) Revoke(actorID uint, cid uint, invitationID uint) error {
+	if int(invitationID) > len(r.invitations) || r.invitations[invitationID-1].CID != cid {
+		return gorm.ErrRecordNotFound
+	}
+	r.invitations[invitationID-1].Status = models.InvitationRevoked
+	return nil
+}
+
+func TestClassInvitations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	classUserRepo := fakes.NewClassUserRepository(10, map[uint]models.Role{
+		1: models.RoleAdmin,
+		2: models.RoleAssistant,
+		3: models.RoleUser,
+		4: models.RoleBlacklist,
+	})
+	repo := &memoryClassInvitationRepository{classUserRepo: classUserRepo}
+	controller := controllers.NewClassInvitationController(services.NewClassInvitationService(repo, classUserRepo))
+
+	router := gin.New()
+	setUser := func(ctx *gin.Context) {
+		uid, _ := strconv.Atoi(ctx.GetHeader("X-User-ID"))
+		ctx.Set("userID", uint(uid))
+	}
+	router.GET("/invitations/class/:cid", setUser, controller.ListInvitations)
+	router.POST("/invitations/class/:cid", setUser, controller.InviteUser)
+	router.POST("/invitations/class/:cid/links", setUser, controller.CreateInvitationLink)
+	router.DELETE("/invitations/class/:cid/:id", setUser, controller.RevokeInvitation)
+	router.POST("/invitations/class/:cid/accept", setUser, controller.AcceptInvitation)
+	router.POST("/invitations/class/:cid/decline", setUser, controller.DeclineInvitation)
+	router.POST("/invitations/links/:token/accept", setUser, controller.AcceptInvitationLink)
+
+	request := func(userID uint, method string, path string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", strconv.Itoa(int(userID)))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	expiresAt := func(d time.Duration) string {
+		return time.Now().Add(d).UTC().Format(time.RFC3339)
+	}
+
+	t.Run("Invite", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			userID uint
+			body   string
+			status int
+		}{
+			{"Not allowed", 2, `{"uid":5,"role":"USER"}`, http.StatusForbidden},
+			{"Invalid role", 1, `{"uid":5,"role":"ADMIN"}`, http.StatusBadRequest},
+			{"Expired", 1, `{"uid":5,"role":"USER","expires_at":"` + expiresAt(-time.Hour) + `"}`, http.StatusBadRequest},
+			{"Already member", 1, `{"uid":3,"role":"USER"}`, http.StatusConflict},
+			{"Blacklisted", 1, `{"uid":4,"role":"USER"}`, http.StatusForbidden},
+			{"Invite", 1, `{"uid":5,"role":"ASSISTANT"}`, http.StatusCreated},
+			{"Invite another", 1, `{"uid":6,"role":"USER"}`, http.StatusCreated},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				assert.Equal(t, tt.status, request(tt.userID, http.MethodPost, "/invitations/class/10", tt.body).Code)
+			})
+		}
+		assert.Equal(t, models.RoleInvite, classUserRepo.Roles[10][5])
+	})
+
+	t.Run("Accept and decline", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, request(3, http.MethodPost, "/invitations/class/10/accept", "").Code)
+
+		assert.Equal(t, http.StatusOK, request(5, http.MethodPost, "/invitations/class/10/accept", "").Code)
+		assert.Equal(t, models.RoleAssistant, classUserRepo.Roles[10][5])
+		assert.Equal(t, http.StatusNotFound, request(5, http.MethodPost, "/invitations/class/10/accept", "").Code)
+
+		assert.Equal(t, http.StatusOK, request(6, http.MethodPost, "/invitations/class/10/decline", "").Code)
+		assert.NotContains(t, classUserRepo.Roles[10], uint(6))
+	})
+
+	t.Run("Link", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, request(1, http.MethodPost, "/invitations/class/10/links", `{"role":"USER"}`).Code)
+
+		w := request(1, http.MethodPost, "/invitations/class/10/links", `{"role":"USER","expires_at":"`+expiresAt(time.Hour)+`"}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response struct {
+			Data dto.CreatedInvitationLinkDTO `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.True(t, strings.HasPrefix(response.Data.Token, "inv_"))
+		assert.Nil(t, response.Data.InviteeID)
+
+		// トークンそのものは保存されない
+		assert.NotEqual(t, response.Data.Token, *repo.invitations[response.Data.ID-1].TokenHash)
+
+		path := "/invitations/links/" + response.Data.Token + "/accept"
+		assert.Equal(t, http.StatusForbidden, request(4, http.MethodPost, path, "").Code)
+		assert.Equal(t, http.StatusConflict, request(3, http.MethodPost, path, "").Code)
+		assert.Equal(t, http.StatusOK, request(7, http.MethodPost, path, "").Code)
+		assert.Equal(t, models.RoleUser, classUserRepo.Roles[10][7])
+
+		// リンクは1回だけ使用できる
+		assert.Equal(t, http.StatusNotFound, request(8, http.MethodPost, path, "").Code)
+		assert.Equal(t, http.StatusNotFound, request(8, http.MethodPost, "/invitations/links/inv_unknown/accept", "").Code)
+	})
+
+	t.Run("List and revoke", func(t *testing.T) {
+		assert.Equal(t, http.StatusCreated, request(1, http.MethodPost, "/invitations/class/10", `{"uid":8,"role":"USER"}`).Code)
+
+		w := request(1, http.MethodGet, "/invitations/class/10", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data []dto.ClassInvitationDTO `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Len(t, response.Data, 1)
+		assert.Equal(t, http.StatusForbidden, request(2, http.MethodGet, "/invitations/class/10", "").Code)
+
+		id := strconv.Itoa(int(response.Data[0].ID))
+		assert.Equal(t, http.StatusOK, request(1, http.MethodDelete, "/invitations/class/10/"+id, "").Code)
+		assert.Equal(t, http.StatusNotFound, request(1, http.MethodDelete, "/invitations/class/10/99", "").Code)
+		assert.Equal(t, http.StatusNotFound, request(8, http.MethodPost, "/invitations/class/10/accept", "").Code)
+	})
+}
@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestListClassMembers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	joinedAt := time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC)
+	members := map[uint]dto.ClassMemberDTO{
+		1: {Uid: 1, Nickname: "佐藤", Role: models.RoleAdmin, JoinedAt: joinedAt},
+		2: {Uid: 2, Nickname: "鈴木", Role: models.RoleUser, JoinedAt: joinedAt},
+		3: {Uid: 3, Nickname: "高橋", Role: models.RoleAssistant, JoinedAt: joinedAt},
+		4: {Uid: 4, Nickname: "鈴木", Role: models.RoleUser, JoinedAt: joinedAt},
+	}
+	listed := []models.Role{models.RoleUser, models.RoleAdmin, models.RoleAssistant}
+	byRoles := mock.MatchedBy(func(query dto.ClassMemberQuery) bool {
+		return reflect.DeepEqual(listed, query.Roles) && query.Sort == "nickname" && query.Order == "asc"
+	})
+	bySearch := func(search string) interface{} {
+		return mock.MatchedBy(func(query dto.ClassMemberQuery) bool { return query.Search == search })
+	}
+	after := mock.MatchedBy(func(cursor *dto.ClassMemberCursor) bool {
+		return cursor != nil && cursor.Nickname == "鈴木" && cursor.UID == 2
+	})
+
+	// リポジトリは1件多く取得するよう limit+1 で呼び出される
+	repo := new(MockClassUserRepository)
+	repo.On("FindClassMembers", uint(10), byRoles, (*dto.ClassMemberCursor)(nil), 3).
+		Return([]dto.ClassMemberDTO{members[1], members[2], members[4]}, int64(4), nil)
+	repo.On("FindClassMembers", uint(10), byRoles, after, 3).
+		Return([]dto.ClassMemberDTO{members[4], members[3]}, int64(4), nil)
+	repo.On("FindClassMembers", uint(10), bySearch("鈴木"), (*dto.ClassMemberCursor)(nil), 51).
+		Return([]dto.ClassMemberDTO{members[2], members[4]}, int64(2), nil)
+	repo.On("FindClassMembers", uint(10), bySearch("伊藤"), (*dto.ClassMemberCursor)(nil), 51).
+		Return(nil, int64(0), nil)
+	controller := controllers.NewClassUserController(services.NewClassUserService(repo, nil))
+	router := gin.New()
+	router.GET("/cu/class/:cid/members", controller.GetClassMembers)
+
+	list := func(query string) (int, dto.ClassMemberPageDTO) {
+		req, _ := http.NewRequest(http.MethodGet, "/cu/class/10/members?"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var response struct {
+			Data dto.ClassMemberPageDTO `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response.Data
+	}
+	uids := func(members []dto.ClassMemberDTO) []uint {
+		result := []uint{}
+		for _, member := range members {
+			result = append(result, member.Uid)
+		}
+		return result
+	}
+
+	t.Run("Pages", func(t *testing.T) {
+		var got []uint
+		query := "role=USER&role=ADMIN&role=ASSISTANT&limit=2"
+		for pages := 0; pages < 3; pages++ {
+			status, page := list(query)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, int64(4), page.Total)
+			got = append(got, uids(page.Items)...)
+			assert.Equal(t, page.NextCursor != nil, page.HasMore)
+			if !page.HasMore {
+				break
+			}
+			query = "role=USER&role=ADMIN&role=ASSISTANT&limit=2&cursor=" + *page.NextCursor
+		}
+		assert.Equal(t, []uint{1, 2, 4, 3}, got)
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		status, page := list("q=鈴木")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, []uint{2, 4}, uids(page.Items))
+		assert.False(t, page.HasMore)
+		assert.Nil(t, page.NextCursor)
+		assert.True(t, joinedAt.Equal(page.Items[0].JoinedAt))
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		status, page := list("q=伊藤")
+		assert.Equal(t, http.StatusOK, status)
+		assert.NotNil(t, page.Items)
+		assert.Empty(t, page.Items)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, query := range []string{"role=OWNER", "cursor=not-a-cursor", "sort=uid", "order=up", "limit=101"} {
+			status, _ := list(query)
+			assert.Equal(t, http.StatusBadRequest, status, query)
+		}
+	})
+
+	repo.AssertExpectations(t)
+}
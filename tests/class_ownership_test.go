@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/tests/fakes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// memoryClassRepository はオーナーの変更のみを実装したテスト用のClassRepository
+type memoryClassRepository struct {
+	repositories.ClassRepository
+	classes       map[uint]*models.Class
+	classUserRepo *fakes.ClassUserRepository
+}
+
+func (r *memoryClassRepository) GetByID(classID uint) (*models.Class, error) {
+	class, ok := r.classes[classID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return class, nil
+}
+
+func (r *memoryClassRepository) TransferOwnership(actorID uint, classID uint, fromUID uint, toUID uint, previousOwnerRole models.Role) error {
+	if r.classes[classID].UID != fromUID {
+		return gorm.ErrRecordNotFound
+	}
+	if !r.classUserRepo.Roles[classID][toUID].Active() {
+		return repositories.ErrInvalidOwner
+	}
+	r.classes[classID].UID = toUID
+	r.classUserRepo.Roles[classID][toUID] = models.RoleAdmin
+	if previousOwnerRole != "" {
+		r.classUserRepo.Roles[classID][fromUID] = previousOwnerRole
+	}
+	return nil
+}
+
+func TestLastAdminProtection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	classUserRepo := fakes.NewClassUserRepository(10, map[uint]models.Role{1: models.RoleAdmin, 2: models.RoleUser})
+	classUserService := services.NewClassUserService(classUserRepo, nil)
+
+	assert.ErrorIs(t, classUserService.ChangeUserRole(1, 1, 10, models.RoleUser), services.ErrLastAdmin)
+	assert.ErrorIs(t, classUserService.AssignRole(1, 1, 10, models.RoleApplicant), services.ErrLastAdmin)
+	assert.ErrorIs(t, classUserService.RemoveUserFromClass(1, 1, 10), services.ErrLastAdmin)
+	assert.Equal(t, models.RoleAdmin, classUserRepo.Roles[10][1])
+
+	router := gin.New()
+	router.DELETE("/cu/:uid/:cid/remove", func(ctx *gin.Context) { ctx.Set("userID", uint(1)) }, controllers.NewClassUserController(classUserService).RemoveUserFromClass)
+	req, _ := http.NewRequest(http.MethodDelete, "/cu/1/10/remove", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// 他に管理者がいれば降格、退会できる
+	assert.NoError(t, classUserService.ChangeUserRole(1, 2, 10, models.RoleAdmin))
+	assert.NoError(t, classUserService.ChangeUserRole(1, 1, 10, models.RoleAssistant))
+	assert.ErrorIs(t, classUserService.RemoveUserFromClass(2, 2, 10), services.ErrLastAdmin)
+	assert.NoError(t, classUserService.RemoveUserFromClass(1, 1, 10))
+}
+
+func TestTransferOwnership(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	classUserRepo := fakes.NewClassUserRepository(10, map[uint]models.Role{
+		1: models.RoleAdmin,
+		2: models.RoleUser,
+		3: models.RoleApplicant,
+		4: models.RoleAssistant,
+		6: models.RoleAdmin,
+	})
+	classRepo := &memoryClassRepository{classes: map[uint]*models.Class{10: {ID: 10, UID: 1}}, classUserRepo: classUserRepo}
+	classService := services.NewCreateClassService(classRepo, classUserRepo, nil, nil)
+
+	assert.ErrorIs(t, classService.TransferOwnership(10, 4, dto.TransferOwnershipRequest{UID: 2}), services.ErrForbidden)
+	assert.ErrorIs(t, classService.TransferOwnership(10, 1, dto.TransferOwnershipRequest{UID: 3}), services.ErrInvalidOwner)
+	assert.ErrorIs(t, classService.TransferOwnership(10, 1, dto.TransferOwnershipRequest{UID: 5}), services.ErrInvalidOwner)
+	assert.ErrorIs(t, classService.TransferOwnership(10, 1, dto.TransferOwnershipRequest{UID: 1}), services.ErrInvalidOwner)
+	assert.ErrorIs(t, classService.TransferOwnership(11, 1, dto.TransferOwnershipRequest{UID: 2}), services.ErrForbidden)
+	// オーナーではない管理者はオーナーを変更できない
+	assert.ErrorIs(t, classService.TransferOwnership(10, 6, dto.TransferOwnershipRequest{UID: 6}), services.ErrForbidden)
+	for _, role := range []models.Role{models.RoleBlacklist, models.RoleApplicant, models.RoleInvite} {
+		role := role
+		assert.ErrorIs(t, classService.TransferOwnership(10, 1, dto.TransferOwnershipRequest{UID: 2, PreviousOwnerRole: &role}), services.ErrInvalidRole)
+	}
+	assert.Equal(t, uint(1), classRepo.classes[10].UID)
+
+	router := gin.New()
+	router.POST("/cl/:cid/transfer", func(ctx *gin.Context) { ctx.Set("userID", uint(1)) }, controllers.NewCreateClassController(classService, nil).TransferOwnership)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"Invalid previous owner role", `{"uid":2,"previous_owner_role":"OWNER"}`, http.StatusBadRequest},
+		{"Applicant", `{"uid":3}`, http.StatusBadRequest},
+		{"Transfer", `{"uid":2,"previous_owner_role":"ASSISTANT"}`, http.StatusOK},
+		{"No longer admin", `{"uid":4}`, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/cl/10/transfer", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+
+	assert.Equal(t, uint(2), classRepo.classes[10].UID)
+	assert.Equal(t, models.RoleAdmin, classUserRepo.Roles[10][2])
+	assert.Equal(t, models.RoleAssistant, classUserRepo.Roles[10][1])
+}
@@ -2,6 +2,7 @@ package tests
 
 import (
 	"github.com/stretchr/testify/mock"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -9,6 +10,8 @@ import (
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/pagination"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
@@ -17,43 +20,58 @@ type MockClassUserService struct {
 	mock.Mock
 }
 
-func (m *MockClassUserService) GetClassMembers(cid uint, roleNames ...string) ([]dto.ClassMemberDTO, error) {
-	args := m.Called(cid, roleNames)
+func (m *MockClassUserService) GetClassMembers(cid uint, roles ...models.Role) ([]dto.ClassMemberDTO, error) {
+	args := m.Called(cid, roles)
 	return args.Get(0).([]dto.ClassMemberDTO), args.Error(1)
 }
 
+func (m *MockClassUserService) ExportMembers(cid uint, role models.Role) ([]dto.ClassMemberDTO, error) {
+	args := m.Called(cid, role)
+	return args.Get(0).([]dto.ClassMemberDTO), args.Error(1)
+}
+
+func (m *MockClassUserService) ListClassMembers(cid uint, query dto.ClassMemberQuery) (dto.ClassMemberPageDTO, error) {
+	args := m.Called(cid, query)
+	return args.Get(0).(dto.ClassMemberPageDTO), args.Error(1)
+}
+
 func (m *MockClassUserService) GetClassUserInfo(uid uint, cid uint) (dto.ClassMemberDTO, error) {
 	args := m.Called(uid, cid)
 	return args.Get(0).(dto.ClassMemberDTO), args.Error(1)
 }
 
-func (m *MockClassUserService) GetUserClasses(uid uint, page int, limit int) ([]dto.UserClassInfoDTO, error) {
-	args := m.Called(uid, page, limit)
-	return args.Get(0).([]dto.UserClassInfoDTO), args.Error(1)
+func (m *MockClassUserService) GetUserClasses(uid uint, query pagination.Query) (pagination.Page[dto.UserClassInfoDTO], error) {
+	args := m.Called(uid, query)
+	return args.Get(0).(pagination.Page[dto.UserClassInfoDTO]), args.Error(1)
 }
 
-func (m *MockClassUserService) GetRole(uid uint, cid uint) (string, error) {
+func (m *MockClassUserService) GetRole(uid uint, cid uint) (models.Role, error) {
 	args := m.Called(uid, cid)
-	return args.String(0), args.Error(1)
+	return args.Get(0).(models.Role), args.Error(1)
 }
 
-func (m *MockClassUserService) GetFavoriteClasses(uid uint, page int, limit int) ([]dto.UserClassInfoDTO, error) {
-	args := m.Called(uid, page, limit)
-	return args.Get(0).([]dto.UserClassInfoDTO), args.Error(1)
+func (m *MockClassUserService) GetFavoriteClasses(uid uint, query pagination.Query) (pagination.Page[dto.UserClassInfoDTO], error) {
+	args := m.Called(uid, query)
+	return args.Get(0).(pagination.Page[dto.UserClassInfoDTO]), args.Error(1)
 }
 
-func (m *MockClassUserService) GetUserClassesByRole(uid uint, roleName string, page int, limit int) ([]dto.UserClassInfoDTO, error) {
-	args := m.Called(uid, roleName, page, limit)
-	return args.Get(0).([]dto.UserClassInfoDTO), args.Error(1)
+func (m *MockClassUserService) GetUserClassesByRole(uid uint, role models.Role, query pagination.Query) (pagination.Page[dto.UserClassInfoDTO], error) {
+	args := m.Called(uid, role, query)
+	return args.Get(0).(pagination.Page[dto.UserClassInfoDTO]), args.Error(1)
+}
+
+func (m *MockClassUserService) AssignRole(actorID uint, uid uint, cid uint, role models.Role) error {
+	args := m.Called(actorID, uid, cid, role)
+	return args.Error(0)
 }
 
-func (m *MockClassUserService) AssignRole(uid uint, cid uint, roleName string) error {
-	args := m.Called(uid, cid, roleName)
+func (m *MockClassUserService) ChangeUserRole(actorID uint, uid uint, cid uint, role models.Role) error {
+	args := m.Called(actorID, uid, cid, role)
 	return args.Error(0)
 }
 
-func (m *MockClassUserService) UpdateUserName(uid uint, cid uint, newName string) error {
-	args := m.Called(uid, cid, newName)
+func (m *MockClassUserService) UpdateUserName(actorID uint, uid uint, cid uint, newName string) error {
+	args := m.Called(actorID, uid, cid, newName)
 	return args.Error(0)
 }
 
@@ -62,8 +80,8 @@ func (m *MockClassUserService) ToggleFavorite(uid uint, cid uint) error {
 	return args.Error(0)
 }
 
-func (m *MockClassUserService) RemoveUserFromClass(uid uint, cid uint) error {
-	args := m.Called(uid, cid)
+func (m *MockClassUserService) RemoveUserFromClass(actorID uint, uid uint, cid uint) error {
+	args := m.Called(actorID, uid, cid)
 	return args.Error(0)
 }
 
@@ -72,6 +90,11 @@ func (m *MockClassUserService) SearchUserClassesByName(uid uint, name string) ([
 	return args.Get(0).([]dto.UserClassInfoDTO), args.Error(1)
 }
 
+func (m *MockClassUserService) ImportMembers(actorID uint, cid uint, r io.Reader, dryRun bool) (dto.MemberImportResultDTO, error) {
+	args := m.Called(actorID, cid, r, dryRun)
+	return args.Get(0).(dto.MemberImportResultDTO), args.Error(1)
+}
+
 func TestGetUserInfo(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -87,7 +110,7 @@ func TestGetUserInfo(t *testing.T) {
 		expectedResponse := dto.ClassMemberDTO{
 			Uid:      uid,
 			Nickname: "testuser",
-			Role:     "USER",
+			Role:     models.RoleUser,
 			Image:    "testimage.png",
 		}
 
@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockUserRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserRepository) GetApplyingClasses(userID uint) ([]models.ClassApplication, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.ClassApplication), args.Error(1)
+}
+
+func (m *MockUserRepository) UserExists(userID uint) (bool, error) {
+	args := m.Called(userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) FindByName(name string) ([]models.User, error) {
+	args := m.Called(name)
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) DeleteUser(userID uint) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) FindByID(userID uint) (*models.User, error) {
+	args := m.Called(userID)
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
+func (m *MockUserRepository) MergeUsers(sourceID uint, targetID uint) error {
+	args := m.Called(sourceID, targetID)
+	return args.Error(0)
+}
+
+func TestDevLogin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userRepo := new(MockUserRepository)
+	authUserRepo := new(MockAuthUserRepository)
+	userRepo.On("FindByID", uint(1)).Return(&models.User{ID: 1, Name: "seeded"}, nil)
+	userRepo.On("FindByID", uint(99)).Return(nil, gorm.ErrRecordNotFound)
+	authUserRepo.On("UpdateOrCreateUser", dto.ExternalUserInfo{Provider: services.DevLoginProvider, Subject: "alice", Name: "alice"}).
+		Return(models.User{ID: 2, Name: "alice"}, nil)
+	authUserRepo.On("UpdateOrCreateUser", dto.ExternalUserInfo{Provider: services.DevLoginProvider, Subject: "developer", Name: "developer"}).
+		Return(models.User{ID: 3, Name: "developer"}, nil)
+
+	jwtService := newTestJWTService(t)
+	controller := controllers.NewDevLoginController(services.NewDevLoginService(userRepo, authUserRepo), jwtService)
+	router := gin.New()
+	router.POST("/auth/dev-login", controller.Login)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		userID uint
+	}{
+		{"Seeded user", `{"user_id": 1}`, http.StatusOK, 1},
+		{"Named developer", `{"name": "alice"}`, http.StatusOK, 2},
+		{"Default developer", ``, http.StatusOK, 3},
+		{"Unknown user", `{"user_id": 99}`, http.StatusNotFound, 0},
+		{"Invalid body", `{"user_id": "one"}`, http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/auth/dev-login", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Data struct {
+					AccessToken string `json:"access_token"`
+				} `json:"data"`
+			}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			token, err := jwtService.ValidateToken(response.Data.AccessToken)
+			assert.NoError(t, err)
+			assert.Equal(t, float64(tt.userID), token.Claims.(jwt.MapClaims)["id"])
+		})
+	}
+}
+
+func TestRefreshAccessTokenRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	controller := controllers.NewDevLoginController(services.NewDevLoginService(new(MockUserRepository), new(MockAuthUserRepository)), newTestJWTService(t))
+	router := gin.New()
+	router.POST("/auth/dev-login/refresh-token", controller.RefreshAccessTokenHandler)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		error  string
+	}{
+		{"Invalid JSON", `{"refresh_token":`, http.StatusBadRequest, constants.InvalidRequest},
+		{"Missing refresh token", `{}`, http.StatusBadRequest, constants.RefreshTokenRequired},
+		{"Empty refresh token", `{"refresh_token": "Bearer "}`, http.StatusBadRequest, constants.RefreshTokenRequired},
+		{"Invalid refresh token", `{"refresh_token": "invalid"}`, http.StatusUnauthorized, constants.InvalidRefreshToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/auth/dev-login/refresh-token", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Contains(t, w.Body.String(), tt.error)
+		})
+	}
+}
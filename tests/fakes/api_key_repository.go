@@ -0,0 +1,88 @@
+package fakes
+
+import (
+	"sync"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"gorm.io/gorm"
+)
+
+// APIKeyRepository はインメモリのAPIKeyRepository
+type APIKeyRepository struct {
+	mu   sync.Mutex
+	Keys map[uint]models.APIKey
+}
+
+var _ repositories.APIKeyRepository = (*APIKeyRepository)(nil)
+
+// NewAPIKeyRepository は空のAPIKeyRepositoryを生成します。
+func NewAPIKeyRepository() *APIKeyRepository {
+	return &APIKeyRepository{Keys: map[uint]models.APIKey{}}
+}
+
+func (r *APIKeyRepository) Create(key *models.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key.ID = uint(len(r.Keys) + 1)
+	key.CreatedAt = time.Now()
+	r.Keys[key.ID] = *key
+	return nil
+}
+
+func (r *APIKeyRepository) FindByHash(keyHash string) (*models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range r.Keys {
+		if key.KeyHash == keyHash {
+			return &key, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *APIKeyRepository) FindByID(id uint) (*models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, ok := r.Keys[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &key, nil
+}
+
+func (r *APIKeyRepository) FindAll(cid *uint) ([]models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var keys []models.APIKey
+	for _, key := range r.Keys {
+		if (key.CID == nil && cid == nil) || (key.CID != nil && cid != nil && *key.CID == *cid) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (r *APIKeyRepository) Update(key *models.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Keys[key.ID] = *key
+	return nil
+}
+
+func (r *APIKeyRepository) Delete(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.Keys, id)
+	return nil
+}
+
+func (r *APIKeyRepository) UpdateLastUsedAt(id uint, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := r.Keys[id]
+	key.LastUsedAt = &at
+	r.Keys[id] = key
+	return nil
+}
@@ -0,0 +1,112 @@
+package fakes
+
+import (
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"gorm.io/gorm"
+)
+
+// ClassCodeRepository は ClassUserRepository のロールを使うインメモリのClassCodeRepository
+// Redeem はコードのロールで ClassUserRepository にメンバーを追加します。
+type ClassCodeRepository struct {
+	ClassUserRepo *ClassUserRepository
+	Codes         []*models.ClassCode
+}
+
+var _ repositories.ClassCodeRepository = (*ClassCodeRepository)(nil)
+
+// Find は保存されているコードを返します。見つからない場合は nil を返します。
+func (r *ClassCodeRepository) Find(cid uint, id uint) *models.ClassCode {
+	for _, code := range r.Codes {
+		if code.ID == id && code.CID == cid {
+			return code
+		}
+	}
+	return nil
+}
+func (r *ClassCodeRepository) FindByCode(value string) (*models.ClassCode, error) {
+	for _, code := range r.Codes {
+		if code.Code == value {
+			found := *code
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *ClassCodeRepository) FindByClassID(cid uint) (*models.ClassCode, error) {
+	return nil, nil
+}
+
+func (r *ClassCodeRepository) FindAllByClassID(cid uint) ([]models.ClassCode, error) {
+	var codes []models.ClassCode
+	for _, code := range r.Codes {
+		if code.CID == cid {
+			codes = append(codes, *code)
+		}
+	}
+	return codes, nil
+}
+
+func (r *ClassCodeRepository) SaveClassCode(classCode *models.ClassCode) error {
+	classCode.ID = uint(len(r.Codes) + 1)
+	saved := *classCode
+	r.Codes = append(r.Codes, &saved)
+	return nil
+}
+
+func (r *ClassCodeRepository) Regenerate(cid uint, id uint, value string) (*models.ClassCode, error) {
+	code := r.Find(cid, id)
+	if code == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	code.Code, code.Uses, code.Enabled = value, 0, true
+	regenerated := *code
+	return &regenerated, nil
+}
+
+func (r *ClassCodeRepository) Revoke(cid uint, id uint) error {
+	code := r.Find(cid, id)
+	if code == nil {
+		return gorm.ErrRecordNotFound
+	}
+	code.Enabled = false
+	return nil
+}
+
+func (r *ClassCodeRepository) UpdateSecret(cid uint, id uint, secret *string) (*models.ClassCode, error) {
+	code := r.Find(cid, id)
+	if code == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	code.Secret = secret
+	updated := *code
+	return &updated, nil
+}
+
+func (r *ClassCodeRepository) FindAllWithSecret() ([]models.ClassCode, error) {
+	var codes []models.ClassCode
+	for _, code := range r.Codes {
+		if code.Secret != nil {
+			codes = append(codes, *code)
+		}
+	}
+	return codes, nil
+}
+
+func (r *ClassCodeRepository) Redeem(uid uint, codeID uint) error {
+	for _, code := range r.Codes {
+		if code.ID != codeID {
+			continue
+		}
+		if !code.Usable(time.Now()) {
+			return gorm.ErrRecordNotFound
+		}
+		code.Uses++
+		r.ClassUserRepo.Roles[code.CID][uid] = code.Role
+		return nil
+	}
+	return gorm.ErrRecordNotFound
+}
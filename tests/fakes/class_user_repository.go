@@ -0,0 +1,65 @@
+// Package fakes はテストで共有するインメモリのリポジトリを提供します。
+// 複数のリポジトリにまたがる状態 (クラスのロールなど) を扱うテストで使用し、
+// 呼び出しと戻り値を決めるだけのテストでは testify のモックを使用してください。
+package fakes
+
+import (
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"gorm.io/gorm"
+)
+
+// ClassUserRepository はロールのみを保持するClassUserRepository
+// 実装していないメソッドを呼び出すと panic します。
+type ClassUserRepository struct {
+	repositories.ClassUserRepository
+	Roles map[uint]map[uint]models.Role // cid -> uid -> role
+}
+
+// NewClassUserRepository はクラス cid のロールを roles としたClassUserRepositoryを生成します。
+func NewClassUserRepository(cid uint, roles map[uint]models.Role) *ClassUserRepository {
+	return &ClassUserRepository{Roles: map[uint]map[uint]models.Role{cid: roles}}
+}
+
+func (r *ClassUserRepository) GetRole(uid uint, cid uint) (models.Role, error) {
+	role, ok := r.Roles[cid][uid]
+	if !ok {
+		return "", gorm.ErrRecordNotFound
+	}
+	return role, nil
+}
+
+// LastAdmin はリポジトリと同じく、uid のロールが newRole になると管理者がいなくなるかを返します。
+func (r *ClassUserRepository) LastAdmin(uid uint, cid uint, newRole models.Role) bool {
+	if r.Roles[cid][uid] != models.RoleAdmin || newRole == models.RoleAdmin {
+		return false
+	}
+	admins := 0
+	for _, role := range r.Roles[cid] {
+		if role == models.RoleAdmin {
+			admins++
+		}
+	}
+	return admins <= 1
+}
+
+func (r *ClassUserRepository) UpdateUserRole(actorID uint, uid uint, cid uint, newRole models.Role) error {
+	if r.LastAdmin(uid, cid, newRole) {
+		return repositories.ErrLastAdmin
+	}
+	r.Roles[cid][uid] = newRole
+	return nil
+}
+
+func (r *ClassUserRepository) CreateUserRole(actorID uint, uid uint, cid uint, role models.Role) error {
+	r.Roles[cid][uid] = role
+	return nil
+}
+
+func (r *ClassUserRepository) DeleteClassUser(actorID uint, uid uint, cid uint) error {
+	if r.LastAdmin(uid, cid, "") {
+		return repositories.ErrLastAdmin
+	}
+	delete(r.Roles[cid], uid)
+	return nil
+}
@@ -4,22 +4,33 @@
 package tests
 
 import (
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/migration"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/pagination"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
 	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 var db *gorm.DB
 var router *gin.Engine
+var redisClient *redis.Client
 
 func TestMain(m *testing.M) {
 	// Set up the environment
@@ -31,7 +42,10 @@ func TestMain(m *testing.M) {
 	if err != nil {
 		log.Fatalf("failed to initialize database: %v", err)
 	}
-	jwtService := services.NewJWTService()
+	redisClient = redis.NewClient(&redis.Options{
+		Addr: os.Getenv("REDIS_HOST") + ":" + os.Getenv("REDIS_PORT"),
+	})
+	jwtService := services.NewJWTService(services.NewRedisRefreshTokenStore(redisClient), services.NewRedisTokenDenylist(redisClient), services.NewRedisSessionStore(redisClient))
 
 	// Set up the router
 	router = setupRouter(db, jwtService)
@@ -49,11 +63,45 @@ func TestMain(m *testing.M) {
 func setupRouter(db *gorm.DB, jwtService services.JWTService) *gin.Engine {
 	router := gin.Default()
 	// Initialize controllers and routes here
-	userController := controllers.NewCreateUserController(services.NewCreateUserService(repositories.NewUserRepository(db)))
+	userController := controllers.NewCreateUserController(services.NewCreateUserService(repositories.NewUserRepository(db), jwtService))
 	router.GET("/api/gin/u/:userID/applying-classes", userController.GetApplyingClasses)
 	return router
 }
 
+func TestRefreshTokenConsumeConcurrent(t *testing.T) {
+	store := services.NewRedisRefreshTokenStore(redisClient)
+	jti := "consume-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	assert.NoError(t, store.Save(jti, "family-"+jti, 1, time.Minute))
+
+	// 同じトークンを同時に使用しても1回だけ成功し、残りは再利用として検出される
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			family, err := store.Consume(jti, time.Minute)
+			assert.Equal(t, "family-"+jti, family)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	consumed := 0
+	for err := range errs {
+		if err == nil {
+			consumed++
+			continue
+		}
+		assert.ErrorIs(t, err, services.ErrRefreshTokenReused)
+	}
+	assert.Equal(t, 1, consumed)
+
+	_, err := store.Consume("missing-"+jti, time.Minute)
+	assert.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+}
+
 func TestGetApplyingClasses(t *testing.T) {
 	req, _ := http.NewRequest(http.MethodGet, "/api/gin/u/1/applying-classes", nil)
 	w := httptest.NewRecorder()
@@ -61,3 +109,745 @@ func TestGetApplyingClasses(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 }
+
+func TestMergeUsers(t *testing.T) {
+	userRepo := repositories.NewUserRepository(db)
+
+	source := models.User{Name: "source", Image: "source.png", PID: "merge-source"}
+	target := models.User{Name: "target", Image: "target.png", PID: "merge-target"}
+	assert.NoError(t, db.Create(&source).Error)
+	assert.NoError(t, db.Create(&target).Error)
+
+	class := models.Class{Name: "merge", UID: source.ID}
+	assert.NoError(t, db.Create(&class).Error)
+	assert.NoError(t, db.Omit(clause.Associations).Create(&models.ClassUser{CID: class.ID, UID: source.ID, Nickname: "source", Role: models.RoleAdmin}).Error)
+	assert.NoError(t, db.Omit(clause.Associations).Create(&models.ClassBoard{Title: "board", Content: "content", CID: class.ID, UID: source.ID}).Error)
+	assert.NoError(t, db.Create(&models.UserIdentity{UID: source.ID, Provider: "google", Subject: "merge-source"}).Error)
+
+	// 統合元だけが招待されているクラスと、両方が招待されているクラス
+	inviter := models.User{Name: "inviter", Image: "inviter.png", PID: "merge-inviter"}
+	assert.NoError(t, db.Create(&inviter).Error)
+	invitedClass := models.Class{Name: "merge-invited", UID: inviter.ID}
+	bothClass := models.Class{Name: "merge-both", UID: inviter.ID}
+	assert.NoError(t, db.Create(&invitedClass).Error)
+	assert.NoError(t, db.Create(&bothClass).Error)
+	invitationRepo := repositories.NewClassInvitationRepository(db)
+	expiresAt := time.Now().Add(time.Hour)
+	invite := func(cid uint, uid uint) models.ClassInvitation {
+		invitation := models.ClassInvitation{CID: cid, InviterID: inviter.ID, InviteeID: &uid, Role: models.RoleUser, Status: models.InvitationPending, ExpiresAt: &expiresAt}
+		assert.NoError(t, invitationRepo.InviteUser(&invitation))
+		return invitation
+	}
+	sourceInvitation := invite(invitedClass.ID, source.ID)
+	invite(bothClass.ID, source.ID)
+	targetInvitation := invite(bothClass.ID, target.ID)
+	// 統合元が招待した記録
+	tokenHash := "merge-link"
+	sentInvitation := models.ClassInvitation{CID: class.ID, InviterID: source.ID, Role: models.RoleUser, TokenHash: &tokenHash, Status: models.InvitationPending, ExpiresAt: &expiresAt}
+	assert.NoError(t, invitationRepo.CreateLink(&sentInvitation))
+
+	assert.NoError(t, userRepo.MergeUsers(source.ID, target.ID))
+
+	var classUser models.ClassUser
+	assert.NoError(t, db.Where("cid = ? AND uid = ?", class.ID, target.ID).First(&classUser).Error)
+	assert.Equal(t, models.RoleAdmin, classUser.Role)
+
+	var count int64
+	db.Model(&models.ClassBoard{}).Where("uid = ?", target.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+	db.Model(&models.UserIdentity{}).Where("uid = ?", target.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	exists, err := userRepo.UserExists(source.ID)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// 両方が招待されていたクラスは統合先の招待だけが残る
+	db.Model(&models.ClassInvitation{}).Where("cid = ? AND status = ?", bothClass.ID, models.InvitationPending).Count(&count)
+	assert.Equal(t, int64(1), count)
+	db.Model(&models.ClassInvitation{}).Where("invitee_id = ?", source.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+	var sent models.ClassInvitation
+	assert.NoError(t, db.First(&sent, sentInvitation.ID).Error)
+	assert.Equal(t, target.ID, sent.InviterID)
+
+	// 統合先ユーザーが移動された招待を承諾、辞退できる
+	assert.NoError(t, invitationRepo.Accept(target.ID, sourceInvitation.ID))
+	role, err := repositories.NewClassUserRepository(db).GetRole(target.ID, invitedClass.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleUser, role)
+	assert.NoError(t, invitationRepo.Decline(target.ID, targetInvitation.ID))
+}
+
+func TestUpdateOrCreateUserLegacyPID(t *testing.T) {
+	authUserRepo := repositories.NewAuthUserRepository(db)
+
+	legacy := models.User{Name: "legacy", Image: "legacy.png", PID: "collide-1"}
+	assert.NoError(t, db.Create(&legacy).Error)
+
+	// google と line 以外のプロバイダは p_id が一致しても既存のユーザーに紐づけない
+	github, err := authUserRepo.UpdateOrCreateUser(dto.ExternalUserInfo{Provider: "github", Subject: "collide-1", Name: "octocat"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, legacy.ID, github.ID)
+	assert.Equal(t, "github:collide-1", github.PID)
+
+	google, err := authUserRepo.UpdateOrCreateUser(dto.ExternalUserInfo{Provider: "google", Subject: "collide-1", Name: "legacy"})
+	assert.NoError(t, err)
+	assert.Equal(t, legacy.ID, google.ID)
+}
+
+func TestClassAuditTrail(t *testing.T) {
+	classUserRepo := repositories.NewClassUserRepository(db)
+	auditRepo := repositories.NewClassAuditRepository(db)
+
+	admin := models.User{Name: "audit-admin", Image: "admin.png", PID: "audit-admin"}
+	member := models.User{Name: "audit-member", Image: "member.png", PID: "audit-member"}
+	assert.NoError(t, db.Create(&admin).Error)
+	assert.NoError(t, db.Create(&member).Error)
+	class := models.Class{Name: "audit", UID: admin.ID}
+	assert.NoError(t, db.Create(&class).Error)
+
+	assert.NoError(t, classUserRepo.CreateUserRole(member.ID, member.ID, class.ID, models.RoleApplicant))
+	assert.NoError(t, classUserRepo.UpdateUserRole(admin.ID, member.ID, class.ID, models.RoleUser))
+	assert.NoError(t, classUserRepo.DeleteClassUser(admin.ID, member.ID, class.ID))
+
+	events, err := auditRepo.FindByClass(class.ID, dto.ClassAuditQuery{TargetID: &member.ID}, pagination.Window{Limit: 10})
+	assert.NoError(t, err)
+	if assert.Len(t, events, 3) {
+		assert.Equal(t, models.AuditMemberRemoved, events[0].Action)
+		assert.Equal(t, admin.ID, events[0].ActorID)
+		assert.Equal(t, models.AuditRoleChanged, events[1].Action)
+		assert.Equal(t, string(models.RoleApplicant), events[1].OldValue)
+		assert.Equal(t, string(models.RoleUser), events[1].NewValue)
+		assert.Equal(t, models.AuditMemberJoined, events[2].Action)
+	}
+
+	// 存在しないメンバーの変更は履歴を残さない
+	assert.Error(t, classUserRepo.UpdateUserRole(admin.ID, member.ID, class.ID, models.RoleAdmin))
+	events, err = auditRepo.FindByClass(class.ID, dto.ClassAuditQuery{Action: string(models.AuditRoleChanged)}, pagination.Window{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	// 履歴は更新、削除できない
+	assert.Error(t, db.Model(&models.ClassAuditEvent{}).Where("cid = ?", class.ID).Update("new_value", "ADMIN").Error)
+	assert.Error(t, db.Where("cid = ?", class.ID).Delete(&models.ClassAuditEvent{}).Error)
+}
+
+func TestTransferOwnershipTransaction(t *testing.T) {
+	classRepo := repositories.NewClassRepository(db)
+	classUserRepo := repositories.NewClassUserRepository(db)
+
+	owner := models.User{Name: "owner", Image: "owner.png", PID: "transfer-owner"}
+	member := models.User{Name: "member", Image: "member.png", PID: "transfer-member"}
+	assert.NoError(t, db.Create(&owner).Error)
+	assert.NoError(t, db.Create(&member).Error)
+	class := models.Class{Name: "transfer", UID: owner.ID}
+	assert.NoError(t, db.Create(&class).Error)
+	assert.NoError(t, classUserRepo.CreateUserRole(owner.ID, owner.ID, class.ID, models.RoleAdmin))
+	assert.NoError(t, classUserRepo.CreateUserRole(owner.ID, member.ID, class.ID, models.RoleUser))
+
+	// 承認済みのメンバーではないユーザーにはオーナーを変更できない
+	assert.NoError(t, classUserRepo.UpdateUserRole(owner.ID, member.ID, class.ID, models.RoleApplicant))
+	assert.ErrorIs(t, classRepo.TransferOwnership(owner.ID, class.ID, owner.ID, member.ID, ""), repositories.ErrInvalidOwner)
+	assert.NoError(t, classUserRepo.UpdateUserRole(owner.ID, member.ID, class.ID, models.RoleUser))
+
+	// 元のオーナーのロールを変更できない場合はオーナーも変更しない
+	assert.Error(t, classRepo.TransferOwnership(owner.ID, class.ID, owner.ID, member.ID, models.Role("OWNER")))
+	reloaded, err := classRepo.GetByID(class.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, owner.ID, reloaded.UID)
+	role, err := classUserRepo.GetRole(member.ID, class.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleUser, role)
+
+	assert.NoError(t, classRepo.TransferOwnership(owner.ID, class.ID, owner.ID, member.ID, models.RoleUser))
+	reloaded, err = classRepo.GetByID(class.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, member.ID, reloaded.UID)
+	role, err = classUserRepo.GetRole(member.ID, class.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, role)
+	role, err = classUserRepo.GetRole(owner.ID, class.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleUser, role)
+
+	assert.ErrorIs(t, classRepo.TransferOwnership(owner.ID, class.ID, owner.ID, member.ID, ""), gorm.ErrRecordNotFound)
+}
+
+func TestLastAdminConcurrent(t *testing.T) {
+	classUserRepo := repositories.NewClassUserRepository(db)
+
+	for _, name := range []string{"demote", "remove"} {
+		t.Run(name, func(t *testing.T) {
+			admins := make([]uint, 2)
+			for i := range admins {
+				user := models.User{Name: "admin", Image: "admin.png", PID: "last-admin-" + name + strconv.Itoa(i)}
+				assert.NoError(t, db.Create(&user).Error)
+				admins[i] = user.ID
+			}
+			class := models.Class{Name: "last-admin", UID: admins[0]}
+			assert.NoError(t, db.Create(&class).Error)
+			for _, uid := range admins {
+				assert.NoError(t, classUserRepo.CreateUserRole(admins[0], uid, class.ID, models.RoleAdmin))
+			}
+
+			// 2人の管理者が同時にお互いを降格または削除しても、どちらか一方のみ成功する
+			var wg sync.WaitGroup
+			errs := make([]error, len(admins))
+			for i := range admins {
+				wg.Add(1)
+				go func(actor uint, target uint, i int) {
+					defer wg.Done()
+					if name == "demote" {
+						errs[i] = classUserRepo.UpdateUserRole(actor, target, class.ID, models.RoleUser)
+					} else {
+						errs[i] = classUserRepo.DeleteClassUser(actor, target, class.ID)
+					}
+				}(admins[i], admins[1-i], i)
+			}
+			wg.Wait()
+
+			failed := 0
+			for _, err := range errs {
+				if err != nil {
+					assert.ErrorIs(t, err, repositories.ErrLastAdmin)
+					failed++
+				}
+			}
+			assert.Equal(t, 1, failed)
+			var count int64
+			db.Model(&models.ClassUser{}).Where("cid = ? AND role = ?", class.ID, models.RoleAdmin).Count(&count)
+			assert.Equal(t, int64(1), count)
+		})
+	}
+}
+
+func TestDecideApplications(t *testing.T) {
+	classUserRepo := repositories.NewClassUserRepository(db)
+	applicationRepo := repositories.NewClassApplicationRepository(db)
+	userRepo := repositories.NewUserRepository(db)
+
+	admin := models.User{Name: "decide-admin", Image: "admin.png", PID: "decide-admin"}
+	approved := models.User{Name: "decide-approved", Image: "approved.png", PID: "decide-approved"}
+	rejected := models.User{Name: "decide-rejected", Image: "rejected.png", PID: "decide-rejected"}
+	for _, user := range []*models.User{&admin, &approved, &rejected} {
+		assert.NoError(t, db.Create(user).Error)
+	}
+	class := models.Class{Name: "decide", UID: admin.ID}
+	assert.NoError(t, db.Create(&class).Error)
+	assert.NoError(t, classUserRepo.CreateUserRole(admin.ID, admin.ID, class.ID, models.RoleAdmin))
+	assert.NoError(t, classUserRepo.CreateUserRole(approved.ID, approved.ID, class.ID, models.RoleApplicant))
+	assert.NoError(t, classUserRepo.CreateUserRole(rejected.ID, rejected.ID, class.ID, models.RoleApplicant))
+
+	pending, err := applicationRepo.FindPending(class.ID)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 2)
+
+	// 申請中ではないユーザーが含まれる場合は何も変更しない
+	_, err = applicationRepo.Decide(admin.ID, class.ID, []uint{approved.ID, admin.ID}, true, nil)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	role, err := classUserRepo.GetRole(approved.ID, class.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleApplicant, role)
+
+	reason := "定員に達しました"
+	_, err = applicationRepo.Decide(admin.ID, class.ID, []uint{approved.ID}, true, nil)
+	assert.NoError(t, err)
+	_, err = applicationRepo.Decide(admin.ID, class.ID, []uint{rejected.ID}, false, &reason)
+	assert.NoError(t, err)
+
+	role, err = classUserRepo.GetRole(approved.ID, class.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleUser, role)
+	_, err = classUserRepo.GetRole(rejected.ID, class.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	applications, err := userRepo.GetApplyingClasses(approved.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, applications)
+	applications, err = userRepo.GetApplyingClasses(rejected.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, applications, 1) {
+		assert.Equal(t, models.ApplicationRejected, applications[0].Status)
+		assert.Equal(t, reason, *applications[0].Reason)
+		assert.Equal(t, admin.ID, *applications[0].DecidedBy)
+		assert.Equal(t, class.Name, applications[0].Class.Name)
+	}
+
+	// 再申請すると最新の申請が申請中になる
+	assert.NoError(t, classUserRepo.CreateUserRole(rejected.ID, rejected.ID, class.ID, models.RoleApplicant))
+	applications, err = userRepo.GetApplyingClasses(rejected.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, applications, 1) {
+		assert.Equal(t, models.ApplicationPending, applications[0].Status)
+	}
+}
+
+func TestClassCapacityWaitlist(t *testing.T) {
+	classUserRepo := repositories.NewClassUserRepository(db)
+	applicationRepo := repositories.NewClassApplicationRepository(db)
+	classRepo := repositories.NewClassRepository(db)
+
+	users := make([]models.User, 4)
+	for i := range users {
+		users[i] = models.User{Name: "capacity", Image: "capacity.png", PID: "capacity-" + strconv.Itoa(i)}
+		assert.NoError(t, db.Create(&users[i]).Error)
+	}
+	admin, first, second, third := users[0].ID, users[1].ID, users[2].ID, users[3].ID
+	limitation := 2
+	class := models.Class{Name: "capacity", UID: admin, Limitation: &limitation}
+	assert.NoError(t, db.Create(&class).Error)
+	assert.NoError(t, classUserRepo.CreateUserRole(admin, admin, class.ID, models.RoleAdmin))
+	for _, uid := range []uint{first, second, third} {
+		assert.NoError(t, classUserRepo.CreateUserRole(uid, uid, class.ID, models.RoleApplicant))
+	}
+
+	// 1人だけ承認でき、残りはキャンセル待ちになる
+	waitlisted, err := applicationRepo.Decide(admin, class.ID, []uint{first, second, third}, true, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{second, third}, waitlisted)
+	role, err := classUserRepo.GetRole(first, class.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleUser, role)
+
+	// 定員を超えるロールの変更はできない
+	assert.ErrorIs(t, classUserRepo.UpdateUserRole(admin, third, class.ID, models.RoleUser), repositories.ErrClassFull)
+
+	// メンバーが退会するとキャンセル待ちの先頭が承認される
+	assert.NoError(t, classUserRepo.DeleteClassUser(first, first, class.ID))
+	role, err = classUserRepo.GetRole(second, class.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleUser, role)
+	role, err = classUserRepo.GetRole(third, class.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleApplicant, role)
+
+	// 定員を増やすと残りのキャンセル待ちも承認される
+	limitation = 3
+	class.Limitation = &limitation
+	assert.NoError(t, classRepo.Update(&class))
+	role, err = classUserRepo.GetRole(third, class.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleUser, role)
+
+	waiting, err := applicationRepo.FindWaitlisted(class.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, waiting)
+}
+
+func TestClassCapacityConcurrentApprovals(t *testing.T) {
+	classUserRepo := repositories.NewClassUserRepository(db)
+	applicationRepo := repositories.NewClassApplicationRepository(db)
+
+	admin := models.User{Name: "concurrent", Image: "concurrent.png", PID: "concurrent-admin"}
+	assert.NoError(t, db.Create(&admin).Error)
+	limitation := 3
+	class := models.Class{Name: "concurrent", UID: admin.ID, Limitation: &limitation}
+	assert.NoError(t, db.Create(&class).Error)
+	assert.NoError(t, classUserRepo.CreateUserRole(admin.ID, admin.ID, class.ID, models.RoleAdmin))
+
+	applicants := make([]uint, 6)
+	for i := range applicants {
+		user := models.User{Name: "concurrent", Image: "concurrent.png", PID: "concurrent-" + strconv.Itoa(i)}
+		assert.NoError(t, db.Create(&user).Error)
+		assert.NoError(t, classUserRepo.CreateUserRole(user.ID, user.ID, class.ID, models.RoleApplicant))
+		applicants[i] = user.ID
+	}
+
+	var wg sync.WaitGroup
+	for _, uid := range applicants {
+		wg.Add(1)
+		go func(uid uint) {
+			defer wg.Done()
+			_, err := applicationRepo.Decide(admin.ID, class.ID, []uint{uid}, true, nil)
+			assert.NoError(t, err)
+		}(uid)
+	}
+	wg.Wait()
+
+	var active int64
+	db.Model(&models.ClassUser{}).Where("cid = ? AND role IN ?", class.ID, []models.Role{models.RoleAdmin, models.RoleUser}).Count(&active)
+	assert.Equal(t, int64(limitation), active)
+	waiting, err := applicationRepo.FindWaitlisted(class.ID)
+	assert.NoError(t, err)
+	assert.Len(t, waiting, len(applicants)-(limitation-1))
+}
+
+func TestClassInvitationCapacity(t *testing.T) {
+	classUserRepo := repositories.NewClassUserRepository(db)
+	invitationRepo := repositories.NewClassInvitationRepository(db)
+
+	users := make([]models.User, 4)
+	for i := range users {
+		users[i] = models.User{Name: "invitation", Image: "invitation.png", PID: "invitation-" + strconv.Itoa(i)}
+		assert.NoError(t, db.Create(&users[i]).Error)
+	}
+	admin, invitee, declined, linked := users[0].ID, users[1].ID, users[2].ID, users[3].ID
+	limitation := 2
+	class := models.Class{Name: "invitation", UID: admin, Limitation: &limitation}
+	assert.NoError(t, db.Create(&class).Error)
+	assert.NoError(t, classUserRepo.CreateUserRole(admin, admin, class.ID, models.RoleAdmin))
+
+	// 招待されたユーザーは承諾するまで INVITE になる
+	invitation := models.ClassInvitation{CID: class.ID, InviterID: admin, InviteeID: &invitee, Role: models.RoleAssistant, Status: models.InvitationPending}
+	assert.NoError(t, invitationRepo.InviteUser(&invitation))
+	role, err := classUserRepo.GetRole(invitee, class.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleInvite, role)
+
+	other := models.ClassInvitation{CID: class.ID, InviterID: admin, InviteeID: &declined, Role: models.RoleUser, Status: models.InvitationPending}
+	assert.NoError(t, invitationRepo.InviteUser(&other))
+	assert.NoError(t, invitationRepo.Decline(declined, other.ID))
+	exists, err := classUserRepo.RoleExists(declined, class.ID)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	assert.NoError(t, invitationRepo.Accept(invitee, invitation.ID))
+	role, err = classUserRepo.GetRole(invitee, class.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleAssistant, role)
+	assert.ErrorIs(t, invitationRepo.Accept(invitee, invitation.ID), gorm.ErrRecordNotFound)
+
+	// 定員に達している場合、招待リンクは使用されない
+	tokenHash := strings.Repeat("a", 64)
+	link := models.ClassInvitation{CID: class.ID, InviterID: admin, Role: models.RoleUser, TokenHash: &tokenHash, Status: models.InvitationPending}
+	assert.NoError(t, invitationRepo.CreateLink(&link))
+	assert.ErrorIs(t, invitationRepo.Accept(linked, link.ID), repositories.ErrClassFull)
+	pending, err := invitationRepo.FindPending(class.ID)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+
+	// 利用停止中のユーザーは招待リンクを使用できない
+	limitation = 3
+	assert.NoError(t, db.Model(&class).Update("limitation", limitation).Error)
+	assert.NoError(t, classUserRepo.CreateUserRole(admin, linked, class.ID, models.RoleBlacklist))
+	assert.ErrorIs(t, invitationRepo.Accept(linked, link.ID), gorm.ErrRecordNotFound)
+}
+
+func TestClassBanLifecycle(t *testing.T) {
+	classUserRepo := repositories.NewClassUserRepository(db)
+	banRepo := repositories.NewClassBanRepository(db)
+	invitationRepo := repositories.NewClassInvitationRepository(db)
+
+	users := make([]models.User, 3)
+	for i := range users {
+		users[i] = models.User{Name: "ban", Image: "ban.png", PID: "ban-" + strconv.Itoa(i)}
+		assert.NoError(t, db.Create(&users[i]).Error)
+	}
+	admin, member, invitee := users[0].ID, users[1].ID, users[2].ID
+	class := models.Class{Name: "ban", UID: admin}
+	assert.NoError(t, db.Create(&class).Error)
+	assert.NoError(t, classUserRepo.CreateUserRole(admin, admin, class.ID, models.RoleAdmin))
+	assert.NoError(t, classUserRepo.CreateUserRole(admin, member, class.ID, models.RoleUser))
+
+	// クラスのオーナーは利用停止にできない
+	assert.ErrorIs(t, banRepo.Ban(&models.ClassBan{CID: class.ID, UID: admin, BannedBy: admin}), repositories.ErrBanOwner)
+
+	// 利用停止にすると理由と期限が記録され、未使用の招待は取り消される
+	invitation := models.ClassInvitation{CID: class.ID, InviterID: admin, InviteeID: &invitee, Role: models.RoleUser, Status: models.InvitationPending}
+	assert.NoError(t, invitationRepo.InviteUser(&invitation))
+	reason := "荒らし行為"
+	expiresAt := time.Now().Add(time.Hour)
+	for _, uid := range []uint{member, invitee} {
+		assert.NoError(t, banRepo.Ban(&models.ClassBan{CID: class.ID, UID: uid, BannedBy: admin, Reason: &reason, ExpiresAt: &expiresAt}))
+		role, err := classUserRepo.GetRole(uid, class.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.RoleBlacklist, role)
+	}
+	assert.ErrorIs(t, invitationRepo.Accept(invitee, invitation.ID), gorm.ErrRecordNotFound)
+	bans, err := banRepo.FindActive(class.ID)
+	assert.NoError(t, err)
+	assert.Len(t, bans, 2)
+	assert.Equal(t, reason, *bans[0].Reason)
+
+	// 利用停止中のユーザーは退会でも削除でも利用停止を外せない
+	assert.ErrorIs(t, classUserRepo.DeleteClassUser(member, member, class.ID), repositories.ErrBannedMember)
+	assert.ErrorIs(t, classUserRepo.DeleteClassUser(admin, member, class.ID), repositories.ErrBannedMember)
+	bans, err = banRepo.FindActive(class.ID)
+	assert.NoError(t, err)
+	assert.Len(t, bans, 2)
+
+	// ロールの変更でも利用停止の記録が合わせられる
+	assert.NoError(t, classUserRepo.UpdateUserRole(admin, invitee, class.ID, models.RoleUser))
+	bans, err = banRepo.FindActive(class.ID)
+	assert.NoError(t, err)
+	assert.Len(t, bans, 1)
+
+	// 期限が切れた利用停止は解除され、クラスから削除される
+	lifted, err := banRepo.LiftExpired(expiresAt.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, lifted)
+	exists, err := classUserRepo.RoleExists(member, class.ID)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	assert.ErrorIs(t, banRepo.Lift(admin, member, class.ID), gorm.ErrRecordNotFound)
+}
+
+func TestImportMembersTransaction(t *testing.T) {
+	classUserRepo := repositories.NewClassUserRepository(db)
+
+	users := make([]models.User, 4)
+	for i := range users {
+		users[i] = models.User{Name: "import", Image: "import.png", PID: "import-" + strconv.Itoa(i)}
+		assert.NoError(t, db.Create(&users[i]).Error)
+	}
+	admin, byID, byIdentity, overLimit := users[0].ID, users[1].ID, users[2].ID, users[3].ID
+	assert.NoError(t, db.Create(&models.UserIdentity{UID: byIdentity, Provider: "google", Subject: "import-subject"}).Error)
+	limitation := 3
+	class := models.Class{Name: "import", UID: admin, Limitation: &limitation}
+	assert.NoError(t, db.Create(&class).Error)
+	assert.NoError(t, classUserRepo.CreateUserRole(admin, admin, class.ID, models.RoleAdmin))
+
+	rows := func() []dto.MemberImportRow {
+		return []dto.MemberImportRow{
+			{Line: 2, UID: byID, Role: models.RoleUser},
+			{Line: 3, Provider: "google", Subject: "import-subject", Nickname: "identity", Role: models.RoleAssistant},
+			{Line: 4, UID: overLimit, Role: models.RoleUser},
+			{Line: 5, UID: admin, Role: models.RoleUser},
+			{Line: 6, Provider: "google", Subject: "unknown", Role: models.RoleUser},
+		}
+	}
+
+	// ドライランでは何も変更しない
+	dryRun := rows()
+	assert.NoError(t, classUserRepo.ImportMembers(admin, class.ID, dryRun, true))
+	exists, err := classUserRepo.RoleExists(byID, class.ID)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	imported := rows()
+	assert.NoError(t, classUserRepo.ImportMembers(admin, class.ID, imported, false))
+	assert.Equal(t, dryRun, imported)
+	assert.Equal(t, []string{"", "", constants.ClassFull, constants.AlreadyMember, constants.UserNotFound},
+		[]string{imported[0].Error, imported[1].Error, imported[2].Error, imported[3].Error, imported[4].Error})
+
+	member, err := classUserRepo.GetClassUserInfo(byIdentity, class.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "identity", member.Nickname)
+	assert.Equal(t, models.RoleAssistant, member.Role)
+	member, err = classUserRepo.GetClassUserInfo(byID, class.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "import", member.Nickname)
+}
+
+func TestFindClassMembers(t *testing.T) {
+	classUserRepo := repositories.NewClassUserRepository(db)
+
+	owner := models.User{Name: "members", Image: "members.png", PID: "members-owner"}
+	assert.NoError(t, db.Create(&owner).Error)
+	class := models.Class{Name: "members", UID: owner.ID}
+	assert.NoError(t, db.Create(&class).Error)
+
+	members := []struct {
+		nickname string
+		role     models.Role
+	}{
+		{"b_admin", models.RoleAdmin},
+		{"a%1user", models.RoleUser},
+		{"c_assistant", models.RoleAssistant},
+		{"a_2user", models.RoleUser},
+		{"applicant", models.RoleApplicant},
+	}
+	joinedAt := time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC)
+	uids := make([]uint, len(members))
+	for i, m := range members {
+		user := models.User{Name: m.nickname, Image: "members.png", PID: "members-" + strconv.Itoa(i)}
+		assert.NoError(t, db.Create(&user).Error)
+		uids[i] = user.ID
+		classUser := models.ClassUser{CID: class.ID, UID: user.ID, Nickname: m.nickname, Role: m.role, JoinedAt: joinedAt.Add(time.Duration(i) * time.Hour)}
+		assert.NoError(t, db.Omit("Class", "User").Create(&classUser).Error)
+	}
+	active := []models.Role{models.RoleAdmin, models.RoleAssistant, models.RoleUser}
+
+	// 複数のロールで絞り込み、カーソルで続きを取得する
+	collect := func(query dto.ClassMemberQuery) []uint {
+		var result []uint
+		var after *dto.ClassMemberCursor
+		for {
+			page, total, err := classUserRepo.FindClassMembers(class.ID, query, after, 2)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(4), total)
+			for _, member := range page {
+				result = append(result, member.Uid)
+			}
+			if len(page) < 2 {
+				return result
+			}
+			last := page[len(page)-1]
+			after = &dto.ClassMemberCursor{Nickname: last.Nickname, Role: last.Role, JoinedAt: last.JoinedAt, UID: last.Uid}
+		}
+	}
+	assert.Equal(t, []uint{uids[1], uids[3], uids[0], uids[2]}, collect(dto.ClassMemberQuery{Roles: active, Sort: "nickname"}))
+	assert.Equal(t, []uint{uids[3], uids[2], uids[1], uids[0]}, collect(dto.ClassMemberQuery{Roles: active, Sort: "joined_at", Order: "desc"}))
+	assert.Equal(t, []uint{uids[0], uids[2], uids[1], uids[3]}, collect(dto.ClassMemberQuery{Roles: active, Sort: "role"}))
+
+	// LIKE の特別な文字はそのまま検索する
+	found, total, err := classUserRepo.FindClassMembers(class.ID, dto.ClassMemberQuery{Search: "A%"}, nil, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, uids[1], found[0].Uid)
+
+	all, err := classUserRepo.GetClassMembers(class.ID, models.RoleAdmin, models.RoleApplicant)
+	assert.NoError(t, err)
+	assert.Len(t, all, 2)
+}
+
+func TestKeysetPagination(t *testing.T) {
+	classUserRepo := repositories.NewClassUserRepository(db)
+	boardRepo := repositories.NewClassBoardRepository(db)
+
+	user := models.User{Name: "keyset", Image: "keyset.png", PID: "keyset"}
+	assert.NoError(t, db.Create(&user).Error)
+	cids := make([]uint, 3)
+	for i := range cids {
+		class := models.Class{Name: "keyset", UID: user.ID}
+		assert.NoError(t, db.Create(&class).Error)
+		cids[i] = class.ID
+		assert.NoError(t, classUserRepo.CreateUserRole(user.ID, user.ID, class.ID, models.RoleAdmin))
+	}
+
+	first, err := classUserRepo.GetUserClasses(user.ID, pagination.Window{Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{cids[0], cids[1]}, []uint{first[0].ID, first[1].ID})
+	// 間に追加されたクラスがあってもカーソルの次から取得する
+	next, err := classUserRepo.GetUserClassesByRole(user.ID, models.RoleAdmin, pagination.Window{AfterID: first[1].ID, Limit: 2})
+	assert.NoError(t, err)
+	assert.Len(t, next, 1)
+	assert.Equal(t, cids[2], next[0].ID)
+
+	boards := make([]uint, 3)
+	for i := range boards {
+		board := models.ClassBoard{Title: "keyset", Content: "keyset", CID: cids[0], UID: user.ID}
+		_, err := boardRepo.InsertClassBoard(&board)
+		assert.NoError(t, err)
+		boards[i] = board.ID
+	}
+	page, err := boardRepo.FindAllPaged(cids[0], pagination.Window{AfterID: boards[2], Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{boards[1], boards[0]}, []uint{page[0].ID, page[1].ID})
+	page, err = boardRepo.FindAllPaged(cids[0], pagination.Window{Offset: 1, Limit: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, boards[1], page[0].ID)
+}
+
+func TestClassCodeUsageLimit(t *testing.T) {
+	classUserRepo := repositories.NewClassUserRepository(db)
+	classCodeRepo := repositories.NewClassCodeRepository(db)
+
+	admin := models.User{Name: "codes", Image: "codes.png", PID: "codes-admin"}
+	assert.NoError(t, db.Create(&admin).Error)
+	class := models.Class{Name: "codes", UID: admin.ID}
+	assert.NoError(t, db.Create(&class).Error)
+	assert.NoError(t, classUserRepo.CreateUserRole(admin.ID, admin.ID, class.ID, models.RoleAdmin))
+
+	maxUses := 2
+	code := models.ClassCode{Code: "CODES1", CID: class.ID, UID: admin.ID, Role: models.RoleAssistant, MaxUses: &maxUses, Enabled: true}
+	assert.NoError(t, classCodeRepo.SaveClassCode(&code))
+
+	users := make([]uint, 5)
+	for i := range users {
+		user := models.User{Name: "codes", Image: "codes.png", PID: "codes-" + strconv.Itoa(i)}
+		assert.NoError(t, db.Create(&user).Error)
+		users[i] = user.ID
+	}
+
+	// 同時に使用しても上限を超えて参加できない
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	redeemed := 0
+	for _, uid := range users {
+		wg.Add(1)
+		go func(uid uint) {
+			defer wg.Done()
+			err := classCodeRepo.Redeem(uid, code.ID)
+			if err == nil {
+				mu.Lock()
+				redeemed++
+				mu.Unlock()
+				return
+			}
+			assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		}(uid)
+	}
+	wg.Wait()
+	assert.Equal(t, maxUses, redeemed)
+
+	var assistants int64
+	db.Model(&models.ClassUser{}).Where("cid = ? AND role = ?", class.ID, models.RoleAssistant).Count(&assistants)
+	assert.Equal(t, int64(maxUses), assistants)
+
+	// 再発行すると回数がリセットされ、無効にすると使用できない
+	regenerated, err := classCodeRepo.Regenerate(class.ID, code.ID, "CODES2")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, regenerated.Uses)
+	secret := "hash"
+	updated, err := classCodeRepo.UpdateSecret(class.ID, code.ID, &secret)
+	assert.NoError(t, err)
+	assert.Equal(t, "CODES2", updated.Code)
+	withSecret, err := classCodeRepo.FindAllWithSecret()
+	assert.NoError(t, err)
+	assert.Contains(t, withSecret, *updated)
+	_, err = classCodeRepo.UpdateSecret(class.ID+1, code.ID, nil)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.NoError(t, classCodeRepo.Revoke(class.ID, code.ID))
+	assert.ErrorIs(t, classCodeRepo.Redeem(users[4], code.ID), gorm.ErrRecordNotFound)
+	found, err := classCodeRepo.FindByClassID(class.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func TestMigrateRoleEnum(t *testing.T) {
+	migration.Migrate(db)
+
+	admin := models.User{Name: "role-admin", Image: "admin.png", PID: "role-admin"}
+	member := models.User{Name: "role-member", Image: "member.png", PID: "role-member"}
+	legacy := models.User{Name: "role-legacy", Image: "legacy.png", PID: "role-legacy"}
+	for _, user := range []*models.User{&admin, &member, &legacy} {
+		assert.NoError(t, db.Create(user).Error)
+	}
+	class := models.Class{Name: "role", UID: admin.ID}
+	assert.NoError(t, db.Create(&class).Error)
+
+	// 列挙型にする前の文字列のロール
+	assert.NoError(t, db.Exec("ALTER TABLE class_users ALTER COLUMN role TYPE text USING role::text").Error)
+	for uid, role := range map[uint]string{admin.ID: "admin", member.ID: " User ", legacy.ID: "OWNER"} {
+		assert.NoError(t, db.Exec("INSERT INTO class_users (cid, uid, nickname, role) VALUES (?, ?, 'role', ?)", class.ID, uid, role).Error)
+	}
+
+	// 変換できないロールがあっても起動を止めない
+	migration.Migrate(db)
+
+	classUserRepo := repositories.NewClassUserRepository(db)
+	role, err := classUserRepo.GetRole(admin.ID, class.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, role)
+	role, err = classUserRepo.GetRole(member.ID, class.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleUser, role)
+
+	exists, err := classUserRepo.RoleExists(legacy.ID, class.ID)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	var quarantined string
+	assert.NoError(t, db.Raw("SELECT role FROM quarantined_class_users WHERE cid = ? AND uid = ?", class.ID, legacy.ID).Scan(&quarantined).Error)
+	assert.Equal(t, "OWNER", quarantined)
+}
+
+func TestMigrateClassUserJoinedAt(t *testing.T) {
+	migration.Migrate(db)
+
+	createdAt := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	user := models.User{Name: "joined", Image: "joined.png", PID: "joined", CreatedAt: createdAt}
+	assert.NoError(t, db.Create(&user).Error)
+	class := models.Class{Name: "joined", UID: user.ID}
+	assert.NoError(t, db.Create(&class).Error)
+	assert.NoError(t, db.Exec("INSERT INTO class_users (cid, uid, nickname, role) VALUES (?, ?, 'joined', ?)", class.ID, user.ID, models.RoleAdmin).Error)
+
+	// joined_at を追加する前のメンバーはユーザーの登録日時で埋められる
+	assert.NoError(t, db.Exec("ALTER TABLE class_users DROP COLUMN joined_at").Error)
+	migration.Migrate(db)
+
+	var classUser models.ClassUser
+	assert.NoError(t, db.First(&classUser, "cid = ? AND uid = ?", class.ID, user.ID).Error)
+	assert.True(t, createdAt.Equal(classUser.JoinedAt))
+}
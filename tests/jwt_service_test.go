@@ -0,0 +1,410 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryRefreshTokenStore はテスト用のインメモリRefreshTokenStore
+type memoryRefreshTokenStore struct {
+	mu       sync.Mutex
+	tokens   map[string]string
+	used     map[string]string
+	families map[string]uint
+}
+
+func newMemoryRefreshTokenStore() *memoryRefreshTokenStore {
+	return &memoryRefreshTokenStore{
+		tokens:   map[string]string{},
+		used:     map[string]string{},
+		families: map[string]uint{},
+	}
+}
+
+func (s *memoryRefreshTokenStore) Save(jti string, family string, userID uint, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[jti] = family
+	s.families[family] = userID
+	return nil
+}
+
+func (s *memoryRefreshTokenStore) Consume(jti string, ttl time.Duration) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	family, ok := s.tokens[jti]
+	if !ok {
+		if usedFamily, used := s.used[jti]; used {
+			return usedFamily, services.ErrRefreshTokenReused
+		}
+		return "", services.ErrInvalidRefreshToken
+	}
+	delete(s.tokens, jti)
+	s.used[jti] = family
+	return family, nil
+}
+
+func (s *memoryRefreshTokenStore) FamilyActive(family string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.families[family]
+	return ok, nil
+}
+
+func (s *memoryRefreshTokenStore) RevokeFamily(family string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.families, family)
+	return nil
+}
+
+func (s *memoryRefreshTokenStore) RevokeUser(userID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for family, uid := range s.families {
+		if uid == userID {
+			delete(s.families, family)
+		}
+	}
+	return nil
+}
+
+// memoryTokenDenylist はテスト用のインメモリTokenDenylist
+type memoryTokenDenylist struct {
+	mu      sync.Mutex
+	denied  map[string]bool
+	revoked map[uint]time.Time
+}
+
+func newMemoryTokenDenylist() *memoryTokenDenylist {
+	return &memoryTokenDenylist{
+		denied:  map[string]bool{},
+		revoked: map[uint]time.Time{},
+	}
+}
+
+func (d *memoryTokenDenylist) Deny(jti string, ttl time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.denied[jti] = true
+	return nil
+}
+
+func (d *memoryTokenDenylist) IsDenied(jti string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.denied[jti], nil
+}
+
+func (d *memoryTokenDenylist) RevokeUser(userID uint, revokedAt time.Time, ttl time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.revoked[userID] = revokedAt
+	return nil
+}
+
+func (d *memoryTokenDenylist) UserRevokedAt(userID uint) (time.Time, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	revokedAt, ok := d.revoked[userID]
+	return revokedAt, ok, nil
+}
+
+// memorySessionStore はテスト用のインメモリSessionStore
+type memorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]services.Session
+}
+
+func newMemorySessionStore() *memorySessionStore {
+	return &memorySessionStore{sessions: map[string]services.Session{}}
+}
+
+func (s *memorySessionStore) Create(session services.Session, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[session.ID] = session
+	return nil
+}
+
+func (s *memorySessionStore) Get(id string) (*services.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	return &session, nil
+}
+
+func (s *memorySessionStore) List(userID uint) ([]services.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var sessions []services.Session
+	for _, session := range s.sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (s *memorySessionStore) Exists(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.sessions[id]
+	return ok, nil
+}
+
+func (s *memorySessionStore) Touch(id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if session, ok := s.sessions[id]; ok {
+		session.LastSeenAt = at
+		s.sessions[id] = session
+	}
+	return nil
+}
+
+func (s *memorySessionStore) Extend(id string, ttl time.Duration) error {
+	return nil
+}
+
+func (s *memorySessionStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *memorySessionStore) DeleteUser(userID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, session := range s.sessions {
+		if session.UserID == userID {
+			delete(s.sessions, id)
+		}
+	}
+	return nil
+}
+
+func newTestJWTService(t *testing.T) *services.JWTServiceImpl {
+	t.Setenv("JWT_SECRET", "test-secret")
+	return services.NewJWTService(newMemoryRefreshTokenStore(), newMemoryTokenDenylist(), newMemorySessionStore())
+}
+
+// startTestSession はユーザーのセッションを開始し、アクセストークンとリフレッシュトークンを返します。
+func startTestSession(t *testing.T, jwtService services.JWTService, userID uint) (string, string) {
+	accessToken, refreshToken, err := jwtService.StartSession(userID, dto.SessionInfo{})
+	assert.NoError(t, err)
+	return accessToken, refreshToken
+}
+
+func TestRefreshAccessToken(t *testing.T) {
+	t.Run("Rotation", func(t *testing.T) {
+		jwtService := newTestJWTService(t)
+		_, refreshToken := startTestSession(t, jwtService, 1)
+
+		accessToken, rotated, err := jwtService.RefreshAccessToken(refreshToken)
+		assert.NoError(t, err)
+		assert.True(t, accessToken.Valid)
+		assert.NotEqual(t, refreshToken, rotated)
+
+		_, _, err = jwtService.RefreshAccessToken(rotated)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Reuse revokes family", func(t *testing.T) {
+		jwtService := newTestJWTService(t)
+		_, refreshToken := startTestSession(t, jwtService, 1)
+		_, rotated, err := jwtService.RefreshAccessToken(refreshToken)
+		assert.NoError(t, err)
+
+		_, _, err = jwtService.RefreshAccessToken(refreshToken)
+		assert.ErrorIs(t, err, services.ErrRefreshTokenReused)
+
+		_, _, err = jwtService.RefreshAccessToken(rotated)
+		assert.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+	})
+
+	t.Run("Logout", func(t *testing.T) {
+		jwtService := newTestJWTService(t)
+		_, refreshToken := startTestSession(t, jwtService, 1)
+
+		assert.ErrorIs(t, jwtService.RevokeRefreshToken(refreshToken, 2), services.ErrUnauthorized)
+		assert.NoError(t, jwtService.RevokeRefreshToken(refreshToken, 1))
+
+		_, _, err := jwtService.RefreshAccessToken(refreshToken)
+		assert.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+	})
+
+	t.Run("Access token rejected", func(t *testing.T) {
+		jwtService := newTestJWTService(t)
+		accessToken, _ := startTestSession(t, jwtService, 1)
+
+		_, _, err := jwtService.RefreshAccessToken(accessToken)
+		assert.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+	})
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	t.Run("Single token", func(t *testing.T) {
+		jwtService := newTestJWTService(t)
+		first, _ := startTestSession(t, jwtService, 1)
+		second, _ := startTestSession(t, jwtService, 1)
+		firstToken, _ := jwtService.ValidateToken(first)
+		secondToken, _ := jwtService.ValidateToken(second)
+
+		assert.NoError(t, jwtService.RevokeToken(firstToken))
+
+		revoked, err := jwtService.IsTokenRevoked(firstToken)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+
+		revoked, err = jwtService.IsTokenRevoked(secondToken)
+		assert.NoError(t, err)
+		assert.False(t, revoked)
+	})
+
+	t.Run("All user tokens", func(t *testing.T) {
+		jwtService := newTestJWTService(t)
+		accessToken, _ := startTestSession(t, jwtService, 1)
+		otherUserToken, _ := startTestSession(t, jwtService, 2)
+		_, refreshToken := startTestSession(t, jwtService, 1)
+
+		assert.NoError(t, jwtService.RevokeAllUserTokens(1))
+
+		token, _ := jwtService.ValidateToken(accessToken)
+		revoked, err := jwtService.IsTokenRevoked(token)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+
+		token, _ = jwtService.ValidateToken(otherUserToken)
+		revoked, err = jwtService.IsTokenRevoked(token)
+		assert.NoError(t, err)
+		assert.False(t, revoked)
+
+		_, _, err = jwtService.RefreshAccessToken(refreshToken)
+		assert.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+
+		// 一括失効の直後 (同じ秒) に発行されたトークンは有効
+		time.Sleep(2 * time.Millisecond)
+		newAccessToken, _ := startTestSession(t, jwtService, 1)
+		token, _ = jwtService.ValidateToken(newAccessToken)
+		revoked, err = jwtService.IsTokenRevoked(token)
+		assert.NoError(t, err)
+		assert.False(t, revoked)
+	})
+}
+
+func TestAsymmetricSigning(t *testing.T) {
+	for _, alg := range []string{services.SigningAlgRS256, services.SigningAlgEdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			dir := t.TempDir()
+			writeSigningKey(t, alg, filepath.Join(dir, "key-1.pem"))
+
+			t.Setenv("JWT_SECRET", "")
+			t.Setenv("JWT_SIGNING_ALG", alg)
+			t.Setenv("JWT_KEYS_DIR", dir)
+			t.Setenv("JWT_ACTIVE_KID", "key-1")
+			oldService := services.NewJWTService(newMemoryRefreshTokenStore(), newMemoryTokenDenylist(), newMemorySessionStore())
+			oldToken, _ := startTestSession(t, oldService, 1)
+
+			// 新しい鍵を追加して署名鍵を切り替えても、古い鍵で署名されたトークンは検証できる
+			writeSigningKey(t, alg, filepath.Join(dir, "key-2.pem"))
+			t.Setenv("JWT_ACTIVE_KID", "key-2")
+			jwtService := services.NewJWTService(newMemoryRefreshTokenStore(), newMemoryTokenDenylist(), newMemorySessionStore())
+
+			token, err := jwtService.ValidateToken(oldToken)
+			assert.NoError(t, err)
+			assert.True(t, token.Valid)
+
+			newToken, _ := startTestSession(t, jwtService, 1)
+			token, err = jwtService.ValidateToken(newToken)
+			assert.NoError(t, err)
+			assert.Equal(t, "key-2", token.Header["kid"])
+			assert.Equal(t, alg, token.Header["alg"])
+
+			jwks := jwtService.JWKS()
+			assert.Len(t, jwks.Keys, 2)
+			assert.Equal(t, alg, jwks.Keys[0].Alg)
+		})
+	}
+
+	t.Run("HS256 token rejected without secret", func(t *testing.T) {
+		hmacService := newTestJWTService(t)
+		hmacToken, _ := startTestSession(t, hmacService, 1)
+
+		dir := t.TempDir()
+		writeSigningKey(t, services.SigningAlgRS256, filepath.Join(dir, "key-1.pem"))
+		t.Setenv("JWT_SECRET", "")
+		t.Setenv("JWT_SIGNING_ALG", services.SigningAlgRS256)
+		t.Setenv("JWT_KEYS_DIR", dir)
+		jwtService := services.NewJWTService(newMemoryRefreshTokenStore(), newMemoryTokenDenylist(), newMemorySessionStore())
+
+		_, err := jwtService.ValidateToken(hmacToken)
+		assert.Error(t, err)
+	})
+}
+
+func writeSigningKey(t *testing.T, alg string, path string) {
+	pemBytes, err := services.GenerateSigningKeyPEM(alg)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(path, pemBytes, 0600))
+}
+
+func TestSessions(t *testing.T) {
+	jwtService := newTestJWTService(t)
+	info := dto.SessionInfo{Provider: "google", UserAgent: "phone", IP: "192.0.2.1"}
+
+	accessToken, refreshToken, err := jwtService.StartSession(1, info)
+	assert.NoError(t, err)
+	_, _, err = jwtService.StartSession(1, dto.SessionInfo{Provider: "line", UserAgent: "laptop"})
+	assert.NoError(t, err)
+
+	token, err := jwtService.ValidateToken(accessToken)
+	assert.NoError(t, err)
+	sessionID := token.Claims.(jwt.MapClaims)["sid"].(string)
+
+	sessions, err := jwtService.ListSessions(1, sessionID)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+	for _, session := range sessions {
+		assert.Equal(t, session.ID == sessionID, session.Current)
+	}
+
+	t.Run("Refreshed token keeps session", func(t *testing.T) {
+		refreshed, rotated, err := jwtService.RefreshAccessToken(refreshToken)
+		assert.NoError(t, err)
+		assert.Equal(t, sessionID, refreshed.Claims.(jwt.MapClaims)["sid"])
+		refreshToken = rotated
+	})
+
+	t.Run("Other users cannot revoke", func(t *testing.T) {
+		assert.ErrorIs(t, jwtService.RevokeSession(2, sessionID), services.ErrNotFound)
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		assert.NoError(t, jwtService.RevokeSession(1, sessionID))
+
+		revoked, err := jwtService.IsTokenRevoked(token)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+
+		_, _, err = jwtService.RefreshAccessToken(refreshToken)
+		assert.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+
+		sessions, err := jwtService.ListSessions(1, "")
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 1)
+		assert.Equal(t, "laptop", sessions[0].UserAgent)
+	})
+}
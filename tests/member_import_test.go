@@ -0,0 +1,201 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/tests/fakes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockClassUserRepository はメンバーの取得と一覧のみを実装したテスト用のClassUserRepository
+type MockClassUserRepository struct {
+	repositories.ClassUserRepository
+	mock.Mock
+}
+
+func (m *MockClassUserRepository) GetClassMembers(cid uint, roles ...models.Role) ([]dto.ClassMemberDTO, error) {
+	args := m.Called(cid, roles)
+	return args.Get(0).([]dto.ClassMemberDTO), args.Error(1)
+}
+
+func (m *MockClassUserRepository) FindClassMembers(cid uint, query dto.ClassMemberQuery, after *dto.ClassMemberCursor, limit int) ([]dto.ClassMemberDTO, int64, error) {
+	args := m.Called(cid, query, after, limit)
+	members, _ := args.Get(0).([]dto.ClassMemberDTO)
+	return members, args.Get(1).(int64), args.Error(2)
+}
+
+// memoryMemberImportRepository はユーザーID 1から9のユーザーが存在するものとして、fakes.ClassUserRepository にメンバーを追加するテスト用のリポジトリ
+type memoryMemberImportRepository struct {
+	*fakes.ClassUserRepository
+}
+
+func (r *memoryMemberImportRepository) ImportMembers(actorID uint, cid uint, rows []dto.MemberImportRow, dryRun bool) error {
+	for i := range rows {
+		row := &rows[i]
+		switch {
+		case row.Error != "":
+		case row.UID == 0 || row.UID > 9:
+			row.Error = constants.UserNotFound
+		case r.Roles[cid][row.UID] != "":
+			row.Error = constants.AlreadyMember
+		case !dryRun:
+			r.Roles[cid][row.UID] = row.Role
+		}
+	}
+	return nil
+}
+
+func TestImportMembers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	repo := &memoryMemberImportRepository{fakes.NewClassUserRepository(10, map[uint]models.Role{
+		1: models.RoleAdmin,
+		2: models.RoleUser,
+	})}
+	controller := controllers.NewClassUserController(services.NewClassUserService(repo, nil))
+	router := gin.New()
+	router.POST("/cl/:cid/members/import", func(ctx *gin.Context) {
+		uid, _ := strconv.Atoi(ctx.GetHeader("X-User-ID"))
+		ctx.Set("userID", uint(uid))
+	}, controller.ImportMembers)
+
+	request := func(userID uint, query string, content string) *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", "members.csv")
+		_, _ = part.Write([]byte(content))
+		_ = writer.Close()
+
+		req, _ := http.NewRequest(http.MethodPost, "/cl/10/members/import"+query, body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("X-User-ID", strconv.Itoa(int(userID)))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) dto.MemberImportResultDTO {
+		var response struct {
+			Data dto.MemberImportResultDTO `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Data
+	}
+
+	content := "\ufeffUID,nickname,role\n" +
+		"3,田中,user\n" +
+		"4,'=1+1,ASSISTANT\n" +
+		"abc,佐藤,USER\n" +
+		"5,鈴木,BLACKLIST\n" +
+		"2,山田,USER\n" +
+		"99,高橋,USER\n" +
+		",伊藤,USER\n" +
+		"6," + strings.Repeat("あ", 51) + ",USER\n"
+
+	t.Run("Not allowed", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, request(2, "", content).Code)
+	})
+
+	t.Run("Invalid CSV", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, request(1, "", "nickname,role\n田中,USER\n").Code)
+		assert.Equal(t, http.StatusBadRequest, request(1, "", "").Code)
+		assert.Equal(t, http.StatusBadRequest, request(1, "?dry_run=maybe", content).Code)
+		assert.Equal(t, http.StatusBadRequest, request(1, "", "uid\n"+strings.Repeat("3\n", 1001)).Code)
+	})
+
+	t.Run("Dry run", func(t *testing.T) {
+		w := request(1, "?dry_run=true", content)
+		assert.Equal(t, http.StatusOK, w.Code)
+		result := decode(w)
+		assert.True(t, result.DryRun)
+		assert.Equal(t, 2, result.Imported)
+		assert.Equal(t, 6, result.Failed)
+		assert.Len(t, repo.Roles[10], 2)
+		// エクスポートで付けた ' は取り除かれる
+		assert.Equal(t, "=1+1", result.Rows[1].Nickname)
+
+		lineErrors := make(map[int]string)
+		for _, row := range result.Rows {
+			lineErrors[row.Line] = row.Error
+		}
+		assert.Equal(t, map[int]string{
+			2: "",
+			3: "",
+			4: constants.InvalidImportUser,
+			5: constants.InvalidRole,
+			6: constants.AlreadyMember,
+			7: constants.UserNotFound,
+			8: constants.InvalidImportUser,
+			9: constants.NicknameTooLong,
+		}, lineErrors)
+	})
+
+	t.Run("Import", func(t *testing.T) {
+		w := request(1, "", content)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 2, decode(w).Imported)
+		assert.Equal(t, models.RoleUser, repo.Roles[10][3])
+		assert.Equal(t, models.RoleAssistant, repo.Roles[10][4])
+	})
+}
+
+func TestExportMembers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockClassUserService)
+	controller := controllers.NewClassUserController(mockService)
+	router := gin.New()
+	router.GET("/cl/:cid/members/export", controller.ExportMembers)
+
+	mockService.On("ExportMembers", uint(10), models.Role("")).Return([]dto.ClassMemberDTO{
+		{Uid: 1, Nickname: "管理者", Role: models.RoleAdmin},
+		{Uid: 2, Nickname: "Doe, John", Role: models.RoleUser},
+		{Uid: 3, Nickname: "=1+1", Role: models.RoleUser},
+		{Uid: 4, Nickname: "@SUM(A1)", Role: models.RoleUser},
+	}, nil)
+
+	req, _ := http.NewRequest(http.MethodGet, "/cl/10/members/export", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "uid,nickname,role\n1,管理者,ADMIN\n2,\"Doe, John\",USER\n3,'=1+1,USER\n4,'@SUM(A1),USER\n", w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestExportMembersRoles(t *testing.T) {
+	repo := new(MockClassUserRepository)
+	service := services.NewClassUserService(repo, nil)
+	members := []dto.ClassMemberDTO{{Uid: 1, Nickname: "管理者", Role: models.RoleAdmin}}
+	repo.On("GetClassMembers", uint(10), []models.Role{models.RoleAdmin, models.RoleAssistant, models.RoleUser}).Return(members, nil)
+	repo.On("GetClassMembers", uint(10), []models.Role{models.RoleAssistant}).Return([]dto.ClassMemberDTO{}, nil)
+
+	// ロールを指定しない場合は ImportMembers で読み込めるロールのメンバーだけを返す
+	result, err := service.ExportMembers(10, "")
+	assert.NoError(t, err)
+	assert.Equal(t, members, result)
+
+	_, err = service.ExportMembers(10, models.RoleAssistant)
+	assert.NoError(t, err)
+
+	for _, role := range []models.Role{models.RoleBlacklist, models.RoleApplicant, models.RoleInvite, "OWNER"} {
+		_, err = service.ExportMembers(10, role)
+		assert.ErrorIs(t, err, services.ErrInvalidRole)
+	}
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,544 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// memoryOAuthStateStore はテスト用のインメモリOAuthStateStore
+type memoryOAuthStateStore struct {
+	mu     sync.Mutex
+	states map[string]services.OAuthState
+}
+
+func newMemoryOAuthStateStore() *memoryOAuthStateStore {
+	return &memoryOAuthStateStore{states: make(map[string]services.OAuthState)}
+}
+
+func (s *memoryOAuthStateStore) Save(state string, data services.OAuthState, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[state] = data
+	return nil
+}
+
+func (s *memoryOAuthStateStore) Consume(state string) (services.OAuthState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.states[state]
+	if !ok {
+		return services.OAuthState{}, services.ErrInvalidOAuthState
+	}
+	delete(s.states, state)
+	return data, nil
+}
+
+type MockAuthUserRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuthUserRepository) UpdateOrCreateUser(userInfo dto.ExternalUserInfo) (models.User, error) {
+	args := m.Called(userInfo)
+	return args.Get(0).(models.User), args.Error(1)
+}
+
+func (m *MockAuthUserRepository) FindIdentity(provider string, subject string) (*models.UserIdentity, error) {
+	args := m.Called(provider, subject)
+	identity, _ := args.Get(0).(*models.UserIdentity)
+	return identity, args.Error(1)
+}
+
+func (m *MockAuthUserRepository) FindIdentities(userID uint) ([]models.UserIdentity, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.UserIdentity), args.Error(1)
+}
+
+func (m *MockAuthUserRepository) CreateIdentity(identity *models.UserIdentity) error {
+	args := m.Called(identity)
+	return args.Error(0)
+}
+
+func (m *MockAuthUserRepository) DeleteIdentity(userID uint, identityID uint) (bool, error) {
+	args := m.Called(userID, identityID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAuthUserRepository) CountIdentities(userID uint) (int64, error) {
+	args := m.Called(userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// fakeAuthorization は疑似OAuthサーバーが認可コードに紐づけて保持する情報
+type fakeAuthorization struct {
+	challenge string
+	nonce     string
+}
+
+// fakeOAuthServer はPKCEを検証し、RS256で署名したid_tokenを発行する疑似OAuthサーバー
+type fakeOAuthServer struct {
+	*httptest.Server
+	key            *rsa.PrivateKey
+	mu             sync.Mutex
+	authorizations map[string]fakeAuthorization
+	jwksRequests   int
+	// claims はid_tokenのクレームを書き換えるためのフック
+	claims func(claims jwt.MapClaims)
+}
+
+func newFakeOAuthServer(t *testing.T) *fakeOAuthServer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	s := &fakeOAuthServer{key: key, authorizations: make(map[string]fakeAuthorization)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		s.mu.Lock()
+		authorization, ok := s.authorizations[r.Form.Get("code")]
+		delete(s.authorizations, r.Form.Get("code"))
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != authorization.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		claims := jwt.MapClaims{
+			"iss":     s.URL,
+			"sub":     "subject-1",
+			"aud":     "client-id",
+			"exp":     time.Now().Add(time.Hour).Unix(),
+			"iat":     time.Now().Unix(),
+			"nonce":   authorization.nonce,
+			"name":    "テストユーザー",
+			"picture": "image.png",
+		}
+		if s.claims != nil {
+			s.claims(claims)
+		}
+		idToken, _ := s.sign(claims)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "fake-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fake-access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":42,"login":"octocat","name":"","avatar_url":"avatar.png"}`))
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.jwksRequests++
+		s.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(dto.JSONWebKeySet{Keys: []dto.JSONWebKey{{
+			Kty: "RSA",
+			Kid: "fake-key",
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fakeOAuthServer) sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "fake-key"
+	return token.SignedString(s.key)
+}
+
+func (s *fakeOAuthServer) verifier() services.IDTokenVerifier {
+	return services.NewIDTokenVerifier([]string{s.URL}, "client-id", s.URL+"/jwks")
+}
+
+func (s *fakeOAuthServer) oauthConfig() *oauth2.Config {
+	return &oauth2.Config{
+		ClientID: "client-id",
+		Endpoint: oauth2.Endpoint{AuthURL: s.URL + "/authorize", TokenURL: s.URL + "/token"},
+	}
+}
+
+// authorize はユーザーがログインを許可したものとして、認可URLのcode_challengeとnonceに対応する認可コードを発行します。
+func (s *fakeOAuthServer) authorize(t *testing.T, authURL string, code string) string {
+	u, err := url.Parse(authURL)
+	assert.NoError(t, err)
+	query := u.Query()
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.NotEmpty(t, query.Get("nonce"))
+
+	s.mu.Lock()
+	s.authorizations[code] = fakeAuthorization{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	s.mu.Unlock()
+	return query.Get("state")
+}
+
+func TestAuthProviderFlow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	server := newFakeOAuthServer(t)
+	repo := new(MockAuthUserRepository)
+	repo.On("UpdateOrCreateUser", dto.ExternalUserInfo{Provider: "google", Subject: "subject-1", Name: "テストユーザー", Picture: "image.png"}).Return(models.User{ID: 1, Name: "テストユーザー"}, nil)
+	repo.On("UpdateOrCreateUser", dto.ExternalUserInfo{Provider: "github", Subject: "42", Name: "octocat", Picture: "avatar.png"}).Return(models.User{ID: 2, Name: "octocat"}, nil)
+
+	registry := services.NewAuthProviderRegistry(
+		services.NewOIDCProvider("google", server.oauthConfig(), newMemoryOAuthStateStore(), server.verifier()),
+		services.NewGitHubProvider("github", server.oauthConfig(), newMemoryOAuthStateStore(), server.URL+"/user"),
+	)
+	controller := controllers.NewAuthProviderController(services.NewAuthProviderService(registry, repo), newTestJWTService(t))
+
+	router := gin.New()
+	router.GET("/auth/:provider/login", controller.LoginHandler)
+	router.POST("/auth/:provider/process", controller.ProcessAuthCode)
+
+	// cookies はログインを開始したブラウザのクッキー
+	var cookies []*http.Cookie
+	loginAs := func(provider string) string {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/auth/"+provider+"/login", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		cookies = w.Result().Cookies()
+
+		var resp struct {
+			Data struct {
+				URL string `json:"url"`
+			} `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Data.URL
+	}
+	processAs := func(provider string, body map[string]string) int {
+		payload, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/auth/"+provider+"/process", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	login := func() string { return loginAs("google") }
+	process := func(body map[string]string) int { return processAs("google", body) }
+
+	t.Run("Success and state is single use", func(t *testing.T) {
+		state := server.authorize(t, login(), "code-1")
+		assert.Equal(t, http.StatusOK, process(map[string]string{"authCode": "code-1", "state": state}))
+		assert.Equal(t, http.StatusBadRequest, process(map[string]string{"authCode": "code-1", "state": state}))
+	})
+
+	t.Run("State cookie", func(t *testing.T) {
+		login()
+		cookie := cookies[0]
+		assert.Equal(t, "oauthstate", cookie.Name)
+		assert.True(t, cookie.HttpOnly)
+		assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+	})
+
+	// 攻撃者が開始したログインのstateと認可コードを、別のブラウザから送信させてもログインできない
+	t.Run("State from another browser", func(t *testing.T) {
+		state := server.authorize(t, login(), "code-9")
+		login()
+		assert.Equal(t, http.StatusBadRequest, process(map[string]string{"authCode": "code-9", "state": state}))
+		cookies = nil
+		assert.Equal(t, http.StatusBadRequest, process(map[string]string{"authCode": "code-9", "state": state}))
+	})
+
+	t.Run("Missing state", func(t *testing.T) {
+		server.authorize(t, login(), "code-2")
+		assert.Equal(t, http.StatusBadRequest, process(map[string]string{"authCode": "code-2"}))
+	})
+
+	t.Run("Unknown state", func(t *testing.T) {
+		server.authorize(t, login(), "code-3")
+		assert.Equal(t, http.StatusBadRequest, process(map[string]string{"authCode": "code-3", "state": "forged"}))
+	})
+
+	t.Run("Code issued for another login", func(t *testing.T) {
+		server.authorize(t, login(), "code-4")
+		state := server.authorize(t, login(), "code-5")
+		assert.Equal(t, http.StatusBadRequest, process(map[string]string{"authCode": "code-4", "state": state}))
+	})
+
+	t.Run("Missing auth code", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, process(map[string]string{"state": "state"}))
+	})
+
+	t.Run("Invalid id_token", func(t *testing.T) {
+		server.claims = func(claims jwt.MapClaims) { claims["nonce"] = "replayed" }
+		defer func() { server.claims = nil }()
+
+		state := server.authorize(t, login(), "code-6")
+		assert.Equal(t, http.StatusUnauthorized, process(map[string]string{"authCode": "code-6", "state": state}))
+	})
+
+	t.Run("GitHub", func(t *testing.T) {
+		state := server.authorize(t, loginAs("github"), "code-7")
+		assert.Equal(t, http.StatusOK, processAs("github", map[string]string{"authCode": "code-7", "state": state}))
+	})
+
+	t.Run("State issued for another provider", func(t *testing.T) {
+		state := server.authorize(t, loginAs("github"), "code-8")
+		assert.Equal(t, http.StatusBadRequest, process(map[string]string{"authCode": "code-8", "state": state}))
+	})
+
+	t.Run("Unknown provider", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/auth/unknown/login", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	repo.AssertNumberOfCalls(t, "UpdateOrCreateUser", 2)
+}
+
+func TestLoadAuthProviderConfigs(t *testing.T) {
+	t.Run("Environment", func(t *testing.T) {
+		t.Setenv("AUTH_PROVIDERS", "google, github")
+		t.Setenv("GOOGLE_CLIENT_ID", "google-client")
+
+		configs, err := services.LoadAuthProviderConfigs()
+		assert.NoError(t, err)
+		assert.Len(t, configs, 2)
+		assert.Equal(t, "google-client", configs[0].ClientID)
+		assert.Equal(t, services.AuthProviderTypeGitHub, configs[1].Type)
+	})
+
+	t.Run("Unknown provider", func(t *testing.T) {
+		t.Setenv("AUTH_PROVIDERS", "unknown")
+
+		_, err := services.LoadAuthProviderConfigs()
+		assert.Error(t, err)
+	})
+
+	t.Run("File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "providers.json")
+		assert.NoError(t, os.WriteFile(path, []byte(`[
+			{"name": "line"},
+			{"name": "school", "type": "oidc", "client_id": "${SCHOOL_ID}", "auth_url": "https://idp.example.com/auth",
+			 "token_url": "https://idp.example.com/token", "issuers": ["https://idp.example.com"], "jwks_url": "https://idp.example.com/jwks"}
+		]`), 0600))
+		t.Setenv("AUTH_PROVIDERS_FILE", path)
+		t.Setenv("LINE_CLIENT_ID", "line-client")
+		t.Setenv("SCHOOL_ID", "school-client")
+
+		configs, err := services.LoadAuthProviderConfigs()
+		assert.NoError(t, err)
+		assert.Equal(t, "line-client", configs[0].ClientID)
+		assert.Equal(t, "https://api.line.me/oauth2/v2.1/certs", configs[0].JWKSURL)
+		assert.True(t, configs[0].IDTokenHS256)
+		assert.Equal(t, "school-client", configs[1].ClientID)
+
+		registry, err := services.NewAuthProviderRegistryFromConfigs(configs, func(string) services.OAuthStateStore {
+			return newMemoryOAuthStateStore()
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"line", "school"}, registry.Names())
+	})
+}
+
+func TestIDTokenVerifier(t *testing.T) {
+	server := newFakeOAuthServer(t)
+	verifier := server.verifier()
+
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   server.URL,
+			"sub":   "subject-1",
+			"aud":   "client-id",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"iat":   time.Now().Unix(),
+			"nonce": "nonce",
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(claims jwt.MapClaims)
+		valid  bool
+	}{
+		{"Valid", func(claims jwt.MapClaims) {}, true},
+		{"Expired", func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Hour).Unix() }, false},
+		{"Missing expiry", func(claims jwt.MapClaims) { delete(claims, "exp") }, false},
+		{"Wrong issuer", func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example.com" }, false},
+		{"Wrong audience", func(claims jwt.MapClaims) { claims["aud"] = "other-client" }, false},
+		{"Wrong nonce", func(claims jwt.MapClaims) { claims["nonce"] = "other" }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			tt.modify(claims)
+			idToken, err := server.sign(claims)
+			assert.NoError(t, err)
+
+			verified, err := verifier.Verify(idToken, "nonce")
+			if tt.valid {
+				assert.NoError(t, err)
+				assert.Equal(t, "subject-1", verified.Subject)
+			} else {
+				assert.ErrorIs(t, err, services.ErrInvalidIDToken)
+			}
+		})
+	}
+
+	t.Run("HS256 signed with public key", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+		token.Header["kid"] = "fake-key"
+		idToken, err := token.SignedString(server.key.N.Bytes())
+		assert.NoError(t, err)
+
+		_, err = verifier.Verify(idToken, "nonce")
+		assert.ErrorIs(t, err, services.ErrInvalidIDToken)
+	})
+
+	t.Run("JWKS is cached", func(t *testing.T) {
+		assert.Equal(t, 1, server.jwksRequests)
+	})
+
+	t.Run("LINE HS256", func(t *testing.T) {
+		lineVerifier := services.NewHS256IDTokenVerifier([]string{server.URL}, "client-id", server.URL+"/jwks", "channel-secret")
+		sign := func(claims jwt.MapClaims, secret string) string {
+			idToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+			assert.NoError(t, err)
+			return idToken
+		}
+
+		verified, err := lineVerifier.Verify(sign(validClaims(), "channel-secret"), "nonce")
+		assert.NoError(t, err)
+		assert.Equal(t, "subject-1", verified.Subject)
+
+		_, err = lineVerifier.Verify(sign(validClaims(), "other-secret"), "nonce")
+		assert.ErrorIs(t, err, services.ErrInvalidIDToken)
+		claims := validClaims()
+		claims["aud"] = "other-client"
+		_, err = lineVerifier.Verify(sign(claims, "channel-secret"), "nonce")
+		assert.ErrorIs(t, err, services.ErrInvalidIDToken)
+		claims = validClaims()
+		claims["iss"] = "https://evil.example.com"
+		_, err = lineVerifier.Verify(sign(claims, "channel-secret"), "nonce")
+		assert.ErrorIs(t, err, services.ErrInvalidIDToken)
+		_, err = lineVerifier.Verify(sign(validClaims(), "channel-secret"), "other")
+		assert.ErrorIs(t, err, services.ErrInvalidIDToken)
+
+		// RS256 のid_tokenも引き続き検証できる
+		idToken, err := server.sign(validClaims())
+		assert.NoError(t, err)
+		_, err = lineVerifier.Verify(idToken, "nonce")
+		assert.NoError(t, err)
+	})
+}
+
+func TestLinkIdentity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	server := newFakeOAuthServer(t)
+	repo := new(MockAuthUserRepository)
+	registry := services.NewAuthProviderRegistry(
+		services.NewGitHubProvider("github", server.oauthConfig(), newMemoryOAuthStateStore(), server.URL+"/user"),
+	)
+	service := services.NewAuthProviderService(registry, repo)
+	controller := controllers.NewAuthProviderController(service, newTestJWTService(t))
+
+	router := gin.New()
+	router.GET("/auth/:provider/login", controller.LoginHandler)
+	identities := router.Group("/auth/identities", func(ctx *gin.Context) { ctx.Set("userID", uint(1)) })
+	identities.POST(":provider", controller.LinkIdentity)
+	identities.DELETE(":identityID", controller.UnlinkIdentity)
+
+	// issue は userID のフローの認可URLを取得し、プロバイダで認可してstateを返します。userID が0の場合はログインのフローです。
+	issue := func(userID uint, code string) string {
+		url, err := service.LinkURL(userID, "github")
+		if userID == 0 {
+			url, _, err = service.AuthCodeURL("github")
+		}
+		assert.NoError(t, err)
+		return server.authorize(t, url, code)
+	}
+	post := func(code string, state string) int {
+
+		payload, _ := json.Marshal(map[string]string{"authCode": code, "state": state})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/auth/identities/github", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	link := func(code string) int {
+		return post(code, issue(1, code))
+	}
+	unlink := func(identityID string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodDelete, "/auth/identities/"+identityID, nil)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("Link", func(t *testing.T) {
+		repo.On("FindIdentity", "github", "42").Return(nil, gorm.ErrRecordNotFound).Once()
+		repo.On("CreateIdentity", &models.UserIdentity{UID: 1, Provider: "github", Subject: "42"}).Return(nil).Once()
+		assert.Equal(t, http.StatusOK, link("link-1"))
+	})
+
+	// 攻撃者が開始したフローのstateと認可コードを被害者に送信させても、被害者のアカウントに紐づけられない
+	t.Run("Login state", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, post("link-login", issue(0, "link-login")))
+	})
+
+	t.Run("Another user's state", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, post("link-other", issue(2, "link-other")))
+	})
+
+	t.Run("Linked to another user", func(t *testing.T) {
+		repo.On("FindIdentity", "github", "42").Return(&models.UserIdentity{ID: 5, UID: 2, Provider: "github", Subject: "42"}, nil).Once()
+		assert.Equal(t, http.StatusConflict, link("link-2"))
+	})
+
+	t.Run("Unlink last identity", func(t *testing.T) {
+		repo.On("CountIdentities", uint(1)).Return(int64(1), nil).Once()
+		assert.Equal(t, http.StatusConflict, unlink("5"))
+	})
+
+	t.Run("Unlink", func(t *testing.T) {
+		repo.On("CountIdentities", uint(1)).Return(int64(2), nil).Once()
+		repo.On("DeleteIdentity", uint(1), uint(5)).Return(true, nil).Once()
+		assert.Equal(t, http.StatusOK, unlink("5"))
+	})
+
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/authorization"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/tests/fakes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOwnershipEnforcement(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	jwtService := newTestJWTService(t)
+	token, _ := startTestSession(t, jwtService, 1)
+
+	// ユーザー1はクラス10の管理者、クラス11の一般ユーザー
+	classUserRepo := fakes.NewClassUserRepository(10, map[uint]models.Role{1: models.RoleAdmin, 2: models.RoleUser})
+	classUserRepo.Roles[11] = map[uint]models.Role{1: models.RoleUser, 2: models.RoleAdmin}
+	authz := services.NewAuthorizationService(classUserRepo, nil)
+
+	mockService := new(MockClassUserService)
+	mockService.On("ChangeUserRole", uint(1), uint(2), uint(10), models.RoleAdmin).Return(nil)
+	classUserController := controllers.NewClassUserController(mockService)
+
+	ok := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+
+	// main.go と同じパスとミドルウェアの構成で、クラスの権限は authorization.Routes の規則で確認する
+	router := gin.New()
+	cl := router.Group("/api/gin/cl", middlewares.TokenAuthMiddleware(jwtService), middlewares.ClassPermissionMiddleware(authz))
+	cl.DELETE(":uid/:cid", middlewares.OwnershipMiddleware("uid"), ok)
+	u := router.Group("/api/gin/u", middlewares.TokenAuthMiddleware(jwtService))
+	u.DELETE(":userID/delete", middlewares.OwnershipMiddleware("userID"), ok)
+	chat := router.Group("/api/gin/chat", middlewares.TokenAuthMiddleware(jwtService), middlewares.ClassPermissionMiddleware(authz))
+	chat.POST("dm/:senderId/:receiverId", middlewares.OwnershipMiddleware("senderId"), ok)
+	chat.GET("dm/:senderId/:receiverId", middlewares.OwnershipMiddleware("senderId", "receiverId"), ok)
+	cc := router.Group("/api/gin/cc", middlewares.TokenAuthMiddleware(jwtService))
+	cc.GET("verifyClassCode", middlewares.OwnershipMiddleware("uid"), ok)
+	cu := router.Group("/api/gin/cu", middlewares.TokenAuthMiddleware(jwtService), middlewares.ClassPermissionMiddleware(authz))
+	cu.PATCH(":uid/:cid/role/:roleName", classUserController.ChangeUserRole)
+
+	for _, route := range router.Routes() {
+		if strings.HasPrefix(route.Path, "/api/gin/u/") || strings.HasPrefix(route.Path, "/api/gin/cc/") {
+			continue
+		}
+		assert.Contains(t, authorization.Routes, route.Method+" "+route.Path)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{"Delete own class", http.MethodDelete, "/api/gin/cl/1/10", http.StatusOK},
+		{"Delete class as another user", http.MethodDelete, "/api/gin/cl/2/10", http.StatusForbidden},
+		{"Delete class without admin", http.MethodDelete, "/api/gin/cl/1/11", http.StatusForbidden},
+		{"Delete another user", http.MethodDelete, "/api/gin/u/2/delete", http.StatusForbidden},
+		{"Send DM as another sender", http.MethodPost, "/api/gin/chat/dm/2/1", http.StatusForbidden},
+		{"Read DM as receiver", http.MethodGet, "/api/gin/chat/dm/2/1", http.StatusOK},
+		{"Read DM of other users", http.MethodGet, "/api/gin/chat/dm/2/3", http.StatusForbidden},
+		{"Verify class code as another user", http.MethodGet, "/api/gin/cc/verifyClassCode?uid=2", http.StatusForbidden},
+		{"Invalid user ID", http.MethodDelete, "/api/gin/cl/abc/10", http.StatusBadRequest},
+		{"Change role as admin", http.MethodPatch, "/api/gin/cu/2/10/role/ADMIN", http.StatusOK},
+		{"Change role without admin", http.MethodPatch, "/api/gin/cu/2/11/role/ADMIN", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/pagination"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursor(t *testing.T) {
+	type key struct {
+		ID uint `json:"id"`
+	}
+	cursor := pagination.Encode("user_classes:1", key{ID: 42})
+
+	var decoded key
+	assert.NoError(t, pagination.Decode("user_classes:1", cursor, &decoded))
+	assert.Equal(t, uint(42), decoded.ID)
+
+	body, signature, _ := strings.Cut(cursor, ".")
+	tampered := pagination.Encode("user_classes:1", key{ID: 43})
+	tamperedBody, _, _ := strings.Cut(tampered, ".")
+	tests := []struct {
+		name   string
+		scope  string
+		cursor string
+	}{
+		{"Other scope", "user_classes:2", cursor},
+		{"Tampered body", "user_classes:1", tamperedBody + "." + signature},
+		{"No signature", "user_classes:1", body},
+		{"Garbage", "user_classes:1", "not-a-cursor"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, pagination.Decode(tt.scope, tt.cursor, &decoded), pagination.ErrInvalidCursor)
+		})
+	}
+}
+
+func TestWindow(t *testing.T) {
+	window, err := pagination.NewWindow("boards", pagination.Query{Page: 3, Limit: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, pagination.Window{Offset: 10, Limit: 6}, window)
+
+	window, err = pagination.NewWindow("boards", pagination.Query{Limit: 1000})
+	assert.NoError(t, err)
+	assert.Equal(t, pagination.MaxLimit+1, window.Limit)
+
+	page := pagination.NewPage("boards", pagination.Window{Limit: 3}, []uint{9, 8, 7}, func(id uint) uint { return id })
+	assert.Equal(t, []uint{9, 8}, page.Items)
+	assert.True(t, page.HasMore)
+
+	// cursor がある場合は page を無視する
+	window, err = pagination.NewWindow("boards", pagination.Query{Cursor: *page.NextCursor, Page: 3, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, pagination.Window{AfterID: 8, Limit: 3}, window)
+
+	_, err = pagination.NewWindow("other", pagination.Query{Cursor: *page.NextCursor})
+	assert.ErrorIs(t, err, pagination.ErrInvalidCursor)
+
+	page = pagination.NewPage("boards", pagination.Window{Limit: 3}, []uint(nil), func(id uint) uint { return id })
+	assert.Equal(t, []uint{}, page.Items)
+	assert.False(t, page.HasMore)
+	assert.Nil(t, page.NextCursor)
+}
+
+// memoryUserClassRepository はクラスIDの昇順で window を適用するテスト用のClassUserRepository
+type memoryUserClassRepository struct {
+	repositories.ClassUserRepository
+	classes []dto.UserClassInfoDTO
+}
+
+func (r *memoryUserClassRepository) GetUserClasses(uid uint, window pagination.Window) ([]dto.UserClassInfoDTO, error) {
+	var result []dto.UserClassInfoDTO
+	for i, class := range r.classes {
+		if class.ID <= window.AfterID || (window.AfterID == 0 && i < window.Offset) {
+			continue
+		}
+		if len(result) == window.Limit {
+			break
+		}
+		result = append(result, class)
+	}
+	return result, nil
+}
+
+func TestGetUserClassesPages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	repo := &memoryUserClassRepository{classes: []dto.UserClassInfoDTO{{ID: 2}, {ID: 3}, {ID: 5}, {ID: 8}, {ID: 13}}}
+	controller := controllers.NewClassUserController(services.NewClassUserService(repo, nil))
+	router := gin.New()
+	router.GET("/cu/:uid/classes", controller.GetUserClasses)
+
+	list := func(path string) (int, pagination.Page[dto.UserClassInfoDTO]) {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var response struct {
+			Data pagination.Page[dto.UserClassInfoDTO] `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response.Data
+	}
+
+	var ids []uint
+	path := "/cu/1/classes?limit=2"
+	for {
+		status, page := list(path)
+		assert.Equal(t, http.StatusOK, status)
+		for _, class := range page.Items {
+			ids = append(ids, class.ID)
+		}
+		if !page.HasMore {
+			break
+		}
+		path = "/cu/1/classes?limit=2&cursor=" + *page.NextCursor
+	}
+	assert.Equal(t, []uint{2, 3, 5, 8, 13}, ids)
+
+	// 従来の page と limit も使用できる
+	status, page := list("/cu/1/classes?page=2&limit=2")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []dto.UserClassInfoDTO{{ID: 5}, {ID: 8}}, page.Items)
+	assert.True(t, page.HasMore)
+
+	// 他のユーザーの一覧のカーソルは使用できない
+	_, first := list("/cu/1/classes?limit=2")
+	status, _ = list("/cu/2/classes?cursor=" + *first.NextCursor)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = list("/cu/1/classes?page=0")
+	assert.Equal(t, http.StatusBadRequest, status)
+}
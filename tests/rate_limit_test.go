@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockScripter struct {
+	mock.Mock
+}
+
+func (m *MockScripter) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	called := m.Called(keys, args)
+	return redis.NewCmdResult(called.Get(0), called.Error(1))
+}
+
+func (m *MockScripter) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	called := m.Called(keys, args)
+	return redis.NewCmdResult(called.Get(0), called.Error(1))
+}
+
+func (m *MockScripter) ScriptExists(ctx context.Context, hashes ...string) *redis.BoolSliceCmd {
+	return redis.NewBoolSliceResult([]bool{true}, nil)
+}
+
+func (m *MockScripter) ScriptLoad(ctx context.Context, script string) *redis.StringCmd {
+	return redis.NewStringResult("", nil)
+}
+
+// memoryRateLimiter はテスト用の固定ウィンドウのRateLimiter
+type memoryRateLimiter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (l *memoryRateLimiter) Allow(key string, limit services.RateLimit) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.counts[key] >= limit.Limit {
+		return false, limit.Window, nil
+	}
+	l.counts[key]++
+	return true, 0, nil
+}
+
+func TestRedisRateLimiter(t *testing.T) {
+	limit := services.RateLimit{Limit: 5, Window: time.Minute}
+	windowArg := mock.MatchedBy(func(args []interface{}) bool {
+		return len(args) == 4 && args[1] == int64(60000) && args[2] == 5
+	})
+
+	scripter := new(MockScripter)
+	scripter.On("EvalSha", []string{"rate_limit:class_code:ABC123"}, windowArg).Return([]interface{}{int64(1), int64(0)}, nil).Once()
+	scripter.On("EvalSha", []string{"rate_limit:class_code:ABC123"}, windowArg).Return([]interface{}{int64(0), int64(1500)}, nil).Once()
+	scripter.On("EvalSha", []string{"rate_limit:class_code:ABC123"}, windowArg).Return(nil, errors.New("connection refused")).Once()
+	limiter := services.NewRedisRateLimiter(scripter)
+
+	allowed, _, err := limiter.Allow("class_code:ABC123", limit)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, retryAfter, err := limiter.Allow("class_code:ABC123", limit)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 1500*time.Millisecond, retryAfter)
+
+	_, _, err = limiter.Allow("class_code:ABC123", limit)
+	assert.Error(t, err)
+	scripter.AssertExpectations(t)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := &memoryRateLimiter{counts: map[string]int{}}
+	limit := services.RateLimit{Limit: 2, Window: 90 * time.Second}
+	router := gin.New()
+	router.GET("/cc/verifyClassCode",
+		middlewares.RateLimitMiddleware(limiter, "class_code", limit, middlewares.RateLimitByQuery("code")),
+		func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	request := func(code string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, "/cc/verifyClassCode?code="+code, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, request("ABC123").Code)
+	assert.Equal(t, http.StatusOK, request("ABC123").Code)
+
+	w := request("ABC123")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "90", w.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, request("XYZ789").Code)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, request("").Code)
+	}
+}
+
+func TestRateLimitByIPTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(t *testing.T) *gin.Engine {
+		router := gin.New()
+		assert.NoError(t, middlewares.ConfigureTrustedProxies(router))
+		limiter := &memoryRateLimiter{counts: map[string]int{}}
+		router.POST("/auth/google/process",
+			middlewares.RateLimitMiddleware(limiter, "auth_process", services.RateLimit{Limit: 2, Window: time.Minute}, middlewares.RateLimitByIP),
+			func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+		return router
+	}
+	request := func(router *gin.Engine, remoteAddr string, forwardedFor string) int {
+		req, _ := http.NewRequest(http.MethodPost, "/auth/google/process", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("Spoofed X-Forwarded-For", func(t *testing.T) {
+		t.Setenv("TRUSTED_PROXIES", "")
+		router := newRouter(t)
+
+		// X-Forwarded-For を変えても接続元のアドレスで制限される
+		assert.Equal(t, http.StatusOK, request(router, "203.0.113.1:1234", "198.51.100.1"))
+		assert.Equal(t, http.StatusOK, request(router, "203.0.113.1:1234", "198.51.100.2"))
+		assert.Equal(t, http.StatusTooManyRequests, request(router, "203.0.113.1:1234", "198.51.100.3"))
+	})
+
+	t.Run("Trusted proxy", func(t *testing.T) {
+		t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8")
+		router := newRouter(t)
+
+		assert.Equal(t, http.StatusOK, request(router, "10.0.0.1:1234", "198.51.100.1"))
+		assert.Equal(t, http.StatusOK, request(router, "10.0.0.1:1234", "198.51.100.1"))
+		assert.Equal(t, http.StatusTooManyRequests, request(router, "10.0.0.1:1234", "198.51.100.1"))
+		assert.Equal(t, http.StatusOK, request(router, "10.0.0.1:1234", "198.51.100.2"))
+		// 信頼しないプロキシからのヘッダーは使用しない
+		assert.Equal(t, http.StatusOK, request(router, "203.0.113.1:1234", "198.51.100.3"))
+		assert.Equal(t, http.StatusOK, request(router, "203.0.113.1:1234", "198.51.100.4"))
+		assert.Equal(t, http.StatusTooManyRequests, request(router, "203.0.113.1:1234", "198.51.100.5"))
+	})
+}
+
+func TestParseRateLimit(t *testing.T) {
+	limit, err := services.ParseRateLimit("10/1m")
+	assert.NoError(t, err)
+	assert.Equal(t, services.RateLimit{Limit: 10, Window: time.Minute}, limit)
+
+	for _, value := range []string{"10", "0/1m", "10/0s", "ten/1m"} {
+		_, err := services.ParseRateLimit(value)
+		assert.Error(t, err, value)
+	}
+}
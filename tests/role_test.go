@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/tests/fakes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRole(t *testing.T) {
+	role, err := models.ParseRole("ASSISTANT")
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleAssistant, role)
+
+	_, err = models.ParseRole("OWNER")
+	assert.ErrorIs(t, err, models.ErrInvalidRole)
+
+	value, err := models.RoleUser.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "USER", value)
+	_, err = models.Role("admin").Value()
+	assert.ErrorIs(t, err, models.ErrInvalidRole)
+
+	var scanned models.Role
+	assert.NoError(t, scanned.Scan([]byte("INVITE")))
+	assert.Equal(t, models.RoleInvite, scanned)
+	assert.ErrorIs(t, scanned.Scan("OWNER"), models.ErrInvalidRole)
+
+	var member struct {
+		Role models.Role `json:"role"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(`{"role":"BLACKLIST"}`), &member))
+	assert.Equal(t, models.RoleBlacklist, member.Role)
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"role":"OWNER"}`), &member), models.ErrInvalidRole)
+}
+
+func TestAssignUnknownRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	classUserService := services.NewClassUserService(fakes.NewClassUserRepository(10, map[uint]models.Role{1: models.RoleAdmin}), nil)
+	assert.ErrorIs(t, classUserService.AssignRole(1, 2, 10, "OWNER"), services.ErrInvalidRole)
+	assert.ErrorIs(t, classUserService.ChangeUserRole(1, 2, 10, "OWNER"), services.ErrInvalidRole)
+
+	router := gin.New()
+	router.PATCH("/cu/:uid/:cid/role/:roleName", func(ctx *gin.Context) { ctx.Set("userID", uint(1)) }, controllers.NewClassUserController(classUserService).ChangeUserRole)
+
+	req, _ := http.NewRequest(http.MethodPatch, "/cu/2/10/role/OWNER", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}